@@ -0,0 +1,206 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/whosafe/uf/uerror"
+)
+
+// EnvRoutes 与 uhttp.EnvRoutes 一致,设置后服务器只打印路由表不监听端口
+const EnvRoutes = "UF_ROUTES"
+
+// RoutesCommand 路由表命令
+type RoutesCommand struct {
+	json    bool
+	timeout time.Duration
+}
+
+// routeTable 服务器输出的路由表
+type routeTable struct {
+	Server  string      `json:"server"`
+	Address string      `json:"address"`
+	Routes  []routeInfo `json:"routes"`
+}
+
+// routeInfo 路由描述
+type routeInfo struct {
+	Method      string   `json:"method"`
+	Pattern     string   `json:"pattern"`
+	Name        string   `json:"name"`
+	Handler     string   `json:"handler"`
+	Middlewares []string `json:"middlewares"`
+	Group       string   `json:"group"`
+	Host        string   `json:"host"`
+}
+
+// HandleRoutes 处理 routes 命令
+func HandleRoutes(args []string) error {
+	cmd := &RoutesCommand{}
+
+	fs := flag.NewFlagSet("routes", flag.ExitOnError)
+	fs.BoolVar(&cmd.json, "json", false, "以 JSON 格式输出")
+	fs.DurationVar(&cmd.timeout, "timeout", time.Minute, "编译和运行的超时时间")
+
+	fs.Usage = func() {
+		fmt.Println(`用法: uf routes [选项] [项目目录]
+
+编译项目并以 UF_ROUTES=1 运行,服务器启动时打印路由表后退出 (不监听端口)。
+项目需在 main 中注册路由后调用 Server.Start,初始化阶段的数据库等依赖需可用。
+
+选项:
+  --json               以 JSON 格式输出 (每个服务器一行)
+  --timeout duration   编译和运行的超时时间 (默认: 1m)
+
+示例:
+  uf routes                  # 列出当前项目的路由
+  uf routes ./my-project     # 列出指定项目的路由
+  uf routes --json > routes.json`)
+	}
+
+	fs.Parse(args)
+
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+	return cmd.Run(dir)
+}
+
+// Run 执行路由表命令
+func (cmd *RoutesCommand) Run(dir string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cmd.timeout)
+	defer cancel()
+
+	tables, err := cmd.collect(ctx, dir)
+	if err != nil {
+		return err
+	}
+
+	if cmd.json {
+		enc := json.NewEncoder(os.Stdout)
+		for _, t := range tables {
+			if err := enc.Encode(t); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i, t := range tables {
+		if i > 0 {
+			fmt.Println()
+		}
+		printRouteTable(t)
+	}
+	return nil
+}
+
+// collect 编译并运行项目,收集输出中的路由表
+func (cmd *RoutesCommand) collect(ctx context.Context, dir string) ([]routeTable, error) {
+	tmpDir, err := os.MkdirTemp("", "uf-routes-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// 编译项目
+	bin := filepath.Join(tmpDir, "app")
+	build := exec.CommandContext(ctx, "go", "build", "-o", bin, ".")
+	build.Dir = dir
+	build.Stdout = os.Stderr
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		return nil, uerror.Wrap(err, "编译项目失败")
+	}
+
+	// 运行项目 (工作目录为项目目录,以便读取配置文件)
+	var stdout, stderr bytes.Buffer
+	run := exec.CommandContext(ctx, bin)
+	run.Dir = dir
+	run.Env = append(os.Environ(), EnvRoutes+"=1")
+	run.Stdout = &stdout
+	run.Stderr = &stderr
+	runErr := run.Run()
+
+	tables, err := parseRouteTables(stdout.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if len(tables) == 0 {
+		if ctx.Err() != nil {
+			return nil, uerror.New("运行项目超时,未获取到路由表")
+		}
+		if runErr != nil {
+			os.Stderr.Write(stderr.Bytes())
+			return nil, uerror.Wrap(runErr, "运行项目失败")
+		}
+		return nil, uerror.New("未获取到路由表,请确认项目使用 uhttp.Server 并在注册路由后调用 Start")
+	}
+	return tables, nil
+}
+
+// parseRouteTables 解析输出中以 {"server" 开头的行
+func parseRouteTables(output []byte) ([]routeTable, error) {
+	var tables []routeTable
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if !bytes.HasPrefix(line, []byte(`{"server"`)) {
+			continue
+		}
+		var t routeTable
+		if err := json.Unmarshal(line, &t); err != nil {
+			return nil, uerror.Wrap(err, "解析路由表失败")
+		}
+		tables = append(tables, t)
+	}
+	return tables, scanner.Err()
+}
+
+// printRouteTable 以表格形式打印路由表
+func printRouteTable(t routeTable) {
+	fmt.Printf("%s (%s) - %d 条路由\n\n", t.Server, t.Address, len(t.Routes))
+
+	// 存在虚拟主机时增加 HOST 列
+	withHost := false
+	for _, r := range t.Routes {
+		if r.Host != "" {
+			withHost = true
+			break
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	if withHost {
+		fmt.Fprint(tw, "HOST\t")
+	}
+	fmt.Fprintln(tw, "METHOD\tPATTERN\tNAME\tHANDLER\tMIDDLEWARES")
+	for _, r := range t.Routes {
+		if withHost {
+			fmt.Fprintf(tw, "%s\t", orDash(r.Host))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			r.Method, r.Pattern, orDash(r.Name), r.Handler, orDash(strings.Join(r.Middlewares, " > ")))
+	}
+	tw.Flush()
+}
+
+// orDash 空值显示为 "-"
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -32,6 +32,11 @@ func main() {
 		handleBuild()
 	case "run":
 		handleRun()
+	case "routes":
+		if err := cli.HandleRoutes(os.Args[2:]); err != nil {
+			fmt.Printf("错误: %v\n", err)
+			os.Exit(1)
+		}
 	case "up", "update":
 		handleUp()
 	case "version", "-v", "--version":
@@ -56,6 +61,7 @@ func printHelp() {
   gen         生成代码（handler/model/middleware/validator）
   build       编译项目
   run         运行开发服务器
+  routes      列出项目注册的 HTTP 路由
   up          更新框架依赖
   version     显示版本信息
   help        显示帮助信息
@@ -68,6 +74,7 @@ func printHelp() {
   uf gen handler User        # 生成 User Handler
   uf build                   # 编译项目
   uf run                     # 运行项目
+  uf routes                  # 列出路由表
 `)
 }
 
@@ -5,10 +5,9 @@ const HTTPCleanMain = `package main
 
 import (
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
+	"github.com/whosafe/uf/uapp"
 	"github.com/whosafe/uf/uconfig"
 	"github.com/whosafe/uf/ucontext"
 	"github.com/whosafe/uf/ulogger"
@@ -31,15 +30,12 @@ func main() {
 	// 创建并配置服务器
 	server := setupServer()
 
-	// 启动服务器
-	go func() {
-		if err := server.Start(); err != nil {
-			ulogger.Error("服务器启动失败", "error", err)
-		}
-	}()
-
-	// 优雅关闭
-	gracefulShutdown(server)
+	// 运行应用,收到 SIGINT/SIGTERM 后优雅关闭
+	app := uapp.New().Add(uapp.HTTP("http", server))
+	if err := app.Run(); err != nil {
+		ulogger.Error("应用运行失败", "error", err)
+		os.Exit(1)
+	}
 }
 
 // setupServer 创建并配置服务器
@@ -58,25 +54,6 @@ func registerRoutes(server *uhttp.Server) {
 	server.GET("/health", handleHealth)
 }
 
-// gracefulShutdown 优雅关闭服务器
-func gracefulShutdown(server *uhttp.Server) {
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	ulogger.Info("正在关闭服务器...")
-
-	ctx := ucontext.New()
-	timeoutCtx, cancel := ctx.WithTimeout(5 * time.Second)
-	defer cancel()
-
-	if err := server.Stop(timeoutCtx.Context()); err != nil {
-		ulogger.Error("服务器关闭失败", "error", err)
-	}
-
-	ulogger.Info("服务器已关闭")
-}
-
 // handleIndex 首页
 func handleIndex(ctx *ucontext.Context, req unet.Request) error {
 	return req.Response().JSON(200, map[string]string{
@@ -117,6 +94,10 @@ const HTTPCleanConfig = `server:
     enable_trace: true
     enable_logger: true
     enable_recovery: true
+
+app:
+  shutdown_timeout: 30s
+  drain_delay: 0s
 `
 
 // go.mod 模板
@@ -221,13 +202,11 @@ curl http://localhost:8080/health
 1. 加载配置文件
 2. 初始化雪花算法
 3. 创建并配置服务器
-4. 启动服务器
-5. 优雅关闭
+4. 通过 uapp 运行服务器并在收到 SIGINT/SIGTERM 后优雅关闭
 
 **辅助函数**：
 - **setupServer()**: 创建服务器并注册路由
 - **registerRoutes()**: 路由注册
-- **gracefulShutdown()**: 优雅关闭处理
 
 ## 配置文件
 
@@ -72,11 +72,9 @@ const HTTPExampleMain = `package main
 
 import (
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
 	"{{.ModulePath}}/internal/router"
+	"github.com/whosafe/uf/uapp"
 	"github.com/whosafe/uf/uconfig"
 	"github.com/whosafe/uf/ucontext"
 	"github.com/whosafe/uf/ulogger"
@@ -101,33 +99,11 @@ func main() {
 	// 注册路由
 	router.New(server)
 
-	// 启动服务器
-	go func() {
-		if err := server.Start(); err != nil {
-			ulogger.Error("服务器启动失败", "error", err)
-		}
-	}()
-
-	// 优雅关闭
-	gracefulShutdown(server)
-}
-
-// gracefulShutdown 优雅关闭服务器
-func gracefulShutdown(server *uhttp.Server) {
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	ulogger.Info("正在关闭服务器...")
-
-	ctx := ucontext.New()
-	timeoutCtx, cancel := ctx.WithTimeout(5 * time.Second)
-	defer cancel()
-
-	if err := server.Stop(timeoutCtx.Context()); err != nil {
-		ulogger.Error("服务器关闭失败", "error", err)
+	// 运行应用,收到 SIGINT/SIGTERM 后优雅关闭
+	app := uapp.New().Add(uapp.HTTP("http", server))
+	if err := app.Run(); err != nil {
+		ulogger.Error("应用运行失败", "error", err)
+		os.Exit(1)
 	}
-
-	ulogger.Info("服务器已关闭")
 }
 `
@@ -0,0 +1,309 @@
+package uapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uerror"
+	"github.com/whosafe/uf/ulogger"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// App 应用生命周期管理器
+// 按依赖顺序启动组件,收到 SIGINT/SIGTERM 或任一组件退出后标记为未就绪,
+// 等待 DrainDelay 后按相反顺序在 ShutdownTimeout 内停止组件
+type App struct {
+	config     *Config
+	logger     *ulogger.Logger
+	components []*Component
+
+	ready    atomic.Bool
+	stopping atomic.Bool
+
+	quit     chan struct{}
+	quitOnce sync.Once
+	quitErr  error // 触发关闭的组件错误
+}
+
+// running 已启动的组件
+type running struct {
+	component *Component
+	cancel    context.CancelFunc // 取消 Run 的 ctx
+	done      chan struct{}      // Run 返回后关闭
+}
+
+// New 使用全局配置创建应用
+func New() *App {
+	return NewWithConfig(GetConfig())
+}
+
+// NewWithConfig 使用指定配置创建应用
+func NewWithConfig(config *Config) *App {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &App{
+		config: config,
+		logger: ulogger.Default(),
+		quit:   make(chan struct{}),
+	}
+}
+
+// SetLogger 设置日志 Logger
+func (a *App) SetLogger(logger *ulogger.Logger) {
+	a.logger = logger
+}
+
+// Add 注册组件 (须在 Run 之前调用)
+func (a *App) Add(components ...*Component) *App {
+	a.components = append(a.components, components...)
+	return a
+}
+
+// Ready 是否就绪 (全部组件启动完成且未开始关闭)
+func (a *App) Ready() bool {
+	return a.ready.Load()
+}
+
+// Shutdown 触发关闭,Run 随后停止所有组件并返回
+func (a *App) Shutdown() {
+	a.trigger(nil)
+}
+
+// ReadyHandler 就绪检查处理器
+// 启动完成前和开始关闭后返回 503,负载均衡据此摘除流量
+func (a *App) ReadyHandler() unet.HandlerFunc {
+	return func(ctx *ucontext.Context, req unet.Request) error {
+		if a.ready.Load() {
+			return req.Response().JSON(200, map[string]string{"status": "ready"})
+		}
+		return req.Response().JSON(503, map[string]string{"status": "not_ready"})
+	}
+}
+
+// LiveHandler 存活检查处理器,进程可以处理请求即返回 200
+func (a *App) LiveHandler() unet.HandlerFunc {
+	return func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().JSON(200, map[string]string{"status": "alive"})
+	}
+}
+
+// Run 启动应用并阻塞到关闭完成
+// 返回组件启动错误、导致关闭的组件错误以及停止过程中的错误
+func (a *App) Run() error {
+	levels, err := a.order()
+	if err != nil {
+		return err
+	}
+
+	sig := make(chan os.Signal, 2)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	started, err := a.start(levels)
+	if err != nil {
+		a.logger.Error("应用启动失败", "error", err)
+		return errors.Join(err, a.shutdown(started, sig))
+	}
+
+	a.ready.Store(true)
+	a.logger.Info("应用已启动", "components", len(a.components))
+
+	select {
+	case s := <-sig:
+		a.logger.Info("收到关闭信号", "signal", s.String())
+	case <-a.quit:
+	}
+
+	stopErr := a.shutdown(started, sig)
+	a.trigger(nil)
+	return errors.Join(a.quitErr, stopErr)
+}
+
+// trigger 触发关闭 (只有第一次生效)
+func (a *App) trigger(err error) {
+	a.quitOnce.Do(func() {
+		a.quitErr = err
+		close(a.quit)
+	})
+}
+
+// start 按层级依次启动组件,返回已启动的组件
+func (a *App) start(levels [][]*Component) ([][]*running, error) {
+	started := make([][]*running, 0, len(levels))
+	for _, level := range levels {
+		group := make([]*running, 0, len(level))
+		for _, c := range level {
+			r, err := a.startComponent(c)
+			if err != nil {
+				return append(started, group), uerror.Wrap(err, "启动组件失败: "+c.Name)
+			}
+			group = append(group, r)
+		}
+		started = append(started, group)
+	}
+	return started, nil
+}
+
+// startComponent 执行启动钩子并在后台运行 Run
+func (a *App) startComponent(c *Component) (*running, error) {
+	if c.Start != nil {
+		ctx, cancel := context.Background(), context.CancelFunc(func() {})
+		if a.config.StartTimeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, a.config.StartTimeout)
+		}
+		err := c.Start(ctx)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r := &running{component: c}
+	if c.Run != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		r.cancel = cancel
+		r.done = make(chan struct{})
+		go a.run(ctx, r)
+	}
+
+	a.logger.Info("组件已启动", "component", c.Name)
+	return r, nil
+}
+
+// run 运行组件,关闭开始前退出时触发应用关闭
+func (a *App) run(ctx context.Context, r *running) {
+	defer close(r.done)
+
+	c := r.component
+	err := func() (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("panic: %v", p)
+			}
+		}()
+		return c.Run(ctx)
+	}()
+
+	if a.stopping.Load() {
+		return
+	}
+	if err != nil {
+		a.logger.Error("组件异常退出", "component", c.Name, "error", err)
+		a.trigger(uerror.Wrap(err, "组件异常退出: "+c.Name))
+		return
+	}
+	a.logger.Info("组件已退出", "component", c.Name)
+	a.trigger(nil)
+}
+
+// shutdown 标记为未就绪,等待摘除流量后停止组件
+// 关闭过程中再次收到信号时立即放弃等待
+func (a *App) shutdown(started [][]*running, sig <-chan os.Signal) error {
+	a.stopping.Store(true)
+	a.ready.Store(false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case s := <-sig:
+			a.logger.Warn("再次收到关闭信号,强制关闭", "signal", s.String())
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if a.config.DrainDelay > 0 {
+		a.logger.Info("已标记为未就绪,等待摘除流量", "delay", a.config.DrainDelay.String())
+		select {
+		case <-time.After(a.config.DrainDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	a.logger.Info("正在关闭应用...")
+	stopCtx := ctx
+	if a.config.ShutdownTimeout > 0 {
+		var stopCancel context.CancelFunc
+		stopCtx, stopCancel = context.WithTimeout(ctx, a.config.ShutdownTimeout)
+		defer stopCancel()
+	}
+
+	err := a.stop(stopCtx, started)
+	if err != nil {
+		a.logger.Error("应用关闭出错", "error", err)
+	} else {
+		a.logger.Info("应用已关闭")
+	}
+	return err
+}
+
+// stop 按层级逆序停止组件,同一层级并行停止
+func (a *App) stop(ctx context.Context, started [][]*running) error {
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		group := started[i]
+		results := make([]error, len(group))
+
+		var wg sync.WaitGroup
+		for j, r := range group {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[j] = a.stopComponent(ctx, r)
+			}()
+		}
+		wg.Wait()
+
+		for _, err := range results {
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stopComponent 取消 Run、执行停止钩子并等待 Run 返回
+func (a *App) stopComponent(ctx context.Context, r *running) error {
+	c := r.component
+	if c.StopTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.StopTimeout)
+		defer cancel()
+	}
+
+	if r.cancel != nil {
+		r.cancel()
+	}
+
+	var err error
+	if c.Stop != nil {
+		err = c.Stop(ctx)
+	}
+	if r.done != nil {
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			if err == nil {
+				err = ctx.Err()
+			}
+		}
+	}
+
+	if err != nil {
+		a.logger.Error("组件停止失败", "component", c.Name, "error", err)
+		return uerror.Wrap(err, "停止组件失败: "+c.Name)
+	}
+	a.logger.Info("组件已停止", "component", c.Name)
+	return nil
+}
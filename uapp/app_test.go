@@ -0,0 +1,272 @@
+package uapp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/whosafe/uf/uprotocol/uhttp"
+)
+
+// recorder 记录组件启动和停止顺序
+type recorder struct {
+	events []string
+	mu     sync.Mutex
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.events)
+}
+
+// component 创建记录启动和停止的组件
+func (r *recorder) component(name string, deps ...string) *Component {
+	return &Component{
+		Name:      name,
+		DependsOn: deps,
+		Start: func(ctx context.Context) error {
+			r.add("start " + name)
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			r.add("stop " + name)
+			return nil
+		},
+	}
+}
+
+// runApp 在后台运行应用
+func runApp(app *App) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- app.Run() }()
+	return done
+}
+
+// waitReady 等待应用就绪
+func waitReady(t *testing.T, app *App) {
+	t.Helper()
+	for i := 0; i < 200; i++ {
+		if app.Ready() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("app not ready")
+}
+
+// TestOrder 测试依赖排序
+func TestOrder(t *testing.T) {
+	r := &recorder{}
+	app := NewWithConfig(nil)
+	app.Add(
+		r.component("http", "db", "cache"),
+		r.component("db"),
+		r.component("cache"),
+		Logger("logger", nil),
+	)
+
+	levels, err := app.order()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got [][]string
+	for _, level := range levels {
+		var names []string
+		for _, c := range level {
+			names = append(names, c.Name)
+		}
+		got = append(got, names)
+	}
+	want := [][]string{{"logger"}, {"db", "cache"}, {"http"}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("Expected levels %v, got %v", want, got)
+	}
+
+	tests := []struct {
+		components []*Component
+		want       string
+	}{
+		{[]*Component{r.component("a", "b"), r.component("b", "a")}, "循环依赖"},
+		{[]*Component{r.component("a", "missing")}, "不存在"},
+		{[]*Component{r.component("a"), r.component("a")}, "重复"},
+		{[]*Component{r.component("")}, "不能为空"},
+	}
+	for _, tt := range tests {
+		_, err := NewWithConfig(nil).Add(tt.components...).order()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected error containing %q, got %v", tt.want, err)
+		}
+	}
+}
+
+// TestRunLifecycle 测试按依赖启动、逆序停止以及关闭前标记未就绪
+func TestRunLifecycle(t *testing.T) {
+	r := &recorder{}
+	app := NewWithConfig(&Config{ShutdownTimeout: time.Second, DrainDelay: 20 * time.Millisecond})
+
+	readyOnStop := true
+	server := r.component("server", "db")
+	server.Stop = func(ctx context.Context) error {
+		readyOnStop = app.Ready()
+		r.add("stop server")
+		return nil
+	}
+	app.Add(server, r.component("db"))
+
+	done := runApp(app)
+	waitReady(t, app)
+	app.Shutdown()
+
+	if err := <-done; err != nil {
+		t.Fatalf("Run returned %v", err)
+	}
+	want := []string{"start db", "start server", "stop server", "stop db"}
+	if got := r.list(); !slices.Equal(got, want) {
+		t.Errorf("Expected events %v, got %v", want, got)
+	}
+	if readyOnStop {
+		t.Error("Expected app to be not ready before stopping components")
+	}
+}
+
+// TestStartFailure 测试启动失败时停止已启动的组件
+func TestStartFailure(t *testing.T) {
+	r := &recorder{}
+	broken := r.component("cache", "db")
+	broken.Start = func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}
+
+	app := NewWithConfig(nil).Add(r.component("db"), broken, r.component("server", "cache"))
+	err := app.Run()
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("Expected start error, got %v", err)
+	}
+
+	want := []string{"start db", "stop db"}
+	if got := r.list(); !slices.Equal(got, want) {
+		t.Errorf("Expected events %v, got %v", want, got)
+	}
+}
+
+// TestWorkerExit 测试组件异常退出触发关闭,其他 Worker 的 ctx 被取消
+func TestWorkerExit(t *testing.T) {
+	cancelled := make(chan struct{})
+	app := NewWithConfig(nil).Add(
+		Worker("consumer", func(ctx context.Context) error {
+			<-ctx.Done()
+			close(cancelled)
+			return nil
+		}),
+		Worker("broken", func(ctx context.Context) error {
+			return errors.New("queue closed")
+		}),
+	)
+
+	select {
+	case err := <-runApp(app):
+		if err == nil || !strings.Contains(err.Error(), "queue closed") {
+			t.Errorf("Expected worker error, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("app did not shut down")
+	}
+
+	select {
+	case <-cancelled:
+	default:
+		t.Error("Expected consumer ctx to be cancelled")
+	}
+}
+
+// TestShutdownTimeout 测试关闭超时
+func TestShutdownTimeout(t *testing.T) {
+	app := NewWithConfig(&Config{ShutdownTimeout: 50 * time.Millisecond})
+	app.Add(&Component{
+		Name: "stuck",
+		Stop: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+
+	done := runApp(app)
+	waitReady(t, app)
+
+	start := time.Now()
+	app.Shutdown()
+	err := <-done
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Shutdown took %s", elapsed)
+	}
+}
+
+// TestHTTPComponent 测试 HTTP 服务器组件与就绪检查
+func TestHTTPComponent(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	cfg := uhttp.DefaultConfig()
+	cfg.Address = addr
+	server := uhttp.NewWithConfig(cfg)
+
+	app := NewWithConfig(nil)
+	server.GET("/ready", app.ReadyHandler())
+	app.Add(HTTP("http", server))
+
+	// 启动前未就绪
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/ready", nil))
+	if w.Code != 503 {
+		t.Errorf("Expected status 503 before start, got %d", w.Code)
+	}
+
+	done := runApp(app)
+	waitReady(t, app)
+
+	var resp *http.Response
+	for i := 0; i < 100; i++ {
+		if resp, err = http.Get("http://" + addr + "/ready"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	app.Shutdown()
+	if err := <-done; err != nil {
+		t.Errorf("Run returned %v", err)
+	}
+	if _, err := http.Get("http://" + addr + "/ready"); err == nil {
+		t.Error("Expected server to be stopped")
+	}
+}
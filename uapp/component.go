@@ -0,0 +1,128 @@
+package uapp
+
+import (
+	"context"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/udb/postgresql"
+	"github.com/whosafe/uf/udb/redis"
+	"github.com/whosafe/uf/ulogger"
+	"github.com/whosafe/uf/uprotocol/uhttp"
+)
+
+// Component 应用组件
+// 启动顺序:依赖先于自身;停止顺序与启动相反,同一层级的组件并行停止
+type Component struct {
+	// Name 组件名称 (应用内唯一)
+	Name string
+
+	// DependsOn 依赖的组件名称
+	DependsOn []string
+
+	// Start 启动钩子,返回后视为启动完成 (可选)
+	Start func(ctx context.Context) error
+
+	// Run 阻塞运行,在 Start 之后于独立 goroutine 中执行 (可选)
+	// 关闭时 ctx 被取消;关闭前返回 (无论是否出错) 都会触发应用关闭
+	Run func(ctx context.Context) error
+
+	// Stop 停止钩子 (可选),停止后会等待 Run 返回
+	Stop func(ctx context.Context) error
+
+	// StopTimeout 单个组件的停止超时,0 表示只受整体关闭超时限制
+	StopTimeout time.Duration
+
+	// final 最先启动、最后停止 (日志等基础组件)
+	final bool
+}
+
+// After 声明依赖,返回组件本身便于链式调用
+func (c *Component) After(names ...string) *Component {
+	c.DependsOn = append(c.DependsOn, names...)
+	return c
+}
+
+// WithStopTimeout 设置停止超时
+func (c *Component) WithStopTimeout(timeout time.Duration) *Component {
+	c.StopTimeout = timeout
+	return c
+}
+
+// NetServer 以地址启动的服务器 (uquic、uudp、ujsonrpc 等)
+type NetServer interface {
+	Start(addr string) error
+	Stop(ctx context.Context) error
+}
+
+// HTTP HTTP 服务器组件
+// 停止时优雅关闭,等待进行中的请求完成
+func HTTP(name string, server *uhttp.Server) *Component {
+	return &Component{
+		Name: name,
+		Run: func(ctx context.Context) error {
+			return server.Start()
+		},
+		Stop: server.Stop,
+	}
+}
+
+// Server 协议服务器组件,addr 为空时使用服务器自身配置的地址
+func Server(name string, server NetServer, addr string) *Component {
+	return &Component{
+		Name: name,
+		Run: func(ctx context.Context) error {
+			return server.Start(addr)
+		},
+		Stop: server.Stop,
+	}
+}
+
+// PostgreSQL 数据库连接池组件
+// 启动时执行健康检查,停止时关闭连接池
+func PostgreSQL(name string, conn *postgresql.Connection) *Component {
+	return &Component{
+		Name:  name,
+		Start: conn.Ping,
+		Stop: func(ctx context.Context) error {
+			conn.Close()
+			return nil
+		},
+	}
+}
+
+// Redis Redis 连接组件
+// 启动时执行健康检查,停止时关闭连接
+func Redis(name string, conn *redis.Connection) *Component {
+	return &Component{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			return conn.Ping(ucontext.NewWithContext(ctx))
+		},
+		Stop: func(ctx context.Context) error {
+			return conn.Close()
+		},
+	}
+}
+
+// Logger 日志组件
+// 最先启动、最后停止,其他组件无需声明对它的依赖;停止时刷盘并关闭日志文件
+func Logger(name string, logger *ulogger.Logger) *Component {
+	return &Component{
+		Name: name,
+		Stop: func(ctx context.Context) error {
+			logger.Sync()
+			return logger.Close()
+		},
+		final: true,
+	}
+}
+
+// Worker 后台任务组件
+// fn 应阻塞运行直到 ctx 被取消
+func Worker(name string, fn func(ctx context.Context) error) *Component {
+	return &Component{
+		Name: name,
+		Run:  fn,
+	}
+}
@@ -0,0 +1,25 @@
+package uapp
+
+import "time"
+
+// Config 应用生命周期配置
+type Config struct {
+	// StartTimeout 单个组件启动钩子的超时时间
+	StartTimeout time.Duration
+
+	// ShutdownTimeout 关闭所有组件的最长时间 (不含 DrainDelay)
+	ShutdownTimeout time.Duration
+
+	// DrainDelay 标记为未就绪后、开始停止组件前的等待时间
+	// 留给负载均衡和服务发现摘除流量,0 表示立即停止
+	DrainDelay time.Duration
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		StartTimeout:    30 * time.Second,
+		ShutdownTimeout: 30 * time.Second,
+		DrainDelay:      0,
+	}
+}
@@ -0,0 +1,40 @@
+package uapp
+
+import (
+	"time"
+
+	"github.com/whosafe/uf/uconfig"
+)
+
+// globalConfig 全局配置
+var globalConfig *Config
+
+// init 自动注册配置回调
+func init() {
+	globalConfig = DefaultConfig()
+	uconfig.Register("app", globalConfig.UnmarshalYAML)
+}
+
+// GetConfig 获取全局配置
+func GetConfig() *Config {
+	return globalConfig
+}
+
+// UnmarshalYAML 实现 uconfig.Unmarshaler 接口
+func (c *Config) UnmarshalYAML(key string, node *uconfig.Node) error {
+	switch key {
+	case "start_timeout":
+		if d, err := time.ParseDuration(node.String()); err == nil {
+			c.StartTimeout = d
+		}
+	case "shutdown_timeout":
+		if d, err := time.ParseDuration(node.String()); err == nil {
+			c.ShutdownTimeout = d
+		}
+	case "drain_delay":
+		if d, err := time.ParseDuration(node.String()); err == nil {
+			c.DrainDelay = d
+		}
+	}
+	return nil
+}
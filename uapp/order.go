@@ -0,0 +1,88 @@
+package uapp
+
+import "github.com/whosafe/uf/uerror"
+
+// order 校验组件并按依赖划分层级
+// 每个组件的层级大于其所有依赖的层级;final 组件 (日志) 位于所有其他组件之前,
+// 同一层级内保持注册顺序
+func (a *App) order() ([][]*Component, error) {
+	byName := make(map[string]*Component, len(a.components))
+	for _, c := range a.components {
+		if c.Name == "" {
+			return nil, uerror.New("组件名称不能为空")
+		}
+		if _, ok := byName[c.Name]; ok {
+			return nil, uerror.New("组件名称重复: " + c.Name)
+		}
+		byName[c.Name] = c
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[*Component]int, len(a.components))
+	level := make(map[*Component]int, len(a.components))
+	base := 0 // 非 final 组件的最低层级
+
+	var visit func(c *Component) error
+	visit = func(c *Component) error {
+		switch state[c] {
+		case visiting:
+			return uerror.New("组件存在循环依赖: " + c.Name)
+		case visited:
+			return nil
+		}
+		state[c] = visiting
+
+		l := 0
+		if !c.final {
+			l = base
+		}
+		for _, name := range c.DependsOn {
+			dep, ok := byName[name]
+			if !ok {
+				return uerror.New("组件 " + c.Name + " 依赖的组件不存在: " + name)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+			l = max(l, level[dep]+1)
+		}
+
+		level[c] = l
+		state[c] = visited
+		return nil
+	}
+
+	for _, c := range a.components {
+		if c.final {
+			if err := visit(c); err != nil {
+				return nil, err
+			}
+			base = max(base, level[c]+1)
+		}
+	}
+	for _, c := range a.components {
+		if err := visit(c); err != nil {
+			return nil, err
+		}
+	}
+
+	var levels [][]*Component
+	for _, c := range a.components {
+		for len(levels) <= level[c] {
+			levels = append(levels, nil)
+		}
+		levels[level[c]] = append(levels[level[c]], c)
+	}
+
+	// 去掉空层级
+	result := levels[:0]
+	for _, l := range levels {
+		if len(l) > 0 {
+			result = append(result, l)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,41 @@
+//go:build unix
+
+package uapp
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestSignalShutdown 测试 SIGTERM 触发关闭
+func TestSignalShutdown(t *testing.T) {
+	stopped := make(chan struct{})
+	app := NewWithConfig(nil).Add(&Component{
+		Name: "server",
+		Stop: func(ctx context.Context) error {
+			close(stopped)
+			return nil
+		},
+	})
+
+	done := runApp(app)
+	waitReady(t, app)
+	syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run returned %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("app did not shut down on SIGTERM")
+	}
+
+	select {
+	case <-stopped:
+	default:
+		t.Error("Expected component to be stopped")
+	}
+}
@@ -65,6 +65,19 @@ func NewWithContext(ctx context.Context) *Context {
 	}
 }
 
+// NewWithoutTrace 从标准 context 创建,不生成追踪信息
+// 用于高频场景 (如 UDP 数据报),避免每次生成 ID 的开销;Trace() 可能返回 nil
+func NewWithoutTrace(ctx context.Context) *Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return &Context{
+		ctx:   ctx,
+		trace: FromContext(ctx),
+	}
+}
+
 // ============================================================================
 // Context 方法
 // ============================================================================
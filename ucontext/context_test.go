@@ -165,3 +165,18 @@ func TestDuration(t *testing.T) {
 		t.Errorf("Duration should be at least 10ms, got %v", duration)
 	}
 }
+
+// TestNewWithoutTrace 测试不生成追踪信息的 Context
+func TestNewWithoutTrace(t *testing.T) {
+	ctx := NewWithoutTrace(context.Background())
+	if ctx.Trace() != nil {
+		t.Error("Trace should be nil when parent has no trace context")
+	}
+
+	// 父 context 中已有追踪信息时沿用
+	tc := NewTraceContext()
+	ctx = NewWithoutTrace(WithContext(context.Background(), tc))
+	if ctx.Trace() != tc {
+		t.Error("Trace should be inherited from parent context")
+	}
+}
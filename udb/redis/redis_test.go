@@ -384,6 +384,39 @@ func TestPipeline(t *testing.T) {
 	}
 }
 
+// 测试 Lua 脚本
+func TestScript(t *testing.T) {
+	config := getTestConfig()
+	conn, err := New(config)
+	if err != nil {
+		t.Fatalf("创建连接失败: %v", err)
+	}
+	defer conn.Close()
+
+	ctx := ucontext.New()
+	key := "test:script"
+	defer conn.Del(ctx, key)
+
+	script := NewScript(`
+local n = redis.call('INCRBY', KEYS[1], ARGV[1])
+return n
+`)
+	for i, want := range []int64{5, 10} {
+		result, err := conn.Run(ctx, script, []string{key}, 5)
+		if err != nil {
+			t.Fatalf("Run 失败: %v", err)
+		}
+		if result.(int64) != want {
+			t.Errorf("第 %d 次执行结果错误: 期望 %d, 实际 %v", i+1, want, result)
+		}
+	}
+
+	// 返回 nil
+	if _, err := conn.Eval(ctx, "return nil", nil); err != ErrNil {
+		t.Errorf("期望 ErrNil, 实际 %v", err)
+	}
+}
+
 // 测试 Pub/Sub
 func TestPubSub(t *testing.T) {
 	config := getTestConfig()
@@ -0,0 +1,77 @@
+package redis
+
+import (
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uerror"
+)
+
+// ==================== Lua 脚本操作 ====================
+
+// Script Lua 脚本，执行时优先使用 EVALSHA，服务端未缓存时自动回退到 EVAL
+type Script struct {
+	script *redis.Script
+}
+
+// NewScript 创建 Lua 脚本
+func NewScript(src string) *Script {
+	return &Script{script: redis.NewScript(src)}
+}
+
+// Hash 脚本的 SHA1
+func (s *Script) Hash() string {
+	return s.script.Hash()
+}
+
+// Run 执行脚本，脚本返回 nil 时返回 ErrNil
+func (c *Connection) Run(ctx *ucontext.Context, script *Script, keys []string, args ...any) (any, error) {
+	startTime := time.Now()
+	result, err := script.script.Run(ctx, c.client, keys, args...).Result()
+	duration := time.Since(startTime)
+
+	c.logCommand(ctx, "EVALSHA", append([]any{script.Hash(), keys}, args...), duration, err)
+
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrNil
+		}
+		return nil, uerror.Wrap(err, "EVALSHA 失败")
+	}
+
+	return result, nil
+}
+
+// Eval 执行 Lua 脚本
+func (c *Connection) Eval(ctx *ucontext.Context, script string, keys []string, args ...any) (any, error) {
+	startTime := time.Now()
+	result, err := c.client.Eval(ctx, script, keys, args...).Result()
+	duration := time.Since(startTime)
+
+	c.logCommand(ctx, "EVAL", append([]any{keys}, args...), duration, err)
+
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrNil
+		}
+		return nil, uerror.Wrap(err, "EVAL 失败")
+	}
+
+	return result, nil
+}
+
+// ScriptLoad 预加载脚本，返回 SHA1
+func (c *Connection) ScriptLoad(ctx *ucontext.Context, script string) (string, error) {
+	startTime := time.Now()
+	result, err := c.client.ScriptLoad(ctx, script).Result()
+	duration := time.Since(startTime)
+
+	c.logCommand(ctx, "SCRIPT LOAD", nil, duration, err)
+
+	if err != nil {
+		return "", uerror.Wrap(err, "SCRIPT LOAD 失败")
+	}
+
+	return result, nil
+}
@@ -0,0 +1,173 @@
+package ujwt
+
+import (
+	"encoding/json"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uerror"
+)
+
+// Claims 令牌声明
+// 标准声明映射到字段,其余声明保存在 Extra 中
+type Claims struct {
+	Issuer    string         // iss 签发者
+	Subject   string         // sub 主体 (通常是用户 ID)
+	Audience  []string       // aud 受众
+	ExpiresAt time.Time      // exp 过期时间
+	NotBefore time.Time      // nbf 生效时间
+	IssuedAt  time.Time      // iat 签发时间
+	ID        string         // jti 令牌 ID,吊销时使用
+	Extra     map[string]any // 自定义声明
+}
+
+// registered 标准声明名称
+var registered = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti"}
+
+// Get 获取自定义声明
+func (c *Claims) Get(name string) (any, bool) {
+	v, ok := c.Extra[name]
+	return v, ok
+}
+
+// GetString 获取字符串类型的自定义声明
+func (c *Claims) GetString(name string) string {
+	s, _ := c.Extra[name].(string)
+	return s
+}
+
+// Set 设置自定义声明,标准声明名称应使用对应字段
+func (c *Claims) Set(name string, value any) {
+	if c.Extra == nil {
+		c.Extra = make(map[string]any)
+	}
+	c.Extra[name] = value
+}
+
+// HasAudience 是否包含指定受众
+func (c *Claims) HasAudience(aud string) bool {
+	return slices.Contains(c.Audience, aud)
+}
+
+// MarshalJSON 序列化为 JWT 载荷,时间使用秒级 NumericDate
+func (c *Claims) MarshalJSON() ([]byte, error) {
+	m := make(map[string]any, len(c.Extra)+len(registered))
+	for k, v := range c.Extra {
+		if !slices.Contains(registered, k) {
+			m[k] = v
+		}
+	}
+	setString := func(name, v string) {
+		if v != "" {
+			m[name] = v
+		}
+	}
+	setTime := func(name string, t time.Time) {
+		if !t.IsZero() {
+			m[name] = t.Unix()
+		}
+	}
+	setString("iss", c.Issuer)
+	setString("sub", c.Subject)
+	setString("jti", c.ID)
+	setTime("exp", c.ExpiresAt)
+	setTime("nbf", c.NotBefore)
+	setTime("iat", c.IssuedAt)
+	switch len(c.Audience) {
+	case 0:
+	case 1:
+		m["aud"] = c.Audience[0]
+	default:
+		m["aud"] = c.Audience
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON 解析 JWT 载荷,aud 可以是字符串或字符串数组
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*c = Claims{}
+
+	var err error
+	stringClaim := func(name string) string {
+		v, ok := m[name]
+		if !ok {
+			return ""
+		}
+		s, isString := v.(string)
+		if !isString && err == nil {
+			err = uerror.New("声明 " + name + " 必须是字符串")
+		}
+		return s
+	}
+	timeClaim := func(name string) time.Time {
+		v, ok := m[name]
+		if !ok {
+			return time.Time{}
+		}
+		f, isNumber := v.(float64)
+		if !isNumber || math.IsNaN(f) || math.IsInf(f, 0) {
+			if err == nil {
+				err = uerror.New("声明 " + name + " 必须是数字")
+			}
+			return time.Time{}
+		}
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9))
+	}
+
+	c.Issuer = stringClaim("iss")
+	c.Subject = stringClaim("sub")
+	c.ID = stringClaim("jti")
+	c.ExpiresAt = timeClaim("exp")
+	c.NotBefore = timeClaim("nbf")
+	c.IssuedAt = timeClaim("iat")
+	switch aud := m["aud"].(type) {
+	case nil:
+	case string:
+		c.Audience = []string{aud}
+	case []any:
+		for _, a := range aud {
+			s, ok := a.(string)
+			if !ok {
+				return uerror.New("声明 aud 必须是字符串或字符串数组")
+			}
+			c.Audience = append(c.Audience, s)
+		}
+	default:
+		return uerror.New("声明 aud 必须是字符串或字符串数组")
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, name := range registered {
+		delete(m, name)
+	}
+	if len(m) > 0 {
+		c.Extra = m
+	}
+	return nil
+}
+
+// claimsKey 上下文中保存声明的键
+type claimsKey struct{}
+
+// WithClaims 将声明存入上下文
+func WithClaims(ctx *ucontext.Context, claims *Claims) *ucontext.Context {
+	return ctx.WithValue(claimsKey{}, claims)
+}
+
+// FromContext 从上下文获取声明
+func FromContext(ctx *ucontext.Context) (*Claims, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
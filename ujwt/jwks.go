@@ -0,0 +1,387 @@
+package ujwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uerror"
+)
+
+// KeySet 按 kid 查找验证密钥
+type KeySet interface {
+	// Key 返回 kid 对应的密钥,找不到时返回 ErrKeyNotFound
+	// 令牌没有 kid 时 kid 为空字符串
+	Key(ctx *ucontext.Context, kid string) (*Key, error)
+}
+
+// StaticKeySet 固定的密钥集合
+type StaticKeySet struct {
+	keys []*Key
+}
+
+// NewKeySet 创建固定的密钥集合
+// 轮换密钥时同时保留新旧密钥,令牌头部的 kid 决定使用哪一个
+func NewKeySet(keys ...*Key) *StaticKeySet {
+	return &StaticKeySet{keys: keys}
+}
+
+// Key 实现 KeySet 接口
+// kid 为空时仅在集合只有一个密钥时返回该密钥
+func (s *StaticKeySet) Key(ctx *ucontext.Context, kid string) (*Key, error) {
+	return findKey(s.keys, kid)
+}
+
+// Keys 返回全部密钥
+func (s *StaticKeySet) Keys() []*Key {
+	return s.keys
+}
+
+// findKey 在密钥列表中按 kid 查找
+func findKey(keys []*Key, kid string) (*Key, error) {
+	if kid == "" {
+		if len(keys) == 1 {
+			return keys[0], nil
+		}
+		return nil, uerror.Wrap(ErrKeyNotFound, "令牌缺少 kid")
+	}
+	for _, k := range keys {
+		if k.ID == kid {
+			return k, nil
+		}
+	}
+	return nil, uerror.Wrap(ErrKeyNotFound, "kid="+kid)
+}
+
+// jwk JSON Web Key (RFC 7517)
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+	K         string `json:"k,omitempty"`
+}
+
+// jwkSet JWKS 文档
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// ParseJWKS 解析 JWKS 文档
+// 支持 RSA、EC (P-256)、OKP (Ed25519) 和 oct 密钥;不支持的密钥和 use 不是 sig 的密钥被跳过
+func ParseJWKS(data []byte) (*StaticKeySet, error) {
+	var set jwkSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, uerror.Wrap(err, "解析 JWKS 失败")
+	}
+	keys := make([]*Key, 0, len(set.Keys))
+	for _, j := range set.Keys {
+		if j.Use != "" && j.Use != "sig" {
+			continue
+		}
+		key, err := j.key()
+		if err != nil {
+			return nil, uerror.Wrap(err, "解析 JWK 失败 (kid="+j.KeyID+")")
+		}
+		if key != nil {
+			keys = append(keys, key)
+		}
+	}
+	return NewKeySet(keys...), nil
+}
+
+// LoadJWKSFile 从文件加载 JWKS
+func LoadJWKSFile(path string) (*StaticKeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, uerror.Wrap(err, "读取 JWKS 文件失败")
+	}
+	return ParseJWKS(data)
+}
+
+// key 转换为密钥,不支持的类型返回 nil
+func (j *jwk) key() (*Key, error) {
+	key := &Key{ID: j.KeyID, Algorithm: j.Algorithm}
+	switch j.KeyType {
+	case "RSA":
+		n, err := decodeSegment(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeSegment(j.E)
+		if err != nil {
+			return nil, err
+		}
+		exp := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exp.IsInt64() || exp.Int64() < 2 || exp.Int64() > 1<<31-1 {
+			return nil, uerror.New("RSA 公钥参数无效")
+		}
+		key.PublicKey = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}
+	case "EC":
+		if j.Curve != "P-256" {
+			return nil, nil
+		}
+		x, err := decodeSegment(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeSegment(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return nil, uerror.New("EC 公钥坐标长度无效")
+		}
+		pub, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), append(append([]byte{4}, x...), y...))
+		if err != nil {
+			return nil, err
+		}
+		key.PublicKey = pub
+	case "OKP":
+		if j.Curve != "Ed25519" {
+			return nil, nil
+		}
+		x, err := decodeSegment(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, uerror.New("Ed25519 公钥长度无效")
+		}
+		key.PublicKey = ed25519.PublicKey(x)
+	case "oct":
+		k, err := decodeSegment(j.K)
+		if err != nil {
+			return nil, err
+		}
+		key.Secret = k
+	default:
+		return nil, nil
+	}
+	if _, err := key.algorithm(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// MarshalJWKS 生成 JWKS 文档,只包含公钥部分 (HMAC 密钥被跳过)
+// 用于对外发布验证密钥,如 /.well-known/jwks.json
+func MarshalJWKS(keys ...*Key) ([]byte, error) {
+	set := jwkSet{Keys: make([]jwk, 0, len(keys))}
+	for _, k := range keys {
+		alg, err := k.algorithm()
+		if err != nil {
+			return nil, err
+		}
+		j := jwk{KeyID: k.ID, Use: "sig", Algorithm: alg}
+		switch pub := k.publicKey().(type) {
+		case *rsa.PublicKey:
+			j.KeyType = "RSA"
+			j.N = encodeSegment(pub.N.Bytes())
+			j.E = encodeSegment(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			raw, err := pub.Bytes()
+			if err != nil {
+				return nil, err
+			}
+			j.KeyType, j.Curve = "EC", "P-256"
+			j.X = encodeSegment(raw[1:33])
+			j.Y = encodeSegment(raw[33:])
+		case ed25519.PublicKey:
+			j.KeyType, j.Curve = "OKP", "Ed25519"
+			j.X = encodeSegment(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, j)
+	}
+	return json.Marshal(set)
+}
+
+// RemoteJWKS 从 HTTP 端点获取的密钥集合
+// 缓存 RefreshInterval 后重新获取;遇到未知 kid 时立即重新获取 (两次获取至少间隔 MinRefreshInterval),
+// 签发方轮换密钥后无需重启即可验证新令牌。获取在锁外进行,并发的获取合并为一次;零值可用
+type RemoteJWKS struct {
+	URL                string        // JWKS 地址
+	Client             *http.Client  // HTTP 客户端,为空时使用 http.DefaultClient (NewRemoteJWKS 设置 10 秒超时)
+	RefreshInterval    time.Duration // 缓存时间,为 0 时 1 小时
+	MinRefreshInterval time.Duration // 未知 kid 触发重新获取的最小间隔,为 0 时 1 分钟
+
+	mu        sync.Mutex
+	keys      []*Key
+	loaded    bool             // 至少成功获取过一次
+	fetchErr  error            // 最近一次获取的错误
+	fetchedAt time.Time        // 最近一次获取结束的时间
+	inflight  *jwksFetch       // 进行中的获取
+	now       func() time.Time // 测试时替换
+}
+
+// jwksFetchTimeout 单次获取的超时,获取不受发起请求的 ctx 取消影响
+const jwksFetchTimeout = 10 * time.Second
+
+// jwksFetch 一次进行中的获取,其他调用方等待 done 关闭后读取 err
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// NewRemoteJWKS 创建远程密钥集合
+func NewRemoteJWKS(url string) *RemoteJWKS {
+	return &RemoteJWKS{
+		URL:                url,
+		Client:             &http.Client{Timeout: 10 * time.Second},
+		RefreshInterval:    time.Hour,
+		MinRefreshInterval: time.Minute,
+		now:                time.Now,
+	}
+}
+
+// Key 实现 KeySet 接口
+func (r *RemoteJWKS) Key(ctx *ucontext.Context, kid string) (*Key, error) {
+	r.mu.Lock()
+	if r.inflight == nil && !r.fetchedAt.IsZero() {
+		age := r.clock().Sub(r.fetchedAt)
+		switch {
+		case age >= r.refreshInterval():
+		case !r.loaded:
+			// 从未获取成功:返回获取错误而不是 ErrKeyNotFound,避免端点故障被当作令牌无效
+			if age < r.minRefreshInterval() {
+				err := r.fetchErr
+				r.mu.Unlock()
+				return nil, err
+			}
+		default:
+			key, err := findKey(r.keys, kid)
+			if err == nil || age < r.minRefreshInterval() {
+				r.mu.Unlock()
+				return key, err
+			}
+		}
+	}
+
+	err := r.join(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.loaded {
+		if err == nil {
+			err = r.fetchErr
+		}
+		return nil, err
+	}
+	// 获取失败时继续使用旧的密钥
+	return findKey(r.keys, kid)
+}
+
+// Refresh 立即重新获取密钥,已有获取进行中时等待其结果
+func (r *RemoteJWKS) Refresh(ctx *ucontext.Context) error {
+	r.mu.Lock()
+	return r.join(ctx)
+}
+
+// join 加入进行中的获取,没有时发起新的获取,等待结果或 ctx 结束
+// 调用方持有锁,join 在等待前释放。获取在独立的 ctx 上进行 (保留追踪信息,超时 jwksFetchTimeout),
+// 发起获取的客户端断开不会让其他等待者失败
+func (r *RemoteJWKS) join(ctx *ucontext.Context) error {
+	call := r.inflight
+	if call == nil {
+		call = &jwksFetch{done: make(chan struct{})}
+		r.inflight = call
+		fetchCtx, cancel := ucontext.WithTimeout(
+			ucontext.NewWithoutTrace(context.WithoutCancel(ctx.Context())), jwksFetchTimeout)
+		go func() {
+			defer cancel()
+			keys, err := r.fetch(fetchCtx)
+
+			r.mu.Lock()
+			if err == nil {
+				r.keys, r.loaded = keys, true
+			}
+			r.fetchErr = err
+			// 失败也记录时间,避免端点故障时每个请求都重新获取
+			r.fetchedAt = r.clock()
+			r.inflight = nil
+			r.mu.Unlock()
+
+			call.err = err
+			close(call.done)
+		}()
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// fetch 获取并解析 JWKS,不访问缓存状态
+func (r *RemoteJWKS) fetch(ctx *ucontext.Context) ([]*Key, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL, nil)
+	if err != nil {
+		return nil, uerror.Wrap(err, "创建 JWKS 请求失败")
+	}
+	req.Header.Set("Accept", "application/json")
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, uerror.Wrap(err, "获取 JWKS 失败")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, uerror.New("获取 JWKS 失败: " + resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, uerror.Wrap(err, "读取 JWKS 失败")
+	}
+	set, err := ParseJWKS(data)
+	if err != nil {
+		return nil, err
+	}
+	return set.keys, nil
+}
+
+// clock 当前时间
+func (r *RemoteJWKS) clock() time.Time {
+	if r.now == nil {
+		return time.Now()
+	}
+	return r.now()
+}
+
+// refreshInterval 缓存时间,未设置时 1 小时
+func (r *RemoteJWKS) refreshInterval() time.Duration {
+	if r.RefreshInterval <= 0 {
+		return time.Hour
+	}
+	return r.RefreshInterval
+}
+
+// minRefreshInterval 未知 kid 重新获取的最小间隔,未设置时 1 分钟
+func (r *RemoteJWKS) minRefreshInterval() time.Duration {
+	if r.MinRefreshInterval <= 0 {
+		return time.Minute
+	}
+	return r.MinRefreshInterval
+}
@@ -0,0 +1,199 @@
+package ujwt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+)
+
+// TestJWKS 测试 JWKS 的生成和解析
+func TestJWKS(t *testing.T) {
+	keys := testKeys(t)
+	data, err := MarshalJWKS(keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	set, err := LoadJWKSFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// HMAC 密钥不会发布
+	if len(set.Keys()) != 3 {
+		t.Fatalf("Expected 3 public keys, got %d", len(set.Keys()))
+	}
+
+	verifier := NewVerifier(set)
+	ctx := ucontext.New()
+	for _, key := range keys[2:] {
+		token, _, err := NewSigner(key, "", time.Hour).Issue("u", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := verifier.Parse(ctx, token); err != nil {
+			t.Errorf("%s: %v", key.ID, err)
+		}
+	}
+
+	// 多个密钥时令牌必须带 kid
+	token, _ := Sign(&Claims{ExpiresAt: time.Now().Add(time.Hour)}, &Key{PrivateKey: keys[2].PrivateKey})
+	if _, err := verifier.Parse(ctx, token); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected key not found without kid, got %v", err)
+	}
+
+	oct, err := ParseJWKS([]byte(`{"keys":[{"kty":"oct","kid":"h","alg":"HS384","k":"c2VjcmV0"},{"kty":"RSA","use":"enc","n":"AQ","e":"AQAB"},{"kty":"EC","crv":"P-521"}]}`))
+	if err != nil || len(oct.Keys()) != 1 || oct.Keys()[0].Algorithm != HS384 {
+		t.Errorf("Expected only the oct key, got %v %v", oct, err)
+	}
+	if _, err := ParseJWKS([]byte(`{"keys":[{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}]}`)); err == nil {
+		t.Error("Expected error for invalid EC key")
+	}
+}
+
+// TestRemoteJWKS 测试从端点获取密钥和密钥轮换
+func TestRemoteJWKS(t *testing.T) {
+	keys := testKeys(t)
+	oldKey, newKey := keys[3], keys[4]
+
+	var published atomic.Value
+	published.Store([]*Key{oldKey})
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		data, _ := MarshalJWKS(published.Load().([]*Key)...)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	remote := NewRemoteJWKS(server.URL)
+	remote.now = func() time.Time { return now }
+	verifier := NewVerifier(remote)
+	ctx := ucontext.New()
+
+	oldToken, _, _ := NewSigner(oldKey, "", 24*time.Hour).Issue("u", nil)
+	if _, err := verifier.Parse(ctx, oldToken); err != nil {
+		t.Fatal(err)
+	}
+	verifier.Parse(ctx, oldToken)
+	if fetches.Load() != 1 {
+		t.Errorf("Expected keys to be cached, got %d fetches", fetches.Load())
+	}
+
+	// 签发方轮换密钥: 新 kid 在最小间隔内不会触发重新获取
+	published.Store([]*Key{oldKey, newKey})
+	newToken, _, _ := NewSigner(newKey, "", 24*time.Hour).Issue("u", nil)
+	if _, err := verifier.Parse(ctx, newToken); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected unknown kid within min interval, got %v", err)
+	}
+	now = now.Add(remote.MinRefreshInterval)
+	if _, err := verifier.Parse(ctx, newToken); err != nil {
+		t.Errorf("Expected unknown kid to trigger refetch, got %v", err)
+	}
+	if fetches.Load() != 2 {
+		t.Errorf("Expected 2 fetches, got %d", fetches.Load())
+	}
+
+	// 端点故障时继续使用缓存的密钥
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	now = now.Add(remote.RefreshInterval)
+	if _, err := verifier.Parse(ctx, oldToken); err != nil {
+		t.Errorf("Expected cached keys on fetch failure, got %v", err)
+	}
+	if err := remote.Refresh(ctx); err == nil {
+		t.Error("Expected refresh error")
+	}
+}
+
+// TestRemoteJWKSConcurrent 测试零值可用、并发获取合并为一次,且发起获取的调用方取消不影响其他等待者
+func TestRemoteJWKSConcurrent(t *testing.T) {
+	key := testKeys(t)[3]
+	data, err := MarshalJWKS(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	arrived, release := make(chan struct{}), make(chan struct{})
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) == 1 {
+			close(arrived)
+		}
+		<-release
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	remote := &RemoteJWKS{URL: server.URL}
+
+	// 发起获取的请求断开:自己立即返回,获取继续进行
+	canceled, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := remote.Key(ucontext.NewWithContext(canceled), key.ID)
+		done <- err
+	}()
+	<-arrived
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Key did not return after its context was canceled")
+	}
+
+	errs := make(chan error, 8)
+	for range cap(errs) {
+		go func() {
+			_, err := remote.Key(ucontext.New(), key.ID)
+			errs <- err
+		}()
+	}
+	close(release)
+	for range cap(errs) {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("Expected concurrent lookups to share one fetch, got %d", fetches.Load())
+	}
+}
+
+// TestRemoteJWKSUnavailable 测试从未获取成功时返回获取错误而不是 ErrKeyNotFound
+func TestRemoteJWKSUnavailable(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	remote := &RemoteJWKS{URL: server.URL}
+	for i := 0; i < 2; i++ {
+		_, err := remote.Key(ucontext.New(), "k1")
+		if err == nil || errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("Expected fetch error, got %v", err)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("Expected failed fetch to be throttled, got %d fetches", fetches.Load())
+	}
+}
@@ -0,0 +1,245 @@
+// Package ujwt JSON Web Token (RFC 7519) 签发与验证,基于标准库 crypto 实现
+package ujwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash"
+	"math/big"
+	"strings"
+
+	"github.com/whosafe/uf/uerror"
+)
+
+// 签名算法
+const (
+	HS256 = "HS256" // HMAC SHA-256
+	HS384 = "HS384" // HMAC SHA-384
+	RS256 = "RS256" // RSASSA-PKCS1-v1_5 SHA-256
+	ES256 = "ES256" // ECDSA P-256 SHA-256
+	EdDSA = "EdDSA" // Ed25519
+)
+
+// 错误定义
+var (
+	// ErrTokenMalformed 令牌格式错误
+	ErrTokenMalformed = errors.New("token malformed")
+	// ErrTokenUnverifiable 令牌算法不受支持或与密钥不匹配
+	ErrTokenUnverifiable = errors.New("token unverifiable")
+	// ErrTokenSignatureInvalid 签名无效
+	ErrTokenSignatureInvalid = errors.New("token signature invalid")
+	// ErrTokenExpired 令牌已过期
+	ErrTokenExpired = errors.New("token expired")
+	// ErrTokenNotValidYet 令牌尚未生效 (nbf 或 iat 在未来)
+	ErrTokenNotValidYet = errors.New("token not valid yet")
+	// ErrTokenInvalidIssuer 签发者不匹配
+	ErrTokenInvalidIssuer = errors.New("token has invalid issuer")
+	// ErrTokenInvalidAudience 受众不匹配
+	ErrTokenInvalidAudience = errors.New("token has invalid audience")
+	// ErrTokenRevoked 令牌已吊销
+	ErrTokenRevoked = errors.New("token revoked")
+	// ErrKeyNotFound 找不到 kid 对应的密钥
+	ErrKeyNotFound = errors.New("key not found")
+)
+
+// Key 签名或验证密钥
+type Key struct {
+	ID         string           // kid,写入令牌头部并用于验证时选择密钥
+	Algorithm  string           // 算法,为空时按密钥类型推断 (HMAC 密钥默认 HS256)
+	Secret     []byte           // HS256/HS384 共享密钥
+	PrivateKey crypto.Signer    // RS256/ES256/EdDSA 私钥 (签发)
+	PublicKey  crypto.PublicKey // RS256/ES256/EdDSA 公钥 (验证),为空时取私钥的公钥
+}
+
+// algorithm 密钥的算法,不匹配的组合返回错误
+func (k *Key) algorithm() (string, error) {
+	pub := k.publicKey()
+	alg := k.Algorithm
+	if alg == "" {
+		switch p := pub.(type) {
+		case *rsa.PublicKey:
+			alg = RS256
+		case *ecdsa.PublicKey:
+			if p.Curve == elliptic.P256() {
+				alg = ES256
+			}
+		case ed25519.PublicKey:
+			alg = EdDSA
+		case nil:
+			if len(k.Secret) > 0 {
+				alg = HS256
+			}
+		}
+	}
+
+	ok := false
+	switch alg {
+	case HS256, HS384:
+		ok = len(k.Secret) > 0
+	case RS256:
+		_, ok = pub.(*rsa.PublicKey)
+	case ES256:
+		p, isEC := pub.(*ecdsa.PublicKey)
+		ok = isEC && p.Curve == elliptic.P256()
+	case EdDSA:
+		_, ok = pub.(ed25519.PublicKey)
+	}
+	if !ok {
+		return "", uerror.Wrap(ErrTokenUnverifiable, "密钥与算法不匹配: "+alg+" (kid="+k.ID+")")
+	}
+	return alg, nil
+}
+
+// publicKey 验证用的公钥
+func (k *Key) publicKey() crypto.PublicKey {
+	if k.PublicKey != nil {
+		return k.PublicKey
+	}
+	if k.PrivateKey != nil {
+		return k.PrivateKey.Public()
+	}
+	return nil
+}
+
+// header 令牌头部
+type header struct {
+	Algorithm string   `json:"alg"`
+	Type      string   `json:"typ,omitempty"`
+	KeyID     string   `json:"kid,omitempty"`
+	Critical  []string `json:"crit,omitempty"`
+}
+
+// Sign 使用密钥签发令牌
+func Sign(claims *Claims, key *Key) (string, error) {
+	alg, err := key.algorithm()
+	if err != nil {
+		return "", err
+	}
+	h, err := json.Marshal(header{Algorithm: alg, Type: "JWT", KeyID: key.ID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", uerror.Wrap(err, "序列化声明失败")
+	}
+
+	signingInput := encodeSegment(h) + "." + encodeSegment(payload)
+	sig, err := sign(alg, key, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + encodeSegment(sig), nil
+}
+
+// sign 计算签名
+func sign(alg string, key *Key, input []byte) ([]byte, error) {
+	switch alg {
+	case HS256, HS384:
+		mac := hmac.New(hashFunc(alg), key.Secret)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	}
+
+	if key.PrivateKey == nil {
+		return nil, uerror.New("缺少私钥,不能签发 " + alg + " 令牌")
+	}
+	switch alg {
+	case RS256:
+		digest := sha256.Sum256(input)
+		return key.PrivateKey.Sign(rand.Reader, digest[:], crypto.SHA256)
+	case ES256:
+		digest := sha256.Sum256(input)
+		der, err := key.PrivateKey.Sign(rand.Reader, digest[:], crypto.SHA256)
+		if err != nil {
+			return nil, err
+		}
+		// JWS 使用定长的 R || S,而不是 ASN.1
+		var rs struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(der, &rs); err != nil {
+			return nil, uerror.Wrap(err, "解析 ECDSA 签名失败")
+		}
+		sig := make([]byte, 64)
+		rs.R.FillBytes(sig[:32])
+		rs.S.FillBytes(sig[32:])
+		return sig, nil
+	case EdDSA:
+		return key.PrivateKey.Sign(rand.Reader, input, crypto.Hash(0))
+	}
+	return nil, uerror.Wrap(ErrTokenUnverifiable, "不支持的算法: "+alg)
+}
+
+// verify 验证签名
+func verify(alg string, key *Key, input, sig []byte) bool {
+	switch alg {
+	case HS256, HS384:
+		mac := hmac.New(hashFunc(alg), key.Secret)
+		mac.Write(input)
+		return hmac.Equal(sig, mac.Sum(nil))
+	case RS256:
+		digest := sha256.Sum256(input)
+		return rsa.VerifyPKCS1v15(key.publicKey().(*rsa.PublicKey), crypto.SHA256, digest[:], sig) == nil
+	case ES256:
+		if len(sig) != 64 {
+			return false
+		}
+		digest := sha256.Sum256(input)
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(key.publicKey().(*ecdsa.PublicKey), digest[:], r, s)
+	case EdDSA:
+		return ed25519.Verify(key.publicKey().(ed25519.PublicKey), input, sig)
+	}
+	return false
+}
+
+// hashFunc HMAC 算法的哈希函数
+func hashFunc(alg string) func() hash.Hash {
+	if alg == HS384 {
+		return sha512.New384
+	}
+	return sha256.New
+}
+
+// splitToken 拆分并解码令牌的三个部分
+func splitToken(token string) (h *header, payload, signingInput, sig []byte, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nil, nil, nil, ErrTokenMalformed
+	}
+	rawHeader, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, nil, nil, nil, uerror.Wrap(ErrTokenMalformed, "头部不是 base64url")
+	}
+	if payload, err = decodeSegment(parts[1]); err != nil {
+		return nil, nil, nil, nil, uerror.Wrap(ErrTokenMalformed, "载荷不是 base64url")
+	}
+	if sig, err = decodeSegment(parts[2]); err != nil {
+		return nil, nil, nil, nil, uerror.Wrap(ErrTokenMalformed, "签名不是 base64url")
+	}
+	h = &header{}
+	if err := json.Unmarshal(rawHeader, h); err != nil {
+		return nil, nil, nil, nil, uerror.Wrap(ErrTokenMalformed, "头部不是 JSON")
+	}
+	return h, payload, []byte(parts[0] + "." + parts[1]), sig, nil
+}
+
+// encodeSegment base64url 编码 (无填充)
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeSegment base64url 解码 (无填充)
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
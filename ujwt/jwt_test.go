@@ -0,0 +1,277 @@
+package ujwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+)
+
+// testKeys 每种算法一个测试密钥
+func testKeys(t *testing.T) []*Key {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []*Key{
+		{ID: "hs256", Secret: []byte("secret-256")},
+		{ID: "hs384", Algorithm: HS384, Secret: []byte("secret-384")},
+		{ID: "rs256", PrivateKey: rsaKey},
+		{ID: "es256", PrivateKey: ecKey},
+		{ID: "eddsa", PrivateKey: edKey},
+	}
+}
+
+// TestSignAndVerify 测试各算法签发和验证
+func TestSignAndVerify(t *testing.T) {
+	keys := testKeys(t)
+	verifier := NewVerifier(NewKeySet(keys...))
+	ctx := ucontext.New()
+
+	for _, key := range keys {
+		signer := NewSigner(key, "uf", time.Hour)
+		token, issued, err := signer.Issue("user-1", map[string]any{"role": "admin"})
+		if err != nil {
+			t.Fatalf("%s: %v", key.ID, err)
+		}
+		claims, err := verifier.Parse(ctx, token)
+		if err != nil {
+			t.Fatalf("%s: %v", key.ID, err)
+		}
+		if claims.Subject != "user-1" || claims.Issuer != "uf" || claims.ID != issued.ID || claims.GetString("role") != "admin" {
+			t.Errorf("%s: unexpected claims %+v", key.ID, claims)
+		}
+
+		// 篡改载荷
+		parts := strings.Split(token, ".")
+		forged := parts[0] + "." + encodeSegment([]byte(`{"sub":"admin","exp":9999999999}`)) + "." + parts[2]
+		if _, err := verifier.Parse(ctx, forged); !errors.Is(err, ErrTokenSignatureInvalid) {
+			t.Errorf("%s: expected signature error, got %v", key.ID, err)
+		}
+	}
+}
+
+// TestAlgorithmConfusion 测试拒绝 none 和与密钥不匹配的算法
+func TestAlgorithmConfusion(t *testing.T) {
+	keys := testKeys(t)
+	rsaKey := keys[2]
+	verifier := NewVerifier(NewKeySet(&Key{ID: "rs256", PublicKey: rsaKey.PrivateKey.Public()}))
+	ctx := ucontext.New()
+	payload := encodeSegment([]byte(`{"sub":"admin","exp":9999999999}`))
+
+	none := encodeSegment([]byte(`{"alg":"none","kid":"rs256"}`)) + "." + payload + "."
+	if _, err := verifier.Parse(ctx, none); !errors.Is(err, ErrTokenUnverifiable) {
+		t.Errorf("Expected alg none to be rejected, got %v", err)
+	}
+
+	// 用 RSA 公钥的字节作为 HMAC 密钥伪造令牌
+	hs, err := Sign(&Claims{Subject: "admin", ExpiresAt: time.Now().Add(time.Hour)},
+		&Key{ID: "rs256", Secret: []byte("public key bytes")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Parse(ctx, hs); !errors.Is(err, ErrTokenUnverifiable) {
+		t.Errorf("Expected HS256 token for RSA key to be rejected, got %v", err)
+	}
+
+	crit := encodeSegment([]byte(`{"alg":"RS256","kid":"rs256","crit":["exp"]}`)) + "." + payload + ".c2ln"
+	if _, err := verifier.Parse(ctx, crit); !errors.Is(err, ErrTokenUnverifiable) {
+		t.Errorf("Expected crit header to be rejected, got %v", err)
+	}
+
+	verifier.Algorithms = []string{ES256}
+	token, _, _ := NewSigner(rsaKey, "", time.Hour).Issue("u", nil)
+	if _, err := verifier.Parse(ctx, token); !errors.Is(err, ErrTokenUnverifiable) {
+		t.Errorf("Expected disallowed algorithm to be rejected, got %v", err)
+	}
+
+	for _, malformed := range []string{"", "a.b", "a.b.c", "e30.e30.!!"} {
+		if _, err := verifier.Parse(ctx, malformed); !errors.Is(err, ErrTokenMalformed) {
+			t.Errorf("Expected malformed error for %q, got %v", malformed, err)
+		}
+	}
+}
+
+// TestValidateClaims 测试标准声明和时钟偏差
+func TestValidateClaims(t *testing.T) {
+	key := &Key{ID: "k", Secret: []byte("secret")}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	verifier := NewVerifier(NewKeySet(key))
+	verifier.Leeway = 30 * time.Second
+	verifier.Issuer = "uf"
+	verifier.Audience = "api"
+	verifier.now = func() time.Time { return now }
+	ctx := ucontext.New()
+
+	base := func() *Claims {
+		return &Claims{Issuer: "uf", Audience: []string{"web", "api"}, ExpiresAt: now.Add(time.Minute)}
+	}
+	tests := []struct {
+		name   string
+		modify func(c *Claims)
+		want   error
+	}{
+		{"valid", func(c *Claims) {}, nil},
+		{"expired within leeway", func(c *Claims) { c.ExpiresAt = now.Add(-20 * time.Second) }, nil},
+		{"expired", func(c *Claims) { c.ExpiresAt = now.Add(-40 * time.Second) }, ErrTokenExpired},
+		{"missing exp", func(c *Claims) { c.ExpiresAt = time.Time{} }, ErrTokenMalformed},
+		{"nbf within leeway", func(c *Claims) { c.NotBefore = now.Add(20 * time.Second) }, nil},
+		{"nbf in future", func(c *Claims) { c.NotBefore = now.Add(time.Minute) }, ErrTokenNotValidYet},
+		{"iat in future", func(c *Claims) { c.IssuedAt = now.Add(time.Minute) }, ErrTokenNotValidYet},
+		{"issuer", func(c *Claims) { c.Issuer = "other" }, ErrTokenInvalidIssuer},
+		{"audience", func(c *Claims) { c.Audience = []string{"web"} }, ErrTokenInvalidAudience},
+	}
+	for _, tt := range tests {
+		c := base()
+		tt.modify(c)
+		token, err := Sign(c, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = verifier.Parse(ctx, token)
+		if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+// TestClaimsJSON 测试声明的序列化
+func TestClaimsJSON(t *testing.T) {
+	c := &Claims{}
+	if err := c.UnmarshalJSON([]byte(`{"sub":"u","aud":"api","exp":1700000000,"iat":1699999999.5,"scope":"read"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if !c.HasAudience("api") || c.ExpiresAt.Unix() != 1700000000 || c.GetString("scope") != "read" {
+		t.Errorf("Unexpected claims: %+v", c)
+	}
+	if _, ok := c.Get("sub"); ok {
+		t.Error("Registered claims should not be in Extra")
+	}
+
+	c.Audience = []string{"a", "b"}
+	data, err := c.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"aud":["a","b"]`) || !strings.Contains(string(data), `"scope":"read"`) {
+		t.Errorf("Unexpected JSON: %s", data)
+	}
+
+	for _, bad := range []string{`{"exp":"tomorrow"}`, `{"sub":1}`, `{"aud":[1]}`} {
+		if err := (&Claims{}).UnmarshalJSON([]byte(bad)); err == nil {
+			t.Errorf("Expected error for %s", bad)
+		}
+	}
+
+	ctx := WithClaims(ucontext.New(), c)
+	if got, ok := FromContext(ctx); !ok || got != c {
+		t.Error("Expected claims from context")
+	}
+	if _, ok := FromContext(ucontext.New()); ok {
+		t.Error("Expected no claims in empty context")
+	}
+}
+
+// TestRefreshAndRevoke 测试刷新令牌和吊销列表
+func TestRefreshAndRevoke(t *testing.T) {
+	key := &Key{ID: "k", Secret: []byte("secret")}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	revocation := NewMemoryRevocation()
+	revocation.now = clock
+	verifier := NewVerifier(NewKeySet(key))
+	verifier.Leeway = 0
+	verifier.RefreshGrace = time.Hour
+	verifier.Revocation = revocation
+	verifier.now = clock
+	signer := NewSigner(key, "", 15*time.Minute)
+	signer.now = clock
+	ctx := ucontext.New()
+
+	token, _, err := signer.Issue("user-1", map[string]any{"role": "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 过期后在宽限期内可以刷新
+	now = now.Add(30 * time.Minute)
+	if _, err := verifier.Parse(ctx, token); !IsExpired(err) {
+		t.Fatalf("Expected expired token, got %v", err)
+	}
+	refreshed, claims, err := verifier.Refresh(ctx, token, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user-1" || claims.GetString("role") != "admin" {
+		t.Errorf("Expected subject and extra claims to be kept, got %+v", claims)
+	}
+	if _, err := verifier.Parse(ctx, refreshed); err != nil {
+		t.Errorf("Expected refreshed token to be valid, got %v", err)
+	}
+
+	// 旧令牌已吊销,不能再次刷新
+	if _, _, err := verifier.Refresh(ctx, token, signer); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Expected old token to be revoked, got %v", err)
+	}
+
+	// 并发刷新同一令牌只有一个成功
+	results := make(chan error, 8)
+	for range cap(results) {
+		go func() {
+			_, _, err := verifier.Refresh(ctx, refreshed, signer)
+			results <- err
+		}()
+	}
+	succeeded := 0
+	for range cap(results) {
+		switch err := <-results; {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrTokenRevoked):
+			t.Errorf("Expected revoked error for concurrent refresh, got %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("Expected exactly one concurrent refresh to succeed, got %d", succeeded)
+	}
+
+	// 主动吊销 (注销)
+	if err := verifier.Revoke(ctx, claims); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Parse(ctx, refreshed); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Expected revoked token, got %v", err)
+	}
+
+	// 超过宽限期
+	now = now.Add(2 * time.Hour)
+	stale, _, _ := signer.Issue("user-2", nil)
+	now = now.Add(2 * time.Hour)
+	if _, _, err := verifier.Refresh(ctx, stale, signer); !IsExpired(err) {
+		t.Errorf("Expected refresh after grace period to fail, got %v", err)
+	}
+
+	// 吊销记录在令牌过期后被清理
+	now = now.Add(24 * time.Hour)
+	revocation.Revoke(ctx, "other", now.Add(time.Minute))
+	if len(revocation.revoked) != 1 {
+		t.Errorf("Expected expired revocations to be removed, got %d", len(revocation.revoked))
+	}
+}
@@ -0,0 +1,113 @@
+package ujwt
+
+import (
+	"sync"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/udb/redis"
+)
+
+// RevocationStore 吊销列表
+// 按 jti 记录,保留到令牌本身过期为止
+type RevocationStore interface {
+	// Revoke 吊销 jti,until 之后记录可以删除
+	Revoke(ctx *ucontext.Context, jti string, until time.Time) error
+	// IsRevoked 检查 jti 是否已吊销
+	IsRevoked(ctx *ucontext.Context, jti string) (bool, error)
+	// TryRevoke 原子地吊销尚未吊销的 jti,已吊销时返回 false (用于刷新令牌只能使用一次)
+	TryRevoke(ctx *ucontext.Context, jti string, until time.Time) (bool, error)
+}
+
+// MemoryRevocation 内存吊销列表 (单实例)
+type MemoryRevocation struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+	now     func() time.Time // 测试时替换
+}
+
+// NewMemoryRevocation 创建内存吊销列表
+func NewMemoryRevocation() *MemoryRevocation {
+	return &MemoryRevocation{
+		revoked: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// Revoke 实现 RevocationStore 接口,吊销时顺带清理已过期的记录
+func (m *MemoryRevocation) Revoke(ctx *ucontext.Context, jti string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	for k, exp := range m.revoked {
+		if !exp.After(now) {
+			delete(m.revoked, k)
+		}
+	}
+	if until.After(now) {
+		m.revoked[jti] = until
+	}
+	return nil
+}
+
+// IsRevoked 实现 RevocationStore 接口
+func (m *MemoryRevocation) IsRevoked(ctx *ucontext.Context, jti string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	until, ok := m.revoked[jti]
+	return ok && until.After(m.now()), nil
+}
+
+// TryRevoke 实现 RevocationStore 接口
+func (m *MemoryRevocation) TryRevoke(ctx *ucontext.Context, jti string, until time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if exp, ok := m.revoked[jti]; ok && exp.After(now) {
+		return false, nil
+	}
+	if until.After(now) {
+		m.revoked[jti] = until
+	}
+	return true, nil
+}
+
+// RedisRevocation Redis 吊销列表,多个实例共享
+// 每个 jti 一个键,过期时间与令牌一致,由 Redis 自动清理
+type RedisRevocation struct {
+	conn   *redis.Connection
+	prefix string
+}
+
+// NewRedisRevocation 创建 Redis 吊销列表,prefix 默认 "jwt:revoked:"
+func NewRedisRevocation(conn *redis.Connection, prefix string) *RedisRevocation {
+	if prefix == "" {
+		prefix = "jwt:revoked:"
+	}
+	return &RedisRevocation{conn: conn, prefix: prefix}
+}
+
+// Revoke 实现 RevocationStore 接口
+func (r *RedisRevocation) Revoke(ctx *ucontext.Context, jti string, until time.Time) error {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+	return r.conn.Set(ctx, r.prefix+jti, 1, ttl)
+}
+
+// IsRevoked 实现 RevocationStore 接口
+func (r *RedisRevocation) IsRevoked(ctx *ucontext.Context, jti string) (bool, error) {
+	n, err := r.conn.Exists(ctx, r.prefix+jti)
+	return n > 0, err
+}
+
+// TryRevoke 实现 RevocationStore 接口,使用 SET NX 保证多个实例中只有一个成功
+func (r *RedisRevocation) TryRevoke(ctx *ucontext.Context, jti string, until time.Time) (bool, error) {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return true, nil
+	}
+	return r.conn.SetNX(ctx, r.prefix+jti, 1, ttl)
+}
@@ -0,0 +1,233 @@
+package ujwt
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uerror"
+)
+
+// Verifier 令牌验证器
+type Verifier struct {
+	Keys         KeySet          // 验证密钥,按令牌头部的 kid 选择
+	Algorithms   []string        // 允许的算法,为空时允许全部支持的算法;算法还必须与密钥类型匹配
+	Issuer       string          // 期望的签发者,为空时不检查
+	Audience     string          // 期望的受众,为空时不检查
+	Leeway       time.Duration   // 时钟偏差容忍,作用于 exp、nbf 和 iat
+	RequireExp   bool            // 要求令牌带 exp
+	Revocation   RevocationStore // 吊销列表,为空时不检查
+	RefreshGrace time.Duration   // 过期后仍可用于 Refresh 的时间
+
+	now func() time.Time // 测试时替换
+}
+
+// NewVerifier 创建验证器,默认要求 exp 并容忍 1 分钟时钟偏差
+func NewVerifier(keys KeySet) *Verifier {
+	return &Verifier{
+		Keys:       keys,
+		Leeway:     time.Minute,
+		RequireExp: true,
+	}
+}
+
+// Parse 验证令牌并返回声明
+// 返回的错误可用 errors.Is 与 ErrTokenExpired 等比较
+func (v *Verifier) Parse(ctx *ucontext.Context, token string) (*Claims, error) {
+	claims, err := v.parse(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.validate(claims, v.clock(), 0); err != nil {
+		return nil, err
+	}
+	if err := v.checkRevoked(ctx, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// parse 检查签名并解析声明,不检查时间等声明
+func (v *Verifier) parse(ctx *ucontext.Context, token string) (*Claims, error) {
+	h, payload, signingInput, sig, err := splitToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if len(h.Critical) > 0 {
+		return nil, uerror.Wrap(ErrTokenUnverifiable, "不支持 crit 头部")
+	}
+	if len(v.Algorithms) > 0 && !slices.Contains(v.Algorithms, h.Algorithm) {
+		return nil, uerror.Wrap(ErrTokenUnverifiable, "不允许的算法: "+h.Algorithm)
+	}
+
+	key, err := v.Keys.Key(ctx, h.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	// 算法必须与密钥一致,防止用公钥当 HMAC 密钥等算法混淆攻击
+	alg, err := key.algorithm()
+	if err != nil {
+		return nil, err
+	}
+	if h.Algorithm != alg {
+		return nil, uerror.Wrap(ErrTokenUnverifiable, "令牌算法 "+h.Algorithm+" 与密钥算法 "+alg+" 不一致")
+	}
+	if !verify(alg, key, signingInput, sig) {
+		return nil, ErrTokenSignatureInvalid
+	}
+
+	claims := &Claims{}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, uerror.Wrap(ErrTokenMalformed, err.Error())
+	}
+	return claims, nil
+}
+
+// validate 检查标准声明,grace 为额外容许的过期时间
+func (v *Verifier) validate(c *Claims, now time.Time, grace time.Duration) error {
+	if c.ExpiresAt.IsZero() {
+		if v.RequireExp {
+			return uerror.Wrap(ErrTokenMalformed, "令牌缺少 exp")
+		}
+	} else if !now.Before(c.ExpiresAt.Add(v.Leeway + grace)) {
+		return ErrTokenExpired
+	}
+	if !c.NotBefore.IsZero() && now.Add(v.Leeway).Before(c.NotBefore) {
+		return ErrTokenNotValidYet
+	}
+	if !c.IssuedAt.IsZero() && now.Add(v.Leeway).Before(c.IssuedAt) {
+		return uerror.Wrap(ErrTokenNotValidYet, "iat 在未来")
+	}
+	if v.Issuer != "" && c.Issuer != v.Issuer {
+		return ErrTokenInvalidIssuer
+	}
+	if v.Audience != "" && !c.HasAudience(v.Audience) {
+		return ErrTokenInvalidAudience
+	}
+	return nil
+}
+
+// checkRevoked 检查吊销列表
+func (v *Verifier) checkRevoked(ctx *ucontext.Context, c *Claims) error {
+	if v.Revocation == nil || c.ID == "" {
+		return nil
+	}
+	revoked, err := v.Revocation.IsRevoked(ctx, c.ID)
+	if err != nil {
+		return uerror.Wrap(err, "查询吊销列表失败")
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// Revoke 吊销令牌,记录保留到令牌过期 (加上 Leeway 和 RefreshGrace)
+func (v *Verifier) Revoke(ctx *ucontext.Context, claims *Claims) error {
+	if v.Revocation == nil {
+		return uerror.New("未配置吊销列表")
+	}
+	if claims.ID == "" {
+		return uerror.New("令牌缺少 jti,无法吊销")
+	}
+	return v.Revocation.Revoke(ctx, claims.ID, v.revokeUntil(claims))
+}
+
+// revokeUntil 吊销记录保留到令牌过期 (加上 Leeway 和 RefreshGrace)
+func (v *Verifier) revokeUntil(claims *Claims) time.Time {
+	until := claims.ExpiresAt
+	if until.IsZero() {
+		// 没有 exp 的令牌永久有效,吊销记录保留一年
+		until = v.clock().AddDate(1, 0, 0)
+	}
+	return until.Add(v.Leeway + v.RefreshGrace)
+}
+
+// clock 当前时间
+func (v *Verifier) clock() time.Time {
+	if v.now != nil {
+		return v.now()
+	}
+	return time.Now()
+}
+
+// Signer 令牌签发器
+type Signer struct {
+	Key      *Key          // 签名密钥,轮换时替换为新密钥 (旧公钥保留在验证方的 KeySet 中)
+	Issuer   string        // iss
+	Audience []string      // aud
+	TTL      time.Duration // 有效期,默认 15 分钟
+
+	now func() time.Time // 测试时替换
+}
+
+// NewSigner 创建签发器
+func NewSigner(key *Key, issuer string, ttl time.Duration) *Signer {
+	return &Signer{Key: key, Issuer: issuer, TTL: ttl}
+}
+
+// Issue 为主体签发令牌,自动填写 iss、aud、iat、nbf、exp 和随机 jti
+func (s *Signer) Issue(subject string, extra map[string]any) (string, *Claims, error) {
+	now := time.Now()
+	if s.now != nil {
+		now = s.now()
+	}
+	ttl := s.TTL
+	if ttl <= 0 {
+		ttl = 15 * time.Minute
+	}
+	claims := &Claims{
+		Issuer:    s.Issuer,
+		Subject:   subject,
+		Audience:  s.Audience,
+		IssuedAt:  now,
+		NotBefore: now,
+		ExpiresAt: now.Add(ttl),
+		ID:        newTokenID(),
+		Extra:     extra,
+	}
+	token, err := Sign(claims, s.Key)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
+}
+
+// Refresh 用旧令牌换取新令牌
+// 旧令牌签名必须有效,可以已过期但不超过 RefreshGrace;配置了吊销列表时签发前原子地吊销旧令牌,
+// 并发刷新同一令牌只有一个成功,其余返回 ErrTokenRevoked;新令牌保留旧令牌的主体和自定义声明
+func (v *Verifier) Refresh(ctx *ucontext.Context, token string, signer *Signer) (string, *Claims, error) {
+	old, err := v.parse(ctx, token)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := v.validate(old, v.clock(), v.RefreshGrace); err != nil {
+		return "", nil, err
+	}
+	if v.Revocation != nil && old.ID != "" {
+		ok, err := v.Revocation.TryRevoke(ctx, old.ID, v.revokeUntil(old))
+		if err != nil {
+			return "", nil, uerror.Wrap(err, "吊销旧令牌失败")
+		}
+		if !ok {
+			return "", nil, ErrTokenRevoked
+		}
+	}
+	return signer.Issue(old.Subject, old.Extra)
+}
+
+// IsExpired 错误是否表示令牌过期 (便于调用方决定是否尝试 Refresh)
+func IsExpired(err error) bool {
+	return errors.Is(err, ErrTokenExpired)
+}
+
+// newTokenID 生成随机 jti
+func newTokenID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
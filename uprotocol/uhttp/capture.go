@@ -0,0 +1,129 @@
+package uhttp
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// capturePool BodyCapture 对象池
+var capturePool = sync.Pool{
+	New: func() any {
+		return &BodyCapture{}
+	},
+}
+
+// BodyCapture 响应体捕获写入器
+// 安装后处理器写入的状态码和响应体先缓冲,由中间件检查后调用 Finish 输出;
+// 处理器调用 Flush (如 SSE 事件流) 时转为直通,之后的数据直接写出
+type BodyCapture struct {
+	http.ResponseWriter // 原写入器 (响应头共享)
+	req                 *Request
+	code                int
+	buf                 bytes.Buffer
+	streamed            bool
+}
+
+// CaptureBody 开始捕获响应,之后写入的内容缓冲在返回的 BodyCapture 中
+// 必须调用 Finish 恢复原写入器,Finish 之后不能再使用 BodyCapture
+func (r *Request) CaptureBody() *BodyCapture {
+	c := capturePool.Get().(*BodyCapture)
+	c.req = r
+	c.code = 0
+	c.buf.Reset()
+	c.streamed = false
+	c.ResponseWriter = r.swapWriter(c)
+	return c
+}
+
+// swapWriter 替换请求和响应的写入器,返回原写入器
+func (r *Request) swapWriter(w http.ResponseWriter) http.ResponseWriter {
+	old := r.response.writer
+	r.writer, r.response.writer = w, w
+	return old
+}
+
+// StatusCode 捕获的状态码,未设置时为 0
+func (c *BodyCapture) StatusCode() int {
+	return c.code
+}
+
+// Body 捕获的响应体
+func (c *BodyCapture) Body() []byte {
+	return c.buf.Bytes()
+}
+
+// Streamed 是否已转为直通
+func (c *BodyCapture) Streamed() bool {
+	return c.streamed
+}
+
+// WriteHeader 实现 http.ResponseWriter 接口
+func (c *BodyCapture) WriteHeader(code int) {
+	if c.streamed {
+		c.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if c.code == 0 {
+		c.code = code
+	}
+}
+
+// Write 实现 http.ResponseWriter 接口
+func (c *BodyCapture) Write(data []byte) (int, error) {
+	if c.streamed {
+		return c.ResponseWriter.Write(data)
+	}
+	if c.code == 0 {
+		c.code = http.StatusOK
+	}
+	return c.buf.Write(data)
+}
+
+// Flush 实现 http.Flusher 接口,写出已缓冲的内容并转为直通
+func (c *BodyCapture) Flush() {
+	if !c.streamed {
+		c.streamed = true
+		if c.code == 0 {
+			c.code = http.StatusOK
+		}
+		c.ResponseWriter.WriteHeader(c.code)
+		c.ResponseWriter.Write(c.buf.Bytes())
+		c.buf.Reset()
+	}
+	http.NewResponseController(c.ResponseWriter).Flush()
+}
+
+// Hijack 实现 http.Hijacker 接口
+func (c *BodyCapture) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(c.ResponseWriter).Hijack()
+}
+
+// Unwrap 返回原始 ResponseWriter (供 http.ResponseController 使用)
+func (c *BodyCapture) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// Finish 恢复原写入器并输出状态码和响应体,code 为 0 时不输出
+// 已转为直通时只恢复写入器
+func (c *BodyCapture) Finish(code int, body []byte) error {
+	req, w, streamed := c.req, c.ResponseWriter, c.streamed
+	req.swapWriter(w)
+
+	var err error
+	if !streamed && code != 0 {
+		req.response.statusCode = code
+		w.WriteHeader(code)
+		if len(body) > 0 {
+			_, err = w.Write(body)
+		}
+	}
+
+	c.req, c.ResponseWriter = nil, nil
+	if c.buf.Cap() <= 1<<20 {
+		capturePool.Put(c)
+	}
+	return err
+}
@@ -0,0 +1,136 @@
+package uhttp
+
+import (
+	"net/http"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// frozenMessage 冻结后注册路由或中间件时 panic 的信息
+const frozenMessage = "服务器已启动,不能再注册路由或中间件"
+
+// handlerSet 服务器自身或虚拟主机编译后的处理器
+// 除 middlewares 外均已应用中间件,请求时直接调用
+type handlerSet struct {
+	router      *Router
+	gen         int                   // 编译时路由器的修改次数
+	middlewares []unet.MiddlewareFunc // 全局中间件 + 主机中间件 (用于重定向等动态处理器)
+	notFound    unet.HandlerFunc
+	notAllowed  unet.HandlerFunc
+	options     unet.HandlerFunc
+}
+
+// Freeze 编译全部路由的处理器链并冻结注册,此后注册路由或中间件会 panic
+// Start/Serve 自动调用;作为其他 mux 的子处理器时建议在注册完成后手动调用,
+// 否则每次请求都要检查路由是否变化
+func (s *Server) Freeze() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.frozen.Load() {
+		return
+	}
+
+	s.compile()
+	s.router.frozen = true
+	for _, h := range s.hosts {
+		h.router.frozen = true
+	}
+	s.frozen.Store(true)
+}
+
+// currentHandlers 获取编译后的处理器
+// 冻结前路由或中间件变化后重新编译
+func (s *Server) currentHandlers() *handlerSet {
+	if s.frozen.Load() {
+		return s.handlers
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stale() {
+		s.compile()
+	}
+	return s.handlers
+}
+
+// stale 检查编译后是否有新的注册 (调用方持有 s.mu)
+func (s *Server) stale() bool {
+	if s.handlers == nil || s.handlers.gen != s.router.gen {
+		return true
+	}
+	for _, h := range s.hosts {
+		if h.handlers == nil || h.handlers.gen != h.router.gen {
+			return true
+		}
+	}
+	return false
+}
+
+// compile 编译服务器自身及虚拟主机的处理器 (调用方持有 s.mu)
+func (s *Server) compile() {
+	s.handlers = newHandlerSet(s.router, s.middlewares, s.notFound, s.notAllowed)
+	for _, h := range s.hosts {
+		notFound, notAllowed := h.notFound, h.notAllowed
+		if notFound == nil {
+			notFound = s.notFound
+		}
+		if notAllowed == nil {
+			notAllowed = s.notAllowed
+		}
+		middlewares := append(append([]unet.MiddlewareFunc(nil), s.middlewares...), h.middlewares...)
+		h.handlers = newHandlerSet(h.router, middlewares, notFound, notAllowed)
+	}
+}
+
+// newHandlerSet 编译路由器中的路由及 404/405/OPTIONS 处理器
+func newHandlerSet(router *Router, middlewares []unet.MiddlewareFunc, notFound, notAllowed unet.HandlerFunc) *handlerSet {
+	for _, rt := range router.routes {
+		if rt.direct {
+			rt.handler = rt.origin
+			continue
+		}
+		rt.handler = applyMiddlewares(rt.origin, rt.chain(middlewares))
+	}
+
+	return &handlerSet{
+		router:      router,
+		gen:         router.gen,
+		middlewares: middlewares,
+		notFound:    applyMiddlewares(notFound, middlewares),
+		notAllowed:  applyMiddlewares(notAllowed, middlewares),
+		options:     applyMiddlewares(autoOptions, middlewares),
+	}
+}
+
+// chain 路由的完整中间件链:外层 (全局、主机) → 分组 (由外到内) → 路由
+func (r *Route) chain(outer []unet.MiddlewareFunc) []unet.MiddlewareFunc {
+	group := r.group.chain()
+	chain := make([]unet.MiddlewareFunc, 0, len(outer)+len(group)+len(r.middlewares))
+	chain = append(chain, outer...)
+	chain = append(chain, group...)
+	return append(chain, r.middlewares...)
+}
+
+// chain 分组的中间件链 (父分组在前)
+func (g *Group) chain() []unet.MiddlewareFunc {
+	if g == nil {
+		return nil
+	}
+	return append(g.parent.chain(), g.middlewares...)
+}
+
+// autoOptions 未注册 OPTIONS 时的默认处理器 (Allow 头已设置)
+func autoOptions(ctx *ucontext.Context, req unet.Request) error {
+	req.Response().Status(http.StatusNoContent)
+	return nil
+}
+
+// applyMiddlewares 应用中间件链
+func applyMiddlewares(handler unet.HandlerFunc, middlewares []unet.MiddlewareFunc) unet.HandlerFunc {
+	// 从后往前应用中间件
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
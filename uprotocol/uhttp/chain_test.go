@@ -0,0 +1,186 @@
+package uhttp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// traceMiddleware 按执行顺序记录中间件名称
+func traceMiddleware(name string, order *[]string) unet.MiddlewareFunc {
+	return func(next unet.HandlerFunc) unet.HandlerFunc {
+		return func(ctx *ucontext.Context, req unet.Request) error {
+			*order = append(*order, name)
+			return next(ctx, req)
+		}
+	}
+}
+
+// TestMiddlewareChain 测试中间件链的顺序与组级中间件的生效范围
+func TestMiddlewareChain(t *testing.T) {
+	var order []string
+	server := New()
+	server.Use(traceMiddleware("global", &order))
+
+	api := server.Group("/api")
+	v1 := api.Group("/v1")
+	v1.GET("/users", namedHandler("users"), traceMiddleware("route1", &order), traceMiddleware("route2", &order)).
+		Use(traceMiddleware("route3", &order))
+
+	// 注册路由之后的组级中间件同样生效,父分组的中间件先于子分组执行
+	v1.Use(traceMiddleware("v1", &order))
+	api.Use(traceMiddleware("api", &order))
+
+	w := serveRoute(server, "GET", "/api/v1/users")
+	if w.Body.String() != "users" {
+		t.Fatalf("Expected users, got %q", w.Body.String())
+	}
+	if got := strings.Join(order, ","); got != "global,api,v1,route1,route2,route3" {
+		t.Errorf("Unexpected middleware order: %s", got)
+	}
+
+	// 未启动时可以继续注册,下一次请求重新编译
+	order = nil
+	server.Use(traceMiddleware("late", &order))
+	serveRoute(server, "GET", "/api/v1/users")
+	if got := strings.Join(order, ","); got != "global,late,api,v1,route1,route2,route3" {
+		t.Errorf("Unexpected middleware order after late Use: %s", got)
+	}
+
+	routes := server.Routes()
+	if len(routes[0].Middlewares) != 7 {
+		t.Errorf("Expected 7 middlewares in route table, got %v", routes[0].Middlewares)
+	}
+}
+
+// TestRouteMeta 测试中间件读取路由元数据
+func TestRouteMeta(t *testing.T) {
+	server := New()
+	server.Use(func(next unet.HandlerFunc) unet.HandlerFunc {
+		return func(ctx *ucontext.Context, req unet.Request) error {
+			rt := req.(*Request).Route()
+			if rt == nil {
+				return next(ctx, req)
+			}
+			if perm, ok := rt.Get("permission"); ok {
+				req.Response().SetHeader("X-Permission", perm.(string))
+			}
+			return next(ctx, req)
+		}
+	})
+	server.DELETE("/users/:id", namedHandler("delete")).Set("permission", "user:delete")
+	server.GET("/users/:id", namedHandler("show"))
+
+	w := serveRoute(server, "DELETE", "/users/1")
+	if w.Header().Get("X-Permission") != "user:delete" || w.Body.String() != "delete id=1" {
+		t.Errorf("Expected permission header, got %q %q", w.Header().Get("X-Permission"), w.Body.String())
+	}
+	if w := serveRoute(server, "GET", "/users/1"); w.Header().Get("X-Permission") != "" {
+		t.Errorf("Expected no permission header, got %q", w.Header().Get("X-Permission"))
+	}
+	if w := serveRoute(server, "GET", "/missing"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", w.Code)
+	}
+}
+
+// TestFreeze 测试冻结后禁止注册
+func TestFreeze(t *testing.T) {
+	server := New()
+	g := server.Group("/api")
+	rt := g.GET("/ping", namedHandler("pong"))
+	host := server.Host("admin.example.com")
+	server.Freeze()
+
+	if w := serveRoute(server, "GET", "/api/ping"); w.Body.String() != "pong" {
+		t.Errorf("Expected pong after freeze, got %q", w.Body.String())
+	}
+
+	calls := map[string]func(){
+		"Server.Use":   func() { server.Use(traceMiddleware("x", new([]string))) },
+		"Server.GET":   func() { server.GET("/late", namedHandler("late")) },
+		"Server.Host":  func() { server.Host("late.example.com") },
+		"Group.Use":    func() { g.Use(traceMiddleware("x", new([]string))) },
+		"Group.GET":    func() { g.GET("/late", namedHandler("late")) },
+		"Route.Use":    func() { rt.Use(traceMiddleware("x", new([]string))) },
+		"Route.Name":   func() { rt.Name("late") },
+		"Host.GET":     func() { host.GET("/late", namedHandler("late")) },
+		"Host.Use":     func() { host.Use(traceMiddleware("x", new([]string))) },
+		"NotFound":     func() { server.NotFound(namedHandler("404")) },
+		"Host.Mount":   func() { host.Mount("/m", http.NotFoundHandler()) },
+		"Server.Mount": func() { server.Mount("/m", http.NotFoundHandler()) },
+	}
+	for name, call := range calls {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected panic after freeze", name)
+				}
+			}()
+			call()
+		}()
+	}
+}
+
+// discardWriter 丢弃响应的 ResponseWriter
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+// chainServer 创建带 n 个全局中间件的服务器
+func chainServer(n int) *Server {
+	server := New()
+	for i := 0; i < n; i++ {
+		server.Use(func(next unet.HandlerFunc) unet.HandlerFunc {
+			return func(ctx *ucontext.Context, req unet.Request) error {
+				return next(ctx, req)
+			}
+		})
+	}
+	server.GET("/users/:id", func(ctx *ucontext.Context, req unet.Request) error {
+		req.Response().Status(http.StatusNoContent)
+		return nil
+	})
+	server.Freeze()
+	return server
+}
+
+// TestMiddlewareChainAllocs 测试中间件数量不影响每次请求的内存分配
+func TestMiddlewareChainAllocs(t *testing.T) {
+	r := httptest.NewRequest("GET", "/users/1", nil)
+	w := &discardWriter{header: make(http.Header)}
+	measure := func(server *Server) float64 {
+		server.ServeHTTP(w, r)
+		return testing.AllocsPerRun(100, func() {
+			server.ServeHTTP(w, r)
+		})
+	}
+
+	base, chained := measure(chainServer(0)), measure(chainServer(5))
+	if chained != base {
+		t.Errorf("Expected same allocations with 5 middlewares, got %v vs %v", chained, base)
+	}
+}
+
+// BenchmarkServeHTTPMiddlewareChain 测试带中间件链的请求处理
+func BenchmarkServeHTTPMiddlewareChain(b *testing.B) {
+	for _, n := range []int{0, 5, 20} {
+		server := chainServer(n)
+		r := httptest.NewRequest("GET", "/users/1", nil)
+		w := &discardWriter{header: make(http.Header)}
+		b.Run(fmt.Sprintf("middlewares=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				server.ServeHTTP(w, r)
+			}
+		})
+	}
+}
@@ -11,7 +11,7 @@ type Config struct {
 	// 基础配置
 	Name     string // 服务名称
 	Protocol string // 协议类型: http, https
-	Address  string // 监听地址
+	Address  string // 监听地址,Unix 套接字使用 "unix:/path/to.sock"
 
 	// 超时配置
 	ReadTimeout  time.Duration // 读取超时
@@ -27,6 +27,19 @@ type Config struct {
 	KeepAlive   bool   // 是否启用 Keep-Alive
 	ServerAgent string // Server 头
 
+	// 代理配置
+	TrustedProxies  []string // 可信代理 (IP、CIDR 或 "unix"),只采信经这些代理转发的 Forwarded/X-Forwarded-* 头
+	ForwardedHeader string   // 采信的转发头: X-Forwarded-For (默认)、Forwarded 或 X-Real-IP,其他转发头忽略
+
+	// 监听配置
+	Listener *ListenerConfig // 监听配置 (Unix 套接字、热重启)
+
+	// 路由配置
+	Router *RouterConfig // 路由配置
+
+	// 虚拟主机配置
+	VHosts map[string][]string // 名称 -> 主机模式列表,通过 Server.VHost(名称) 注册路由
+
 	// 静态文件配置
 	Static *StaticFileConfig // 静态文件配置
 
@@ -59,6 +72,10 @@ type MiddlewareConfig struct {
 	EnableCSRF bool        // 是否启用 CSRF 中间件
 	CSRF       *CSRFConfig // CSRF 配置
 
+	// 压缩中间件
+	EnableCompress bool            // 是否启用响应压缩中间件
+	Compress       *CompressConfig // 压缩配置
+
 	// 超时中间件
 	EnableTimeout bool   // 是否启用超时中间件
 	Timeout       string // 超时时间,如 "30s"
@@ -78,6 +95,33 @@ type CookieFileConfig struct {
 	SameSite string // SameSite 策略: strict, lax, none
 }
 
+// ListenerConfig 监听配置
+type ListenerConfig struct {
+	SocketMode      string        // Unix 套接字权限,如 "0660"
+	SocketUser      string        // Unix 套接字属主 (用户名或 UID)
+	SocketGroup     string        // Unix 套接字属组 (组名或 GID)
+	GracefulRestart bool          // 是否在收到 SIGHUP/SIGUSR2 时热重启
+	ShutdownTimeout time.Duration // 关闭时等待进行中请求完成的最长时间
+}
+
+// RouterConfig 路由配置
+type RouterConfig struct {
+	RedirectTrailingSlash  bool // 路径仅结尾 '/' 不同时重定向到已注册的路由
+	RedirectFixedPath      bool // 路径规范化 (大小写、多余的 '/'、'..') 后重定向到已注册的路由
+	HandleMethodNotAllowed bool // 路径存在但方法不匹配时返回 405 和 Allow 头,否则返回 404
+	HandleOPTIONS          bool // 未注册 OPTIONS 路由时自动响应 OPTIONS 请求
+}
+
+// DefaultRouterConfig 默认路由配置 (全部启用)
+func DefaultRouterConfig() *RouterConfig {
+	return &RouterConfig{
+		RedirectTrailingSlash:  true,
+		RedirectFixedPath:      true,
+		HandleMethodNotAllowed: true,
+		HandleOPTIONS:          true,
+	}
+}
+
 // SessionFileConfig Session 文件配置
 type SessionFileConfig struct {
 	Enabled    bool   // 是否启用
@@ -88,11 +132,15 @@ type SessionFileConfig struct {
 
 // StaticFileConfig 静态文件配置
 type StaticFileConfig struct {
-	Enabled bool     // 是否启用
-	Root    string   // 静态文件根目录
-	Prefix  string   // URL 前缀
-	Index   []string // 索引文件列表
-	Browse  bool     // 是否允许目录浏览
+	Enabled       bool     // 是否启用
+	Root          string   // 静态文件根目录
+	Prefix        string   // URL 前缀
+	Index         []string // 索引文件列表
+	Browse        bool     // 是否允许目录浏览
+	SPA           bool     // 单页应用模式
+	Precompressed bool     // 返回预压缩的 .br / .gz 文件
+	Immutable     bool     // 带哈希的文件名使用 immutable 缓存
+	CacheControl  string   // 其他文件的 Cache-Control
 }
 
 // LogConfig 日志配置
@@ -129,17 +177,23 @@ func DefaultSessionConfig() *SessionConfig {
 // DefaultConfig 返回默认配置
 func DefaultConfig() *Config {
 	return &Config{
-		Name:           "uhttp-server",
-		Protocol:       "http",
-		Address:        ":8080",
-		ReadTimeout:    30 * time.Second,
-		WriteTimeout:   30 * time.Second,
-		IdleTimeout:    120 * time.Second,
-		MaxHeaderBytes: 1 << 20,  // 1MB
-		MaxBodyBytes:   10 << 20, // 10MB
-		MaxFormBytes:   10 << 20, // 10MB
-		KeepAlive:      true,
-		ServerAgent:    "UF/1.0",
+		Name:            "uhttp-server",
+		Protocol:        "http",
+		Address:         ":8080",
+		ReadTimeout:     30 * time.Second,
+		WriteTimeout:    30 * time.Second,
+		IdleTimeout:     120 * time.Second,
+		MaxHeaderBytes:  1 << 20,  // 1MB
+		MaxBodyBytes:    10 << 20, // 10MB
+		MaxFormBytes:    10 << 20, // 10MB
+		KeepAlive:       true,
+		ServerAgent:     "UF/1.0",
+		ForwardedHeader: "X-Forwarded-For",
+		Listener: &ListenerConfig{
+			GracefulRestart: false,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Router: DefaultRouterConfig(),
 		Middleware: &MiddlewareConfig{
 			// 核心中间件默认启用
 			EnableTrace:    true,
@@ -147,6 +201,7 @@ func DefaultConfig() *Config {
 			EnableRecovery: true,
 			// 其他中间件默认禁用
 			EnableCORS:      false,
+			EnableCompress:  false,
 			EnableTimeout:   false,
 			EnableRateLimit: false,
 		},
@@ -55,6 +55,40 @@ func (c *Config) UnmarshalYAML(key string, node *uconfig.Node) error {
 		c.KeepAlive = uconv.ToBoolDef(node, true)
 	case "server_agent":
 		c.ServerAgent = node.String()
+	case "trusted_proxies":
+		proxies, err := stringList(node)
+		if err != nil {
+			return uerror.Wrap(err, "解析 trusted_proxies 失败")
+		}
+		c.TrustedProxies = proxies
+	case "forwarded_header":
+		c.ForwardedHeader = node.String()
+	case "listener":
+		if c.Listener == nil {
+			c.Listener = &ListenerConfig{}
+		}
+		if err := node.Decode(c.Listener); err != nil {
+			return uerror.Wrap(err, "解析 listener 失败")
+		}
+	case "router":
+		if c.Router == nil {
+			c.Router = DefaultRouterConfig()
+		}
+		if err := node.Decode(c.Router); err != nil {
+			return uerror.Wrap(err, "解析 router 失败")
+		}
+	case "vhosts":
+		if node.Kind != uconfig.MappingNode {
+			return uerror.New("vhosts 必须是 名称: 主机列表 的映射")
+		}
+		c.VHosts = make(map[string][]string, len(node.Children))
+		for name, child := range node.Children {
+			hosts, err := stringList(child)
+			if err != nil {
+				return uerror.Wrap(err, "解析 vhosts."+name+" 失败")
+			}
+			c.VHosts[name] = hosts
+		}
 	case "static":
 		if c.Static == nil {
 			c.Static = &StaticFileConfig{}
@@ -140,6 +174,40 @@ func (l *LogConfig) UnmarshalYAML(key string, node *uconfig.Node) error {
 	return nil
 }
 
+// UnmarshalYAML 实现 uconfig.Unmarshaler 接口
+func (l *ListenerConfig) UnmarshalYAML(key string, node *uconfig.Node) error {
+	switch key {
+	case "socket_mode":
+		l.SocketMode = node.String()
+	case "socket_user":
+		l.SocketUser = node.String()
+	case "socket_group":
+		l.SocketGroup = node.String()
+	case "graceful_restart":
+		l.GracefulRestart = uconv.ToBoolDef(node, false)
+	case "shutdown_timeout":
+		if d, err := time.ParseDuration(node.String()); err == nil {
+			l.ShutdownTimeout = d
+		}
+	}
+	return nil
+}
+
+// UnmarshalYAML 实现 uconfig.Unmarshaler 接口
+func (r *RouterConfig) UnmarshalYAML(key string, node *uconfig.Node) error {
+	switch key {
+	case "redirect_trailing_slash":
+		r.RedirectTrailingSlash = uconv.ToBoolDef(node, true)
+	case "redirect_fixed_path":
+		r.RedirectFixedPath = uconv.ToBoolDef(node, true)
+	case "handle_method_not_allowed":
+		r.HandleMethodNotAllowed = uconv.ToBoolDef(node, true)
+	case "handle_options":
+		r.HandleOPTIONS = uconv.ToBoolDef(node, true)
+	}
+	return nil
+}
+
 // UnmarshalYAML 实现 uconfig.Unmarshaler 接口
 func (s *StaticFileConfig) UnmarshalYAML(key string, node *uconfig.Node) error {
 
@@ -162,6 +230,14 @@ func (s *StaticFileConfig) UnmarshalYAML(key string, node *uconfig.Node) error {
 		}
 	case "browse":
 		s.Browse = uconv.ToBoolDef(node, false)
+	case "spa":
+		s.SPA = uconv.ToBoolDef(node, false)
+	case "precompressed":
+		s.Precompressed = uconv.ToBoolDef(node, false)
+	case "immutable":
+		s.Immutable = uconv.ToBoolDef(node, false)
+	case "cache_control":
+		s.CacheControl = node.String()
 	}
 	return nil
 }
@@ -218,6 +294,16 @@ func (m *MiddlewareConfig) UnmarshalYAML(key string, node *uconfig.Node) error {
 		if err := node.Decode(m.CORS); err != nil {
 			return uerror.Wrap(err, "解析 cors 失败")
 		}
+	case "enable_compress":
+		m.EnableCompress = uconv.ToBoolDef(node, false)
+	case "compress":
+		if m.Compress == nil {
+			cfg := DefaultCompressConfig()
+			m.Compress = &cfg
+		}
+		if err := node.Decode(m.Compress); err != nil {
+			return uerror.Wrap(err, "解析 compress 失败")
+		}
 	case "enable_timeout":
 		m.EnableTimeout = uconv.ToBoolDef(node, false)
 	case "timeout":
@@ -266,6 +352,73 @@ func (r *RateLimitConfig) UnmarshalYAML(key string, node *uconfig.Node) error {
 		} else {
 			r.Window = 1 * time.Minute // 默认 1 分钟
 		}
+	case "algorithm":
+		switch a := RateLimitAlgorithm(node.String()); a {
+		case "", RateLimitFixedWindow, RateLimitTokenBucket, RateLimitSlidingWindow, RateLimitGCRA:
+			r.Algorithm = a
+		default:
+			return uerror.New("不支持的限流算法: " + string(a))
+		}
+	case "tiers":
+		if node.Kind != uconfig.MappingNode {
+			return uerror.New("tiers 必须是 等级名: 规则 的映射")
+		}
+		r.Tiers = make(map[string]RateLimit, len(node.Children))
+		for name, child := range node.Children {
+			var tier RateLimit
+			if err := child.Decode(&tier); err != nil {
+				return uerror.Wrap(err, "解析 tiers."+name+" 失败")
+			}
+			r.Tiers[name] = tier
+		}
+	case "disable_headers":
+		r.DisableHeaders = uconv.ToBoolDef(node, false)
+	case "fail_closed":
+		r.FailClosed = uconv.ToBoolDef(node, false)
+	}
+	return nil
+}
+
+// UnmarshalYAML 实现 uconfig.Unmarshaler 接口
+func (l *RateLimit) UnmarshalYAML(key string, node *uconfig.Node) error {
+	switch key {
+	case "max_requests", "limit":
+		l.Limit = uconv.ToIntDef(node, 0)
+	case "window":
+		d, err := time.ParseDuration(node.String())
+		if err != nil {
+			return uerror.Wrap(err, "window 格式错误")
+		}
+		l.Window = d
 	}
 	return nil
 }
+
+// UnmarshalYAML 实现 uconfig.Unmarshaler 接口
+func (c *CompressConfig) UnmarshalYAML(key string, node *uconfig.Node) error {
+	var err error
+	switch key {
+	case "level":
+		c.Level = uconv.ToIntDef(node, 0)
+	case "min_length":
+		c.MinLength = uconv.ToIntDef(node, 1024)
+	case "encodings":
+		c.Encodings, err = stringList(node)
+	case "excluded_types":
+		c.ExcludedTypes, err = stringList(node)
+	}
+	return err
+}
+
+// stringList 解析字符串列表,单个值可以直接写字符串
+func stringList(node *uconfig.Node) ([]string, error) {
+	if node.Kind == uconfig.ScalarNode {
+		return []string{node.String()}, nil
+	}
+	list := make([]string, 0, len(node.List))
+	err := node.Iter(func(i int, v *uconfig.Node) error {
+		list = append(list, v.String())
+		return nil
+	})
+	return list, err
+}
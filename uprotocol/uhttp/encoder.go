@@ -0,0 +1,212 @@
+package uhttp
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/whosafe/uf/uerror"
+	"github.com/whosafe/uf/uprotocol/umarshal"
+)
+
+// Encoder 响应编码器
+// 内置 JSON、XML、MessagePack、CSV 编码器,数据实现对应的 IMarshaler 风格接口
+// (umarshal.IMarshaler、IXMLMarshaler、IMsgPackMarshaler、ICSVMarshaler) 时不使用反射
+type Encoder interface {
+	// ContentType 响应的 Content-Type
+	ContentType() string
+	// Encode 编码数据
+	Encode(v any) ([]byte, error)
+}
+
+// ErrUnsupportedType 编码器不支持数据的类型 (用 uerror.Wrap 包装后返回)
+// Negotiate 遇到该错误时尝试下一个可接受的编码器
+var ErrUnsupportedType = uerror.New("编码器不支持该类型")
+
+// encoderEntry 注册的编码器
+type encoderEntry struct {
+	mediaType string // 小写,如 "application/json"
+	encoder   Encoder
+}
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = []encoderEntry{
+		{"application/json", JSONEncoder{}},
+		{"application/xml", XMLEncoder{}},
+		{"text/xml", XMLEncoder{}},
+		{"application/msgpack", MsgPackEncoder{}},
+		{"application/x-msgpack", MsgPackEncoder{}},
+		{"application/vnd.msgpack", MsgPackEncoder{}},
+		{"text/csv", CSVEncoder{}},
+	}
+)
+
+// RegisterEncoder 注册媒体类型的编码器,已注册的媒体类型替换其编码器
+// 新注册的媒体类型排在内置类型之后;Accept 为空或 */* 时使用第一个 (JSON)
+func RegisterEncoder(mediaType string, encoder Encoder) {
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if !strings.Contains(mediaType, "/") || strings.Contains(mediaType, "*") {
+		panic("媒体类型无效: " + mediaType)
+	}
+
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	for i := range encoders {
+		if encoders[i].mediaType == mediaType {
+			encoders[i].encoder = encoder
+			return
+		}
+	}
+	encoders = append(encoders, encoderEntry{mediaType, encoder})
+}
+
+// LookupEncoder 根据 Accept 头选择编码器,没有可接受的编码器时返回 nil
+// 按 q 值选择,q 值相同时优先 Accept 中靠前的类型;带结构化后缀的类型
+// (如 application/vnd.app.v2+json) 同时匹配后缀对应的编码器
+func LookupEncoder(accept string) Encoder {
+	if candidates := acceptableEncoders(accept); len(candidates) > 0 {
+		return candidates[0]
+	}
+	return nil
+}
+
+// acceptableEncoders 按优先级排列的可接受编码器 (规则同 LookupEncoder),同一编码器只出现一次
+func acceptableEncoders(accept string) []Encoder {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	type candidate struct {
+		encoder Encoder
+		q       float64
+		index   int
+	}
+	candidates := make([]candidate, 0, len(encoders))
+	for _, e := range encoders {
+		q, index := 1.0, 0
+		if strings.TrimSpace(accept) != "" {
+			q, index = acceptQuality(accept, e.mediaType)
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{e.encoder, q, index})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].q != candidates[j].q {
+			return candidates[i].q > candidates[j].q
+		}
+		return candidates[i].index < candidates[j].index
+	})
+
+	result := make([]Encoder, 0, len(candidates))
+	for _, c := range candidates {
+		if !slices.Contains(result, c.encoder) {
+			result = append(result, c.encoder)
+		}
+	}
+	return result
+}
+
+// acceptQuality 媒体类型在 Accept 中的 q 值及匹配项的位置
+// 多项匹配时取最具体的一项 (完整类型 > type/* > */*)
+func acceptQuality(accept, mediaType string) (float64, int) {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	q, index, specificity := 0.0, 0, -1
+
+	for i := 0; accept != ""; i++ {
+		var item string
+		item, accept, _ = strings.Cut(accept, ",")
+		rangeType, params, _ := strings.Cut(item, ";")
+		rangeType = strings.TrimSpace(rangeType)
+
+		s := -1
+		switch {
+		case rangeType == "*/*":
+			s = 0
+		case strings.EqualFold(rangeType, typ+"/*"):
+			s = 1
+		case strings.EqualFold(rangeType, mediaType):
+			s = 3
+		default:
+			// 结构化后缀: application/vnd.app.v2+json 匹配 application/json
+			if rt, rs, ok := strings.Cut(rangeType, "/"); ok && strings.EqualFold(rt, typ) {
+				if j := strings.LastIndexByte(rs, '+'); j >= 0 && strings.EqualFold(rs[j+1:], subtype) {
+					s = 2
+				}
+			}
+		}
+		if s > specificity {
+			q, index, specificity = parseQuality(params), i, s
+		}
+	}
+	return q, index
+}
+
+// parseQuality 解析媒体类型参数中的 q 值,缺省为 1
+func parseQuality(params string) float64 {
+	for params != "" {
+		var param string
+		param, params, _ = strings.Cut(params, ";")
+		key, value, _ := strings.Cut(param, "=")
+		if strings.TrimSpace(key) != "q" {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || q < 0 {
+			return 0
+		}
+		return min(q, 1)
+	}
+	return 1
+}
+
+// acceptableTypes 已注册的媒体类型,用于 406 响应
+func acceptableTypes() string {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	types := make([]string, len(encoders))
+	for i, e := range encoders {
+		types[i] = e.mediaType
+	}
+	return strings.Join(types, ", ")
+}
+
+// Negotiate 根据请求的 Accept 头选择编码器返回响应
+// 编码器不支持数据的类型 (ErrUnsupportedType) 时按优先级尝试下一个可接受的编码器,
+// 都不可用时返回 406 及支持的类型列表
+func (r *Response) Negotiate(code int, data any) error {
+	r.AddHeader("Vary", "Accept")
+
+	for _, encoder := range acceptableEncoders(r.request.Header.Get("Accept")) {
+		body, err := encoder.Encode(data)
+		if errors.Is(err, ErrUnsupportedType) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		r.SetHeader("Content-Type", encoder.ContentType())
+		r.Status(code)
+		_, err = r.writer.Write(body)
+		return err
+	}
+	return r.String(http.StatusNotAcceptable, "406 Not Acceptable, supported: "+acceptableTypes())
+}
+
+// JSONEncoder JSON 编码器 (umarshal)
+type JSONEncoder struct{}
+
+// ContentType 实现 Encoder 接口
+func (JSONEncoder) ContentType() string {
+	return "application/json"
+}
+
+// Encode 实现 Encoder 接口
+func (JSONEncoder) Encode(v any) ([]byte, error) {
+	return umarshal.Marshal(v)
+}
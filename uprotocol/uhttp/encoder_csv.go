@@ -0,0 +1,71 @@
+package uhttp
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+
+	"github.com/whosafe/uf/uerror"
+)
+
+// ICSVMarshaler 自定义 CSV 序列化接口 (不使用反射)
+// 实现方写入表头和数据行
+type ICSVMarshaler interface {
+	MarshalCSV(w *CSVWriter) error
+}
+
+// CSVWriter CSV 写入器
+type CSVWriter struct {
+	buf bytes.Buffer
+	csv *csv.Writer
+}
+
+// newCSVWriter 创建 CSV 写入器
+func newCSVWriter() *CSVWriter {
+	w := &CSVWriter{}
+	w.csv = csv.NewWriter(&w.buf)
+	return w
+}
+
+// WriteRow 写入一行
+func (w *CSVWriter) WriteRow(fields ...string) error {
+	return w.csv.Write(fields)
+}
+
+// Bytes 获取已写入的数据
+func (w *CSVWriter) Bytes() ([]byte, error) {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return nil, err
+	}
+	return w.buf.Bytes(), nil
+}
+
+// CSVEncoder CSV 编码器
+// 数据需实现 ICSVMarshaler 或为 [][]string
+type CSVEncoder struct{}
+
+// ContentType 实现 Encoder 接口
+func (CSVEncoder) ContentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+// Encode 实现 Encoder 接口
+func (CSVEncoder) Encode(v any) ([]byte, error) {
+	w := newCSVWriter()
+	switch val := v.(type) {
+	case ICSVMarshaler:
+		if err := val.MarshalCSV(w); err != nil {
+			return nil, err
+		}
+	case [][]string:
+		for _, row := range val {
+			if err := w.WriteRow(row...); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, uerror.Wrap(ErrUnsupportedType, fmt.Sprintf("CSV 不支持类型 %T,请实现 ICSVMarshaler", v))
+	}
+	return w.Bytes()
+}
@@ -0,0 +1,251 @@
+package uhttp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/whosafe/uf/uerror"
+)
+
+// IMsgPackMarshaler 自定义 MessagePack 序列化接口 (不使用反射)
+type IMsgPackMarshaler interface {
+	MarshalMsgPack(w *MsgPackWriter) error
+}
+
+// MsgPackWriter MessagePack 写入器
+type MsgPackWriter struct {
+	buf []byte
+}
+
+// Bytes 获取已写入的数据
+func (w *MsgPackWriter) Bytes() []byte {
+	return w.buf
+}
+
+// WriteNil 写入 nil
+func (w *MsgPackWriter) WriteNil() {
+	w.buf = append(w.buf, 0xc0)
+}
+
+// WriteBool 写入布尔值
+func (w *MsgPackWriter) WriteBool(b bool) {
+	if b {
+		w.buf = append(w.buf, 0xc3)
+	} else {
+		w.buf = append(w.buf, 0xc2)
+	}
+}
+
+// WriteInt 写入有符号整数 (使用最短编码)
+func (w *MsgPackWriter) WriteInt(n int64) {
+	switch {
+	case n >= 0:
+		w.WriteUint(uint64(n))
+	case n >= -32:
+		w.buf = append(w.buf, byte(n))
+	case n >= math.MinInt8:
+		w.buf = append(w.buf, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, 0xd1), uint16(n))
+	case n >= math.MinInt32:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xd2), uint32(n))
+	default:
+		w.buf = binary.BigEndian.AppendUint64(append(w.buf, 0xd3), uint64(n))
+	}
+}
+
+// WriteUint 写入无符号整数 (使用最短编码)
+func (w *MsgPackWriter) WriteUint(n uint64) {
+	switch {
+	case n <= 0x7f:
+		w.buf = append(w.buf, byte(n))
+	case n <= math.MaxUint8:
+		w.buf = append(w.buf, 0xcc, byte(n))
+	case n <= math.MaxUint16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, 0xcd), uint16(n))
+	case n <= math.MaxUint32:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xce), uint32(n))
+	default:
+		w.buf = binary.BigEndian.AppendUint64(append(w.buf, 0xcf), n)
+	}
+}
+
+// WriteFloat32 写入 float32
+func (w *MsgPackWriter) WriteFloat32(f float32) {
+	w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xca), math.Float32bits(f))
+}
+
+// WriteFloat64 写入 float64
+func (w *MsgPackWriter) WriteFloat64(f float64) {
+	w.buf = binary.BigEndian.AppendUint64(append(w.buf, 0xcb), math.Float64bits(f))
+}
+
+// WriteString 写入字符串
+func (w *MsgPackWriter) WriteString(s string) {
+	n := len(s)
+	switch {
+	case n <= 31:
+		w.buf = append(w.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		w.buf = append(w.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, 0xda), uint16(n))
+	default:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xdb), uint32(n))
+	}
+	w.buf = append(w.buf, s...)
+}
+
+// WriteBinary 写入二进制数据
+func (w *MsgPackWriter) WriteBinary(b []byte) {
+	n := len(b)
+	switch {
+	case n <= math.MaxUint8:
+		w.buf = append(w.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, 0xc5), uint16(n))
+	default:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xc6), uint32(n))
+	}
+	w.buf = append(w.buf, b...)
+}
+
+// WriteArrayHeader 写入数组头,之后写入 n 个元素
+func (w *MsgPackWriter) WriteArrayHeader(n int) {
+	switch {
+	case n <= 15:
+		w.buf = append(w.buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, 0xdc), uint16(n))
+	default:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xdd), uint32(n))
+	}
+}
+
+// WriteMapHeader 写入映射头,之后写入 n 组键值
+func (w *MsgPackWriter) WriteMapHeader(n int) {
+	switch {
+	case n <= 15:
+		w.buf = append(w.buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, 0xde), uint16(n))
+	default:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xdf), uint32(n))
+	}
+}
+
+// WriteValue 写入值,支持 IMsgPackMarshaler、基础类型、map[string]any/string/int 和 []any/[]string
+// 其他类型返回错误
+func (w *MsgPackWriter) WriteValue(v any) error {
+	switch val := v.(type) {
+	case nil:
+		w.WriteNil()
+	case IMsgPackMarshaler:
+		return val.MarshalMsgPack(w)
+	case string:
+		w.WriteString(val)
+	case []byte:
+		w.WriteBinary(val)
+	case bool:
+		w.WriteBool(val)
+	case int:
+		w.WriteInt(int64(val))
+	case int8:
+		w.WriteInt(int64(val))
+	case int16:
+		w.WriteInt(int64(val))
+	case int32:
+		w.WriteInt(int64(val))
+	case int64:
+		w.WriteInt(val)
+	case uint:
+		w.WriteUint(uint64(val))
+	case uint8:
+		w.WriteUint(uint64(val))
+	case uint16:
+		w.WriteUint(uint64(val))
+	case uint32:
+		w.WriteUint(uint64(val))
+	case uint64:
+		w.WriteUint(val)
+	case float32:
+		w.WriteFloat32(val)
+	case float64:
+		w.WriteFloat64(val)
+	case map[string]any:
+		w.WriteMapHeader(len(val))
+		for _, k := range sortedKeys(val) {
+			w.WriteString(k)
+			if err := w.WriteValue(val[k]); err != nil {
+				return err
+			}
+		}
+	case map[string]string:
+		w.WriteMapHeader(len(val))
+		for _, k := range sortedKeys(val) {
+			w.WriteString(k)
+			w.WriteString(val[k])
+		}
+	case map[string]int:
+		w.WriteMapHeader(len(val))
+		for _, k := range sortedKeys(val) {
+			w.WriteString(k)
+			w.WriteInt(int64(val[k]))
+		}
+	case []any:
+		w.WriteArrayHeader(len(val))
+		for _, item := range val {
+			if err := w.WriteValue(item); err != nil {
+				return err
+			}
+		}
+	case []string:
+		w.WriteArrayHeader(len(val))
+		for _, item := range val {
+			w.WriteString(item)
+		}
+	case []int:
+		w.WriteArrayHeader(len(val))
+		for _, item := range val {
+			w.WriteInt(int64(item))
+		}
+	case []int64:
+		w.WriteArrayHeader(len(val))
+		for _, item := range val {
+			w.WriteInt(item)
+		}
+	case []float64:
+		w.WriteArrayHeader(len(val))
+		for _, item := range val {
+			w.WriteFloat64(item)
+		}
+	case []map[string]any:
+		w.WriteArrayHeader(len(val))
+		for _, item := range val {
+			if err := w.WriteValue(item); err != nil {
+				return err
+			}
+		}
+	default:
+		return uerror.Wrap(ErrUnsupportedType, fmt.Sprintf("MessagePack 不支持类型 %T,请实现 IMsgPackMarshaler", v))
+	}
+	return nil
+}
+
+// MsgPackEncoder MessagePack 编码器
+type MsgPackEncoder struct{}
+
+// ContentType 实现 Encoder 接口
+func (MsgPackEncoder) ContentType() string {
+	return "application/msgpack"
+}
+
+// Encode 实现 Encoder 接口
+func (MsgPackEncoder) Encode(v any) ([]byte, error) {
+	w := &MsgPackWriter{buf: make([]byte, 0, 256)}
+	if err := w.WriteValue(v); err != nil {
+		return nil, err
+	}
+	return w.buf, nil
+}
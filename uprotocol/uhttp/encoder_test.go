@@ -0,0 +1,189 @@
+package uhttp
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/umarshal"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// userList 实现各编码器接口的测试数据
+type userList []string
+
+func (u userList) MarshalCSV(w *CSVWriter) error {
+	if err := w.WriteRow("id", "name"); err != nil {
+		return err
+	}
+	for i, name := range u {
+		if err := w.WriteRow(string(rune('1'+i)), name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonOnly 只实现 umarshal.IMarshaler 的数据
+type jsonOnly struct{}
+
+func (jsonOnly) Marshal(w *umarshal.Writer) error {
+	w.WriteString("json")
+	return nil
+}
+
+// badXMLName 使用无效元素名称的 IXMLMarshaler
+type badXMLName struct{}
+
+func (badXMLName) MarshalXMLTo(w *XMLWriter) error {
+	w.WriteElement("a b", "x")
+	return nil
+}
+
+// TestLookupEncoder 测试根据 Accept 选择编码器
+func TestLookupEncoder(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   Encoder
+	}{
+		{"", JSONEncoder{}},
+		{"*/*", JSONEncoder{}},
+		{"application/xml", XMLEncoder{}},
+		{"text/csv;q=0.5, application/msgpack", MsgPackEncoder{}},
+		{"application/xml, application/json", XMLEncoder{}},
+		{"application/json;q=0.1, text/csv;q=0.8", CSVEncoder{}},
+		{"text/*", XMLEncoder{}},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", XMLEncoder{}},
+		{"application/vnd.app.v2+json", JSONEncoder{}},
+		{"application/json;q=0, */*", XMLEncoder{}},
+		{"Application/X-MsgPack", MsgPackEncoder{}},
+		{"image/png", nil},
+	}
+	for _, tt := range tests {
+		if got := LookupEncoder(tt.accept); got != tt.want {
+			t.Errorf("LookupEncoder(%q) = %T, want %T", tt.accept, got, tt.want)
+		}
+	}
+}
+
+// TestNegotiate 测试内容协商响应
+func TestNegotiate(t *testing.T) {
+	server := New()
+	server.GET("/user", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().(*Response).Negotiate(201, Success(map[string]any{"name": "a<b", "age": 3}))
+	})
+	server.GET("/users", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().(*Response).Negotiate(200, userList{"alice", "bob"})
+	})
+	server.GET("/numbers", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().(*Response).Negotiate(200, Success([]int{1, 2}))
+	})
+	server.GET("/custom", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().(*Response).Negotiate(200, Success(jsonOnly{}))
+	})
+
+	tests := []struct {
+		target, accept, contentType, body string
+		code                              int
+	}{
+		{"/user", "", "application/json", `"code":0`, 201},
+		{"/user", "application/xml", "application/xml; charset=utf-8",
+			`<response><code>0</code><data><age>3</age><name>a&lt;b</name></data></response>`, 201},
+		{"/user", "application/msgpack", "application/msgpack",
+			"\x82\xa4code\x00\xa4data\x82\xa3age\x03\xa4name\xa3a<b", 201},
+		{"/users", "text/csv", "text/csv; charset=utf-8", "id,name\n1,alice\n2,bob\n", 200},
+		{"/user", "image/png", "text/plain; charset=utf-8", "406 Not Acceptable", 406},
+		{"/numbers", "application/msgpack", "application/msgpack", "\xa4data\x92\x01\x02", 200},
+		// 编码器不支持数据类型时尝试下一个可接受的编码器
+		{"/user", "text/csv, application/xml;q=0.5", "application/xml; charset=utf-8", "<response>", 201},
+		{"/custom", "application/msgpack, application/xml, */*;q=0.1", "application/json", `"data":"json"`, 200},
+		{"/custom", "application/msgpack, application/xml", "text/plain; charset=utf-8", "406 Not Acceptable", 406},
+		{"/user", "text/csv", "text/plain; charset=utf-8", "406 Not Acceptable", 406},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.target, nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+		if w.Code != tt.code || w.Header().Get("Content-Type") != tt.contentType ||
+			!strings.Contains(w.Body.String(), tt.body) || w.Header().Get("Vary") != "Accept" {
+			t.Errorf("%s (%s) = %d %q %q", tt.target, tt.accept, w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+	}
+
+	// 不是合法 XML 名称的键写为属性,不会注入标签
+	data, err := (XMLEncoder{}).Encode(map[string]any{"x><admin>1</admin><y": "b", "first name": 1, "名字": "a"})
+	if err != nil || !strings.Contains(string(data),
+		`<response><item key="first name">1</item><item key="x&gt;&lt;admin&gt;1&lt;/admin&gt;&lt;y">b</item><名字>a</名字></response>`) {
+		t.Errorf("Unexpected XML for invalid keys: %s %v", data, err)
+	}
+	if _, err := (XMLEncoder{}).Encode(badXMLName{}); err == nil {
+		t.Error("Expected error for invalid element name")
+	}
+
+	// 不支持的类型返回错误
+	if _, err := (CSVEncoder{}).Encode(map[string]any{}); err == nil {
+		t.Error("Expected CSV error for map")
+	}
+	if _, err := (MsgPackEncoder{}).Encode(struct{}{}); err == nil {
+		t.Error("Expected MessagePack error for struct")
+	}
+}
+
+// TestMsgPackWriter 测试 MessagePack 编码长度边界
+func TestMsgPackWriter(t *testing.T) {
+	tests := []struct {
+		v    any
+		want []byte
+	}{
+		{-1, []byte{0xff}},
+		{-33, []byte{0xd0, 0xdf}},
+		{200, []byte{0xcc, 0xc8}},
+		{70000, []byte{0xce, 0x00, 0x01, 0x11, 0x70}},
+		{int64(-40000), []byte{0xd2, 0xff, 0xff, 0x63, 0xc0}},
+		{true, []byte{0xc3}},
+		{nil, []byte{0xc0}},
+		{[]string{"a"}, []byte{0x91, 0xa1, 'a'}},
+		{strings.Repeat("x", 40), append([]byte{0xd9, 40}, strings.Repeat("x", 40)...)},
+		{1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		got, err := (MsgPackEncoder{}).Encode(tt.v)
+		if err != nil || !bytes.Equal(got, tt.want) {
+			t.Errorf("Encode(%v) = %x, %v, want %x", tt.v, got, err, tt.want)
+		}
+	}
+}
+
+// customEncoder 自定义编码器
+type customEncoder struct{}
+
+func (customEncoder) ContentType() string          { return "text/x-custom" }
+func (customEncoder) Encode(v any) ([]byte, error) { return []byte("custom"), nil }
+
+// TestRegisterEncoder 测试注册自定义编码器
+func TestRegisterEncoder(t *testing.T) {
+	RegisterEncoder("text/x-custom", customEncoder{})
+	defer func() {
+		encodersMu.Lock()
+		encoders = encoders[:len(encoders)-1]
+		encodersMu.Unlock()
+	}()
+
+	if got := LookupEncoder("text/x-custom"); got != (customEncoder{}) {
+		t.Errorf("Expected custom encoder, got %T", got)
+	}
+	if got := LookupEncoder("*/*"); got != (JSONEncoder{}) {
+		t.Errorf("Expected JSON for */*, got %T", got)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for wildcard media type")
+		}
+	}()
+	RegisterEncoder("text/*", customEncoder{})
+}
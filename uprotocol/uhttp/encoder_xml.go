@@ -0,0 +1,248 @@
+package uhttp
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"unicode"
+
+	"github.com/whosafe/uf/uerror"
+	"github.com/whosafe/uf/uprotocol/umarshal"
+)
+
+// IXMLMarshaler 自定义 XML 序列化接口 (不使用反射)
+type IXMLMarshaler interface {
+	MarshalXMLTo(w *XMLWriter) error
+}
+
+// XMLWriter XML 写入器
+type XMLWriter struct {
+	buf []byte
+	err error // 第一个无效的元素名称,Encode 返回该错误
+}
+
+// Bytes 获取已写入的数据
+func (w *XMLWriter) Bytes() []byte {
+	return w.buf
+}
+
+// StartElement 写入开始标签 <name>
+// name 必须是合法的 XML 名称,否则不写入并记录错误 (Encode 返回该错误)
+func (w *XMLWriter) StartElement(name string) {
+	if !w.checkName(name) {
+		return
+	}
+	w.buf = append(w.buf, '<')
+	w.buf = append(w.buf, name...)
+	w.buf = append(w.buf, '>')
+}
+
+// EndElement 写入结束标签 </name>
+func (w *XMLWriter) EndElement(name string) {
+	if !w.checkName(name) {
+		return
+	}
+	w.buf = append(w.buf, '<', '/')
+	w.buf = append(w.buf, name...)
+	w.buf = append(w.buf, '>')
+}
+
+// checkName 检查元素名称,无效时记录错误
+func (w *XMLWriter) checkName(name string) bool {
+	if isXMLName(name) {
+		return true
+	}
+	if w.err == nil {
+		w.err = uerror.New("XML 元素名称无效: " + strconv.Quote(name))
+	}
+	return false
+}
+
+// isXMLName 是否是合法的 XML 名称 (不含命名空间前缀):字母或下划线开头,后续为字母、数字、'-'、'.'、'_'
+func isXMLName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+		case i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r)):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// writeField 写入 map 的一项,键不是合法的 XML 名称时写为 <item key="...">
+func (w *XMLWriter) writeField(key string, v any) error {
+	if isXMLName(key) {
+		return w.WriteValue(key, v)
+	}
+	w.buf = append(w.buf, `<item key="`...)
+	w.WriteText(key)
+	w.buf = append(w.buf, `">`...)
+	if err := w.writeContent(v); err != nil {
+		return err
+	}
+	w.EndElement("item")
+	return nil
+}
+
+// WriteText 写入转义后的文本
+func (w *XMLWriter) WriteText(s string) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '<':
+			w.buf = append(w.buf, "&lt;"...)
+		case '>':
+			w.buf = append(w.buf, "&gt;"...)
+		case '&':
+			w.buf = append(w.buf, "&amp;"...)
+		case '"':
+			w.buf = append(w.buf, "&#34;"...)
+		case '\'':
+			w.buf = append(w.buf, "&#39;"...)
+		case '\t', '\n', '\r':
+			w.buf = append(w.buf, c)
+		default:
+			if c < 0x20 {
+				w.buf = append(w.buf, "\uFFFD"...) // XML 不允许的控制字符
+				continue
+			}
+			w.buf = append(w.buf, c)
+		}
+	}
+}
+
+// WriteElement 写入文本元素 <name>text</name>
+func (w *XMLWriter) WriteElement(name, text string) {
+	w.StartElement(name)
+	w.WriteText(text)
+	w.EndElement(name)
+}
+
+// WriteValue 写入元素,值支持 IXMLMarshaler、基础类型、map[string]any/string/int 和 []any/[]string
+// 数组元素写为 <item>,不是合法 XML 名称的 map 键写为 <item key="...">;其他类型使用 encoding/xml (反射) 序列化
+func (w *XMLWriter) WriteValue(name string, v any) error {
+	w.StartElement(name)
+	if err := w.writeContent(v); err != nil {
+		return err
+	}
+	w.EndElement(name)
+	return nil
+}
+
+// writeContent 写入元素内容
+func (w *XMLWriter) writeContent(v any) error {
+	switch val := v.(type) {
+	case nil:
+	case IXMLMarshaler:
+		return val.MarshalXMLTo(w)
+	case umarshal.IMarshaler:
+		// 按 encoding/xml 反射的结果与 JSON (Marshal) 不一致,交给下一个编码器
+		return uerror.Wrap(ErrUnsupportedType, fmt.Sprintf("XML 不支持类型 %T,请实现 IXMLMarshaler", v))
+	case string:
+		w.WriteText(val)
+	case []byte:
+		w.WriteText(string(val))
+	case bool:
+		w.buf = strconv.AppendBool(w.buf, val)
+	case int:
+		w.buf = strconv.AppendInt(w.buf, int64(val), 10)
+	case int8:
+		w.buf = strconv.AppendInt(w.buf, int64(val), 10)
+	case int16:
+		w.buf = strconv.AppendInt(w.buf, int64(val), 10)
+	case int32:
+		w.buf = strconv.AppendInt(w.buf, int64(val), 10)
+	case int64:
+		w.buf = strconv.AppendInt(w.buf, val, 10)
+	case uint:
+		w.buf = strconv.AppendUint(w.buf, uint64(val), 10)
+	case uint8:
+		w.buf = strconv.AppendUint(w.buf, uint64(val), 10)
+	case uint16:
+		w.buf = strconv.AppendUint(w.buf, uint64(val), 10)
+	case uint32:
+		w.buf = strconv.AppendUint(w.buf, uint64(val), 10)
+	case uint64:
+		w.buf = strconv.AppendUint(w.buf, val, 10)
+	case float32:
+		w.buf = strconv.AppendFloat(w.buf, float64(val), 'g', -1, 32)
+	case float64:
+		w.buf = strconv.AppendFloat(w.buf, val, 'g', -1, 64)
+	case map[string]any:
+		for _, k := range sortedKeys(val) {
+			if err := w.writeField(k, val[k]); err != nil {
+				return err
+			}
+		}
+	case map[string]string:
+		for _, k := range sortedKeys(val) {
+			w.writeField(k, val[k])
+		}
+	case map[string]int:
+		for _, k := range sortedKeys(val) {
+			w.writeField(k, val[k])
+		}
+	case []any:
+		for _, item := range val {
+			if err := w.WriteValue("item", item); err != nil {
+				return err
+			}
+		}
+	case []string:
+		for _, item := range val {
+			w.WriteElement("item", item)
+		}
+	default:
+		data, err := xml.Marshal(v)
+		if err != nil {
+			var unsupported *xml.UnsupportedTypeError
+			if errors.As(err, &unsupported) {
+				return uerror.Wrap(ErrUnsupportedType, err.Error())
+			}
+			return err
+		}
+		w.buf = append(w.buf, data...)
+	}
+	return nil
+}
+
+// sortedKeys map 的有序键,保证输出稳定
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// XMLEncoder XML 编码器
+// 实现 IXMLMarshaler 的数据自行写入根元素,其他数据包装在 <response> 中
+type XMLEncoder struct{}
+
+// ContentType 实现 Encoder 接口
+func (XMLEncoder) ContentType() string {
+	return "application/xml; charset=utf-8"
+}
+
+// Encode 实现 Encoder 接口
+func (XMLEncoder) Encode(v any) ([]byte, error) {
+	w := &XMLWriter{buf: append(make([]byte, 0, 512), xml.Header...)}
+	if m, ok := v.(IXMLMarshaler); ok {
+		if err := m.MarshalXMLTo(w); err != nil {
+			return nil, err
+		}
+	} else if err := w.WriteValue("response", v); err != nil {
+		return nil, err
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+	return w.buf, nil
+}
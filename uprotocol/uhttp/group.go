@@ -6,64 +6,76 @@ import "github.com/whosafe/uf/uprotocol/unet"
 type Group struct {
 	prefix      string
 	server      *Server
+	router      *Router  // 服务器或虚拟主机的路由器
+	parent      *Group   // 父分组,其中间件在本组之前执行
+	version     *Version // 所属 API 版本
 	middlewares []unet.MiddlewareFunc
 }
 
-// Group 创建子路由组
+// Group 创建子路由组,继承父分组 (包括之后注册) 的中间件
 func (g *Group) Group(prefix string) *Group {
 	return &Group{
-		prefix:      g.prefix + prefix,
-		server:      g.server,
-		middlewares: append([]unet.MiddlewareFunc{}, g.middlewares...),
+		prefix:  g.prefix + prefix,
+		server:  g.server,
+		router:  g.router,
+		parent:  g,
+		version: g.version,
 	}
 }
 
 // Use 注册组级中间件
+// 对组内 (包括子分组) 所有路由生效,与路由注册的先后顺序无关
 func (g *Group) Use(middleware ...unet.MiddlewareFunc) {
+	if g.router.frozen {
+		panic(frozenMessage)
+	}
 	g.middlewares = append(g.middlewares, middleware...)
+	g.router.gen++
 }
 
 // GET 注册 GET 请求处理器
-func (g *Group) GET(path string, handler unet.HandlerFunc) {
-	g.handle("GET", path, handler)
+// middlewares 为路由级中间件,在组级中间件之后执行
+func (g *Group) GET(path string, handler unet.HandlerFunc, middlewares ...unet.MiddlewareFunc) *Route {
+	return g.handle("GET", path, handler, middlewares)
 }
 
 // POST 注册 POST 请求处理器
-func (g *Group) POST(path string, handler unet.HandlerFunc) {
-	g.handle("POST", path, handler)
+func (g *Group) POST(path string, handler unet.HandlerFunc, middlewares ...unet.MiddlewareFunc) *Route {
+	return g.handle("POST", path, handler, middlewares)
 }
 
 // PUT 注册 PUT 请求处理器
-func (g *Group) PUT(path string, handler unet.HandlerFunc) {
-	g.handle("PUT", path, handler)
+func (g *Group) PUT(path string, handler unet.HandlerFunc, middlewares ...unet.MiddlewareFunc) *Route {
+	return g.handle("PUT", path, handler, middlewares)
 }
 
 // DELETE 注册 DELETE 请求处理器
-func (g *Group) DELETE(path string, handler unet.HandlerFunc) {
-	g.handle("DELETE", path, handler)
+func (g *Group) DELETE(path string, handler unet.HandlerFunc, middlewares ...unet.MiddlewareFunc) *Route {
+	return g.handle("DELETE", path, handler, middlewares)
 }
 
 // PATCH 注册 PATCH 请求处理器
-func (g *Group) PATCH(path string, handler unet.HandlerFunc) {
-	g.handle("PATCH", path, handler)
+func (g *Group) PATCH(path string, handler unet.HandlerFunc, middlewares ...unet.MiddlewareFunc) *Route {
+	return g.handle("PATCH", path, handler, middlewares)
 }
 
 // HEAD 注册 HEAD 请求处理器
-func (g *Group) HEAD(path string, handler unet.HandlerFunc) {
-	g.handle("HEAD", path, handler)
+func (g *Group) HEAD(path string, handler unet.HandlerFunc, middlewares ...unet.MiddlewareFunc) *Route {
+	return g.handle("HEAD", path, handler, middlewares)
 }
 
 // OPTIONS 注册 OPTIONS 请求处理器
-func (g *Group) OPTIONS(path string, handler unet.HandlerFunc) {
-	g.handle("OPTIONS", path, handler)
+func (g *Group) OPTIONS(path string, handler unet.HandlerFunc, middlewares ...unet.MiddlewareFunc) *Route {
+	return g.handle("OPTIONS", path, handler, middlewares)
 }
 
 // handle 处理路由注册
-func (g *Group) handle(method, path string, handler unet.HandlerFunc) {
-	// 应用组级中间件
-	finalHandler := applyMiddlewares(handler, g.middlewares)
-
-	// 添加路由
-	fullPath := g.prefix + path
-	g.server.router.addRoute(method, fullPath, finalHandler)
+func (g *Group) handle(method, path string, handler unet.HandlerFunc, middlewares []unet.MiddlewareFunc) *Route {
+	rt := g.router.addRoute(method, g.prefix+path, handler)
+	rt.group = g
+	rt.middlewares = middlewares
+	if g.version != nil {
+		g.version.register(rt)
+	}
+	return rt
 }
@@ -0,0 +1,265 @@
+package uhttp
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// routeGroup Group 的别名,内嵌时字段名不与 Group 方法冲突
+type routeGroup = Group
+
+// Host 虚拟主机
+// 拥有独立的路由器、中间件和 404/405 处理器,路由注册方法与 Group 相同
+type Host struct {
+	*routeGroup
+	patterns    []string
+	middlewares []unet.MiddlewareFunc
+	notFound    unet.HandlerFunc // 为空时使用服务器的处理器
+	notAllowed  unet.HandlerFunc // 为空时使用服务器的处理器
+	handlers    *handlerSet      // 编译后的处理器 (启动时生成)
+}
+
+// hostPattern 解析后的主机模式
+type hostPattern struct {
+	pattern string
+	labels  []hostLabel
+	params  int
+	host    *Host
+}
+
+// hostLabel 主机模式中以 '.' 分隔的一段
+type hostLabel struct {
+	static     string           // 静态文本 (小写)
+	param      string           // 参数名称 (非空时为参数段)
+	constraint *paramConstraint // 参数约束
+}
+
+// Host 创建虚拟主机,请求的 Host (去掉端口,不区分大小写) 匹配任一模式时使用该主机的路由
+// 模式中的参数占完整的一段,如 "{tenant}.example.com"、"{id:int}.shop.example.com",
+// 参数值通过 req.Param 获取 (位于路径参数之前);静态模式优先于参数模式,参数少的优先。
+// 未匹配任何虚拟主机的请求使用服务器自身的路由;模式重复或格式错误时 panic
+func (s *Server) Host(pattern string, aliases ...string) *Host {
+	if s.router.frozen {
+		panic(frozenMessage)
+	}
+	h := &Host{patterns: append([]string{pattern}, aliases...)}
+	router := NewRouter()
+	router.names = s.router.names // 路由名称全局唯一,Server.URL 可生成虚拟主机的路径
+	h.routeGroup = &Group{server: s, router: router}
+
+	for _, p := range h.patterns {
+		hp := parseHostPattern(p)
+		hp.host = h
+		if s.hostExists(hp.pattern) {
+			panic("虚拟主机重复: " + p)
+		}
+		if hp.params == 0 {
+			if s.staticHosts == nil {
+				s.staticHosts = make(map[string]*Host)
+			}
+			s.staticHosts[hp.pattern] = h
+			continue
+		}
+		s.hostPatterns = append(s.hostPatterns, hp)
+	}
+	sort.SliceStable(s.hostPatterns, func(i, j int) bool {
+		return s.hostPatterns[i].params < s.hostPatterns[j].params
+	})
+
+	s.hosts = append(s.hosts, h)
+	s.router.gen++
+	return h
+}
+
+// VHost 获取配置文件 vhosts 中定义的虚拟主机,同名多次调用返回同一个 Host
+// 未配置时 panic
+func (s *Server) VHost(name string) *Host {
+	if h, ok := s.vhosts[name]; ok {
+		return h
+	}
+	patterns := s.config.VHosts[name]
+	if len(patterns) == 0 {
+		panic("虚拟主机未配置: " + name)
+	}
+
+	h := s.Host(patterns[0], patterns[1:]...)
+	if s.vhosts == nil {
+		s.vhosts = make(map[string]*Host)
+	}
+	s.vhosts[name] = h
+	return h
+}
+
+// hostExists 检查模式是否已注册
+func (s *Server) hostExists(pattern string) bool {
+	if _, ok := s.staticHosts[pattern]; ok {
+		return true
+	}
+	for _, hp := range s.hostPatterns {
+		if hp.pattern == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// Use 注册虚拟主机中间件,对该主机的所有请求生效 (包括 404/405),在全局中间件之后执行
+func (h *Host) Use(middleware ...unet.MiddlewareFunc) {
+	if h.router.frozen {
+		panic(frozenMessage)
+	}
+	h.middlewares = append(h.middlewares, middleware...)
+	h.router.gen++
+}
+
+// NotFound 设置该主机路由不存在时的处理器
+func (h *Host) NotFound(handler unet.HandlerFunc) {
+	if h.router.frozen {
+		panic(frozenMessage)
+	}
+	h.notFound = handler
+	h.router.gen++
+}
+
+// MethodNotAllowed 设置该主机方法不允许时的处理器 (Allow 头已设置)
+func (h *Host) MethodNotAllowed(handler unet.HandlerFunc) {
+	if h.router.frozen {
+		panic(frozenMessage)
+	}
+	h.notAllowed = handler
+	h.router.gen++
+}
+
+// Patterns 主机模式 (包括别名)
+func (h *Host) Patterns() []string {
+	return h.patterns
+}
+
+// matchHost 查找请求对应的虚拟主机,主机参数写入 req.params
+func (s *Server) matchHost(req *Request) *Host {
+	if len(s.hosts) == 0 {
+		return nil
+	}
+
+	host := hostname(req.raw.Host)
+	if h, ok := s.staticHosts[host]; ok {
+		return h
+	}
+	for _, hp := range s.hostPatterns {
+		if hp.match(host, &req.params) {
+			return hp.host
+		}
+	}
+	return nil
+}
+
+// hostname 去掉端口和结尾的 '.',转为小写
+func hostname(host string) string {
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && strings.IndexByte(host[i:], ']') < 0 {
+		host = host[:i]
+	}
+	host = strings.TrimSuffix(host, ".")
+	for i := 0; i < len(host); i++ {
+		if c := host[i]; c >= 'A' && c <= 'Z' {
+			return strings.ToLower(host)
+		}
+	}
+	return host
+}
+
+// match 匹配主机名,参数追加到 params;未匹配时 params 保持调用前的长度
+func (hp *hostPattern) match(host string, params *Params) bool {
+	base := len(*params)
+	for i, label := range hp.labels {
+		seg := host
+		if i < len(hp.labels)-1 {
+			end := strings.IndexByte(host, '.')
+			if end < 0 {
+				*params = (*params)[:base]
+				return false
+			}
+			seg, host = host[:end], host[end+1:]
+		}
+
+		if label.param == "" {
+			if seg != label.static {
+				*params = (*params)[:base]
+				return false
+			}
+			continue
+		}
+		if seg == "" || strings.IndexByte(seg, '.') >= 0 ||
+			(label.constraint != nil && !label.constraint.match(seg)) {
+			*params = (*params)[:base]
+			return false
+		}
+		*params = append(*params, Param{Key: label.param, Value: seg})
+	}
+	return true
+}
+
+// parseHostPattern 解析主机模式,格式错误时 panic
+func parseHostPattern(pattern string) *hostPattern {
+	hp := &hostPattern{pattern: strings.TrimSuffix(pattern, ".")}
+	if hp.pattern == "" {
+		panic("虚拟主机模式不能为空")
+	}
+
+	rest := hp.pattern
+	for rest != "" {
+		// 按 '.' 分段,忽略约束正则中的 '.'
+		end, depth := len(rest), 0
+		for i := 0; i < len(rest); i++ {
+			if rest[i] == '{' {
+				depth++
+			} else if rest[i] == '}' {
+				depth--
+			} else if rest[i] == '.' && depth == 0 {
+				end = i
+				break
+			}
+		}
+		seg := rest[:end]
+		if end < len(rest) {
+			rest = rest[end+1:]
+			if rest == "" {
+				panic("虚拟主机模式格式错误: " + pattern)
+			}
+		} else {
+			rest = ""
+		}
+
+		if seg == "" {
+			panic("虚拟主机模式格式错误: " + pattern)
+		}
+		if seg[0] != '{' {
+			if strings.ContainsAny(seg, "{}") {
+				panic("虚拟主机参数必须占完整的一段: " + pattern)
+			}
+			hp.labels = append(hp.labels, hostLabel{static: strings.ToLower(seg)})
+			continue
+		}
+		if closingBrace(seg) != len(seg)-1 {
+			panic("虚拟主机参数必须占完整的一段: " + pattern)
+		}
+
+		name, expr, _ := strings.Cut(seg[1:len(seg)-1], ":")
+		validateParamName(name, pattern)
+		label := hostLabel{param: name}
+		if expr != "" {
+			c, err := newParamConstraint(expr)
+			if err != nil {
+				panic("虚拟主机参数约束无效: " + pattern + ": " + err.Error())
+			}
+			label.constraint = c
+		}
+		hp.labels = append(hp.labels, label)
+		hp.params++
+	}
+	if hp.params == 0 {
+		hp.pattern = strings.ToLower(hp.pattern)
+	}
+	return hp
+}
@@ -0,0 +1,152 @@
+package uhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/whosafe/uf/uconfig"
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// serveHost 以指定 Host 发送请求
+func serveHost(server *Server, method, host, target string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	r.Host = host
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	return w
+}
+
+// TestHostRouting 测试虚拟主机路由
+func TestHostRouting(t *testing.T) {
+	server := New()
+	server.GET("/", namedHandler("default"))
+
+	admin := server.Host("admin.example.com", "admin.localhost")
+	admin.Use(func(next unet.HandlerFunc) unet.HandlerFunc {
+		return func(ctx *ucontext.Context, req unet.Request) error {
+			req.Response().SetHeader("X-Host", "admin")
+			return next(ctx, req)
+		}
+	})
+	admin.GET("/", namedHandler("admin"))
+	admin.NotFound(func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(http.StatusNotFound, "admin 404")
+	})
+
+	tenant := server.Host("{tenant}.example.com")
+	tenant.GET("/users/:id", namedHandler("tenant")).Name("tenant.user")
+	tenant.Group("/api").GET("/ping", namedHandler("ping"))
+
+	shop := server.Host("{shop:int}.shop.example.com")
+	shop.GET("/", namedHandler("shop"))
+
+	tests := []struct {
+		host, target, want string
+	}{
+		{"example.com", "/", "default"},
+		{"admin.example.com", "/", "admin"},
+		{"ADMIN.Example.com:8080", "/", "admin"},
+		{"admin.localhost:3000", "/", "admin"},
+		{"admin.example.com", "/missing", "admin 404"},
+		{"acme.example.com", "/users/7", "tenant tenant=acme id=7"},
+		{"acme.example.com", "/api/ping", "ping tenant=acme"},
+		{"42.shop.example.com", "/", "shop shop=42"},
+		{"a.b.example.com", "/", "default"},
+		{"abc.shop.example.com", "/", "default"},
+	}
+	for _, tt := range tests {
+		w := serveHost(server, "GET", tt.host, tt.target)
+		if w.Body.String() != tt.want {
+			t.Errorf("GET %s%s = %q, want %q", tt.host, tt.target, w.Body.String(), tt.want)
+		}
+	}
+
+	// 主机中间件对 404 同样生效
+	if w := serveHost(server, "GET", "admin.example.com", "/missing"); w.Header().Get("X-Host") != "admin" || w.Code != 404 {
+		t.Errorf("Expected host middleware on 404, got %d %q", w.Code, w.Header().Get("X-Host"))
+	}
+	// 虚拟主机的 405 和重定向使用该主机的路由
+	if w := serveHost(server, "POST", "acme.example.com", "/users/1"); w.Code != 405 || w.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Errorf("Expected 405 on tenant host, got %d %q", w.Code, w.Header().Get("Allow"))
+	}
+	if w := serveHost(server, "GET", "acme.example.com", "/api/ping/"); w.Code != 301 || w.Header().Get("Location") != "/api/ping" {
+		t.Errorf("Expected redirect on tenant host, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	// 命名路由全局唯一
+	if got, err := server.URL("tenant.user", "id", 3); err != nil || got != "/users/3" {
+		t.Errorf("URL(tenant.user) = %q, %v", got, err)
+	}
+
+	// 路由表包含虚拟主机
+	routes := server.Routes()
+	if len(routes) != 5 || routes[1].Host != "admin.example.com,admin.localhost" || routes[2].Host != "{tenant}.example.com" {
+		t.Errorf("Unexpected routes: %+v", routes)
+	}
+}
+
+// TestHostPatternErrors 测试主机模式校验
+func TestHostPatternErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+	}{
+		{"duplicate", []string{"a.example.com", "A.example.com"}},
+		{"partial param", []string{"api-{tenant}.example.com"}},
+		{"empty label", []string{"a..example.com"}},
+		{"bad constraint", []string{"{id:[}.example.com"}},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected panic for %v", tt.name, tt.patterns)
+				}
+			}()
+			server := New()
+			for _, p := range tt.patterns {
+				server.Host(p)
+			}
+		}()
+	}
+}
+
+// TestVHostConfig 测试配置文件定义的虚拟主机
+func TestVHostConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	node, err := uconfig.Parse([]byte(`
+vhosts:
+  admin:
+    - admin.example.com
+    - admin.localhost
+  tenant: "{tenant}.example.com"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := node.Decode(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.VHosts["admin"]) != 2 || cfg.VHosts["tenant"][0] != "{tenant}.example.com" {
+		t.Fatalf("Unexpected vhosts: %v", cfg.VHosts)
+	}
+
+	server := NewWithConfig(cfg)
+	server.VHost("admin").GET("/", namedHandler("admin"))
+	if server.VHost("admin") != server.VHost("admin") {
+		t.Error("Expected VHost to return the same host")
+	}
+	if w := serveHost(server, "GET", "admin.localhost", "/"); w.Body.String() != "admin" {
+		t.Errorf("Expected admin, got %q", w.Body.String())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for missing vhost")
+		}
+	}()
+	server.VHost("missing")
+}
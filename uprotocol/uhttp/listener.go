@@ -0,0 +1,273 @@
+package uhttp
+
+import (
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/whosafe/uf/uerror"
+)
+
+// 监听继承使用的环境变量
+const (
+	// systemd 套接字激活 (sd_listen_fds)
+	envListenFDs     = "LISTEN_FDS"
+	envListenPID     = "LISTEN_PID"
+	envListenFDNames = "LISTEN_FDNAMES"
+
+	// 热重启时父进程传递给子进程
+	envRestartFDs     = "UF_LISTEN_FDS"
+	envRestartReadyFD = "UF_READY_FD"
+
+	// 继承的文件描述符起始编号 (0、1、2 为标准输入输出)
+	listenFDsStart = 3
+)
+
+// unixAddressPrefix Unix 套接字地址前缀
+const unixAddressPrefix = "unix:"
+
+// inherited 从 systemd 或父进程继承的监听
+var inherited struct {
+	once      sync.Once
+	listeners []net.Listener
+	ready     *os.File // 热重启就绪通知管道,全部监听被接管后写入
+	mu        sync.Mutex
+}
+
+// loadInheritedListeners 读取环境变量并恢复继承的监听 (只执行一次)
+// 读取后清除相关环境变量,避免传递给再下一级子进程
+func loadInheritedListeners() {
+	inherited.once.Do(func() {
+		defer func() {
+			os.Unsetenv(envListenFDs)
+			os.Unsetenv(envListenPID)
+			os.Unsetenv(envListenFDNames)
+			os.Unsetenv(envRestartFDs)
+			os.Unsetenv(envRestartReadyFD)
+		}()
+
+		n := 0
+		if v := os.Getenv(envRestartFDs); v != "" {
+			n, _ = strconv.Atoi(v)
+			if fd, err := strconv.Atoi(os.Getenv(envRestartReadyFD)); err == nil {
+				inherited.ready = os.NewFile(uintptr(fd), "ready")
+			}
+		} else if pid, _ := strconv.Atoi(os.Getenv(envListenPID)); pid == os.Getpid() {
+			n, _ = strconv.Atoi(os.Getenv(envListenFDs))
+		}
+		if n <= 0 {
+			return
+		}
+
+		listeners, err := listenersFromFDs(listenFDsStart, n)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return
+		}
+		inherited.listeners = listeners
+	})
+}
+
+// listenersFromFDs 将连续的文件描述符恢复为监听
+func listenersFromFDs(start, n int) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, n)
+	for i := 0; i < n; i++ {
+		f := os.NewFile(uintptr(start+i), "listener-"+strconv.Itoa(start+i))
+		if f == nil {
+			return listeners, uerror.New("无效的文件描述符: " + strconv.Itoa(start+i))
+		}
+
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return listeners, uerror.Wrap(err, "恢复继承的监听失败")
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// takeInheritedListener 取出与地址匹配的继承监听,没有时返回 nil
+func takeInheritedListener(network, address string) net.Listener {
+	loadInheritedListeners()
+
+	inherited.mu.Lock()
+	defer inherited.mu.Unlock()
+
+	for i, l := range inherited.listeners {
+		if !listenerMatches(l.Addr(), network, address) {
+			continue
+		}
+
+		inherited.listeners = append(inherited.listeners[:i], inherited.listeners[i+1:]...)
+
+		// 全部监听都已接管,通知父进程可以退出
+		if len(inherited.listeners) == 0 && inherited.ready != nil {
+			inherited.ready.Write([]byte{1})
+			inherited.ready.Close()
+			inherited.ready = nil
+		}
+		return l
+	}
+	return nil
+}
+
+// listenerMatches 判断监听地址是否与配置地址一致
+// TCP 地址的主机为空或未指定地址 (0.0.0.0、::) 时只比较端口
+func listenerMatches(addr net.Addr, network, address string) bool {
+	if network == "unix" {
+		return addr.Network() == "unix" && addr.String() == address
+	}
+
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	portNum, err := net.LookupPort("tcp", port)
+	if err != nil || portNum != tcpAddr.Port {
+		return false
+	}
+
+	if host == "" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		resolved, err := net.ResolveTCPAddr("tcp", address)
+		if err != nil {
+			return false
+		}
+		ip = resolved.IP
+	}
+	return ip.IsUnspecified() || ip.Equal(tcpAddr.IP)
+}
+
+// parseListenAddress 解析监听地址,返回网络类型和地址
+func parseListenAddress(address string) (network, addr string) {
+	if path, ok := strings.CutPrefix(address, unixAddressPrefix); ok {
+		return "unix", path
+	}
+	if address == "" {
+		address = ":http"
+	}
+	return "tcp", address
+}
+
+// Listen 创建监听
+// 优先接管从 systemd (LISTEN_FDS) 或热重启父进程继承的同地址监听,
+// 地址以 "unix:" 开头时创建 Unix 套接字并按配置设置权限
+func (s *Server) Listen() (net.Listener, error) {
+	network, address := parseListenAddress(s.httpServer.Addr)
+
+	if l := takeInheritedListener(network, address); l != nil {
+		s.accessLogger.Info("使用继承的监听", "addr", l.Addr().String())
+		return l, nil
+	}
+
+	if network == "unix" {
+		return listenUnix(address, s.config.Listener)
+	}
+	return net.Listen(network, address)
+}
+
+// listenUnix 创建 Unix 套接字监听
+// 清理残留的套接字文件,并按配置设置权限和属主
+func listenUnix(path string, cfg *ListenerConfig) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		// 仍有进程在监听时不删除
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, uerror.New("Unix 套接字已被占用: " + path)
+		}
+		os.Remove(path)
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg != nil {
+		if err := applySocketPermissions(path, cfg); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// applySocketPermissions 设置 Unix 套接字权限和属主
+func applySocketPermissions(path string, cfg *ListenerConfig) error {
+	if cfg.SocketMode != "" {
+		mode, err := strconv.ParseUint(cfg.SocketMode, 8, 32)
+		if err != nil {
+			return uerror.Wrap(err, "无效的 socket_mode: "+cfg.SocketMode)
+		}
+		if err := os.Chmod(path, os.FileMode(mode)); err != nil {
+			return uerror.Wrap(err, "设置 Unix 套接字权限失败")
+		}
+	}
+
+	if cfg.SocketUser == "" && cfg.SocketGroup == "" {
+		return nil
+	}
+
+	uid, gid := -1, -1
+	if cfg.SocketUser != "" {
+		id, err := lookupID(cfg.SocketUser, func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		})
+		if err != nil {
+			return uerror.Wrap(err, "查找 socket_user 失败")
+		}
+		uid = id
+	}
+	if cfg.SocketGroup != "" {
+		id, err := lookupID(cfg.SocketGroup, func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		})
+		if err != nil {
+			return uerror.Wrap(err, "查找 socket_group 失败")
+		}
+		gid = id
+	}
+
+	if err := os.Chown(path, uid, gid); err != nil {
+		return uerror.Wrap(err, "设置 Unix 套接字属主失败")
+	}
+	return nil
+}
+
+// lookupID 解析数字 ID 或按名称查找
+func lookupID(name string, lookup func(string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+	idStr, err := lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return 0, uerror.New("ID 不是数字: " + idStr)
+	}
+	return id, nil
+}
@@ -0,0 +1,264 @@
+//go:build linux
+
+package uhttp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// waitForSocket 等待 Unix 套接字可以连接
+func waitForSocket(t *testing.T, path string) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("socket %s not listening", path)
+}
+
+// TestListenUnixSocket 测试 Unix 套接字监听与权限
+func TestListenUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+
+	// 残留的套接字文件应被清理
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	cfg := DefaultConfig()
+	cfg.Address = "unix:" + path
+	cfg.Listener = &ListenerConfig{SocketMode: "0600"}
+	server := NewWithConfig(cfg)
+	server.GET("/ping", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(200, "pong")
+	})
+
+	served := make(chan error, 1)
+	go func() { served <- server.Start() }()
+	waitForSocket(t, path)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected socket mode 0600, got %o", info.Mode().Perm())
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://unix/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "pong" {
+		t.Errorf("Expected body 'pong', got '%s'", body)
+	}
+
+	// 正在使用的套接字不能被再次监听
+	if _, err := listenUnix(path, nil); err == nil {
+		t.Error("Expected error when socket is in use")
+	}
+
+	server.Stop(context.Background())
+	<-served
+}
+
+// TestListenerMatches 测试继承监听的地址匹配
+func TestListenerMatches(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080}
+	any := &net.TCPAddr{IP: net.IPv4zero, Port: 8080}
+
+	tests := []struct {
+		addr    net.Addr
+		network string
+		address string
+		want    bool
+	}{
+		{addr, "tcp", ":8080", true},
+		{addr, "tcp", "127.0.0.1:8080", true},
+		{addr, "tcp", "0.0.0.0:8080", true},
+		{addr, "tcp", "127.0.0.1:9090", false},
+		{addr, "tcp", "10.0.0.1:8080", false},
+		{any, "tcp", ":8080", true},
+		{addr, "tcp", ":http-alt", true},
+		{&net.UnixAddr{Name: "/run/app.sock", Net: "unix"}, "unix", "/run/app.sock", true},
+		{&net.UnixAddr{Name: "/run/app.sock", Net: "unix"}, "unix", "/run/other.sock", false},
+		{&net.UnixAddr{Name: "/run/app.sock", Net: "unix"}, "tcp", ":8080", false},
+	}
+
+	for _, tt := range tests {
+		if got := listenerMatches(tt.addr, tt.network, tt.address); got != tt.want {
+			t.Errorf("listenerMatches(%s, %s, %s) = %v, want %v", tt.addr, tt.network, tt.address, got, tt.want)
+		}
+	}
+}
+
+// TestInheritedListener 测试从文件描述符恢复并接管监听
+func TestInheritedListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := l.(*net.TCPListener).File()
+	l.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 复制出独立的描述符,由 listenersFromFDs 接管并关闭
+	fd, err := syscall.Dup(int(f.Fd()))
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listeners, err := listenersFromFDs(fd, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loadInheritedListeners()
+	inherited.mu.Lock()
+	inherited.listeners = append(inherited.listeners, listeners...)
+	inherited.mu.Unlock()
+
+	cfg := DefaultConfig()
+	cfg.Address = listeners[0].Addr().String()
+	server := NewWithConfig(cfg)
+
+	got, err := server.Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer got.Close()
+	if got != listeners[0] {
+		t.Error("Expected inherited listener to be reused")
+	}
+
+	inherited.mu.Lock()
+	remaining := len(inherited.listeners)
+	inherited.mu.Unlock()
+	if remaining != 0 {
+		t.Errorf("Expected inherited listener to be taken, %d remaining", remaining)
+	}
+}
+
+// TestGracefulRestart 测试信号触发热重启:子进程接管监听,父进程处理完进行中的请求后退出
+func TestGracefulRestart(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+
+	t.Setenv("UF_TEST_RESTART_ADDR", addr)
+	restartCommand = func() (string, []string, error) {
+		return os.Args[0], []string{"-test.run=^TestRestartChild$"}, nil
+	}
+
+	cfg := DefaultConfig()
+	cfg.Listener = &ListenerConfig{GracefulRestart: true, ShutdownTimeout: 5 * time.Second}
+	server := NewWithConfig(cfg)
+	server.GET("/pid", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(200, strconv.Itoa(os.Getpid()))
+	})
+	server.GET("/slow", func(ctx *ucontext.Context, req unet.Request) error {
+		time.Sleep(300 * time.Millisecond)
+		return req.Response().String(200, "slow")
+	})
+
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+
+	client := &http.Client{
+		Transport: &http.Transport{DisableKeepAlives: true},
+		Timeout:   10 * time.Second,
+	}
+	get := func(path string) (string, error) {
+		resp, err := client.Get("http://" + addr + path)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	if pid, err := get("/pid"); err != nil || pid != strconv.Itoa(os.Getpid()) {
+		t.Fatalf("parent pid = %q, err = %v", pid, err)
+	}
+
+	// 进行中的请求
+	slow := make(chan string, 1)
+	go func() {
+		body, err := get("/slow")
+		if err != nil {
+			body = err.Error()
+		}
+		slow <- body
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("Serve returned %v", err)
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatal("parent did not hand off")
+	}
+	if body := <-slow; body != "slow" {
+		t.Errorf("in-flight request = %q", body)
+	}
+
+	childPid, err := get("/pid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, _ := strconv.Atoi(childPid)
+	if pid == 0 || pid == os.Getpid() {
+		t.Fatalf("Expected response from child, got %q", childPid)
+	}
+	syscall.Kill(pid, syscall.SIGKILL)
+}
+
+// TestRestartChild 热重启测试的子进程
+func TestRestartChild(t *testing.T) {
+	addr := os.Getenv("UF_TEST_RESTART_ADDR")
+	if addr == "" || os.Getenv(envRestartFDs) == "" {
+		t.Skip("only runs as restart child")
+	}
+
+	cfg := DefaultConfig()
+	cfg.Address = addr
+	server := NewWithConfig(cfg)
+	server.GET("/pid", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(200, strconv.Itoa(os.Getpid()))
+	})
+	server.Start()
+}
@@ -7,7 +7,7 @@ import (
 )
 
 // ApplyDefaultMiddlewares 应用默认中间件到服务器
-// 根据服务器配置自动应用中间件 (Trace, Logger, Recovery, CORS, CSRF, Timeout, RateLimit)
+// 根据服务器配置自动应用中间件 (Trace, Logger, Recovery, CORS, CSRF, Compress, Timeout, RateLimit)
 func ApplyDefaultMiddlewares(server *Server) {
 	cfg := server.MiddlewareConfig()
 
@@ -35,7 +35,16 @@ func ApplyDefaultMiddlewares(server *Server) {
 		}
 	}
 
-	// 4. Timeout - 超时控制
+	// 4. Compress - 响应压缩
+	if cfg.EnableCompress {
+		if cfg.Compress != nil {
+			server.Use(MiddlewareCompressWithConfig(*cfg.Compress))
+		} else {
+			server.Use(MiddlewareCompress())
+		}
+	}
+
+	// 5. Timeout - 超时控制
 	if cfg.EnableTimeout {
 		timeout := 30 * time.Second // 默认 30 秒
 		if cfg.Timeout != "" {
@@ -46,13 +55,13 @@ func ApplyDefaultMiddlewares(server *Server) {
 		server.Use(MiddlewareTimeout(timeout))
 	}
 
-	// 4. RateLimit - 限流
+	// 6. RateLimit - 限流
 	if cfg.EnableRateLimit {
 		if cfg.RateLimit != nil {
 			// 如果没有设置 KeyFunc,使用默认的基于 IP 的限流
 			if cfg.RateLimit.KeyFunc == nil {
 				cfg.RateLimit.KeyFunc = func(req *Request) string {
-					return req.RemoteAddr().String()
+					return req.ClientIP()
 				}
 			}
 			server.Use(MiddlewareRateLimitWithConfig(cfg.RateLimit))
@@ -61,12 +70,12 @@ func ApplyDefaultMiddlewares(server *Server) {
 		}
 	}
 
-	// 5. Logger - 记录请求日志
+	// 7. Logger - 记录请求日志
 	if cfg.EnableLogger {
 		server.Use(MiddlewareLogger())
 	}
 
-	// 6. Recovery - 最后执行,捕获 panic
+	// 8. Recovery - 最后执行,捕获 panic
 	if cfg.EnableRecovery {
 		server.Use(MiddlewareRecovery())
 	}
@@ -0,0 +1,263 @@
+package uhttp
+
+import (
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// CacheConfig HTTP 缓存中间件配置
+type CacheConfig struct {
+	WeakETag     bool   // 生成弱 ETag (W/"..."),响应内容语义相同但字节可能不同时使用
+	CacheControl string // 未设置 Cache-Control 的响应使用的默认值,为空时不设置
+
+	// ETagFunc 返回资源当前的 ETag,用于 PUT/PATCH 的 If-Match 检查;返回空字符串表示资源不存在
+	// 未设置时直接调用同一路径 GET 路由的处理器 (不经过任何中间件) 并按响应体计算
+	ETagFunc func(ctx *ucontext.Context, req *Request) (string, error)
+}
+
+// MiddlewareCache HTTP 缓存中间件 (强 ETag,不设置默认 Cache-Control)
+func MiddlewareCache() unet.MiddlewareFunc {
+	return MiddlewareCacheWithConfig(CacheConfig{})
+}
+
+// MiddlewareCacheWithConfig HTTP 缓存中间件
+// GET/HEAD 缓冲响应体计算 ETag (处理器已设置时沿用),按 If-None-Match / If-Modified-Since 返回 304;
+// PUT/PATCH 带 If-Match 时取当前表示的 ETag (ETagFunc 或 GET 路由的处理器),不匹配时返回 412 且不执行处理器
+func MiddlewareCacheWithConfig(cfg CacheConfig) unet.MiddlewareFunc {
+	return func(next unet.HandlerFunc) unet.HandlerFunc {
+		return func(ctx *ucontext.Context, req unet.Request) error {
+			httpReq := req.(*Request)
+			switch httpReq.raw.Method {
+			case http.MethodGet, http.MethodHead:
+			case http.MethodPut, http.MethodPatch:
+				if ifMatch := httpReq.raw.Header.Get("If-Match"); ifMatch != "" {
+					ok, err := httpReq.checkIfMatch(ctx, ifMatch, cfg)
+					if err != nil {
+						return err
+					}
+					if !ok {
+						return httpReq.response.Error(http.StatusPreconditionFailed, CodePreconditionFailed, "资源已被修改")
+					}
+				}
+				return next(ctx, req)
+			default:
+				return next(ctx, req)
+			}
+
+			capture := httpReq.CaptureBody()
+			if err := next(ctx, req); err != nil {
+				capture.Finish(capture.StatusCode(), capture.Body())
+				return err
+			}
+			if capture.Streamed() || capture.StatusCode() != http.StatusOK {
+				return capture.Finish(capture.StatusCode(), capture.Body())
+			}
+
+			header := capture.Header()
+			if cfg.CacheControl != "" && header.Get("Cache-Control") == "" {
+				header.Set("Cache-Control", cfg.CacheControl)
+			}
+			etag := header.Get("ETag")
+			if etag == "" {
+				etag = computeETag(capture.Body(), cfg.WeakETag)
+				header.Set("ETag", etag)
+			}
+
+			if notModified(httpReq.raw.Header, etag, header.Get("Last-Modified")) {
+				header.Del("Content-Type")
+				header.Del("Content-Length")
+				return capture.Finish(http.StatusNotModified, nil)
+			}
+			return capture.Finish(http.StatusOK, capture.Body())
+		}
+	}
+}
+
+// CacheControl 设置 Cache-Control 的中间件,用于按路由或分组声明缓存策略
+//
+//	api.Use(uhttp.CacheControl("no-cache"))
+//	api.GET("/articles", list, uhttp.CacheControl("public, max-age=60"))
+func CacheControl(policy string) unet.MiddlewareFunc {
+	return func(next unet.HandlerFunc) unet.HandlerFunc {
+		return func(ctx *ucontext.Context, req unet.Request) error {
+			req.Response().SetHeader("Cache-Control", policy)
+			return next(ctx, req)
+		}
+	}
+}
+
+// computeETag 根据响应体计算 ETag (FNV-1a 64 位 + 长度)
+func computeETag(body []byte, weak bool) string {
+	h := fnv.New64a()
+	h.Write(body)
+
+	buf := make([]byte, 0, 40)
+	if weak {
+		buf = append(buf, "W/"...)
+	}
+	buf = append(buf, '"')
+	buf = strconv.AppendUint(buf, h.Sum64(), 16)
+	buf = append(buf, '-')
+	buf = strconv.AppendInt(buf, int64(len(body)), 16)
+	buf = append(buf, '"')
+	return string(buf)
+}
+
+// notModified 判断条件请求是否可以返回 304
+// If-None-Match 存在时忽略 If-Modified-Since (RFC 9110 13.2.2)
+func notModified(reqHeader http.Header, etag, lastModified string) bool {
+	if inm := reqHeader.Get("If-None-Match"); inm != "" {
+		return etagListMatch(inm, etag, false)
+	}
+
+	ims := reqHeader.Get("If-Modified-Since")
+	if ims == "" || lastModified == "" {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// etagListMatch 检查 ETag 是否在列表中,strong 为 true 时使用强比较 (弱 ETag 不匹配)
+func etagListMatch(list, etag string, strong bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+	if strong && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for list != "" {
+		var item string
+		item, list, _ = strings.Cut(list, ",")
+		item = strings.TrimSpace(item)
+		if strong && strings.HasPrefix(item, "W/") {
+			continue
+		}
+		if strings.TrimPrefix(item, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch 检查 If-Match 前置条件,资源不存在时前置条件失败
+// 没有 ETagFunc 时直接调用 GET 路由的处理器,不经过全局、分组和路由中间件 (限流、日志、认证等不会重复执行);
+// 没有 GET 路由时无法得知当前表示,跳过检查
+func (r *Request) checkIfMatch(ctx *ucontext.Context, ifMatch string, cfg CacheConfig) (bool, error) {
+	var etag string
+	if cfg.ETagFunc != nil {
+		var err error
+		if etag, err = cfg.ETagFunc(ctx, r); err != nil {
+			return false, err
+		}
+	} else {
+		rt, params := r.getRoute()
+		if rt == nil {
+			return true, nil
+		}
+		var err error
+		if etag, err = r.currentETag(ctx, rt, params); err != nil {
+			return false, err
+		}
+	}
+	if etag == "" {
+		return false, nil
+	}
+	if etagListMatch(ifMatch, etag, true) {
+		return true, nil
+	}
+	// 压缩中间件和 WeakETag 把强 ETag 降为 W/"...",客户端回传的是降级后的值,按原强 ETag 比较
+	return !strings.HasPrefix(etag, "W/") && etagListMatch(ifMatch, "W/"+etag, false), nil
+}
+
+// getRoute 查找同一路径的 GET 路由,版本分发路由解析为请求版本的目标路由
+func (r *Request) getRoute() (*Route, Params) {
+	handlers := r.server.currentHandlers()
+	if host := r.server.matchHost(r); host != nil {
+		handlers = host.handlers
+	}
+	var params Params
+	rt := handlers.router.find(http.MethodGet, r.raw.URL.Path, &params)
+	if rt != nil && rt.dispatch != nil {
+		rt = rt.dispatch.route(requestedVersion(r.raw.Header))
+	}
+	return rt, params
+}
+
+// currentETag 以 GET 调用路由注册时的处理器获取当前表示的强 ETag,非 200 时返回空字符串
+// 处理器已设置 ETag 时沿用 (弱 ETag 不会通过强比较)
+func (r *Request) currentETag(ctx *ucontext.Context, rt *Route, params Params) (string, error) {
+	get := r.raw.Clone(r.raw.Context())
+	get.Method = http.MethodGet
+	get.Body = http.NoBody
+	get.ContentLength = 0
+	for _, key := range []string{"If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since",
+		"Accept-Encoding", "Content-Type", "Content-Encoding", "Content-Length"} {
+		get.Header.Del(key)
+	}
+
+	w := &currentRepresentation{header: make(http.Header)}
+	sub := newRequest(get, w, r.server)
+	defer func() {
+		sub.response.release()
+		sub.release()
+	}()
+	sub.params = append(sub.params, params...)
+	sub.route = rt
+	for k, v := range r.store {
+		sub.Set(k, v)
+	}
+	if err := rt.origin(ctx, sub); err != nil {
+		return "", err
+	}
+	if w.code != http.StatusOK {
+		return "", nil
+	}
+
+	if etag := w.header.Get("ETag"); etag != "" {
+		return etag, nil
+	}
+	return computeETag(w.body, false), nil
+}
+
+// currentRepresentation 记录 GET 子请求的响应
+type currentRepresentation struct {
+	header http.Header
+	code   int
+	body   []byte
+}
+
+// Header 实现 http.ResponseWriter 接口
+func (w *currentRepresentation) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader 实现 http.ResponseWriter 接口
+func (w *currentRepresentation) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+// Write 实现 http.ResponseWriter 接口
+func (w *currentRepresentation) Write(data []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	w.body = append(w.body, data...)
+	return len(data), nil
+}
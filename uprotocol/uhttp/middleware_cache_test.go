@@ -0,0 +1,230 @@
+package uhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// conditional 发送带请求头的请求
+func conditional(server *Server, method, target string, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader("{}"))
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	return w
+}
+
+// TestMiddlewareCache 测试 ETag 与条件请求
+func TestMiddlewareCache(t *testing.T) {
+	modified := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	content := "v1"
+	updates := 0
+
+	server := New()
+	server.Use(MiddlewareCache())
+	api := server.Group("/api")
+	api.Use(CacheControl("no-cache"))
+	api.GET("/doc", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(200, content)
+	})
+	api.PUT("/doc", func(ctx *ucontext.Context, req unet.Request) error {
+		updates++
+		content = "v2"
+		return req.Response().String(200, "updated")
+	})
+	server.GET("/file", func(ctx *ucontext.Context, req unet.Request) error {
+		req.Response().SetHeader("Last-Modified", modified.Format(http.TimeFormat))
+		return req.Response().String(200, "file")
+	}, CacheControl("public, max-age=60"))
+	server.GET("/missing", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(404, "missing")
+	})
+
+	w := conditional(server, "GET", "/api/doc")
+	etag := w.Header().Get("ETag")
+	if w.Code != 200 || w.Body.String() != "v1" || !strings.HasPrefix(etag, `"`) || w.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("Unexpected first response: %d %q %q", w.Code, etag, w.Header().Get("Cache-Control"))
+	}
+
+	// If-None-Match 使用弱比较
+	for _, inm := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		w = conditional(server, "GET", "/api/doc", "If-None-Match", inm)
+		if w.Code != 304 || w.Body.Len() != 0 || w.Header().Get("ETag") != etag || w.Header().Get("Content-Type") != "" {
+			t.Errorf("If-None-Match %s: got %d %q", inm, w.Code, w.Body.String())
+		}
+	}
+	if w = conditional(server, "GET", "/api/doc", "If-None-Match", `"other"`); w.Code != 200 {
+		t.Errorf("Expected 200 for mismatched If-None-Match, got %d", w.Code)
+	}
+
+	// If-Modified-Since
+	if w = conditional(server, "GET", "/file", "If-Modified-Since", modified.Format(http.TimeFormat)); w.Code != 304 ||
+		w.Header().Get("Cache-Control") != "public, max-age=60" {
+		t.Errorf("Expected 304 for If-Modified-Since, got %d", w.Code)
+	}
+	if w = conditional(server, "GET", "/file", "If-Modified-Since", modified.Add(-time.Hour).Format(http.TimeFormat)); w.Code != 200 {
+		t.Errorf("Expected 200 for older If-Modified-Since, got %d", w.Code)
+	}
+	// 非 200 响应不生成 ETag
+	if w = conditional(server, "GET", "/missing"); w.Code != 404 || w.Header().Get("ETag") != "" || w.Body.String() != "missing" {
+		t.Errorf("Unexpected 404 response: %d %q", w.Code, w.Header().Get("ETag"))
+	}
+
+	// If-Match 不匹配时返回 412,处理器不执行
+	if w = conditional(server, "PUT", "/api/doc", "If-Match", `"stale"`); w.Code != 412 || updates != 0 {
+		t.Errorf("Expected 412, got %d (updates=%d)", w.Code, updates)
+	}
+	if w = conditional(server, "PUT", "/api/doc", "If-Match", etag); w.Code != 200 || updates != 1 {
+		t.Errorf("Expected update with matching If-Match, got %d", w.Code)
+	}
+	// 内容已变化,旧 ETag 失效
+	if w = conditional(server, "PUT", "/api/doc", "If-Match", etag); w.Code != 412 || updates != 1 {
+		t.Errorf("Expected 412 after update, got %d", w.Code)
+	}
+	if w = conditional(server, "PUT", "/api/doc", "If-Match", "*"); w.Code != 200 {
+		t.Errorf("Expected 200 for If-Match *, got %d", w.Code)
+	}
+}
+
+// TestCacheWithCompress 测试压缩后的 ETag 降为弱 ETag
+func TestCacheWithCompress(t *testing.T) {
+	server := New()
+	server.Use(MiddlewareCompress(), MiddlewareCache())
+	body := strings.Repeat("cacheable ", 200)
+	server.GET("/doc", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(200, body)
+	})
+
+	w := conditional(server, "GET", "/doc", "Accept-Encoding", "gzip")
+	etag := w.Header().Get("ETag")
+	if w.Header().Get("Content-Encoding") != "gzip" || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("Expected weak ETag on compressed response, got %q", etag)
+	}
+	if w = conditional(server, "GET", "/doc", "Accept-Encoding", "gzip", "If-None-Match", etag); w.Code != 304 {
+		t.Errorf("Expected 304, got %d", w.Code)
+	}
+
+	// 客户端只拿到降级后的弱 ETag,If-Match 按原强 ETag 比较
+	server.PUT("/doc", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(200, "updated")
+	})
+	if w = conditional(server, "PUT", "/doc", "If-Match", etag); w.Code != 200 {
+		t.Errorf("Expected compressed ETag to pass If-Match, got %d", w.Code)
+	}
+	if w = conditional(server, "PUT", "/doc", "If-Match", `W/"stale"`); w.Code != 412 {
+		t.Errorf("Expected 412 for stale weak ETag, got %d", w.Code)
+	}
+}
+
+// TestCacheIfMatch 测试 If-Match 检查不经过中间件链,以及 ETagFunc
+func TestCacheIfMatch(t *testing.T) {
+	calls := 0
+	server := New()
+	server.Use(func(next unet.HandlerFunc) unet.HandlerFunc {
+		return func(ctx *ucontext.Context, req unet.Request) error {
+			calls++
+			return next(ctx, req)
+		}
+	}, MiddlewareCache())
+	server.GET("/items/:id", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(200, "item "+req.(*Request).Param("id"))
+	})
+	updated := func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(200, "updated")
+	}
+	server.PUT("/items/:id", updated)
+	server.PUT("/uploads/:name", updated)
+
+	etag := conditional(server, "GET", "/items/1").Header().Get("ETag")
+	calls = 0
+	if w := conditional(server, "PUT", "/items/1", "If-Match", etag); w.Code != 200 || calls != 1 {
+		t.Errorf("Expected one pass through middlewares, got %d (calls=%d)", w.Code, calls)
+	}
+	if w := conditional(server, "PUT", "/items/2", "If-Match", etag); w.Code != 412 {
+		t.Errorf("Expected 412 for other item, got %d", w.Code)
+	}
+	// 没有 GET 路由时跳过检查
+	if w := conditional(server, "PUT", "/uploads/a.txt", "If-Match", `"any"`); w.Code != 200 {
+		t.Errorf("Expected check to be skipped without GET route, got %d", w.Code)
+	}
+
+	// 版本分发路由按请求版本解析为目标路由,不经过中间件
+	server = New()
+	server.Use(func(next unet.HandlerFunc) unet.HandlerFunc {
+		return func(ctx *ucontext.Context, req unet.Request) error {
+			calls++
+			return next(ctx, req)
+		}
+	}, MiddlewareCache())
+	api := server.Versions("/api")
+	v1, v2 := api.Version("v1"), api.Version("v2")
+	v1.GET("/items/:id", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(200, "v1 item "+req.(*Request).Param("id"))
+	})
+	v2.GET("/items/:id", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(200, "v2 item "+req.(*Request).Param("id"))
+	})
+	v1.PUT("/items/:id", updated)
+	v2.PUT("/items/:id", updated)
+	v1ETag := conditional(server, "GET", "/api/v1/items/1").Header().Get("ETag")
+	calls = 0
+	if w := conditional(server, "PUT", "/api/items/1", "If-Match", v1ETag, HeaderAcceptVersion, "v1"); w.Code != 200 || calls != 1 {
+		t.Errorf("Expected one pass through middlewares for versioned route, got %d (calls=%d)", w.Code, calls)
+	}
+	if w := conditional(server, "PUT", "/api/items/1", "If-Match", v1ETag); w.Code != 412 {
+		t.Errorf("Expected 412 against latest version, got %d", w.Code)
+	}
+
+	versions := map[string]string{"1": `"v3"`}
+	server = New()
+	server.Use(MiddlewareCacheWithConfig(CacheConfig{
+		ETagFunc: func(ctx *ucontext.Context, req *Request) (string, error) {
+			return versions[req.Param("id")], nil
+		},
+	}))
+	server.PUT("/items/:id", updated)
+	for _, tt := range []struct {
+		target, ifMatch string
+		code            int
+	}{
+		{"/items/1", `"v3"`, 200},
+		{"/items/1", `"v2", "v3"`, 200},
+		{"/items/1", `"v2"`, 412},
+		{"/items/2", "*", 412},
+	} {
+		if w := conditional(server, "PUT", tt.target, "If-Match", tt.ifMatch); w.Code != tt.code {
+			t.Errorf("%s If-Match %s: expected %d, got %d", tt.target, tt.ifMatch, tt.code, w.Code)
+		}
+	}
+}
+
+// TestBodyCapture 测试响应体捕获
+func TestBodyCapture(t *testing.T) {
+	server := New()
+	server.Use(func(next unet.HandlerFunc) unet.HandlerFunc {
+		return func(ctx *ucontext.Context, req unet.Request) error {
+			capture := req.(*Request).CaptureBody()
+			if err := next(ctx, req); err != nil {
+				capture.Finish(capture.StatusCode(), capture.Body())
+				return err
+			}
+			return capture.Finish(capture.StatusCode(), []byte(strings.ToUpper(string(capture.Body()))))
+		}
+	})
+	server.GET("/", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(201, "hello")
+	})
+
+	w := conditional(server, "GET", "/")
+	if w.Code != 201 || w.Body.String() != "HELLO" {
+		t.Errorf("Expected transformed body, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,338 @@
+package uhttp
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uerror"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// CompressConfig 响应压缩配置
+type CompressConfig struct {
+	Level         int      // 压缩级别 1-9,0 为各算法的默认级别 (zstd 按 zstd 级别换算)
+	MinLength     int      // 响应体小于该字节数时不压缩,0 表示总是压缩,负数使用默认值 1024
+	Encodings     []string // 启用的编码,按服务端优先级排列,默认 zstd, gzip, deflate
+	ExcludedTypes []string // 不压缩的 Content-Type 前缀,默认为图片、音视频、字体和压缩包等已压缩的类型
+}
+
+// DefaultCompressConfig 默认压缩配置
+func DefaultCompressConfig() CompressConfig {
+	return CompressConfig{
+		MinLength: 1024,
+		Encodings: []string{"zstd", "gzip", "deflate"},
+		ExcludedTypes: []string{
+			"image/", "video/", "audio/", "font/woff",
+			"application/zip", "application/gzip", "application/x-gzip", "application/zstd",
+			"application/x-7z-compressed", "application/x-rar-compressed", "application/x-bzip2",
+			"application/x-xz", "application/pdf", "application/wasm", "application/octet-stream",
+		},
+	}
+}
+
+// compressEncoder 压缩器,gzip.Writer、flate.Writer 和 zstd.Encoder 均满足
+type compressEncoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressor 一种编码及其压缩器池
+type compressor struct {
+	encoding string
+	pool     sync.Pool
+}
+
+// compressMiddleware 压缩中间件的共享状态
+type compressMiddleware struct {
+	minLength     int
+	excludedTypes []string
+	compressors   []*compressor
+	writers       sync.Pool // *compressWriter
+}
+
+// MiddlewareCompress 响应压缩中间件 (默认配置)
+func MiddlewareCompress() unet.MiddlewareFunc {
+	return MiddlewareCompressWithConfig(DefaultCompressConfig())
+}
+
+// MiddlewareCompressWithConfig 响应压缩中间件
+// 按 Accept-Encoding 协商编码,响应体达到 MinLength 且类型未被排除时压缩;
+// 处理器调用 Flush (如 SSE 事件流) 时立即开始压缩并刷新。编码不支持时 panic
+func MiddlewareCompressWithConfig(cfg CompressConfig) unet.MiddlewareFunc {
+	def := DefaultCompressConfig()
+	if cfg.MinLength < 0 {
+		cfg.MinLength = def.MinLength
+	}
+	if len(cfg.Encodings) == 0 {
+		cfg.Encodings = def.Encodings
+	}
+	if cfg.ExcludedTypes == nil {
+		cfg.ExcludedTypes = def.ExcludedTypes
+	}
+
+	m := &compressMiddleware{minLength: cfg.MinLength, excludedTypes: cfg.ExcludedTypes}
+	for _, encoding := range cfg.Encodings {
+		c, err := newCompressor(strings.ToLower(strings.TrimSpace(encoding)), cfg.Level)
+		if err != nil {
+			panic(err.Error())
+		}
+		m.compressors = append(m.compressors, c)
+	}
+
+	return func(next unet.HandlerFunc) unet.HandlerFunc {
+		return func(ctx *ucontext.Context, req unet.Request) error {
+			httpReq := req.(*Request)
+			resp := httpReq.response
+			resp.AddHeader("Vary", "Accept-Encoding")
+
+			// 协议升级 (WebSocket) 的响应不经过压缩
+			if httpReq.raw.Header.Get("Upgrade") != "" {
+				return next(ctx, req)
+			}
+			c := m.negotiate(httpReq.raw.Header.Get("Accept-Encoding"))
+			if c == nil {
+				return next(ctx, req)
+			}
+
+			cw, _ := m.writers.Get().(*compressWriter)
+			if cw == nil {
+				cw = &compressWriter{m: m}
+			}
+			cw.reset(resp.writer, c)
+			httpReq.swapWriter(cw)
+
+			err := next(ctx, req)
+
+			cw.finish()
+			httpReq.swapWriter(cw.ResponseWriter)
+			cw.reset(nil, nil)
+			m.writers.Put(cw)
+			return err
+		}
+	}
+}
+
+// newCompressor 创建编码的压缩器池
+func newCompressor(encoding string, level int) (*compressor, error) {
+	c := &compressor{encoding: encoding}
+	switch encoding {
+	case "gzip":
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+			return nil, uerror.Wrap(err, "gzip 压缩级别无效")
+		}
+		c.pool.New = func() any {
+			w, _ := gzip.NewWriterLevel(io.Discard, level)
+			return w
+		}
+	case "deflate":
+		if level == 0 {
+			level = flate.DefaultCompression
+		}
+		if _, err := flate.NewWriter(io.Discard, level); err != nil {
+			return nil, uerror.Wrap(err, "deflate 压缩级别无效")
+		}
+		c.pool.New = func() any {
+			w, _ := flate.NewWriter(io.Discard, level)
+			return w
+		}
+	case "zstd":
+		zstdLevel := zstd.SpeedDefault
+		if level > 0 {
+			zstdLevel = zstd.EncoderLevelFromZstd(level)
+		}
+		c.pool.New = func() any {
+			w, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstdLevel), zstd.WithEncoderConcurrency(1))
+			return w
+		}
+	default:
+		return nil, uerror.New("不支持的压缩编码: " + encoding)
+	}
+	return c, nil
+}
+
+// negotiate 按 Accept-Encoding 的 q 值选择压缩器,q 值相同时按配置顺序
+func (m *compressMiddleware) negotiate(acceptEncoding string) *compressor {
+	if acceptEncoding == "" {
+		return nil
+	}
+
+	var best *compressor
+	bestQ := 0.0
+	for _, c := range m.compressors {
+		if q := encodingQuality(acceptEncoding, c.encoding); q > bestQ {
+			best, bestQ = c, q
+		}
+	}
+	return best
+}
+
+// encodingQuality 编码在 Accept-Encoding 中的 q 值,未列出时使用 * 的 q 值
+func encodingQuality(acceptEncoding, encoding string) float64 {
+	wildcard := 0.0
+	for acceptEncoding != "" {
+		var item string
+		item, acceptEncoding, _ = strings.Cut(acceptEncoding, ",")
+		coding, params, _ := strings.Cut(item, ";")
+		coding = strings.TrimSpace(coding)
+		if strings.EqualFold(coding, encoding) {
+			return parseQuality(params)
+		}
+		if coding == "*" {
+			wildcard = parseQuality(params)
+		}
+	}
+	return wildcard
+}
+
+// excluded 检查 Content-Type 是否不压缩
+func (m *compressMiddleware) excluded(contentType string) bool {
+	for _, prefix := range m.excludedTypes {
+		if len(contentType) >= len(prefix) && strings.EqualFold(contentType[:len(prefix)], prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter 压缩响应写入器
+// 响应体先缓冲到 minLength 再决定是否压缩,Flush 时立即决定
+type compressWriter struct {
+	http.ResponseWriter
+	m       *compressMiddleware
+	c       *compressor
+	encoder compressEncoder
+	buf     []byte
+	code    int  // 处理器设置的状态码,决定前暂不写出
+	decided bool // 已写出响应头
+}
+
+// reset 复用写入器
+func (w *compressWriter) reset(rw http.ResponseWriter, c *compressor) {
+	w.ResponseWriter = rw
+	w.c = c
+	w.encoder = nil
+	w.buf = w.buf[:0]
+	w.code = 0
+	w.decided = false
+}
+
+// WriteHeader 实现 http.ResponseWriter 接口
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided {
+		return
+	}
+	w.code = code
+	// 没有响应体的状态码和部分内容直接写出
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified || code == http.StatusPartialContent {
+		w.decide(false)
+	}
+}
+
+// Write 实现 http.ResponseWriter 接口
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		if w.encoder != nil {
+			return w.encoder.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buf = append(w.buf, data...)
+	if len(w.buf) >= w.m.minLength {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// Flush 实现 http.Flusher 接口,流式响应不等待 minLength
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(true)
+	}
+	if w.encoder != nil {
+		w.encoder.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack 实现 http.Hijacker 接口
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap 返回原始 ResponseWriter (供 http.ResponseController 使用)
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide 写出响应头和缓冲的数据,compress 为 false 或类型不适合压缩时原样输出
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+
+	header := w.Header()
+	contentType := header.Get("Content-Type")
+	if contentType == "" && len(w.buf) > 0 {
+		contentType = http.DetectContentType(w.buf)
+		header.Set("Content-Type", contentType)
+	}
+
+	// Content-Range 的偏移指向未压缩的字节,范围响应 (206、416) 不能压缩
+	if compress && header.Get("Content-Encoding") == "" && header.Get("Content-Range") == "" &&
+		w.code != http.StatusPartialContent && !w.m.excluded(contentType) {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", w.c.encoding)
+		// 压缩后的表示与原始字节不同,强 ETag 降为弱 ETag
+		if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			header.Set("ETag", "W/"+etag)
+		}
+		w.encoder = w.c.pool.Get().(compressEncoder)
+		w.encoder.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.code)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = w.buf[:0]
+	return err
+}
+
+// finish 处理器返回后写出剩余数据,归还压缩器
+func (w *compressWriter) finish() {
+	if !w.decided {
+		// 处理器未写入任何内容时交给 net/http 处理
+		if w.code == 0 && len(w.buf) == 0 {
+			return
+		}
+		w.decide(false)
+	}
+	if w.encoder != nil {
+		w.encoder.Close()
+		w.encoder.Reset(io.Discard)
+		w.c.pool.Put(w.encoder)
+		w.encoder = nil
+	}
+}
@@ -0,0 +1,273 @@
+package uhttp
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/whosafe/uf/uconfig"
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// decompress 按 Content-Encoding 解压响应体
+func decompress(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+	var r io.Reader
+	var err error
+	switch encoding {
+	case "gzip":
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		r = flate.NewReader(bytes.NewReader(body))
+	case "zstd":
+		var d *zstd.Decoder
+		d, err = zstd.NewReader(bytes.NewReader(body))
+		r = d
+	default:
+		return string(body)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// TestCompress 测试响应压缩
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"name":"uf"},`, 200)
+
+	server := New()
+	server.Use(MiddlewareCompress())
+	server.GET("/large", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(200, large)
+	})
+	server.GET("/small", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(200, "ok")
+	})
+	server.GET("/image", func(ctx *ucontext.Context, req unet.Request) error {
+		req.Response().SetHeader("Content-Type", "image/png")
+		return req.Response().Bytes(200, []byte(large))
+	})
+	server.GET("/encoded", func(ctx *ucontext.Context, req unet.Request) error {
+		req.Response().SetHeader("Content-Encoding", "br")
+		return req.Response().Bytes(200, []byte(large))
+	})
+	server.GET("/empty", func(ctx *ucontext.Context, req unet.Request) error {
+		req.Response().Status(http.StatusNoContent)
+		return nil
+	})
+
+	tests := []struct {
+		target, acceptEncoding, encoding, body string
+		code                                   int
+	}{
+		{"/large", "gzip, deflate", "gzip", large, 200},
+		{"/large", "gzip, deflate, br, zstd", "zstd", large, 200},
+		{"/large", "deflate", "deflate", large, 200},
+		{"/large", "gzip;q=0.5, deflate", "deflate", large, 200},
+		{"/large", "*", "zstd", large, 200},
+		{"/large", "gzip;q=0, *", "zstd", large, 200},
+		{"/large", "", "", large, 200},
+		{"/large", "br", "", large, 200},
+		{"/small", "gzip", "", "ok", 200},
+		{"/image", "gzip", "", large, 200},
+		{"/encoded", "gzip", "br", large, 200},
+		{"/empty", "gzip", "", "", 204},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.target, nil)
+		if tt.acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+
+		encoding := w.Header().Get("Content-Encoding")
+		if w.Code != tt.code || encoding != tt.encoding || w.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%s (%q) = %d %q, want %d %q", tt.target, tt.acceptEncoding, w.Code, encoding, tt.code, tt.encoding)
+			continue
+		}
+		if encoding != "br" {
+			if got := decompress(t, encoding, w.Body.Bytes()); got != tt.body {
+				t.Errorf("%s (%q): unexpected body %q", tt.target, tt.acceptEncoding, got)
+			}
+		}
+	}
+}
+
+// TestCompressRange 测试范围响应不压缩,MinLength 为 0 时总是压缩
+func TestCompressRange(t *testing.T) {
+	content := strings.Repeat("0123456789", 1200)
+	server := New()
+	server.Use(MiddlewareCompressWithConfig(CompressConfig{MinLength: 0}))
+	server.GET("/download", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().(*Response).Attachment("data.txt", strings.NewReader(content), time.Time{})
+	})
+	server.GET("/tiny", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(200, "ok")
+	})
+
+	w := conditional(server, "GET", "/download", "Accept-Encoding", "gzip", "Range", "bytes=0-4999")
+	if w.Code != 206 || w.Header().Get("Content-Encoding") != "" || w.Body.String() != content[:5000] ||
+		w.Header().Get("Content-Range") != "bytes 0-4999/12000" {
+		t.Errorf("Expected uncompressed 206, got %d %q len %d", w.Code, w.Header().Get("Content-Encoding"), w.Body.Len())
+	}
+	w = conditional(server, "GET", "/download", "Accept-Encoding", "gzip", "Range", "bytes=0-9,20-29")
+	if w.Code != 206 || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected uncompressed multipart 206, got %d %q", w.Code, w.Header().Get("Content-Encoding"))
+	}
+	w = conditional(server, "GET", "/download", "Accept-Encoding", "gzip", "Range", "bytes=20000-")
+	if w.Code != 416 || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected uncompressed 416, got %d %q", w.Code, w.Header().Get("Content-Encoding"))
+	}
+
+	// 完整下载仍然压缩
+	w = conditional(server, "GET", "/download", "Accept-Encoding", "gzip")
+	if w.Code != 200 || w.Header().Get("Content-Encoding") != "gzip" || decompress(t, "gzip", w.Body.Bytes()) != content {
+		t.Errorf("Expected gzip for full download, got %d %q", w.Code, w.Header().Get("Content-Encoding"))
+	}
+	w = conditional(server, "GET", "/tiny", "Accept-Encoding", "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || decompress(t, "gzip", w.Body.Bytes()) != "ok" {
+		t.Errorf("Expected MinLength 0 to compress small bodies, got %q", w.Header().Get("Content-Encoding"))
+	}
+}
+
+// TestCompressSSE 测试事件流逐条压缩刷新
+func TestCompressSSE(t *testing.T) {
+	next := make(chan struct{})
+	server := New()
+	server.Use(MiddlewareCompress())
+	server.GET("/events", func(ctx *ucontext.Context, req unet.Request) error {
+		stream, err := req.Response().(*Response).SSE(ctx, nil)
+		if err != nil {
+			return err
+		}
+		stream.SendData("first")
+		<-next
+		stream.SendData("second")
+		return nil
+	})
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	httpReq, _ := http.NewRequest("GET", ts.URL+"/events", nil)
+	httpReq.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected gzip event stream, got %q", resp.Header.Get("Content-Encoding"))
+	}
+
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(zr)
+	// 第一条事件在处理器返回前即可读取
+	if lines := readSSEBlock(t, r); len(lines) != 1 || lines[0] != "data: first" {
+		t.Fatalf("Unexpected first event: %v", lines)
+	}
+	close(next)
+	if lines := readSSEBlock(t, r); len(lines) != 1 || lines[0] != "data: second" {
+		t.Fatalf("Unexpected second event: %v", lines)
+	}
+}
+
+// TestRequestGzipBody 测试解压 gzip 请求体
+func TestRequestGzipBody(t *testing.T) {
+	gz := func(data string) *bytes.Buffer {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(data))
+		zw.Close()
+		return &buf
+	}
+
+	cfg := DefaultConfig()
+	cfg.MaxBodyBytes = 1024
+	server := NewWithConfig(cfg)
+	server.POST("/echo", func(ctx *ucontext.Context, req unet.Request) error {
+		body, err := req.(*Request).Body()
+		if err != nil {
+			return req.Response().String(400, err.Error())
+		}
+		return req.Response().String(200, string(body))
+	})
+
+	tests := []struct {
+		encoding string
+		body     io.Reader
+		code     int
+		want     string
+	}{
+		{"gzip", gz(`{"name":"uf"}`), 200, `{"name":"uf"}`},
+		{"", strings.NewReader("plain"), 200, "plain"},
+		// 压缩后很小,解压后超过限制
+		{"gzip", gz(strings.Repeat("0", 4096)), 400, ErrRequestBodyTooLarge.Error()},
+		{"br", strings.NewReader("x"), 400, ErrUnsupportedContentEncoding.Error()},
+		{"gzip", strings.NewReader("this is not gzip data"), 400, "gzip: invalid header"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/echo", tt.body)
+		if tt.encoding != "" {
+			r.Header.Set("Content-Encoding", tt.encoding)
+		}
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+		if w.Code != tt.code || w.Body.String() != tt.want {
+			t.Errorf("%q: got %d %q, want %d %q", tt.encoding, w.Code, w.Body.String(), tt.code, tt.want)
+		}
+	}
+}
+
+// TestCompressConfig 测试压缩配置解析
+func TestCompressConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	node, err := uconfig.Parse([]byte(`
+middleware:
+  enable_compress: true
+  compress:
+    level: 6
+    min_length: 256
+    encodings:
+      - gzip
+      - deflate
+    excluded_types: image/
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := node.Decode(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	c := cfg.Middleware.Compress
+	if !cfg.Middleware.EnableCompress || c.Level != 6 || c.MinLength != 256 ||
+		strings.Join(c.Encodings, ",") != "gzip,deflate" || len(c.ExcludedTypes) != 1 {
+		t.Errorf("Unexpected compress config: %+v", c)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for unsupported encoding")
+		}
+	}()
+	MiddlewareCompressWithConfig(CompressConfig{Encodings: []string{"br"}})
+}
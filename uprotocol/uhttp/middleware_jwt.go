@@ -0,0 +1,142 @@
+package uhttp
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/ujwt"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// JWTClaimsKey 请求存储中 JWT 声明的键,值为 *ujwt.Claims
+// 处理函数一般通过 ujwt.FromContext(ctx) 获取;只能拿到 Request 的地方 (如限流的 TierFunc) 使用 JWTClaims(req)
+const JWTClaimsKey = "jwt_claims"
+
+// JWTConfig JWT 认证配置
+type JWTConfig struct {
+	Verifier *ujwt.Verifier // 令牌验证器
+
+	// TokenLookup 令牌位置,格式为 "来源:名称",可用逗号分隔多个,按顺序查找
+	// 来源: header (Bearer 方案)、cookie、query;默认 "header:Authorization"
+	TokenLookup string
+
+	// Optional 没有令牌时继续处理 (匿名访问),令牌存在但无效时仍返回 401
+	Optional bool
+}
+
+// tokenSource 令牌来源
+type tokenSource struct {
+	kind string
+	name string
+}
+
+// parseTokenLookup 解析 TokenLookup,格式错误时 panic
+func parseTokenLookup(lookup string) []tokenSource {
+	if lookup == "" {
+		lookup = "header:Authorization"
+	}
+	var sources []tokenSource
+	for _, part := range strings.Split(lookup, ",") {
+		kind, name, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok || name == "" || (kind != "header" && kind != "cookie" && kind != "query") {
+			panic("JWT TokenLookup 格式错误: " + part)
+		}
+		sources = append(sources, tokenSource{kind: kind, name: name})
+	}
+	return sources
+}
+
+// extract 从请求中取出令牌
+func (s tokenSource) extract(req *Request) string {
+	switch s.kind {
+	case "header":
+		v := req.Header(s.name)
+		if scheme, token, ok := strings.Cut(v, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	case "cookie":
+		v, _ := req.GetCookie(s.name)
+		return v
+	default:
+		return req.Query(s.name)
+	}
+}
+
+// isTokenError 是否是令牌本身的问题 (返回 401),其他错误来自密钥端点或吊销列表
+func isTokenError(err error) bool {
+	for _, target := range []error{
+		ujwt.ErrTokenMalformed, ujwt.ErrTokenUnverifiable, ujwt.ErrTokenSignatureInvalid,
+		ujwt.ErrTokenExpired, ujwt.ErrTokenNotValidYet, ujwt.ErrTokenInvalidIssuer,
+		ujwt.ErrTokenInvalidAudience, ujwt.ErrTokenRevoked, ujwt.ErrKeyNotFound,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// MiddlewareJWT JWT 认证中间件,从 Authorization: Bearer 头读取令牌
+func MiddlewareJWT(verifier *ujwt.Verifier) unet.MiddlewareFunc {
+	return MiddlewareJWTWithConfig(&JWTConfig{Verifier: verifier})
+}
+
+// MiddlewareJWTWithConfig 使用自定义配置的 JWT 认证中间件
+// 验证通过后声明存入 ctx (ujwt.FromContext) 和请求存储 (JWTClaims);
+// 令牌无效返回 401 并带 WWW-Authenticate 头,密钥端点或吊销列表出错返回 503
+func MiddlewareJWTWithConfig(config *JWTConfig) unet.MiddlewareFunc {
+	if config.Verifier == nil {
+		panic("JWT 中间件缺少 Verifier")
+	}
+	sources := parseTokenLookup(config.TokenLookup)
+
+	return func(next unet.HandlerFunc) unet.HandlerFunc {
+		return func(ctx *ucontext.Context, req unet.Request) error {
+			httpReq := req.(*Request)
+			httpResp := req.Response().(*Response)
+
+			var token string
+			for _, s := range sources {
+				if token = s.extract(httpReq); token != "" {
+					break
+				}
+			}
+			if token == "" {
+				if config.Optional {
+					return next(ctx, req)
+				}
+				httpResp.SetHeader("WWW-Authenticate", "Bearer")
+				return httpResp.Unauthorized("缺少访问令牌")
+			}
+
+			claims, err := config.Verifier.Parse(ctx, token)
+			if err != nil {
+				if !isTokenError(err) {
+					httpReq.Server().ErrorLogger().ErrorCtx(ctx.Context(), "令牌验证失败", "error", err)
+					return httpResp.Error(503, CodeInternalError, "服务暂时不可用,请稍后再试")
+				}
+				message := "访问令牌无效"
+				if ujwt.IsExpired(err) {
+					message = "访问令牌已过期"
+				}
+				httpResp.SetHeader("WWW-Authenticate", `Bearer error="invalid_token"`)
+				return httpResp.Unauthorized(message)
+			}
+
+			httpReq.Set(JWTClaimsKey, claims)
+			return next(ujwt.WithClaims(ctx, claims), req)
+		}
+	}
+}
+
+// JWTClaims 获取 JWT 中间件验证通过的声明
+func JWTClaims(req *Request) (*ujwt.Claims, bool) {
+	v, ok := req.Get(JWTClaimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := v.(*ujwt.Claims)
+	return claims, ok
+}
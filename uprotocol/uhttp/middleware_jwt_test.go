@@ -0,0 +1,113 @@
+package uhttp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/ujwt"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// failingRevocation 总是返回错误的吊销列表
+type failingRevocation struct{}
+
+func (failingRevocation) Revoke(ctx *ucontext.Context, jti string, until time.Time) error {
+	return errors.New("redis unavailable")
+}
+
+func (failingRevocation) IsRevoked(ctx *ucontext.Context, jti string) (bool, error) {
+	return false, errors.New("redis unavailable")
+}
+
+func (failingRevocation) TryRevoke(ctx *ucontext.Context, jti string, until time.Time) (bool, error) {
+	return false, errors.New("redis unavailable")
+}
+
+// TestMiddlewareJWT 测试令牌提取、声明传递和错误响应
+func TestMiddlewareJWT(t *testing.T) {
+	key := &ujwt.Key{ID: "k1", Secret: []byte("secret")}
+	verifier := ujwt.NewVerifier(ujwt.NewKeySet(key))
+	verifier.Revocation = ujwt.NewMemoryRevocation()
+	signer := ujwt.NewSigner(key, "uf", time.Hour)
+
+	whoami := func(ctx *ucontext.Context, req unet.Request) error {
+		subject := "anonymous"
+		if claims, ok := ujwt.FromContext(ctx); ok {
+			stored, _ := JWTClaims(req.(*Request))
+			if stored != claims {
+				return errors.New("claims in context and request store differ")
+			}
+			subject = claims.Subject
+		}
+		return req.Response().String(200, subject)
+	}
+	server := New()
+	server.GET("/me", whoami, MiddlewareJWTWithConfig(&JWTConfig{
+		Verifier:    verifier,
+		TokenLookup: "header:Authorization, cookie:access_token, query:token",
+	}))
+	server.GET("/feed", whoami, MiddlewareJWTWithConfig(&JWTConfig{Verifier: verifier, Optional: true}))
+
+	token, claims, err := signer.Issue("user-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, headers := range [][]string{
+		{"Authorization", "Bearer " + token},
+		{"Authorization", "bearer " + token},
+		{"Cookie", "access_token=" + token},
+	} {
+		if w := conditional(server, "GET", "/me", headers...); w.Code != 200 || w.Body.String() != "user-1" {
+			t.Errorf("%s: expected user-1, got %d %s", headers[0], w.Code, w.Body.String())
+		}
+	}
+	if w := conditional(server, "GET", "/me?token="+token); w.Code != 200 {
+		t.Errorf("Expected token from query, got %d", w.Code)
+	}
+
+	w := conditional(server, "GET", "/me")
+	if w.Code != 401 || w.Header().Get("WWW-Authenticate") != "Bearer" {
+		t.Errorf("Expected 401 without token, got %d %v", w.Code, w.Header())
+	}
+	w = conditional(server, "GET", "/me", "Authorization", "Bearer "+token[:len(token)-2])
+	if w.Code != 401 || !strings.Contains(w.Header().Get("WWW-Authenticate"), "invalid_token") {
+		t.Errorf("Expected 401 for invalid token, got %d %v", w.Code, w.Header())
+	}
+	if w := conditional(server, "GET", "/me", "Authorization", "Basic dTpw"); w.Code != 401 {
+		t.Errorf("Expected 401 for non-bearer scheme, got %d", w.Code)
+	}
+
+	// 可选认证: 匿名可访问,无效令牌仍拒绝
+	if w := conditional(server, "GET", "/feed"); w.Code != 200 || w.Body.String() != "anonymous" {
+		t.Errorf("Expected anonymous access, got %d %s", w.Code, w.Body.String())
+	}
+	if w := conditional(server, "GET", "/feed", "Authorization", "Bearer x.y.z"); w.Code != 401 {
+		t.Errorf("Expected 401 for invalid optional token, got %d", w.Code)
+	}
+
+	// 吊销后拒绝
+	if err := verifier.Revoke(ucontext.New(), claims); err != nil {
+		t.Fatal(err)
+	}
+	if w := conditional(server, "GET", "/me", "Authorization", "Bearer "+token); w.Code != 401 {
+		t.Errorf("Expected 401 for revoked token, got %d", w.Code)
+	}
+
+	// 吊销列表不可用时返回 503 而不是 401
+	verifier.Revocation = failingRevocation{}
+	token, _, _ = signer.Issue("user-2", nil)
+	if w := conditional(server, "GET", "/me", "Authorization", "Bearer "+token); w.Code != 503 {
+		t.Errorf("Expected 503 when revocation store fails, got %d", w.Code)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for invalid TokenLookup")
+		}
+	}()
+	MiddlewareJWTWithConfig(&JWTConfig{Verifier: verifier, TokenLookup: "body:token"})
+}
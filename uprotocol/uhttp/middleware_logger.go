@@ -24,13 +24,20 @@ func MiddlewareLogger() unet.MiddlewareFunc {
 			duration := time.Since(start)
 			resp := req.Response().(*Response)
 
-			logger.InfoCtx(ctx.Context(), "HTTP Request",
+			args := []any{
 				"method", httpReq.Method(),
 				"path", httpReq.Path(),
 				"status", resp.StatusCode(),
 				"duration_ms", duration.Milliseconds(),
-				"client_ip", httpReq.RemoteAddr().String(),
-			)
+				"client_ip", httpReq.ClientIP(),
+			}
+
+			// 事件流在结束后记录,附带发送的事件数
+			if stream := resp.sse.Load(); stream != nil {
+				args = append(args, "stream", "sse", "events", stream.Events())
+			}
+
+			logger.InfoCtx(ctx.Context(), "HTTP Request", args...)
 
 			return err
 		}
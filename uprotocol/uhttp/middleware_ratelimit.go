@@ -1,18 +1,30 @@
 package uhttp
 
 import (
-	"sync"
+	"strconv"
 	"time"
 
 	"github.com/whosafe/uf/ucontext"
 	"github.com/whosafe/uf/uprotocol/unet"
 )
 
+// RateLimitMetaKey 路由元数据中限流规则的键
+// 值为 RateLimit (该路由单独计数) 或等级名 string (使用 RateLimitConfig.Tiers 中的规则)
+//
+//	server.POST("/login", login).Set(uhttp.RateLimitMetaKey, uhttp.RateLimit{Limit: 5, Window: time.Minute})
+const RateLimitMetaKey = "rate_limit"
+
 // RateLimitConfig 限流配置
 type RateLimitConfig struct {
-	MaxRequests int                       // 最大请求数
-	Window      time.Duration             // 时间窗口
-	KeyFunc     func(req *Request) string // 获取限流 key 的函数
+	MaxRequests    int                       // 最大请求数
+	Window         time.Duration             // 时间窗口
+	KeyFunc        func(req *Request) string // 获取限流 key 的函数
+	Algorithm      RateLimitAlgorithm        // 限流算法,默认固定窗口 (Limiter 为空时生效)
+	Limiter        Limiter                   // 限流器,默认按 Algorithm 创建内存限流器;多实例部署使用 NewRedisLimiter
+	Tiers          map[string]RateLimit      // 分级规则: 等级名 -> 规则,Limit 为 0 的等级不限流
+	TierFunc       func(req *Request) string // 返回请求所属等级 (如认证主体的套餐),路由元数据未指定规则时使用
+	DisableHeaders bool                      // 不输出 RateLimit-* 响应头
+	FailClosed     bool                      // 限流器出错时拒绝请求 (503),默认放行并记录错误日志
 }
 
 // DefaultRateLimitConfig 默认限流配置
@@ -21,96 +33,37 @@ func DefaultRateLimitConfig() *RateLimitConfig {
 		MaxRequests: 100,
 		Window:      1 * time.Minute,
 		KeyFunc: func(req *Request) string {
-			// 默认使用 IP 地址作为 key
-			return req.RemoteAddr().String()
+			// 默认使用客户端 IP 作为 key
+			return req.ClientIP()
 		},
 	}
 }
 
-// rateLimiter 限流器
-type rateLimiter struct {
-	visitors map[string]*visitor
-	mu       sync.RWMutex
-	config   *RateLimitConfig
-}
-
-// visitor 访问者
-type visitor struct {
-	count     int
-	lastReset time.Time
-	mu        sync.Mutex
-}
-
-// newRateLimiter 创建限流器
-func newRateLimiter(config *RateLimitConfig) *rateLimiter {
-	limiter := &rateLimiter{
-		visitors: make(map[string]*visitor),
-		config:   config,
-	}
-
-	// 启动清理 goroutine
-	go limiter.cleanup()
-
-	return limiter
-}
-
-// cleanup 定期清理过期的访问者
-func (rl *rateLimiter) cleanup() {
-	ticker := time.NewTicker(rl.config.Window)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		rl.mu.Lock()
-		for key, v := range rl.visitors {
-			v.mu.Lock()
-			if time.Since(v.lastReset) > rl.config.Window*2 {
-				delete(rl.visitors, key)
+// rule 确定请求适用的规则,scope 区分不同规则的计数
+// 优先级: 路由元数据 > TierFunc 返回的等级 > 默认规则
+func (c *RateLimitConfig) rule(req *Request) (limit RateLimit, scope string) {
+	if route := req.Route(); route != nil {
+		if meta, ok := route.Get(RateLimitMetaKey); ok {
+			switch v := meta.(type) {
+			case RateLimit:
+				return v, "route:" + route.Method() + " " + route.Pattern()
+			case *RateLimit:
+				return *v, "route:" + route.Method() + " " + route.Pattern()
+			case string:
+				if tier, ok := c.Tiers[v]; ok {
+					return tier, "tier:" + v
+				}
 			}
-			v.mu.Unlock()
 		}
-		rl.mu.Unlock()
 	}
-}
-
-// getVisitor 获取或创建访问者
-func (rl *rateLimiter) getVisitor(key string) *visitor {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	v, exists := rl.visitors[key]
-	if !exists {
-		v = &visitor{
-			count:     0,
-			lastReset: time.Now(),
+	if c.TierFunc != nil {
+		if name := c.TierFunc(req); name != "" {
+			if tier, ok := c.Tiers[name]; ok {
+				return tier, "tier:" + name
+			}
 		}
-		rl.visitors[key] = v
 	}
-
-	return v
-}
-
-// allow 检查是否允许请求
-func (rl *rateLimiter) allow(key string) bool {
-	v := rl.getVisitor(key)
-
-	v.mu.Lock()
-	defer v.mu.Unlock()
-
-	now := time.Now()
-
-	// 检查是否需要重置
-	if now.Sub(v.lastReset) > rl.config.Window {
-		v.count = 0
-		v.lastReset = now
-	}
-
-	// 检查是否超过限制
-	if v.count >= rl.config.MaxRequests {
-		return false
-	}
-
-	v.count++
-	return true
+	return RateLimit{Limit: c.MaxRequests, Window: c.Window}, ""
 }
 
 // MiddlewareRateLimit 限流中间件 (使用默认配置)
@@ -119,20 +72,55 @@ func MiddlewareRateLimit() unet.MiddlewareFunc {
 }
 
 // MiddlewareRateLimitWithConfig 使用自定义配置的限流中间件
+// 响应带 RateLimit-Limit、RateLimit-Remaining、RateLimit-Reset、RateLimit-Policy 头,拒绝时带 Retry-After
 func MiddlewareRateLimitWithConfig(config *RateLimitConfig) unet.MiddlewareFunc {
-	limiter := newRateLimiter(config)
+	limiter := config.Limiter
+	if limiter == nil {
+		limiter = NewMemoryLimiter(config.Algorithm)
+	}
+	keyFunc := config.KeyFunc
+	if keyFunc == nil {
+		keyFunc = func(req *Request) string {
+			return req.ClientIP()
+		}
+	}
 
 	return func(next unet.HandlerFunc) unet.HandlerFunc {
 		return func(ctx *ucontext.Context, req unet.Request) error {
 			httpReq := req.(*Request)
+			httpResp := req.Response().(*Response)
+
+			limit, scope := config.rule(httpReq)
+			if limit.Limit <= 0 {
+				// 不限流的等级
+				return next(ctx, req)
+			}
 
 			// 获取限流 key
-			key := config.KeyFunc(httpReq)
+			key := keyFunc(httpReq)
+			if scope != "" {
+				key += "|" + scope
+			}
 
 			// 检查是否允许
-			if !limiter.allow(key) {
+			result, err := limiter.Allow(ctx, key, limit)
+			if err != nil {
+				httpReq.Server().ErrorLogger().ErrorCtx(ctx.Context(), "限流检查失败", "key", key, "error", err)
+				if config.FailClosed {
+					return httpResp.Error(503, CodeInternalError, "服务暂时不可用,请稍后再试")
+				}
+				return next(ctx, req)
+			}
+
+			if !config.DisableHeaders {
+				httpResp.SetHeader("RateLimit-Limit", strconv.Itoa(result.Limit))
+				httpResp.SetHeader("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+				httpResp.SetHeader("RateLimit-Reset", strconv.Itoa(durationSeconds(result.ResetAfter)))
+				httpResp.SetHeader("RateLimit-Policy", limit.policy())
+			}
+			if !result.Allowed {
 				// 超过限制,返回 429
-				httpResp := req.Response().(*Response)
+				httpResp.SetHeader("Retry-After", strconv.Itoa(max(durationSeconds(result.RetryAfter), 1)))
 				return httpResp.Error(429, CodeRateLimitExceeded, "请求过于频繁,请稍后再试")
 			}
 
@@ -147,7 +135,7 @@ func MiddlewareRateLimitByIP(maxRequests int, window time.Duration) unet.Middlew
 		MaxRequests: maxRequests,
 		Window:      window,
 		KeyFunc: func(req *Request) string {
-			return req.RemoteAddr().String()
+			return req.ClientIP()
 		},
 	})
 }
@@ -169,7 +157,7 @@ func MiddlewareRateLimitByIPAndPath(maxRequests int, window time.Duration) unet.
 		MaxRequests: maxRequests,
 		Window:      window,
 		KeyFunc: func(req *Request) string {
-			return req.RemoteAddr().String() + ":" + req.Path()
+			return req.ClientIP() + ":" + req.Path()
 		},
 	})
 }
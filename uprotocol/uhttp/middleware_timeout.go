@@ -9,12 +9,13 @@ import (
 )
 
 // MiddlewareTimeout 超时控制中间件
+// 超时限制的是开始响应前的处理时间:处理器已开启 SSE 事件流或升级为 WebSocket 时不再中断,等待处理器返回
 func MiddlewareTimeout(timeout time.Duration) unet.MiddlewareFunc {
 	return func(next unet.HandlerFunc) unet.HandlerFunc {
 		return func(ctx *ucontext.Context, req unet.Request) error {
-			// 创建带超时的 context
-			timeoutCtx, cancel := context.WithTimeout(ctx.Context(), timeout)
-			defer cancel()
+			// 创建可取消的 context,超时后再决定是否取消
+			timeoutCtx, cancel := context.WithCancelCause(ctx.Context())
+			defer cancel(nil)
 
 			// 创建新的 ucontext.Context
 			newCtx := ucontext.NewWithContext(timeoutCtx)
@@ -26,14 +27,26 @@ func MiddlewareTimeout(timeout time.Duration) unet.MiddlewareFunc {
 				done <- next(newCtx, req)
 			}()
 
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+
+			httpResp := req.Response().(*Response)
+
 			select {
 			case err := <-done:
 				return err
+			case <-timer.C:
 			case <-timeoutCtx.Done():
-				httpResp := req.Response().(*Response)
-				httpResp.Error(408, CodeInternalError, "请求超时")
-				return timeoutCtx.Err()
 			}
+
+			// 事件流已开始或连接已劫持,超时不再适用;
+			// 处理器可能正在升级连接或开启事件流,markTimedOut 保证只有一方接管响应
+			if !httpResp.markTimedOut() {
+				return <-done
+			}
+			cancel(context.DeadlineExceeded)
+			httpResp.Error(408, CodeInternalError, "请求超时")
+			return context.Cause(timeoutCtx)
 		}
 	}
 }
@@ -11,10 +11,11 @@ func MiddlewareTrace() unet.MiddlewareFunc {
 		return func(ctx *ucontext.Context, req unet.Request) error {
 			httpReq := req.(*Request)
 
-			// 从 HTTP Header 提取或创建追踪上下文
-			tc := ucontext.ExtractHTTPHeaders(httpReq.Raw().Header)
+			// 作为子处理器挂载时沿用外层已注入请求 context 的追踪上下文,
+			// 否则从 HTTP Header 提取或创建
+			tc := ucontext.FromContext(httpReq.Raw().Context())
 			if tc == nil {
-				tc = ucontext.NewTraceContext()
+				tc = ucontext.ExtractHTTPHeaders(httpReq.Raw().Header)
 			}
 
 			// 创建新的 ucontext.Context 并注入追踪信息
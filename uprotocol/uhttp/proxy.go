@@ -0,0 +1,320 @@
+package uhttp
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/whosafe/uf/uerror"
+)
+
+// 可采信的转发头
+const (
+	headerForwarded     = "Forwarded"
+	headerXForwardedFor = "X-Forwarded-For"
+	headerXRealIP       = "X-Real-Ip"
+)
+
+// parseForwardedHeader 解析采信的转发头 (不区分大小写),为空时使用 X-Forwarded-For
+func parseForwardedHeader(name string) (string, error) {
+	switch name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name {
+	case "":
+		return headerXForwardedFor, nil
+	case headerForwarded, headerXForwardedFor, headerXRealIP:
+		return name, nil
+	default:
+		return headerXForwardedFor, uerror.New("不支持的转发头: " + name)
+	}
+}
+
+// trustedProxies 可信代理列表
+type trustedProxies struct {
+	prefixes []netip.Prefix
+	unix     bool // 信任 Unix 套接字对端 (同机反向代理)
+}
+
+// parseTrustedProxies 解析可信代理,支持 IP、CIDR 和 "unix"
+func parseTrustedProxies(list []string) (*trustedProxies, error) {
+	tp := &trustedProxies{}
+	for _, item := range list {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+			continue
+		case item == "unix":
+			tp.unix = true
+		case strings.Contains(item, "/"):
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, uerror.Wrap(err, "可信代理 CIDR 无效: "+item)
+			}
+			tp.prefixes = append(tp.prefixes, prefix.Masked())
+		default:
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, uerror.Wrap(err, "可信代理地址无效: "+item)
+			}
+			addr = addr.Unmap()
+			tp.prefixes = append(tp.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return tp, nil
+}
+
+// contains 地址是否属于可信代理
+func (tp *trustedProxies) contains(addr netip.Addr) bool {
+	if tp == nil || !addr.IsValid() {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range tp.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetTrustedProxies 设置可信代理 (IP、CIDR 或 "unix"),替换配置文件中的 trusted_proxies
+// 只有直接对端属于可信代理时才采信转发头 (见 SetForwardedHeader),须在启动前调用
+func (s *Server) SetTrustedProxies(proxies ...string) error {
+	tp, err := parseTrustedProxies(proxies)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.router.frozen {
+		panic(frozenMessage)
+	}
+	s.proxies = tp
+	return nil
+}
+
+// SetForwardedHeader 设置采信的转发头 (Forwarded、X-Forwarded-For 或 X-Real-IP),替换配置文件中的 forwarded_header
+// 只采信这一个头,其他转发头即使存在也忽略,应与代理实际设置 (并覆盖客户端传入值) 的头一致,须在启动前调用
+func (s *Server) SetForwardedHeader(header string) error {
+	forwarded, err := parseForwardedHeader(header)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.router.frozen {
+		panic(frozenMessage)
+	}
+	s.forwarded = forwarded
+	return nil
+}
+
+// clientInfo 经可信代理解析后的客户端信息
+type clientInfo struct {
+	ip     string
+	scheme string
+	host   string
+}
+
+// ClientIP 客户端 IP (不含端口)
+// 直接对端是可信代理时,从采信的转发头 (默认 X-Forwarded-For) 中由近及远跳过可信代理,
+// 取第一个不可信的地址;否则为连接的对端地址
+func (r *Request) ClientIP() string {
+	return r.clientInfo().ip
+}
+
+// Scheme 客户端请求使用的协议 (http 或 https),可信代理转发时取 Forwarded proto 或 X-Forwarded-Proto (随采信的转发头)
+func (r *Request) Scheme() string {
+	return r.clientInfo().scheme
+}
+
+// Host 客户端请求的主机 (可能含端口),可信代理转发时取 Forwarded host 或 X-Forwarded-Host (随采信的转发头)
+func (r *Request) Host() string {
+	return r.clientInfo().host
+}
+
+// clientInfo 解析并缓存客户端信息
+func (r *Request) clientInfo() *clientInfo {
+	if r.client != nil {
+		return r.client
+	}
+
+	info := &clientInfo{scheme: "http", host: r.raw.Host}
+	if r.raw.TLS != nil || r.raw.ProtoMajor == 3 {
+		info.scheme = "https"
+	}
+	r.client = info
+
+	var proxies *trustedProxies
+	forwarded := headerXForwardedFor
+	if r.server != nil {
+		proxies = r.server.proxies
+		if r.server.forwarded != "" {
+			forwarded = r.server.forwarded
+		}
+	}
+
+	// 对端地址,Unix 套接字没有地址
+	peer, unix := netip.Addr{}, false
+	if ap, err := netip.ParseAddrPort(r.raw.RemoteAddr); err == nil {
+		peer = ap.Addr().Unmap()
+		info.ip = peer.String()
+	} else if host, _, err := net.SplitHostPort(r.raw.RemoteAddr); err == nil {
+		info.ip = host
+	} else {
+		info.ip = r.raw.RemoteAddr
+		unix = r.raw.RemoteAddr == "" || r.raw.RemoteAddr == "@"
+	}
+	if !proxies.contains(peer) && !(unix && proxies != nil && proxies.unix) {
+		return info
+	}
+
+	// 由近及远逐跳解析,hops[i] 为第 i 跳的转发信息
+	// 只读取采信的转发头:代理通常只覆盖自己设置的头,其他头可能由客户端伪造
+	var hops []forwardedHop
+	switch forwarded {
+	case headerForwarded:
+		hops = parseForwarded(r.raw.Header.Values(headerForwarded))
+	case headerXRealIP:
+		if realIP := strings.TrimSpace(r.raw.Header.Get(headerXRealIP)); realIP != "" {
+			hops = []forwardedHop{{forIP: realIP, proto: lastValue(r.raw.Header.Values("X-Forwarded-Proto"))}}
+		}
+	default:
+		hops = parseXForwarded(r.raw.Header.Values(headerXForwardedFor),
+			r.raw.Header.Values("X-Forwarded-Proto"), r.raw.Header.Values("X-Forwarded-Host"))
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := hops[i]
+		addr, ok := parseHopAddr(hop.forIP)
+		if !ok {
+			// 无法识别的地址 (unknown、混淆标识),停在最后一个可信代理
+			break
+		}
+		info.ip = addr.String()
+		if proto := strings.ToLower(hop.proto); proto == "http" || proto == "https" {
+			info.scheme = proto
+		}
+		if hop.host != "" {
+			info.host = hop.host
+		}
+		if !proxies.contains(addr) {
+			break
+		}
+	}
+	return info
+}
+
+// forwardedHop 一跳代理记录的转发信息
+type forwardedHop struct {
+	forIP string
+	proto string
+	host  string
+}
+
+// parseForwarded 解析 Forwarded (RFC 7239),如 for=192.0.2.60;proto=https;host=example.com, for="[2001:db8::1]:4711"
+func parseForwarded(values []string) []forwardedHop {
+	var hops []forwardedHop
+	for _, value := range values {
+		for _, element := range splitQuoted(value, ',') {
+			var hop forwardedHop
+			for _, pair := range splitQuoted(element, ';') {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				val = strings.TrimSpace(val)
+				if len(val) >= 2 && val[0] == '"' && val[len(val)-1] == '"' {
+					val = strings.ReplaceAll(val[1:len(val)-1], `\`, "")
+				}
+				switch strings.ToLower(strings.TrimSpace(key)) {
+				case "for":
+					hop.forIP = val
+				case "proto":
+					hop.proto = val
+				case "host":
+					hop.host = val
+				}
+			}
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+// parseXForwarded 解析 X-Forwarded-For,X-Forwarded-Proto/Host 与之逐项对应时按跳取值,否则取最后一个值
+func parseXForwarded(forValues, protoValues, hostValues []string) []forwardedHop {
+	var ips, protos, hosts []string
+	for _, v := range forValues {
+		ips = append(ips, strings.Split(v, ",")...)
+	}
+	for _, v := range protoValues {
+		protos = append(protos, strings.Split(v, ",")...)
+	}
+	for _, v := range hostValues {
+		hosts = append(hosts, strings.Split(v, ",")...)
+	}
+
+	hops := make([]forwardedHop, len(ips))
+	for i, ip := range ips {
+		hops[i].forIP = strings.TrimSpace(ip)
+		if len(protos) == len(ips) {
+			hops[i].proto = strings.TrimSpace(protos[i])
+		}
+		if len(hosts) == len(ips) {
+			hops[i].host = strings.TrimSpace(hosts[i])
+		}
+	}
+	// 只有一个值时视为最近的代理设置的值
+	if last := len(hops) - 1; last >= 0 {
+		if len(protos) != len(ips) && len(protos) > 0 {
+			hops[last].proto = strings.TrimSpace(protos[len(protos)-1])
+		}
+		if len(hosts) != len(ips) && len(hosts) > 0 {
+			hops[last].host = strings.TrimSpace(hosts[len(hosts)-1])
+		}
+	}
+	return hops
+}
+
+// parseHopAddr 解析转发记录中的地址: 192.0.2.1、192.0.2.1:80、[2001:db8::1]:80、2001:db8::1
+func parseHopAddr(s string) (netip.Addr, bool) {
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().Unmap(), true
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// lastValue 多个逗号分隔值中的最后一个
+func lastValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	v := values[len(values)-1]
+	if i := strings.LastIndexByte(v, ','); i >= 0 {
+		v = v[i+1:]
+	}
+	return strings.TrimSpace(v)
+}
+
+// splitQuoted 按分隔符切分,忽略引号内的分隔符
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted, start := false, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && quoted:
+			i++
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
@@ -0,0 +1,156 @@
+package uhttp
+
+import (
+	"crypto/tls"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// TestClientInfo 测试经可信代理解析客户端 IP、协议和主机
+func TestClientInfo(t *testing.T) {
+	servers := make(map[string]*Server)
+	for _, header := range []string{"", "Forwarded", "x-real-ip"} {
+		server := New()
+		if err := server.SetTrustedProxies("10.0.0.0/8", "192.168.1.1", "2001:db8::/32"); err != nil {
+			t.Fatal(err)
+		}
+		if header != "" {
+			if err := server.SetForwardedHeader(header); err != nil {
+				t.Fatal(err)
+			}
+		}
+		server.GET("/", func(ctx *ucontext.Context, req unet.Request) error {
+			r := req.(*Request)
+			return req.Response().String(200, r.ClientIP()+"|"+r.Scheme()+"|"+r.Host())
+		})
+		servers[header] = server
+	}
+
+	tests := []struct {
+		name, header, remote string
+		headers              []string
+		want                 string
+	}{
+		{"direct", "", "203.0.113.5:4000", nil, "203.0.113.5|http|example.com"},
+		{"untrusted peer ignores headers", "", "203.0.113.5:4000",
+			[]string{"X-Forwarded-For", "1.2.3.4", "X-Forwarded-Proto", "https", "X-Forwarded-Host", "evil.com"},
+			"203.0.113.5|http|example.com"},
+		{"x-forwarded-for", "", "10.0.0.2:4000",
+			[]string{"X-Forwarded-For", "198.51.100.7", "X-Forwarded-Proto", "https", "X-Forwarded-Host", "app.example.com"},
+			"198.51.100.7|https|app.example.com"},
+		{"spoofed leftmost entry", "", "10.0.0.2:4000",
+			[]string{"X-Forwarded-For", "1.2.3.4, 198.51.100.7, 10.0.0.9"},
+			"198.51.100.7|http|example.com"},
+		{"all hops trusted", "", "10.0.0.2:4000",
+			[]string{"X-Forwarded-For", "10.1.1.1, 10.0.0.9"},
+			"10.1.1.1|http|example.com"},
+		{"invalid hop", "", "10.0.0.2:4000",
+			[]string{"X-Forwarded-For", "198.51.100.7, garbage, 10.0.0.9"},
+			"10.0.0.9|http|example.com"},
+		{"invalid proto", "", "10.0.0.2:4000",
+			[]string{"X-Forwarded-For", "198.51.100.7", "X-Forwarded-Proto", "javascript"},
+			"198.51.100.7|http|example.com"},
+		// 代理只追加 X-Forwarded-For,客户端伪造的 Forwarded / X-Real-IP 不生效
+		{"spoofed forwarded ignored", "", "10.0.0.2:4000",
+			[]string{"Forwarded", "for=1.2.3.4;proto=https;host=evil.com", "X-Real-IP", "1.2.3.4", "X-Forwarded-For", "198.51.100.7"},
+			"198.51.100.7|http|example.com"},
+		{"spoofed forwarded without x-forwarded-for", "", "10.0.0.2:4000",
+			[]string{"Forwarded", "for=1.2.3.4", "X-Real-IP", "1.2.3.4"},
+			"10.0.0.2|http|example.com"},
+		{"forwarded", "Forwarded", "192.168.1.1:4000",
+			[]string{"Forwarded", `for=198.51.100.7;proto=https;host="shop.example.com", for=10.0.0.9;proto=http`},
+			"198.51.100.7|https|shop.example.com"},
+		{"forwarded ipv6", "Forwarded", "[2001:db8::1]:4000",
+			[]string{"Forwarded", `for="[2001:db8:cafe::17]:4711", for="[2001:db9::1]"`},
+			"2001:db9::1|http|example.com"},
+		{"spoofed x-forwarded-for ignored", "Forwarded", "10.0.0.2:4000",
+			[]string{"Forwarded", "for=198.51.100.7", "X-Forwarded-For", "1.2.3.4", "X-Forwarded-Proto", "https"},
+			"198.51.100.7|http|example.com"},
+		{"forwarded unknown", "Forwarded", "10.0.0.2:4000",
+			[]string{"Forwarded", "for=unknown"},
+			"10.0.0.2|http|example.com"},
+		{"x-real-ip", "x-real-ip", "10.0.0.2:4000",
+			[]string{"X-Real-IP", "198.51.100.7", "X-Forwarded-Proto", "https"},
+			"198.51.100.7|https|example.com"},
+		{"spoofed x-forwarded-for with x-real-ip", "x-real-ip", "10.0.0.2:4000",
+			[]string{"X-Forwarded-For", "1.2.3.4"},
+			"10.0.0.2|http|example.com"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "http://example.com/", nil)
+		r.RemoteAddr = tt.remote
+		for i := 0; i+1 < len(tt.headers); i += 2 {
+			r.Header.Set(tt.headers[i], tt.headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		servers[tt.header].ServeHTTP(w, r)
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+
+	// TLS 连接
+	r := httptest.NewRequest("GET", "https://example.com/", nil)
+	r.TLS = &tls.ConnectionState{}
+	w := httptest.NewRecorder()
+	servers[""].ServeHTTP(w, r)
+	if got := w.Body.String(); !strings.HasSuffix(got, "|https|example.com") {
+		t.Errorf("Expected https for TLS request, got %q", got)
+	}
+}
+
+// TestTrustedProxiesConfig 测试可信代理配置
+func TestTrustedProxiesConfig(t *testing.T) {
+	if _, err := parseTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("Expected error for invalid CIDR")
+	}
+	if _, err := parseTrustedProxies([]string{"proxy.local"}); err == nil {
+		t.Error("Expected error for hostname")
+	}
+	if err := New().SetForwardedHeader("X-Client-IP"); err == nil {
+		t.Error("Expected error for unsupported forwarded header")
+	}
+
+	cfg := DefaultConfig()
+	cfg.TrustedProxies = []string{"unix"}
+	server := NewWithConfig(cfg)
+	server.GET("/", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(200, req.(*Request).ClientIP())
+	})
+
+	// Unix 套接字上的反向代理
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "@"
+	r.Header.Set("X-Forwarded-For", "198.51.100.7")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	if w.Body.String() != "198.51.100.7" {
+		t.Errorf("Expected forwarded IP over unix socket, got %q", w.Body.String())
+	}
+}
+
+// TestRateLimitByClientIP 测试限流按客户端 IP 而非连接计数
+func TestRateLimitByClientIP(t *testing.T) {
+	server := New()
+	server.GET("/", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(200, "ok")
+	}, MiddlewareRateLimitByIP(2, time.Minute))
+
+	codes := make([]int, 0, 3)
+	for port := 4000; port < 4003; port++ {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "203.0.113.5:" + strconv.Itoa(port)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+		codes = append(codes, w.Code)
+	}
+	if codes[0] != 200 || codes[1] != 200 || codes[2] != 429 {
+		t.Errorf("Expected third request from the same IP to be limited, got %v", codes)
+	}
+}
@@ -0,0 +1,224 @@
+package uhttp
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uerror"
+)
+
+// RateLimitAlgorithm 限流算法
+type RateLimitAlgorithm string
+
+const (
+	// RateLimitFixedWindow 固定窗口: 每个窗口最多 Limit 次,窗口边界处可能出现两倍突发
+	RateLimitFixedWindow RateLimitAlgorithm = "fixed_window"
+	// RateLimitTokenBucket 令牌桶: 容量 Limit,每 Window 匀速补满
+	RateLimitTokenBucket RateLimitAlgorithm = "token_bucket"
+	// RateLimitSlidingWindow 滑动窗口日志: 任意 Window 长的时间段内最多 Limit 次 (按请求记录时间戳)
+	RateLimitSlidingWindow RateLimitAlgorithm = "sliding_window"
+	// RateLimitGCRA 通用信元速率算法: 与令牌桶等价,每个键只保存一个时间戳
+	RateLimitGCRA RateLimitAlgorithm = "gcra"
+)
+
+// RateLimit 限流规则: 每 Window 最多 Limit 次
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+// valid 规则是否有效
+func (l RateLimit) valid() bool {
+	return l.Limit > 0 && l.Window > 0
+}
+
+// policy RateLimit-Policy 的值,如 100;w=60
+func (l RateLimit) policy() string {
+	return strconv.Itoa(l.Limit) + ";w=" + strconv.FormatInt(int64(durationSeconds(l.Window)), 10)
+}
+
+// RateLimitResult 限流结果
+type RateLimitResult struct {
+	Allowed    bool          // 是否放行
+	Limit      int           // 规则允许的次数
+	Remaining  int           // 剩余次数
+	RetryAfter time.Duration // 被拒绝时距下次可放行的时间
+	ResetAfter time.Duration // 距配额完全恢复的时间
+}
+
+// Limiter 限流器
+type Limiter interface {
+	// Allow 按规则为 key 消耗一次配额
+	Allow(ctx *ucontext.Context, key string, limit RateLimit) (*RateLimitResult, error)
+}
+
+// tokenEpsilon 令牌桶比较时容许的浮点误差
+const tokenEpsilon = 1e-9
+
+// errInvalidRateLimit 限流规则无效
+var errInvalidRateLimit = uerror.New("限流规则无效: Limit 和 Window 必须大于 0")
+
+// limitState 单个键的限流状态
+type limitState struct {
+	count   int         // 固定窗口: 窗口内次数
+	start   time.Time   // 固定窗口: 窗口开始时间
+	tokens  float64     // 令牌桶: 剩余令牌
+	last    time.Time   // 令牌桶: 上次补充时间; GCRA: 理论到达时间 (TAT)
+	log     []time.Time // 滑动窗口: 窗口内的请求时间
+	expires time.Time   // 状态过期时间,之后等同于新键
+}
+
+// apply 按算法消耗一次配额
+func (a RateLimitAlgorithm) apply(st *limitState, now time.Time, limit RateLimit) *RateLimitResult {
+	res := &RateLimitResult{Limit: limit.Limit}
+	n, window := limit.Limit, limit.Window
+
+	switch a {
+	case RateLimitTokenBucket:
+		rate := float64(n) / float64(window) // 每纳秒补充的令牌
+		if st.last.IsZero() {
+			st.tokens = float64(n)
+		} else if elapsed := now.Sub(st.last); elapsed > 0 {
+			st.tokens = min(float64(n), st.tokens+float64(elapsed)*rate)
+		}
+		st.last = now
+		// 浮点误差: 等待 RetryAfter 后的令牌可能略小于 1
+		if st.tokens >= 1-tokenEpsilon {
+			st.tokens = max(st.tokens-1, 0)
+			res.Allowed = true
+		} else {
+			res.RetryAfter = time.Duration(math.Ceil((1 - st.tokens) / rate))
+		}
+		res.Remaining = int(st.tokens + tokenEpsilon)
+		res.ResetAfter = time.Duration(math.Ceil((float64(n) - st.tokens) / rate))
+		st.expires = now.Add(res.ResetAfter)
+
+	case RateLimitSlidingWindow:
+		i := 0
+		for i < len(st.log) && !st.log[i].Add(window).After(now) {
+			i++
+		}
+		st.log = append(st.log[:0], st.log[i:]...)
+		if len(st.log) < n {
+			st.log = append(st.log, now)
+			res.Allowed = true
+		} else {
+			res.RetryAfter = st.log[0].Add(window).Sub(now)
+		}
+		res.Remaining = n - len(st.log)
+		res.ResetAfter = st.log[len(st.log)-1].Add(window).Sub(now)
+		st.expires = now.Add(res.ResetAfter)
+
+	case RateLimitGCRA:
+		interval := window / time.Duration(n)
+		tat := st.last
+		if tat.Before(now) {
+			tat = now
+		}
+		newTat := tat.Add(interval)
+		allowAt := newTat.Add(-window)
+		if now.Before(allowAt) {
+			res.RetryAfter = allowAt.Sub(now)
+			res.ResetAfter = tat.Sub(now)
+		} else {
+			st.last = newTat
+			res.Allowed = true
+			res.Remaining = int(now.Sub(allowAt) / interval)
+			res.ResetAfter = newTat.Sub(now)
+		}
+		st.expires = st.last
+
+	default: // RateLimitFixedWindow
+		if st.start.IsZero() || now.Sub(st.start) >= window {
+			st.start, st.count = now, 0
+		}
+		res.ResetAfter = st.start.Add(window).Sub(now)
+		if st.count < n {
+			st.count++
+			res.Allowed = true
+		} else {
+			res.RetryAfter = res.ResetAfter
+		}
+		res.Remaining = n - st.count
+		st.expires = st.start.Add(window)
+	}
+	return res
+}
+
+// checkAlgorithm 检查算法名称,为空时使用固定窗口
+func checkAlgorithm(a RateLimitAlgorithm) RateLimitAlgorithm {
+	switch a {
+	case "":
+		return RateLimitFixedWindow
+	case RateLimitFixedWindow, RateLimitTokenBucket, RateLimitSlidingWindow, RateLimitGCRA:
+		return a
+	}
+	panic("不支持的限流算法: " + string(a))
+}
+
+// MemoryLimiter 内存限流器 (单实例)
+// 过期状态在调用 Allow 时顺带清理,不启动后台协程
+type MemoryLimiter struct {
+	algorithm RateLimitAlgorithm
+	mu        sync.Mutex
+	states    map[string]*limitState
+	sweptAt   time.Time
+	now       func() time.Time // 测试时替换
+}
+
+// limiterSweepInterval 清理过期状态的最小间隔
+const limiterSweepInterval = time.Minute
+
+// NewMemoryLimiter 创建内存限流器,algorithm 为空时使用固定窗口
+func NewMemoryLimiter(algorithm RateLimitAlgorithm) *MemoryLimiter {
+	return &MemoryLimiter{
+		algorithm: checkAlgorithm(algorithm),
+		states:    make(map[string]*limitState),
+		now:       time.Now,
+	}
+}
+
+// Allow 实现 Limiter 接口
+func (l *MemoryLimiter) Allow(ctx *ucontext.Context, key string, limit RateLimit) (*RateLimitResult, error) {
+	if !limit.valid() {
+		return nil, errInvalidRateLimit
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.sweptAt) >= limiterSweepInterval {
+		for k, st := range l.states {
+			if !st.expires.After(now) {
+				delete(l.states, k)
+			}
+		}
+		l.sweptAt = now
+	}
+
+	st, ok := l.states[key]
+	if !ok || !st.expires.After(now) {
+		st = &limitState{}
+		l.states[key] = st
+	}
+	return l.algorithm.apply(st, now, limit), nil
+}
+
+// Len 当前保存状态的键数量
+func (l *MemoryLimiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.states)
+}
+
+// durationSeconds 向上取整的秒数,用于响应头
+func durationSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int((d + time.Second - 1) / time.Second)
+}
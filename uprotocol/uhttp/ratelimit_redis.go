@@ -0,0 +1,152 @@
+package uhttp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/udb/redis"
+	"github.com/whosafe/uf/uerror"
+)
+
+// 限流 Lua 脚本
+// 时间取 Redis 服务端的 TIME (毫秒,带小数),多实例之间不受本机时钟偏差影响
+// 参数: KEYS[1] 状态键, ARGV[1] 次数, ARGV[2] 窗口毫秒, ARGV[3] 请求标识 (滑动窗口)
+// 返回: {是否放行, 剩余次数, 重试毫秒, 恢复毫秒}
+const redisLimiterPrelude = `
+if redis.replicate_commands then redis.replicate_commands() end
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + tonumber(t[2]) / 1000
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+`
+
+var redisLimiterScripts = map[RateLimitAlgorithm]*redis.Script{
+	RateLimitFixedWindow: redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local count = redis.call('INCR', KEYS[1])
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then
+	redis.call('PEXPIRE', KEYS[1], window)
+	ttl = window
+end
+if count > limit then
+	return {0, 0, ttl, ttl}
+end
+return {1, limit - count, 0, ttl}
+`),
+
+	RateLimitTokenBucket: redis.NewScript(redisLimiterPrelude + `
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+local rate = limit / window
+if tokens == nil or ts == nil then
+	tokens = limit
+elseif now > ts then
+	tokens = math.min(limit, tokens + (now - ts) * rate)
+end
+local allowed, retry = 0, 0
+if tokens >= 1 - 1e-9 then
+	tokens = math.max(tokens - 1, 0)
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+local reset = math.ceil((limit - tokens) / rate)
+redis.call('HSET', KEYS[1], 'tokens', string.format('%.6f', tokens), 'ts', string.format('%.3f', now))
+redis.call('PEXPIRE', KEYS[1], math.max(1, reset))
+return {allowed, math.floor(tokens + 1e-9), retry, reset}
+`),
+
+	RateLimitSlidingWindow: redis.NewScript(redisLimiterPrelude + `
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', string.format('%.3f', now - window))
+local count = redis.call('ZCARD', KEYS[1])
+local allowed, retry = 0, 0
+if count < limit then
+	redis.call('ZADD', KEYS[1], string.format('%.3f', now), ARGV[3])
+	count = count + 1
+	allowed = 1
+else
+	local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+	retry = math.ceil(tonumber(oldest[2]) + window - now)
+end
+local newest = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+local reset = math.max(1, math.ceil(tonumber(newest[2]) + window - now))
+redis.call('PEXPIRE', KEYS[1], reset)
+return {allowed, limit - count, retry, reset}
+`),
+
+	RateLimitGCRA: redis.NewScript(redisLimiterPrelude + `
+local interval = window / limit
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if tat == nil or tat < now then
+	tat = now
+end
+local new_tat = tat + interval
+local allow_at = new_tat - window
+if now < allow_at then
+	return {0, 0, math.ceil(allow_at - now), math.ceil(tat - now)}
+end
+local reset = math.max(1, math.ceil(new_tat - now))
+redis.call('SET', KEYS[1], string.format('%.3f', new_tat), 'PX', reset)
+return {1, math.floor((now - allow_at) / interval + 1e-6), 0, reset}
+`),
+}
+
+// RedisLimiter Redis 限流器,多个实例共享配额,每次检查是一次原子的 Lua 脚本调用
+type RedisLimiter struct {
+	conn      *redis.Connection
+	algorithm RateLimitAlgorithm
+	prefix    string
+}
+
+// NewRedisLimiter 创建 Redis 限流器
+// algorithm 为空时使用固定窗口;prefix 默认 "ratelimit:",实际键为 prefix + 算法 + ":" + key
+func NewRedisLimiter(conn *redis.Connection, algorithm RateLimitAlgorithm, prefix string) *RedisLimiter {
+	if prefix == "" {
+		prefix = "ratelimit:"
+	}
+	return &RedisLimiter{conn: conn, algorithm: checkAlgorithm(algorithm), prefix: prefix}
+}
+
+// Allow 实现 Limiter 接口
+func (l *RedisLimiter) Allow(ctx *ucontext.Context, key string, limit RateLimit) (*RateLimitResult, error) {
+	if !limit.valid() {
+		return nil, errInvalidRateLimit
+	}
+
+	window := max(limit.Window.Milliseconds(), 1)
+	var id [8]byte
+	rand.Read(id[:])
+	reply, err := l.conn.Run(ctx, redisLimiterScripts[l.algorithm],
+		[]string{l.prefix + string(l.algorithm) + ":" + key},
+		limit.Limit, window, hex.EncodeToString(id[:]))
+	if err != nil {
+		return nil, err
+	}
+	return parseLimiterReply(reply, limit)
+}
+
+// parseLimiterReply 解析脚本返回的 {是否放行, 剩余次数, 重试毫秒, 恢复毫秒}
+func parseLimiterReply(reply any, limit RateLimit) (*RateLimitResult, error) {
+	values, ok := reply.([]any)
+	if !ok || len(values) != 4 {
+		return nil, uerror.New("限流脚本返回格式错误")
+	}
+	var n [4]int64
+	for i, v := range values {
+		if n[i], ok = v.(int64); !ok {
+			return nil, uerror.New("限流脚本返回格式错误")
+		}
+	}
+	return &RateLimitResult{
+		Allowed:    n[0] == 1,
+		Limit:      limit.Limit,
+		Remaining:  int(max(n[1], 0)),
+		RetryAfter: time.Duration(max(n[2], 0)) * time.Millisecond,
+		ResetAfter: time.Duration(max(n[3], 0)) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,265 @@
+package uhttp
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/whosafe/uf/uconfig"
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+func newFakeClock() *fakeClock               { return &fakeClock{t: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)} }
+func (c *fakeClock) limiter(a RateLimitAlgorithm) *MemoryLimiter {
+	l := NewMemoryLimiter(a)
+	l.now = c.now
+	return l
+}
+
+// allowN 连续请求 n 次,返回放行的次数和最后一次结果
+func allowN(t *testing.T, l Limiter, key string, limit RateLimit, n int) (int, *RateLimitResult) {
+	t.Helper()
+	allowed := 0
+	var last *RateLimitResult
+	for i := 0; i < n; i++ {
+		res, err := l.Allow(ucontext.New(), key, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed {
+			allowed++
+		}
+		last = res
+	}
+	return allowed, last
+}
+
+// TestLimiterAlgorithms 测试各算法的突发、恢复和剩余次数
+func TestLimiterAlgorithms(t *testing.T) {
+	limit := RateLimit{Limit: 4, Window: 4 * time.Second}
+
+	for _, algorithm := range []RateLimitAlgorithm{RateLimitFixedWindow, RateLimitTokenBucket, RateLimitSlidingWindow, RateLimitGCRA} {
+		clock := newFakeClock()
+		l := clock.limiter(algorithm)
+
+		// 突发 Limit 次后拒绝
+		allowed, res := allowN(t, l, "a", limit, 4)
+		if allowed != 4 || res.Remaining != 0 {
+			t.Errorf("%s: expected burst of 4, got %d (remaining %d)", algorithm, allowed, res.Remaining)
+		}
+		_, res = allowN(t, l, "a", limit, 1)
+		if res.Allowed || res.RetryAfter <= 0 || res.RetryAfter > limit.Window {
+			t.Errorf("%s: expected rejection with retry, got %+v", algorithm, res)
+		}
+
+		// 其他键不受影响
+		if _, res := allowN(t, l, "b", limit, 1); !res.Allowed || res.Remaining != 3 {
+			t.Errorf("%s: expected independent key, got %+v", algorithm, res)
+		}
+
+		// 等待 RetryAfter 后放行
+		clock.advance(res.RetryAfter)
+		if _, res := allowN(t, l, "a", limit, 1); !res.Allowed {
+			t.Errorf("%s: expected allowed after RetryAfter, got %+v", algorithm, res)
+		}
+
+		// 完整窗口后恢复全部配额
+		clock.advance(limit.Window)
+		if allowed, _ := allowN(t, l, "a", limit, 5); allowed != 4 {
+			t.Errorf("%s: expected full quota after window, got %d", algorithm, allowed)
+		}
+	}
+}
+
+// TestLimiterSmoothing 测试令牌桶、滑动窗口和 GCRA 不出现固定窗口的边界突发
+func TestLimiterSmoothing(t *testing.T) {
+	limit := RateLimit{Limit: 4, Window: 4 * time.Second}
+	// 第一次请求之后约一个窗口内连续请求: 固定窗口在边界两侧各放行 4 次,
+	// 其他算法只多放行第一次请求过期 (或补充) 的 1 次
+	expected := map[RateLimitAlgorithm]int{
+		RateLimitFixedWindow:   8,
+		RateLimitTokenBucket:   5,
+		RateLimitSlidingWindow: 5,
+		RateLimitGCRA:          5,
+	}
+	for algorithm, want := range expected {
+		clock := newFakeClock()
+		l := clock.limiter(algorithm)
+		allowN(t, l, "a", limit, 1)
+		clock.advance(limit.Window - time.Millisecond)
+		a1, _ := allowN(t, l, "a", limit, 3)
+		clock.advance(2 * time.Millisecond)
+		a2, _ := allowN(t, l, "a", limit, 4)
+		if got := 1 + a1 + a2; got != want {
+			t.Errorf("%s: expected %d requests around the boundary, got %d", algorithm, want, got)
+		}
+	}
+}
+
+// TestMemoryLimiterSweep 测试过期状态被清理
+func TestMemoryLimiterSweep(t *testing.T) {
+	clock := newFakeClock()
+	l := clock.limiter(RateLimitGCRA)
+	limit := RateLimit{Limit: 10, Window: time.Second}
+	for _, key := range []string{"a", "b", "c"} {
+		allowN(t, l, key, limit, 1)
+	}
+	clock.advance(2 * limiterSweepInterval)
+	allowN(t, l, "d", limit, 1)
+	if l.Len() != 1 {
+		t.Errorf("Expected expired states to be swept, got %d", l.Len())
+	}
+
+	if _, err := l.Allow(ucontext.New(), "a", RateLimit{}); err == nil {
+		t.Error("Expected error for invalid rule")
+	}
+}
+
+// errLimiter 总是返回错误的限流器
+type errLimiter struct{}
+
+func (errLimiter) Allow(ctx *ucontext.Context, key string, limit RateLimit) (*RateLimitResult, error) {
+	return nil, errors.New("redis unavailable")
+}
+
+// TestMiddlewareRateLimit 测试响应头、分级规则和限流器故障处理
+func TestMiddlewareRateLimit(t *testing.T) {
+	ok := func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(200, "ok")
+	}
+	server := New()
+	server.Use(MiddlewareRateLimitWithConfig(&RateLimitConfig{
+		MaxRequests: 2,
+		Window:      time.Minute,
+		Algorithm:   RateLimitGCRA,
+		Tiers: map[string]RateLimit{
+			"pro":      {Limit: 5, Window: time.Minute},
+			"internal": {},
+			"search":   {Limit: 1, Window: time.Minute},
+		},
+		TierFunc: func(req *Request) string {
+			return req.Header("X-Plan")
+		},
+	}))
+	server.GET("/", ok)
+	server.POST("/login", ok).Set(RateLimitMetaKey, RateLimit{Limit: 1, Window: 10 * time.Second})
+	server.GET("/search", ok).Set(RateLimitMetaKey, "search")
+
+	codes := func(method, path string, n int, headers ...string) []int {
+		var out []int
+		for i := 0; i < n; i++ {
+			out = append(out, conditional(server, method, path, headers...).Code)
+		}
+		return out
+	}
+
+	w := conditional(server, "GET", "/")
+	if w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != "1" ||
+		w.Header().Get("RateLimit-Policy") != "2;w=60" || w.Header().Get("RateLimit-Reset") != "30" {
+		t.Errorf("Unexpected headers: %v", w.Header())
+	}
+	conditional(server, "GET", "/")
+	w = conditional(server, "GET", "/")
+	if w.Code != 429 || w.Header().Get("Retry-After") != "30" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("Expected 429 with Retry-After, got %d %v", w.Code, w.Header())
+	}
+
+	// 路由元数据单独计数,不受默认规则耗尽影响
+	if got := codes("POST", "/login", 2); got[0] != 200 || got[1] != 429 {
+		t.Errorf("Expected route rule 1/10s, got %v", got)
+	}
+	if got := codes("GET", "/search", 2, "X-Plan", "pro"); got[0] != 200 || got[1] != 429 {
+		t.Errorf("Expected route tier to take precedence, got %v", got)
+	}
+
+	// 认证主体的等级
+	if got := codes("GET", "/", 6, "X-Plan", "pro"); got[4] != 200 || got[5] != 429 {
+		t.Errorf("Expected pro tier 5/min, got %v", got)
+	}
+	if got := codes("GET", "/", 10, "X-Plan", "internal"); got[9] != 200 {
+		t.Errorf("Expected internal tier to be unlimited, got %v", got)
+	}
+
+	// 限流器故障: 默认放行,FailClosed 时拒绝
+	for failClosed, want := range map[bool]int{false: 200, true: 503} {
+		s := New()
+		s.GET("/", ok, MiddlewareRateLimitWithConfig(&RateLimitConfig{MaxRequests: 1, Window: time.Second, Limiter: errLimiter{}, FailClosed: failClosed}))
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != want {
+			t.Errorf("FailClosed=%v: expected %d, got %d", failClosed, want, w.Code)
+		}
+	}
+}
+
+// TestRateLimitConfig 测试限流配置解析
+func TestRateLimitConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	node, err := uconfig.Parse([]byte(`
+middleware:
+  enable_rate_limit: true
+  rate_limit:
+    max_requests: 60
+    window: 1m
+    algorithm: token_bucket
+    tiers:
+      pro:
+        max_requests: 600
+        window: 1m
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := node.Decode(cfg); err != nil {
+		t.Fatal(err)
+	}
+	r := cfg.Middleware.RateLimit
+	if r.MaxRequests != 60 || r.Algorithm != RateLimitTokenBucket || r.Tiers["pro"] != (RateLimit{Limit: 600, Window: time.Minute}) {
+		t.Errorf("Unexpected rate limit config: %+v", r)
+	}
+
+	// 算法名称拼写错误时解析失败,而不是在应用中间件时 panic
+	node, err = uconfig.Parse([]byte(`
+middleware:
+  rate_limit:
+    algorithm: token-bucket
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := node.Decode(DefaultConfig()); err == nil {
+		t.Error("Expected error for unsupported algorithm in config")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for unsupported algorithm")
+		}
+	}()
+	NewMemoryLimiter("leaky")
+}
+
+// TestParseLimiterReply 测试解析 Redis 脚本返回值
+func TestParseLimiterReply(t *testing.T) {
+	limit := RateLimit{Limit: 10, Window: time.Minute}
+	res, err := parseLimiterReply([]any{int64(0), int64(0), int64(1500), int64(6000)}, limit)
+	if err != nil || res.Allowed || res.Limit != 10 || res.RetryAfter != 1500*time.Millisecond || res.ResetAfter != 6*time.Second {
+		t.Errorf("Unexpected result: %+v %v", res, err)
+	}
+	if _, err := parseLimiterReply([]any{"1"}, limit); err == nil {
+		t.Error("Expected error for malformed reply")
+	}
+	for algorithm := range redisLimiterScripts {
+		checkAlgorithm(algorithm)
+	}
+	if len(redisLimiterScripts) != 4 {
+		t.Errorf("Expected a script per algorithm, got %d", len(redisLimiterScripts))
+	}
+}
@@ -1,11 +1,13 @@
 package uhttp
 
 import (
+	"compress/gzip"
 	"errors"
 	"io"
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 
 	"github.com/whosafe/uf/uprotocol/ubind"
@@ -16,13 +18,16 @@ import (
 var (
 	// ErrRequestBodyTooLarge 请求体过大
 	ErrRequestBodyTooLarge = errors.New("request body too large")
+	// ErrUnsupportedContentEncoding 不支持的请求体编码 (仅支持 gzip)
+	ErrUnsupportedContentEncoding = errors.New("unsupported content encoding")
 )
 
 // requestPool Request 对象池
 var requestPool = sync.Pool{
 	New: func() any {
 		return &Request{
-			store: make(map[string]any),
+			params: make(Params, 0, 8),
+			store:  make(map[string]any),
 		}
 	},
 }
@@ -36,40 +41,69 @@ type Request struct {
 	store    map[string]any
 	response *Response
 	server   *Server
+	route    *Route      // 匹配的路由
+	client   *clientInfo // 经可信代理解析后的客户端信息 (首次访问时解析)
 }
 
-// Params 路径参数
-type Params map[string]string
+// Param 路径参数
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params 路径参数列表 (按路由中出现的顺序)
+type Params []Param
+
+// Get 获取参数值,不存在时返回空字符串
+func (ps Params) Get(key string) string {
+	for _, p := range ps {
+		if p.Key == key {
+			return p.Value
+		}
+	}
+	return ""
+}
 
 // newRequest 创建新的 Request (从对象池获取)
 func newRequest(r *http.Request, w http.ResponseWriter, server *Server) *Request {
 	req := requestPool.Get().(*Request)
 	req.raw = r
 	req.writer = w
-	req.params = make(Params)
+	req.params = req.params[:0]
 	req.query = nil
 	// 清空 store
 	for k := range req.store {
 		delete(req.store, k)
 	}
-	req.response = newResponse(w)
+	req.response = newResponse(w, r)
 	req.server = server
+	req.route = nil
+	req.client = nil
 	return req
 }
 
 // release 释放 Request 回对象池
 func (r *Request) release() {
+	if body, ok := r.raw.Body.(*gzipBody); ok {
+		body.Close()
+	}
 	r.raw = nil
 	r.writer = nil
-	r.params = nil
+	r.params = r.params[:0]
 	r.query = nil
 	r.response = nil
 	r.server = nil
+	r.route = nil
+	r.client = nil
 	requestPool.Put(r)
 }
 
 // Protocol 获取协议类型
 func (r *Request) Protocol() unet.Protocol {
+	// HTTP/3 请求来自 QUIC 传输
+	if r.raw.ProtoMajor == 3 {
+		return unet.ProtocolQUIC
+	}
 	if r.raw.TLS != nil {
 		return unet.ProtocolHTTPS
 	}
@@ -78,6 +112,10 @@ func (r *Request) Protocol() unet.Protocol {
 
 // RemoteAddr 获取远程地址
 func (r *Request) RemoteAddr() net.Addr {
+	if r.raw.ProtoMajor == 3 {
+		addr, _ := net.ResolveUDPAddr("udp", r.raw.RemoteAddr)
+		return addr
+	}
 	addr, _ := net.ResolveTCPAddr("tcp", r.raw.RemoteAddr)
 	return addr
 }
@@ -111,6 +149,10 @@ func (r *Request) Set(key string, value any) {
 
 // Bind 绑定请求数据
 func (r *Request) Bind(obj ubind.Binder) error {
+	if err := r.decodeBody(); err != nil {
+		return err
+	}
+
 	// 【安全修复】限制请求体大小,防止 DoS 攻击
 	maxBodySize := r.getMaxBodySize()
 	limitedReader := io.LimitReader(r.raw.Body, maxBodySize)
@@ -139,7 +181,12 @@ func (r *Request) Response() unet.Response {
 
 // Param 获取路径参数
 func (r *Request) Param(key string) string {
-	return r.params[key]
+	return r.params.Get(key)
+}
+
+// Params 获取全部路径参数
+func (r *Request) Params() Params {
+	return r.params
 }
 
 // Query 获取查询参数
@@ -171,6 +218,10 @@ func (r *Request) Cookie(name string) (*http.Cookie, error) {
 
 // Body 获取请求体
 func (r *Request) Body() ([]byte, error) {
+	if err := r.decodeBody(); err != nil {
+		return nil, err
+	}
+
 	// 【安全修复】限制请求体大小,防止 DoS 攻击
 	maxBodySize := r.getMaxBodySize()
 	limitedReader := io.LimitReader(r.raw.Body, maxBodySize)
@@ -190,6 +241,10 @@ func (r *Request) Body() ([]byte, error) {
 
 // BindJSON 绑定 JSON 数据
 func (r *Request) BindJSON(obj ubind.Binder) error {
+	if err := r.decodeBody(); err != nil {
+		return err
+	}
+
 	// 【安全修复】限制请求体大小,防止 DoS 攻击
 	maxBodySize := r.getMaxBodySize()
 	limitedReader := io.LimitReader(r.raw.Body, maxBodySize)
@@ -211,6 +266,9 @@ func (r *Request) BindJSON(obj ubind.Binder) error {
 
 // BindForm 绑定 Form 数据
 func (r *Request) BindForm(obj ubind.Binder) error {
+	if err := r.decodeBody(); err != nil {
+		return err
+	}
 	if err := r.raw.ParseForm(); err != nil {
 		return err
 	}
@@ -251,6 +309,11 @@ func (r *Request) Session() (unet.Session, error) {
 	return nil, errors.New("session manager not found")
 }
 
+// Route 获取匹配的路由,未匹配 (404/405/重定向) 时返回 nil
+func (r *Request) Route() *Route {
+	return r.route
+}
+
 // Raw 获取原始 *http.Request
 func (r *Request) Raw() *http.Request {
 	return r.raw
@@ -265,3 +328,61 @@ func (r *Request) getMaxBodySize() int64 {
 	// 默认 10MB
 	return 10 << 20
 }
+
+// gzipReaderPool 请求体解压器池
+var gzipReaderPool sync.Pool
+
+// gzipBody 解压后的请求体,关闭时归还解压器
+type gzipBody struct {
+	zr   *gzip.Reader
+	body io.ReadCloser
+}
+
+// Read 实现 io.Reader 接口
+func (b *gzipBody) Read(p []byte) (int, error) {
+	if b.zr == nil {
+		return 0, http.ErrBodyReadAfterClose
+	}
+	return b.zr.Read(p)
+}
+
+// Close 实现 io.Closer 接口
+func (b *gzipBody) Close() error {
+	if b.zr == nil {
+		return nil
+	}
+	gzipReaderPool.Put(b.zr)
+	b.zr = nil
+	return b.body.Close()
+}
+
+// decodeBody 按 Content-Encoding 解压请求体,之后读取时的大小限制作用于解压后的数据
+func (r *Request) decodeBody() error {
+	encoding := r.raw.Header.Get("Content-Encoding")
+	if encoding == "" || strings.EqualFold(encoding, "identity") {
+		return nil
+	}
+	if !strings.EqualFold(encoding, "gzip") && !strings.EqualFold(encoding, "x-gzip") {
+		return ErrUnsupportedContentEncoding
+	}
+
+	zr, _ := gzipReaderPool.Get().(*gzip.Reader)
+	var err error
+	if zr == nil {
+		zr, err = gzip.NewReader(r.raw.Body)
+	} else {
+		err = zr.Reset(r.raw.Body)
+	}
+	if err != nil {
+		if zr != nil {
+			gzipReaderPool.Put(zr)
+		}
+		return err
+	}
+
+	r.raw.Body = &gzipBody{zr: zr, body: r.raw.Body}
+	r.raw.Header.Del("Content-Encoding")
+	r.raw.Header.Del("Content-Length")
+	r.raw.ContentLength = -1
+	return nil
+}
@@ -3,6 +3,7 @@ package uhttp
 import (
 	"net/http"
 	"sync"
+	"sync/atomic"
 
 	"github.com/whosafe/uf/uprotocol/umarshal"
 )
@@ -17,22 +18,40 @@ var responsePool = sync.Pool{
 // Response HTTP 响应 (实现 unet.Response 接口)
 type Response struct {
 	writer     http.ResponseWriter
+	request    *http.Request
 	statusCode int
 	written    bool
+	sse        atomic.Pointer[SSEStream] // 已开启的事件流 (超时中间件跨协程读取)
+	takeover   atomic.Int32              // 连接接管状态,见 takeoverNone 等 (超时中间件跨协程读取)
 }
 
+// 连接接管状态: 升级为长连接、开启事件流和超时中间件写入 408 只能有一方成功
+const (
+	takeoverNone      int32 = iota
+	takeoverHijacked        // 连接已被劫持 (如 WebSocket),处理器返回前不能释放请求
+	takeoverStreaming       // 已开启事件流,超时不再适用
+	takeoverTimedOut        // 超时中间件已写入 408,不能再劫持连接或开启事件流
+)
+
 // newResponse 创建新的 Response (从对象池获取)
-func newResponse(w http.ResponseWriter) *Response {
+func newResponse(w http.ResponseWriter, r *http.Request) *Response {
 	resp := responsePool.Get().(*Response)
 	resp.writer = w
+	resp.request = r
 	resp.statusCode = http.StatusOK
 	resp.written = false
+	resp.takeover.Store(takeoverNone)
 	return resp
 }
 
 // release 释放 Response 回对象池
 func (r *Response) release() {
+	// 处理器返回后结束事件流,避免心跳在释放后写入
+	if stream := r.sse.Swap(nil); stream != nil {
+		stream.Close()
+	}
 	r.writer = nil
+	r.request = nil
 	r.statusCode = 0
 	r.written = false
 	responsePool.Put(r)
@@ -134,3 +153,28 @@ func (r *Response) StatusCode() int {
 func (r *Response) IsWritten() bool {
 	return r.written
 }
+
+// IsStreaming 检查响应是否为事件流
+func (r *Response) IsStreaming() bool {
+	return r.sse.Load() != nil
+}
+
+// IsHijacked 检查连接是否已被劫持为长连接 (如 WebSocket)
+func (r *Response) IsHijacked() bool {
+	return r.takeover.Load() == takeoverHijacked
+}
+
+// markHijacked 在劫持连接前调用,超时中间件已接管响应时返回 false
+func (r *Response) markHijacked() bool {
+	return r.takeover.CompareAndSwap(takeoverNone, takeoverHijacked)
+}
+
+// markStreaming 在写入事件流响应头前调用,超时中间件已接管响应时返回 false
+func (r *Response) markStreaming() bool {
+	return r.takeover.CompareAndSwap(takeoverNone, takeoverStreaming)
+}
+
+// markTimedOut 在写入超时响应前调用,连接已被劫持或已开启事件流时返回 false
+func (r *Response) markTimedOut() bool {
+	return r.takeover.CompareAndSwap(takeoverNone, takeoverTimedOut)
+}
@@ -0,0 +1,368 @@
+package uhttp
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whosafe/uf/uerror"
+)
+
+// maxRanges 单个请求最多的区间数,超过时返回完整内容
+const maxRanges = 64
+
+// errRangeUnsatisfiable 请求的区间都不在内容范围内
+var errRangeUnsatisfiable = errors.New("range not satisfiable")
+
+// httpRange 字节区间
+type httpRange struct {
+	start, length int64
+}
+
+// contentRange Content-Range 的值,size 为 -1 时总长度为 *
+func (r httpRange) contentRange(size int64) string {
+	total := "*"
+	if size >= 0 {
+		total = strconv.FormatInt(size, 10)
+	}
+	return "bytes " + strconv.FormatInt(r.start, 10) + "-" + strconv.FormatInt(r.start+r.length-1, 10) + "/" + total
+}
+
+// File 发送文件,支持 Range、If-Range 和条件请求,Content-Type 按扩展名或内容确定
+func (r *Response) File(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return r.NotFound("文件不存在")
+		}
+		return uerror.Wrap(err, "打开文件失败")
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return uerror.Wrap(err, "读取文件信息失败")
+	}
+	if info.IsDir() {
+		return r.NotFound("文件不存在")
+	}
+	return r.serveContent(filepath.Base(path), info.ModTime(), f, info.Size())
+}
+
+// Attachment 以附件形式发送内容,浏览器提示下载并使用 name 作为文件名 (支持中文)
+// modtime 非零时设置 Last-Modified,支持 Range、If-Range 和条件请求
+func (r *Response) Attachment(name string, content io.ReadSeeker, modtime time.Time) error {
+	size, err := content.Seek(0, io.SeekEnd)
+	if err != nil {
+		return uerror.Wrap(err, "读取内容长度失败")
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return uerror.Wrap(err, "读取内容失败")
+	}
+	r.SetHeader("Content-Disposition", ContentDisposition("attachment", name))
+	return r.serveContent(name, modtime, content, size)
+}
+
+// Stream 发送流式内容
+// content 实现 io.Seeker 时支持任意区间;否则长度未知,只支持升序且不重叠的闭区间 (如 bytes=0-99,200-299),
+// 其他 Range 请求返回完整内容
+func (r *Response) Stream(contentType string, content io.Reader) error {
+	if contentType != "" {
+		r.SetHeader("Content-Type", contentType)
+	}
+	if seeker, ok := content.(io.ReadSeeker); ok {
+		size, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return uerror.Wrap(err, "读取内容长度失败")
+		}
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return uerror.Wrap(err, "读取内容失败")
+		}
+		return r.serveContent("", time.Time{}, seeker, size)
+	}
+	return r.serveContent("", time.Time{}, content, -1)
+}
+
+// ContentDisposition 生成 Content-Disposition,非 ASCII 文件名使用 RFC 6266 的 filename* 编码,
+// 同时提供 ASCII 的 filename 兼容旧客户端
+//
+//	ContentDisposition("attachment", "报告.pdf")
+//	// attachment; filename="__.pdf"; filename*=UTF-8''%E6%8A%A5%E5%91%8A.pdf
+func ContentDisposition(disposition, filename string) string {
+	var fallback strings.Builder
+	ascii := true
+	for _, c := range filename {
+		switch {
+		case c >= 0x80:
+			ascii = false
+			fallback.WriteByte('_')
+		case c < 0x20 || c == 0x7f || c == '"' || c == '\\':
+			fallback.WriteByte('_')
+		default:
+			fallback.WriteRune(c)
+		}
+	}
+	if ascii {
+		return disposition + `; filename="` + fallback.String() + `"`
+	}
+	// url.PathEscape 保留的 RFC 5987 attr-char 之外的字符需要编码
+	encoded := strings.NewReplacer("'", "%27", "(", "%28", ")", "%29", "*", "%2A", ";", "%3B", ",", "%2C", "=", "%3D", "@", "%40", ":", "%3A").
+		Replace(url.PathEscape(filename))
+	return disposition + `; filename="` + fallback.String() + `"; filename*=UTF-8''` + encoded
+}
+
+// serveContent 输出内容,处理条件请求、If-Range 和 Range
+// size 为 -1 表示长度未知 (content 不支持 Seek)
+func (r *Response) serveContent(name string, modtime time.Time, content io.Reader, size int64) error {
+	header := r.writer.Header()
+	seeker, seekable := content.(io.ReadSeeker)
+
+	if header.Get("Content-Type") == "" {
+		ctype := mime.TypeByExtension(filepath.Ext(name))
+		if ctype == "" && seekable {
+			// 按内容探测类型
+			var buf [512]byte
+			n, _ := io.ReadFull(seeker, buf[:])
+			ctype = http.DetectContentType(buf[:n])
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return uerror.Wrap(err, "读取内容失败")
+			}
+		}
+		if ctype == "" {
+			ctype = "application/octet-stream"
+		}
+		header.Set("Content-Type", ctype)
+	}
+	lastModified := ""
+	if !modtime.IsZero() && !modtime.Equal(time.Unix(0, 0)) {
+		lastModified = modtime.UTC().Format(http.TimeFormat)
+		header.Set("Last-Modified", lastModified)
+	}
+
+	// 条件请求
+	etag := header.Get("ETag")
+	if code := r.checkPreconditions(etag, modtime); code != 0 {
+		r.Status(code)
+		return nil
+	}
+	if notModified(r.request.Header, etag, lastModified) {
+		header.Del("Content-Type")
+		r.Status(http.StatusNotModified)
+		return nil
+	}
+
+	ranges, err := r.requestRanges(etag, modtime, size)
+	if err == errRangeUnsatisfiable {
+		header.Set("Content-Range", "bytes */"+strconv.FormatInt(size, 10))
+		header.Del("Content-Type")
+		r.Status(http.StatusRequestedRangeNotSatisfiable)
+		return nil
+	}
+	header.Set("Accept-Ranges", "bytes")
+	head := r.request.Method == http.MethodHead
+
+	switch len(ranges) {
+	case 0:
+		if size >= 0 {
+			header.Set("Content-Length", strconv.FormatInt(size, 10))
+		}
+		r.Status(http.StatusOK)
+		if head {
+			return nil
+		}
+		_, err := io.Copy(r.writer, content)
+		return err
+
+	case 1:
+		ra := ranges[0]
+		header.Set("Content-Range", ra.contentRange(size))
+		header.Set("Content-Length", strconv.FormatInt(ra.length, 10))
+		r.Status(http.StatusPartialContent)
+		if head {
+			return nil
+		}
+		if err := skipTo(content, 0, ra.start); err != nil {
+			return err
+		}
+		_, err := io.CopyN(r.writer, content, ra.length)
+		return err
+	}
+
+	// 多区间: multipart/byteranges
+	ctype := header.Get("Content-Type")
+	mw := multipart.NewWriter(r.writer)
+	header.Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+	header.Del("Content-Length")
+	r.Status(http.StatusPartialContent)
+	if head {
+		return nil
+	}
+
+	pos := int64(0)
+	for _, ra := range ranges {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":  {ctype},
+			"Content-Range": {ra.contentRange(size)},
+		})
+		if err != nil {
+			return err
+		}
+		if err := skipTo(content, pos, ra.start); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(part, content, ra.length); err != nil {
+			return err
+		}
+		pos = ra.start + ra.length
+	}
+	return mw.Close()
+}
+
+// checkPreconditions 检查 If-Match 和 If-Unmodified-Since,不满足时返回 412
+func (r *Response) checkPreconditions(etag string, modtime time.Time) int {
+	if ifMatch := r.request.Header.Get("If-Match"); ifMatch != "" {
+		if !etagListMatch(ifMatch, etag, true) {
+			return http.StatusPreconditionFailed
+		}
+		return 0
+	}
+	if ius := r.request.Header.Get("If-Unmodified-Since"); ius != "" && !modtime.IsZero() {
+		if t, err := http.ParseTime(ius); err == nil && modtime.Truncate(time.Second).After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+	return 0
+}
+
+// requestRanges 解析请求的区间,If-Range 不匹配或区间不适用时返回 nil (发送完整内容)
+func (r *Response) requestRanges(etag string, modtime time.Time, size int64) ([]httpRange, error) {
+	rangeHeader := r.request.Header.Get("Range")
+	if rangeHeader == "" || (r.request.Method != http.MethodGet && r.request.Method != http.MethodHead) {
+		return nil, nil
+	}
+
+	// If-Range: 表示未变化 (强 ETag 相同或修改时间相同) 时才按区间发送
+	if ifRange := r.request.Header.Get("If-Range"); ifRange != "" {
+		if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+			if etag == "" || strings.HasPrefix(ifRange, "W/") || strings.HasPrefix(etag, "W/") || ifRange != etag {
+				return nil, nil
+			}
+		} else {
+			t, err := http.ParseTime(ifRange)
+			if err != nil || modtime.IsZero() || !modtime.Truncate(time.Second).Equal(t) {
+				return nil, nil
+			}
+		}
+	}
+
+	ranges, err := parseRange(rangeHeader, size)
+	if err != nil || len(ranges) == 0 {
+		return nil, err
+	}
+	if len(ranges) > maxRanges {
+		return nil, nil
+	}
+	if size < 0 {
+		// 长度未知时只能顺序读取
+		for i := 1; i < len(ranges); i++ {
+			if ranges[i].start < ranges[i-1].start+ranges[i-1].length {
+				return nil, nil
+			}
+		}
+		return ranges, nil
+	}
+	// 区间总长超过内容长度 (大量重叠) 时发送完整内容
+	var total int64
+	for _, ra := range ranges {
+		total += ra.length
+	}
+	if total > size {
+		return nil, nil
+	}
+	return ranges, nil
+}
+
+// parseRange 解析 Range 头 (bytes=0-99,200-,-500)
+// size 为 -1 时只接受闭区间;格式错误或单位不是 bytes 时返回 nil (忽略 Range)
+// 所有区间都不在内容范围内时返回 errRangeUnsatisfiable
+func parseRange(header string, size int64) ([]httpRange, error) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok {
+		return nil, nil
+	}
+
+	var ranges []httpRange
+	unsatisfiable := false
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		first, last, ok := strings.Cut(item, "-")
+		if !ok {
+			return nil, nil
+		}
+		first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+
+		var ra httpRange
+		switch {
+		case first == "":
+			// 后缀区间: 最后 N 字节
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 || size < 0 {
+				return nil, nil
+			}
+			if n == 0 || size == 0 {
+				unsatisfiable = true
+				continue
+			}
+			n = min(n, size)
+			ra = httpRange{start: size - n, length: n}
+		default:
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, nil
+			}
+			if size >= 0 && start >= size {
+				unsatisfiable = true
+				continue
+			}
+			end := size - 1
+			if last != "" {
+				if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+					return nil, nil
+				}
+				if size >= 0 {
+					end = min(end, size-1)
+				}
+			} else if size < 0 {
+				return nil, nil
+			}
+			ra = httpRange{start: start, length: end - start + 1}
+		}
+		ranges = append(ranges, ra)
+	}
+	if len(ranges) == 0 && unsatisfiable {
+		return nil, errRangeUnsatisfiable
+	}
+	return ranges, nil
+}
+
+// skipTo 把内容移动到 start,支持 Seek 时直接定位,否则丢弃中间的数据
+func skipTo(content io.Reader, pos, start int64) error {
+	if seeker, ok := content.(io.Seeker); ok {
+		_, err := seeker.Seek(start, io.SeekStart)
+		return err
+	}
+	_, err := io.CopyN(io.Discard, content, start-pos)
+	return err
+}
@@ -0,0 +1,214 @@
+package uhttp
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// fileServer 注册下载相关路由
+func fileServer(t *testing.T, content string, modified time.Time) *Server {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.csv")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, modified, modified)
+
+	server := New()
+	server.GET("/file", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().(*Response).File(path)
+	})
+	server.GET("/missing", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().(*Response).File(filepath.Join(dir, "missing.csv"))
+	})
+	server.GET("/attachment", func(ctx *ucontext.Context, req unet.Request) error {
+		resp := req.Response().(*Response)
+		resp.SetHeader("ETag", `"v1"`)
+		return resp.Attachment("季度报告.csv", strings.NewReader(content), modified)
+	})
+	server.GET("/stream", func(ctx *ucontext.Context, req unet.Request) error {
+		// OneByteReader 不支持 Seek,模拟长度未知的流
+		return req.Response().(*Response).Stream("text/plain", iotest.OneByteReader(strings.NewReader(content)))
+	})
+	return server
+}
+
+// TestResponseFile 测试文件下载、单区间和条件请求
+func TestResponseFile(t *testing.T) {
+	modified := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	server := fileServer(t, "0123456789", modified)
+
+	w := conditional(server, "GET", "/file")
+	if w.Code != 200 || w.Body.String() != "0123456789" || w.Header().Get("Accept-Ranges") != "bytes" {
+		t.Fatalf("Unexpected response: %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Expected text/csv, got %q", ct)
+	}
+	if lm := w.Header().Get("Last-Modified"); lm != modified.Format(http.TimeFormat) {
+		t.Errorf("Unexpected Last-Modified %q", lm)
+	}
+	if w.Header().Get("Content-Disposition") != "" {
+		t.Error("File should not set Content-Disposition")
+	}
+
+	tests := []struct {
+		rangeHeader, contentRange, body string
+		code                            int
+	}{
+		{"bytes=2-4", "bytes 2-4/10", "234", 206},
+		{"bytes=7-", "bytes 7-9/10", "789", 206},
+		{"bytes=-3", "bytes 7-9/10", "789", 206},
+		{"bytes=5-100", "bytes 5-9/10", "56789", 206},
+		{"bytes=20-30", "bytes */10", "", 416},
+		{"bytes=4-2", "", "0123456789", 200},
+		{"items=0-1", "", "0123456789", 200},
+		{"bytes=0-5,2-8", "", "0123456789", 200},
+	}
+	for _, tt := range tests {
+		w := conditional(server, "GET", "/file", "Range", tt.rangeHeader)
+		if w.Code != tt.code || w.Body.String() != tt.body || w.Header().Get("Content-Range") != tt.contentRange {
+			t.Errorf("%s: got %d %q %q", tt.rangeHeader, w.Code, w.Body.String(), w.Header().Get("Content-Range"))
+		}
+	}
+
+	// If-Range 日期相同时按区间发送,否则发送完整内容
+	w = conditional(server, "GET", "/file", "Range", "bytes=0-1", "If-Range", modified.Format(http.TimeFormat))
+	if w.Code != 206 || w.Body.String() != "01" {
+		t.Errorf("Expected partial content for matching If-Range, got %d %q", w.Code, w.Body.String())
+	}
+	w = conditional(server, "GET", "/file", "Range", "bytes=0-1", "If-Range", modified.Add(-time.Hour).Format(http.TimeFormat))
+	if w.Code != 200 || w.Body.String() != "0123456789" {
+		t.Errorf("Expected full content for stale If-Range, got %d %q", w.Code, w.Body.String())
+	}
+
+	// 条件请求
+	w = conditional(server, "GET", "/file", "If-Modified-Since", modified.Format(http.TimeFormat))
+	if w.Code != 304 || w.Body.Len() != 0 {
+		t.Errorf("Expected 304, got %d", w.Code)
+	}
+	w = conditional(server, "GET", "/file", "If-Unmodified-Since", modified.Add(-time.Hour).Format(http.TimeFormat))
+	if w.Code != 412 {
+		t.Errorf("Expected 412, got %d", w.Code)
+	}
+
+	// HEAD 只返回头部
+	w = conditional(server, "HEAD", "/file", "Range", "bytes=0-3")
+	if w.Code != 206 || w.Body.Len() != 0 || w.Header().Get("Content-Length") != "4" {
+		t.Errorf("Unexpected HEAD response: %d %d %q", w.Code, w.Body.Len(), w.Header().Get("Content-Length"))
+	}
+
+	if w := conditional(server, "GET", "/missing"); w.Code != 404 {
+		t.Errorf("Expected 404 for missing file, got %d", w.Code)
+	}
+}
+
+// TestResponseAttachment 测试附件文件名编码、ETag If-Range 和多区间
+func TestResponseAttachment(t *testing.T) {
+	server := fileServer(t, "0123456789", time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+
+	w := conditional(server, "GET", "/attachment")
+	want := `attachment; filename="____.csv"; filename*=UTF-8''%E5%AD%A3%E5%BA%A6%E6%8A%A5%E5%91%8A.csv`
+	if got := w.Header().Get("Content-Disposition"); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	_, params, err := mime.ParseMediaType(w.Header().Get("Content-Disposition"))
+	if err != nil || params["filename"] != "季度报告.csv" {
+		t.Errorf("Content-Disposition should decode to the original name, got %v %v", params, err)
+	}
+
+	// 弱 ETag 和不同的 ETag 不满足 If-Range
+	for ifRange, code := range map[string]int{`"v1"`: 206, `W/"v1"`: 200, `"v0"`: 200} {
+		w := conditional(server, "GET", "/attachment", "Range", "bytes=0-0", "If-Range", ifRange)
+		if w.Code != code {
+			t.Errorf("If-Range %s: expected %d, got %d", ifRange, code, w.Code)
+		}
+	}
+	if w := conditional(server, "GET", "/attachment", "If-Match", `"v0"`); w.Code != 412 {
+		t.Errorf("Expected 412 for If-Match mismatch, got %d", w.Code)
+	}
+
+	// 多区间
+	w = conditional(server, "GET", "/attachment", "Range", "bytes=0-1,-2")
+	mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if w.Code != 206 || err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("Unexpected multi-range response: %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	parts := readByteranges(t, w.Body, params["boundary"])
+	if len(parts) != 2 || parts[0] != "bytes 0-1/10:01" || parts[1] != "bytes 8-9/10:89" {
+		t.Errorf("Unexpected parts: %q", parts)
+	}
+}
+
+// TestResponseStream 测试长度未知的流
+func TestResponseStream(t *testing.T) {
+	server := fileServer(t, "0123456789", time.Time{})
+
+	w := conditional(server, "GET", "/stream")
+	if w.Code != 200 || w.Body.String() != "0123456789" || w.Header().Get("Content-Type") != "text/plain" || w.Header().Get("Content-Length") != "" {
+		t.Fatalf("Unexpected response: %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	w = conditional(server, "GET", "/stream", "Range", "bytes=3-5")
+	if w.Code != 206 || w.Body.String() != "345" || w.Header().Get("Content-Range") != "bytes 3-5/*" {
+		t.Errorf("Unexpected single range: %d %q %q", w.Code, w.Body.String(), w.Header().Get("Content-Range"))
+	}
+
+	w = conditional(server, "GET", "/stream", "Range", "bytes=1-2,5-6")
+	_, params, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	parts := readByteranges(t, w.Body, params["boundary"])
+	if w.Code != 206 || len(parts) != 2 || parts[0] != "bytes 1-2/*:12" || parts[1] != "bytes 5-6/*:56" {
+		t.Errorf("Unexpected multi-range: %d %q", w.Code, parts)
+	}
+
+	// 长度未知时无法处理后缀区间和倒序区间
+	for _, rangeHeader := range []string{"bytes=-3", "bytes=5-", "bytes=5-6,1-2"} {
+		w := conditional(server, "GET", "/stream", "Range", rangeHeader)
+		if w.Code != 200 || w.Body.String() != "0123456789" {
+			t.Errorf("%s: expected full content, got %d %q", rangeHeader, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestContentDisposition 测试文件名编码
+func TestContentDisposition(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"report.pdf", `attachment; filename="report.pdf"`},
+		{`a"b\c.txt`, `attachment; filename="a_b_c.txt"`},
+		{"数据 (1).xlsx", `attachment; filename="__ (1).xlsx"; filename*=UTF-8''%E6%95%B0%E6%8D%AE%20%281%29.xlsx`},
+	}
+	for _, tt := range tests {
+		if got := ContentDisposition("attachment", tt.name); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+// readByteranges 读取 multipart/byteranges,返回 "Content-Range:内容"
+func readByteranges(t *testing.T, body io.Reader, boundary string) []string {
+	t.Helper()
+	var parts []string
+	mr := multipart.NewReader(body, boundary)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return parts
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(part)
+		parts = append(parts, part.Header.Get("Content-Range")+":"+string(data))
+	}
+}
@@ -1,6 +1,8 @@
 package uhttp
 
 import (
+	"strconv"
+
 	"github.com/whosafe/uf/uprotocol/umarshal"
 )
 
@@ -47,6 +49,56 @@ func (a *APIResponse) Marshal(w *umarshal.Writer) error {
 	return nil
 }
 
+// MarshalXMLTo 实现 IXMLMarshaler 接口,根元素为 <response>
+func (a *APIResponse) MarshalXMLTo(w *XMLWriter) error {
+	w.StartElement("response")
+	w.StartElement("code")
+	w.buf = strconv.AppendInt(w.buf, int64(a.Code), 10)
+	w.EndElement("code")
+	if a.Message != "" {
+		w.WriteElement("message", a.Message)
+	}
+	if a.Data != nil {
+		if err := w.WriteValue("data", a.Data); err != nil {
+			return err
+		}
+	}
+	if a.Error != "" {
+		w.WriteElement("error", a.Error)
+	}
+	w.EndElement("response")
+	return nil
+}
+
+// MarshalMsgPack 实现 IMsgPackMarshaler 接口
+func (a *APIResponse) MarshalMsgPack(w *MsgPackWriter) error {
+	n := 1
+	for _, set := range []bool{a.Message != "", a.Data != nil, a.Error != ""} {
+		if set {
+			n++
+		}
+	}
+
+	w.WriteMapHeader(n)
+	w.WriteString("code")
+	w.WriteInt(int64(a.Code))
+	if a.Message != "" {
+		w.WriteString("message")
+		w.WriteString(a.Message)
+	}
+	if a.Data != nil {
+		w.WriteString("data")
+		if err := w.WriteValue(a.Data); err != nil {
+			return err
+		}
+	}
+	if a.Error != "" {
+		w.WriteString("error")
+		w.WriteString(a.Error)
+	}
+	return nil
+}
+
 // Success 成功响应
 func Success(data any) *APIResponse {
 	return &APIResponse{
@@ -83,16 +135,17 @@ func ErrorWithData(code int, message string, data any) *APIResponse {
 
 // 常用业务错误码
 const (
-	CodeSuccess           = 0     // 成功
-	CodeInvalidParams     = 10001 // 参数错误
-	CodeNotFound          = 10002 // 资源不存在
-	CodeUnauthorized      = 10003 // 未授权
-	CodeForbidden         = 10004 // 禁止访问
-	CodeInternalError     = 10005 // 内部错误
-	CodeDatabaseError     = 10006 // 数据库错误
-	CodeValidationError   = 10007 // 验证失败
-	CodeDuplicateError    = 10008 // 重复数据
-	CodeRateLimitExceeded = 10009 // 超过限流
+	CodeSuccess            = 0     // 成功
+	CodeInvalidParams      = 10001 // 参数错误
+	CodeNotFound           = 10002 // 资源不存在
+	CodeUnauthorized       = 10003 // 未授权
+	CodeForbidden          = 10004 // 禁止访问
+	CodeInternalError      = 10005 // 内部错误
+	CodeDatabaseError      = 10006 // 数据库错误
+	CodeValidationError    = 10007 // 验证失败
+	CodeDuplicateError     = 10008 // 重复数据
+	CodeRateLimitExceeded  = 10009 // 超过限流
+	CodePreconditionFailed = 10010 // 前置条件失败 (If-Match 不匹配)
 )
 
 // Response 辅助方法 - 成功响应
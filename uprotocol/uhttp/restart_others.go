@@ -0,0 +1,19 @@
+//go:build !unix
+
+package uhttp
+
+import "github.com/whosafe/uf/uerror"
+
+// ErrRestartUnsupported 当前平台不支持热重启
+var ErrRestartUnsupported = uerror.New("当前平台不支持热重启")
+
+// registerRestart 当前平台不支持热重启
+func registerRestart(s *Server) {}
+
+// unregisterRestart 当前平台不支持热重启
+func unregisterRestart(s *Server) {}
+
+// Restart 当前平台不支持热重启
+func Restart() error {
+	return ErrRestartUnsupported
+}
@@ -0,0 +1,225 @@
+//go:build unix
+
+package uhttp
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/whosafe/uf/uerror"
+	"github.com/whosafe/uf/ulogger"
+)
+
+// ErrRestartInProgress 热重启正在进行
+var ErrRestartInProgress = uerror.New("热重启正在进行")
+
+// restartReadyTimeout 等待子进程接管监听的最长时间
+var restartReadyTimeout = 30 * time.Second
+
+// restartCommand 获取重启使用的可执行文件和参数 (测试时可替换)
+var restartCommand = func() (string, []string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", nil, err
+	}
+	return path, os.Args[1:], nil
+}
+
+// restarter 热重启协调器
+// 进程内所有启用热重启的服务器共享一次重启:监听一并传递给子进程,子进程就绪后一并优雅关闭
+var restarter struct {
+	servers    map[*Server]struct{}
+	restarting bool
+	signalOnce sync.Once
+	mu         sync.Mutex
+}
+
+// registerRestart 登记服务器并安装信号处理
+func registerRestart(s *Server) {
+	restarter.mu.Lock()
+	if restarter.servers == nil {
+		restarter.servers = make(map[*Server]struct{})
+	}
+	restarter.servers[s] = struct{}{}
+	restarter.mu.Unlock()
+
+	restarter.signalOnce.Do(func() {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, syscall.SIGHUP, syscall.SIGUSR2)
+		go func() {
+			for sig := range ch {
+				ulogger.Info("收到热重启信号", "signal", sig.String())
+				if err := Restart(); err != nil {
+					ulogger.Error("热重启失败", "error", err)
+				}
+			}
+		}()
+	})
+}
+
+// unregisterRestart 注销服务器
+func unregisterRestart(s *Server) {
+	restarter.mu.Lock()
+	defer restarter.mu.Unlock()
+	delete(restarter.servers, s)
+}
+
+// Restart 热重启
+// 以相同参数启动新进程并传递所有启用热重启的服务器的监听,新进程接管全部监听后,
+// 当前进程停止接收新连接并等待进行中的请求完成 (WebSocket 连接以 1001 关闭,由客户端重连到新进程),随后各服务器的 Start 返回 nil;
+// 新进程启动失败时当前进程继续提供服务
+func Restart() error {
+	restarter.mu.Lock()
+	if restarter.restarting {
+		restarter.mu.Unlock()
+		return ErrRestartInProgress
+	}
+	restarter.restarting = true
+	servers := make([]*Server, 0, len(restarter.servers))
+	for s := range restarter.servers {
+		servers = append(servers, s)
+	}
+	restarter.mu.Unlock()
+
+	if err := forkChild(servers); err != nil {
+		restarter.mu.Lock()
+		restarter.restarting = false
+		restarter.mu.Unlock()
+		return err
+	}
+
+	// 子进程已接管监听,当前进程的服务器并行优雅关闭
+	go func() {
+		var wg sync.WaitGroup
+		for _, s := range servers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.handoffShutdown()
+			}()
+		}
+		wg.Wait()
+
+		restarter.mu.Lock()
+		restarter.restarting = false
+		restarter.mu.Unlock()
+	}()
+	return nil
+}
+
+// forkChild 启动子进程并等待其接管监听
+func forkChild(servers []*Server) error {
+	if len(servers) == 0 {
+		return uerror.New("没有可传递的监听")
+	}
+
+	files := make([]*os.File, 0, len(servers)+1)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	for _, s := range servers {
+		s.mu.RLock()
+		listener := s.listener
+		s.mu.RUnlock()
+
+		f, err := listenerFile(listener)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return uerror.Wrap(err, "创建就绪通知管道失败")
+	}
+	defer readyR.Close()
+	files = append(files, readyW)
+
+	path, args, err := restartCommand()
+	if err != nil {
+		return uerror.Wrap(err, "获取可执行文件失败")
+	}
+
+	n := len(servers)
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(restartEnv(),
+		envRestartFDs+"="+strconv.Itoa(n),
+		envRestartReadyFD+"="+strconv.Itoa(listenFDsStart+n),
+	)
+
+	if err := cmd.Start(); err != nil {
+		return uerror.Wrap(err, "启动新进程失败")
+	}
+	ulogger.Info("新进程已启动,等待接管监听", "pid", cmd.Process.Pid)
+
+	// 关闭父进程持有的写端,子进程异常退出时读端会返回 EOF
+	readyW.Close()
+	files = files[:n]
+
+	ready := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		_, err := readyR.Read(buf)
+		ready <- err
+	}()
+
+	select {
+	case err := <-ready:
+		if err == nil {
+			ulogger.Info("新进程已接管监听", "pid", cmd.Process.Pid)
+			go cmd.Wait()
+			return nil
+		}
+		cmd.Process.Kill()
+		cmd.Wait()
+		return uerror.Wrap(err, "新进程未能接管监听")
+	case <-time.After(restartReadyTimeout):
+		cmd.Process.Kill()
+		cmd.Wait()
+		return uerror.New("等待新进程接管监听超时")
+	}
+}
+
+// listenerFile 获取监听的文件描述符副本
+// Unix 套接字关闭时不再删除文件,交由子进程继续使用
+func listenerFile(listener net.Listener) (*os.File, error) {
+	switch l := listener.(type) {
+	case *net.TCPListener:
+		return l.File()
+	case *net.UnixListener:
+		l.SetUnlinkOnClose(false)
+		return l.File()
+	default:
+		return nil, uerror.New("监听不支持传递文件描述符")
+	}
+}
+
+// restartEnv 当前环境变量,去除监听继承相关变量
+func restartEnv() []string {
+	env := os.Environ()
+	result := make([]string, 0, len(env))
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		switch name {
+		case envListenFDs, envListenPID, envListenFDNames, envRestartFDs, envRestartReadyFD:
+			continue
+		}
+		result = append(result, kv)
+	}
+	return result
+}
@@ -0,0 +1,216 @@
+package uhttp
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/whosafe/uf/uconv"
+	"github.com/whosafe/uf/uerror"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// Route 路由句柄,由 GET/POST 等注册方法返回
+type Route struct {
+	method      string
+	pattern     string
+	handler     unet.HandlerFunc      // 编译后的处理器 (已应用完整中间件链)
+	origin      unet.HandlerFunc      // 注册时传入的处理器
+	handlerName string                // 处理器名称 (为空时取 origin 的函数名)
+	group       *Group                // 所属分组
+	middlewares []unet.MiddlewareFunc // 路由级中间件
+	meta        map[string]any        // 路由元数据
+	direct      bool                  // 不应用中间件 (版本分发路由,由目标路由应用)
+	dispatch    *versionDispatch      // 版本分发路由的分发表
+	name        string
+	parts       []patternPart // 命名后解析的路由模式,用于生成 URL
+	router      *Router
+}
+
+// patternPart 路由模式片段
+type patternPart struct {
+	static     string           // 静态文本
+	param      string           // 参数名称 (非空时为参数片段)
+	constraint *paramConstraint // 参数约束
+	catchAll   bool             // 是否为通配符
+}
+
+// Name 为路由命名,用于 Server.URL / Request.URLFor 生成路径
+// 名称为空或重复时 panic
+func (r *Route) Name(name string) *Route {
+	if r.router.frozen {
+		panic(frozenMessage)
+	}
+	if name == "" {
+		panic("路由名称不能为空")
+	}
+	if existing, ok := r.router.names[name]; ok {
+		panic("路由名称重复: " + name + " 已用于 " + existing.method + " " + existing.pattern)
+	}
+	if r.name != "" {
+		delete(r.router.names, r.name)
+	}
+
+	r.name = name
+	r.parts = parsePattern(r.pattern)
+	r.router.names[name] = r
+	return r
+}
+
+// Use 追加路由级中间件 (用于 RPC、WS 等不接收中间件参数的注册方法)
+func (r *Route) Use(middleware ...unet.MiddlewareFunc) *Route {
+	if r.router.frozen {
+		panic(frozenMessage)
+	}
+	r.middlewares = append(r.middlewares, middleware...)
+	r.router.gen++
+	return r
+}
+
+// Set 设置路由元数据,如所需权限、限流等级、超时时间,中间件通过 req.Route().Get 读取
+func (r *Route) Set(key string, value any) *Route {
+	if r.router.frozen {
+		panic(frozenMessage)
+	}
+	if r.meta == nil {
+		r.meta = make(map[string]any)
+	}
+	r.meta[key] = value
+	return r
+}
+
+// Get 获取路由元数据
+func (r *Route) Get(key string) (any, bool) {
+	value, ok := r.meta[key]
+	return value, ok
+}
+
+// Method 请求方法 (Server.Handle 注册的路由为 "ANY")
+func (r *Route) Method() string {
+	return r.method
+}
+
+// Pattern 完整路由模式 (包含分组前缀)
+func (r *Route) Pattern() string {
+	return r.pattern
+}
+
+// parsePattern 将已通过校验的路由模式拆分为静态片段和参数片段
+func parsePattern(pattern string) []patternPart {
+	var parts []patternPart
+	for pattern != "" {
+		i := strings.IndexAny(pattern, ":*{")
+		if i < 0 {
+			parts = append(parts, patternPart{static: pattern})
+			break
+		}
+		if i > 0 {
+			parts = append(parts, patternPart{static: pattern[:i]})
+		}
+		pattern = pattern[i:]
+
+		var part patternPart
+		switch pattern[0] {
+		case ':':
+			end := strings.IndexByte(pattern, '/')
+			if end < 0 {
+				end = len(pattern)
+			}
+			part.param, pattern = pattern[1:end], pattern[end:]
+		case '*':
+			part.param, part.catchAll, pattern = pattern[1:], true, ""
+		case '{':
+			end := closingBrace(pattern)
+			spec := pattern[1:end]
+			pattern = pattern[end+1:]
+			if name, ok := strings.CutSuffix(spec, "..."); ok {
+				part.param, part.catchAll = name, true
+				break
+			}
+			name, expr, _ := strings.Cut(spec, ":")
+			part.param = name
+			if expr != "" {
+				part.constraint, _ = newParamConstraint(expr)
+			}
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// url 生成命名路由的路径
+func (r *Router) url(name string, pairs []any) (string, error) {
+	rt, ok := r.names[name]
+	if !ok {
+		return "", uerror.New("路由不存在: " + name)
+	}
+	if len(pairs)%2 != 0 {
+		return "", uerror.New("路由参数必须成对出现: " + name)
+	}
+
+	values := make(map[string]string, len(pairs)/2)
+	keys := make([]string, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return "", uerror.New("路由参数名称必须是字符串: " + name)
+		}
+		if _, dup := values[key]; !dup {
+			keys = append(keys, key)
+		}
+		values[key] = uconv.ToString(pairs[i+1])
+	}
+
+	var sb strings.Builder
+	used := make(map[string]bool, len(rt.parts))
+	for _, part := range rt.parts {
+		if part.param == "" {
+			sb.WriteString(part.static)
+			continue
+		}
+
+		value, ok := values[part.param]
+		if !ok || (value == "" && !part.catchAll) {
+			return "", uerror.New("缺少路由参数 " + part.param + ": " + name)
+		}
+		if part.constraint != nil && !part.constraint.match(value) {
+			return "", uerror.New("路由参数 " + part.param + " 不满足约束 " + part.constraint.expr + ": " + value)
+		}
+		used[part.param] = true
+
+		if part.catchAll {
+			segments := strings.Split(value, "/")
+			for i, seg := range segments {
+				segments[i] = url.PathEscape(seg)
+			}
+			sb.WriteString(strings.Join(segments, "/"))
+		} else {
+			sb.WriteString(url.PathEscape(value))
+		}
+	}
+
+	// 未用于路径的参数作为查询字符串
+	sep := byte('?')
+	for _, key := range keys {
+		if used[key] {
+			continue
+		}
+		sb.WriteByte(sep)
+		sb.WriteString(url.QueryEscape(key))
+		sb.WriteByte('=')
+		sb.WriteString(url.QueryEscape(values[key]))
+		sep = '&'
+	}
+	return sb.String(), nil
+}
+
+// URL 根据路由名称生成路径
+// pairs 为键值对:与路由参数同名的填入路径 (转义后),其余按顺序作为查询参数;
+// 路由不存在、缺少参数或参数不满足约束时返回错误
+func (s *Server) URL(name string, pairs ...any) (string, error) {
+	return s.router.url(name, pairs)
+}
+
+// URLFor 根据路由名称生成路径,参见 Server.URL
+func (r *Request) URLFor(name string, pairs ...any) (string, error) {
+	return r.server.URL(name, pairs...)
+}
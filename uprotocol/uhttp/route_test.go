@@ -0,0 +1,153 @@
+package uhttp
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// TestRouteURL 测试命名路由生成路径
+func TestRouteURL(t *testing.T) {
+	server := New()
+	api := server.Group("/api/v1")
+	api.GET("/users/:id", namedHandler("show")).Name("user.show")
+	api.GET("/users/{id:int}/posts/{slug}", namedHandler("post")).Name("user.post")
+	server.GET("/files/*path", namedHandler("file")).Name("file")
+	server.GET("/", namedHandler("home")).Name("home")
+
+	tests := []struct {
+		name  string
+		pairs []any
+		want  string
+	}{
+		{"user.show", []any{"id", 42}, "/api/v1/users/42"},
+		{"user.show", []any{"id", "a b/c"}, "/api/v1/users/a%20b%2Fc"},
+		{"user.show", []any{"id", 1, "tab", "posts", "q", "x&y"}, "/api/v1/users/1?tab=posts&q=x%26y"},
+		{"user.post", []any{"slug", "hello", "id", 7}, "/api/v1/users/7/posts/hello"},
+		{"file", []any{"path", "css/main app.css"}, "/files/css/main%20app.css"},
+		{"home", nil, "/"},
+	}
+	for _, tt := range tests {
+		got, err := server.URL(tt.name, tt.pairs...)
+		if err != nil || got != tt.want {
+			t.Errorf("URL(%s, %v) = %q, %v; want %q", tt.name, tt.pairs, got, err, tt.want)
+		}
+	}
+
+	errorTests := []struct {
+		name  string
+		pairs []any
+		want  string
+	}{
+		{"missing", nil, "路由不存在"},
+		{"user.show", nil, "缺少路由参数 id"},
+		{"user.show", []any{"id"}, "成对"},
+		{"user.post", []any{"id", "abc", "slug", "x"}, "不满足约束"},
+	}
+	for _, tt := range errorTests {
+		if _, err := server.URL(tt.name, tt.pairs...); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("URL(%s, %v) error = %v, want containing %q", tt.name, tt.pairs, err, tt.want)
+		}
+	}
+
+	// 请求中生成路径
+	server.GET("/go/:id", func(ctx *ucontext.Context, req unet.Request) error {
+		target, err := req.(*Request).URLFor("user.show", "id", req.(*Request).Param("id"))
+		if err != nil {
+			return err
+		}
+		return req.Response().Redirect(302, target)
+	})
+	if w := serveRoute(server, "GET", "/go/9"); w.Header().Get("Location") != "/api/v1/users/9" {
+		t.Errorf("Expected Location /api/v1/users/9, got %q", w.Header().Get("Location"))
+	}
+
+	// 名称重复时 panic
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for duplicate route name")
+		}
+	}()
+	server.POST("/users", namedHandler("create")).Name("user.show")
+}
+
+// listUsers 用于测试处理器名称
+func listUsers(ctx *ucontext.Context, req unet.Request) error {
+	return req.Response().String(200, "users")
+}
+
+// TestServerRoutes 测试路由表
+func TestServerRoutes(t *testing.T) {
+	server := New()
+	server.Use(MiddlewareTrace())
+	server.GET("/", namedHandler("home")).Name("home")
+	api := server.Group("/api")
+	api.Use(MiddlewareRecovery())
+	api.GET("/users", listUsers).Name("users")
+	api.POST("/users/{id:int}", namedHandler("update"))
+	server.DebugRoutes("/debug/routes")
+
+	routes := server.Routes()
+	if len(routes) != 4 {
+		t.Fatalf("Expected 4 routes, got %d", len(routes))
+	}
+
+	users := routes[1]
+	if users.Method != "GET" || users.Pattern != "/api/users" || users.Name != "users" || users.Group != "/api" {
+		t.Errorf("Unexpected route info: %+v", users)
+	}
+	if users.Handler != "uhttp.listUsers" {
+		t.Errorf("Expected handler uhttp.listUsers, got %q", users.Handler)
+	}
+	if got := strings.Join(users.Middlewares, ","); got != "uhttp.MiddlewareTrace,uhttp.MiddlewareRecovery" {
+		t.Errorf("Unexpected middlewares: %s", got)
+	}
+	if routes[0].Handler != "uhttp.namedHandler" || len(routes[0].Middlewares) != 1 || routes[0].Group != "" {
+		t.Errorf("Unexpected route info: %+v", routes[0])
+	}
+	if routes[2].Pattern != "/api/users/{id:int}" {
+		t.Errorf("Expected constrained pattern, got %q", routes[2].Pattern)
+	}
+
+	// /debug/routes 输出 JSON
+	w := serveRoute(server, "GET", "/debug/routes")
+	body := w.Body.String()
+	if w.Code != 200 || !strings.HasPrefix(body, "[{") ||
+		!strings.Contains(body, `"pattern":"/api/users/{id:int}"`) ||
+		!strings.Contains(body, `"middlewares":["uhttp.MiddlewareTrace","uhttp.MiddlewareRecovery"]`) {
+		t.Errorf("Unexpected debug routes response: %d %s", w.Code, body)
+	}
+}
+
+// TestPrintRoutes 测试 UF_ROUTES 模式下打印路由表而不监听
+func TestPrintRoutes(t *testing.T) {
+	t.Setenv(EnvRoutes, "1")
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	server := New()
+	server.GET("/ping", namedHandler("ping"))
+	startErr := server.Start("127.0.0.1:0")
+	w.Close()
+	os.Stdout = stdout
+
+	out, _ := io.ReadAll(r)
+	if startErr != nil {
+		t.Fatalf("Start returned error: %v", startErr)
+	}
+	line := string(out)
+	if !strings.HasPrefix(line, `{"server":"`) || !strings.Contains(line, `"address":"127.0.0.1:0"`) ||
+		!strings.Contains(line, `"pattern":"/ping"`) || !strings.HasSuffix(line, "}\n") {
+		t.Errorf("Unexpected route table output: %s", line)
+	}
+}
@@ -1,158 +1,469 @@
 package uhttp
 
 import (
+	"net/http"
+	"path"
+	"slices"
 	"strings"
 
 	"github.com/whosafe/uf/uprotocol/unet"
 )
 
+// methodAny 匹配任意方法的路由 (Server.Handle)
+const methodAny = "ANY"
+
 // Router 路由器
+// 所有方法共用一棵压缩前缀树,匹配优先级为 静态 > 有约束的参数 > 参数 > 通配符,
+// 高优先级分支无法匹配 (路径、约束或方法) 时回溯尝试低优先级分支
 type Router struct {
-	trees map[string]*node // 每个 HTTP 方法一棵树
+	root   *node
+	routes []*Route          // 按注册顺序的全部路由
+	names  map[string]*Route // 命名路由
+	gen    int               // 修改次数,冻结前据此判断是否需要重新编译
+	frozen bool              // 已冻结,不能再注册
 }
 
 // NewRouter 创建新的路由器
 func NewRouter() *Router {
 	return &Router{
-		trees: make(map[string]*node),
+		root:  &node{},
+		names: make(map[string]*Route),
 	}
 }
 
+// nodeKind 节点类型
+type nodeKind uint8
+
+const (
+	staticNode   nodeKind = iota // 静态路径片段
+	paramNode                    // :param 或 {param:约束},匹配一个路径段
+	catchAllNode                 // *param 或 {param...},匹配剩余全部路径
+)
+
+// node 路由树节点
+type node struct {
+	kind       nodeKind
+	prefix     string           // 静态节点的路径片段
+	param      string           // 参数名称
+	constraint *paramConstraint // 参数约束 (nil 表示任意非空路径段)
+	indices    []byte           // 静态子节点的首字节,与 children 一一对应
+	children   []*node          // 静态子节点
+	wilds      []*node          // 参数子节点,有约束的排在无约束的之前
+	catchAll   *node            // 通配符子节点
+	routes     []*Route         // 该节点上注册的各方法路由
+}
+
 // addRoute 添加路由
-func (r *Router) addRoute(method, path string, handler unet.HandlerFunc) {
-	if path == "" {
+// 路由重复、参数名冲突、约束无效或参数不在完整路径段上时 panic
+func (r *Router) addRoute(method, pattern string, handler unet.HandlerFunc) *Route {
+	if pattern == "" {
 		panic("path cannot be empty")
 	}
-	if path[0] != '/' {
+	if pattern[0] != '/' {
 		panic("path must begin with '/'")
 	}
 	if handler == nil {
 		panic("handler cannot be nil")
 	}
+	if r.frozen {
+		panic(frozenMessage)
+	}
 
-	root := r.trees[method]
-	if root == nil {
-		root = &node{path: "/"}
-		r.trees[method] = root
+	rt := &Route{
+		method:  method,
+		pattern: pattern,
+		handler: handler,
+		origin:  handler,
+		router:  r,
 	}
+	r.root.add(pattern, rt)
+	r.routes = append(r.routes, rt)
+	r.gen++
+	return rt
+}
 
-	root.addRoute(path, handler)
+// find 查找路由,参数追加到 params
+// 未匹配时 params 保持调用前的长度
+func (r *Router) find(method, path string, params *Params) *Route {
+	return r.root.match(path, method, params)
 }
 
-// getValue 获取路由处理器和参数
-func (r *Router) getValue(method, path string) (unet.HandlerFunc, Params) {
-	root := r.trees[method]
-	if root == nil {
-		// 尝试 ANY 方法
-		root = r.trees["ANY"]
-		if root == nil {
-			return nil, nil
+// allowed 获取能够匹配路径的所有方法 (用于 405 和 OPTIONS)
+func (r *Router) allowed(path string) []string {
+	var methods []string
+	r.root.collect(path, func(n *node) {
+		for _, rt := range n.routes {
+			if !slices.Contains(methods, rt.method) {
+				methods = append(methods, rt.method)
+			}
 		}
+	})
+	if len(methods) == 0 {
+		return nil
+	}
+
+	if slices.Contains(methods, http.MethodGet) && !slices.Contains(methods, http.MethodHead) {
+		methods = append(methods, http.MethodHead)
+	}
+	if !slices.Contains(methods, http.MethodOptions) {
+		methods = append(methods, http.MethodOptions)
 	}
+	slices.Sort(methods)
+	return methods
+}
 
-	handler, params := root.getValue(path)
-	return handler, params
+// findFold 忽略大小写查找路由,返回按注册时大小写修正后的路径
+func (r *Router) findFold(method, path string) (string, bool) {
+	buf, ok := r.root.matchFold(path, method, make([]byte, 0, len(path)))
+	return string(buf), ok
 }
 
-// node 路由树节点
-type node struct {
-	path     string           // 节点路径
-	handler  unet.HandlerFunc // 处理器
-	children []*node          // 子节点
-	isWild   bool             // 是否是通配符节点 (:param 或 *path)
-	param    string           // 参数名称
-}
-
-// addRoute 添加路由到节点
-func (n *node) addRoute(path string, handler unet.HandlerFunc) {
-	// 移除前导斜杠
-	if path == "/" {
-		n.handler = handler
+// add 将 path 添加到节点下 (节点自身的路径已消耗)
+func (n *node) add(path string, rt *Route) {
+	if path == "" {
+		n.setRoute(rt)
 		return
 	}
 
-	path = strings.TrimPrefix(path, "/")
-	parts := strings.Split(path, "/")
+	switch path[0] {
+	case ':':
+		name, rest := path[1:], ""
+		if i := strings.IndexByte(name, '/'); i >= 0 {
+			name, rest = name[:i], name[i:]
+		}
+		n.addParam(name, "", rest, rt)
 
-	current := n
-	for i, part := range parts {
-		child := current.matchChild(part)
-		if child == nil {
-			// 创建新节点
-			child = &node{
-				path: part,
-			}
+	case '*':
+		n.addCatchAll(path[1:], "", rt)
+
+	case '{':
+		end := closingBrace(path)
+		if end < 0 {
+			panic("路由参数缺少 '}': " + rt.pattern)
+		}
+		spec, rest := path[1:end], path[end+1:]
+		if name, ok := strings.CutSuffix(spec, "..."); ok {
+			n.addCatchAll(name, rest, rt)
+			return
+		}
+		if rest != "" && rest[0] != '/' {
+			panic("路由参数必须占据完整的路径段: " + rt.pattern)
+		}
+		name, expr, _ := strings.Cut(spec, ":")
+		n.addParam(name, expr, rest, rt)
+
+	default:
+		end := strings.IndexAny(path, ":*{")
+		if end < 0 {
+			end = len(path)
+		} else if path[end-1] != '/' {
+			panic("路由参数必须占据完整的路径段: " + rt.pattern)
+		}
+		n.addStatic(path[:end], path[end:], rt)
+	}
+}
+
+// addParam 添加参数子节点
+// 约束相同的参数节点共用,名称必须一致;有约束的节点排在无约束的之前
+func (n *node) addParam(name, expr, rest string, rt *Route) {
+	validateParamName(name, rt.pattern)
+
+	for _, w := range n.wilds {
+		if w.constraintExpr() != expr {
+			continue
+		}
+		if w.param != name {
+			panic("路由参数冲突: " + rt.pattern + " 中的 " + name + " 与已注册的 " + w.param + " 位于同一位置且约束相同")
+		}
+		w.add(rest, rt)
+		return
+	}
+
+	w := &node{kind: paramNode, param: name}
+	if expr != "" {
+		c, err := newParamConstraint(expr)
+		if err != nil {
+			panic("路由参数约束无效: " + rt.pattern + ": " + err.Error())
+		}
+		w.constraint = c
+	}
+
+	// 无约束的节点始终位于最后
+	if expr != "" && len(n.wilds) > 0 && n.wilds[len(n.wilds)-1].constraint == nil {
+		n.wilds = slices.Insert(n.wilds, len(n.wilds)-1, w)
+	} else {
+		n.wilds = append(n.wilds, w)
+	}
+	w.add(rest, rt)
+}
+
+// addCatchAll 添加通配符子节点
+func (n *node) addCatchAll(name, rest string, rt *Route) {
+	if rest != "" || strings.IndexByte(name, '/') >= 0 {
+		panic("通配符必须位于路径末尾: " + rt.pattern)
+	}
+	validateParamName(name, rt.pattern)
+
+	if n.catchAll == nil {
+		n.catchAll = &node{kind: catchAllNode, param: name}
+	} else if n.catchAll.param != name {
+		panic("路由通配符冲突: " + rt.pattern + " 中的 " + name + " 与已注册的 " + n.catchAll.param + " 位于同一位置")
+	}
+	n.catchAll.setRoute(rt)
+}
+
+// constraintExpr 参数约束表达式,无约束时为空
+func (n *node) constraintExpr() string {
+	if n.constraint == nil {
+		return ""
+	}
+	return n.constraint.expr
+}
+
+// matchSegment 参数节点是否接受该路径段
+func (n *node) matchSegment(segment string) bool {
+	return n.constraint == nil || n.constraint.match(segment)
+}
 
-			// 检查是否是参数节点
-			if strings.HasPrefix(part, ":") {
-				child.isWild = true
-				child.param = part[1:]
-			} else if strings.HasPrefix(part, "*") {
-				child.isWild = true
-				child.param = part[1:]
+// addStatic 添加静态片段,必要时拆分已有节点
+func (n *node) addStatic(static, rest string, rt *Route) {
+	for i, c := range n.indices {
+		if c != static[0] {
+			continue
+		}
+
+		child := n.children[i]
+		l := commonPrefix(child.prefix, static)
+		if l < len(child.prefix) {
+			split := &node{
+				prefix:   child.prefix[l:],
+				indices:  child.indices,
+				children: child.children,
+				wilds:    child.wilds,
+				catchAll: child.catchAll,
+				routes:   child.routes,
 			}
+			*child = node{
+				prefix:   child.prefix[:l],
+				indices:  []byte{split.prefix[0]},
+				children: []*node{split},
+			}
+		}
 
-			current.children = append(current.children, child)
+		if l == len(static) {
+			child.add(rest, rt)
+		} else {
+			child.addStatic(static[l:], rest, rt)
 		}
+		return
+	}
 
-		current = child
+	child := &node{prefix: static}
+	n.indices = append(n.indices, static[0])
+	n.children = append(n.children, child)
+	child.add(rest, rt)
+}
 
-		// 最后一个部分,设置处理器
-		if i == len(parts)-1 {
-			current.handler = handler
+// setRoute 在节点上设置路由
+func (n *node) setRoute(rt *Route) {
+	for _, existing := range n.routes {
+		if existing.method == rt.method {
+			panic("路由重复: " + rt.method + " " + rt.pattern + " 与已注册的 " + existing.pattern + " 冲突")
 		}
 	}
+	n.routes = append(n.routes, rt)
 }
 
-// getValue 获取处理器和参数
-func (n *node) getValue(path string) (unet.HandlerFunc, Params) {
-	// 根路径
-	if path == "/" {
-		return n.handler, nil
+// route 获取节点上与方法匹配的路由
+// 优先精确匹配,其次 ANY,HEAD 请求最后回退到 GET
+func (n *node) route(method string) *Route {
+	var anyRoute, getRoute *Route
+	for _, rt := range n.routes {
+		switch rt.method {
+		case method:
+			return rt
+		case methodAny:
+			anyRoute = rt
+		case http.MethodGet:
+			getRoute = rt
+		}
+	}
+	if anyRoute != nil {
+		return anyRoute
+	}
+	if method == http.MethodHead {
+		return getRoute
 	}
+	return nil
+}
 
-	path = strings.TrimPrefix(path, "/")
-	parts := strings.Split(path, "/")
-	params := make(Params)
+// match 匹配剩余路径 (节点自身的路径已消耗)
+func (n *node) match(path, method string, params *Params) *Route {
+	if path == "" {
+		if rt := n.route(method); rt != nil {
+			return rt
+		}
+	} else {
+		// 静态子节点
+		c := path[0]
+		for i, idx := range n.indices {
+			if idx == c {
+				child := n.children[i]
+				if len(path) >= len(child.prefix) && path[:len(child.prefix)] == child.prefix {
+					if rt := child.match(path[len(child.prefix):], method, params); rt != nil {
+						return rt
+					}
+				}
+				break
+			}
+		}
 
-	current := n
-	for i, part := range parts {
-		child := current.matchChild(part)
-		if child == nil {
-			return nil, nil
+		// 参数子节点
+		if len(n.wilds) > 0 {
+			end := strings.IndexByte(path, '/')
+			if end < 0 {
+				end = len(path)
+			}
+			if end > 0 {
+				segment := path[:end]
+				for _, w := range n.wilds {
+					if !w.matchSegment(segment) {
+						continue
+					}
+					*params = append(*params, Param{Key: w.param, Value: segment})
+					if rt := w.match(path[end:], method, params); rt != nil {
+						return rt
+					}
+					*params = (*params)[:len(*params)-1]
+				}
+			}
 		}
+	}
 
-		// 如果是参数节点,保存参数值
-		if child.isWild && child.param != "" {
-			params[child.param] = part
+	// 通配符子节点 (可以匹配空路径)
+	if ca := n.catchAll; ca != nil {
+		if rt := ca.route(method); rt != nil {
+			*params = append(*params, Param{Key: ca.param, Value: path})
+			return rt
 		}
+	}
+	return nil
+}
 
-		current = child
+// collect 遍历所有能够匹配路径的节点 (不区分方法)
+func (n *node) collect(path string, fn func(*node)) {
+	if path == "" {
+		if len(n.routes) > 0 {
+			fn(n)
+		}
+	} else {
+		c := path[0]
+		for i, idx := range n.indices {
+			if idx == c {
+				child := n.children[i]
+				if strings.HasPrefix(path, child.prefix) {
+					child.collect(path[len(child.prefix):], fn)
+				}
+				break
+			}
+		}
 
-		// 最后一个部分,返回处理器
-		if i == len(parts)-1 {
-			return current.handler, params
+		if len(n.wilds) > 0 {
+			end := strings.IndexByte(path, '/')
+			if end < 0 {
+				end = len(path)
+			}
+			if end > 0 {
+				for _, w := range n.wilds {
+					if w.matchSegment(path[:end]) {
+						w.collect(path[end:], fn)
+					}
+				}
+			}
 		}
 	}
 
-	return nil, nil
+	if n.catchAll != nil && len(n.catchAll.routes) > 0 {
+		fn(n.catchAll)
+	}
 }
 
-// matchChild 匹配子节点
-func (n *node) matchChild(part string) *node {
-	// 精确匹配
-	for _, child := range n.children {
-		if child.path == part {
-			return child
+// matchFold 忽略大小写匹配,将按注册大小写修正后的路径追加到 buf
+func (n *node) matchFold(path, method string, buf []byte) ([]byte, bool) {
+	if path == "" {
+		if n.route(method) != nil {
+			return buf, true
+		}
+	} else {
+		for _, child := range n.children {
+			if len(path) >= len(child.prefix) && strings.EqualFold(path[:len(child.prefix)], child.prefix) {
+				if out, ok := child.matchFold(path[len(child.prefix):], method, append(buf, child.prefix...)); ok {
+					return out, true
+				}
+			}
+		}
+
+		if len(n.wilds) > 0 {
+			end := strings.IndexByte(path, '/')
+			if end < 0 {
+				end = len(path)
+			}
+			if end > 0 {
+				for _, w := range n.wilds {
+					if !w.matchSegment(path[:end]) {
+						continue
+					}
+					if out, ok := w.matchFold(path[end:], method, append(buf, path[:end]...)); ok {
+						return out, true
+					}
+				}
+			}
 		}
 	}
 
-	// 通配符匹配
-	for _, child := range n.children {
-		if child.isWild {
-			return child
+	if ca := n.catchAll; ca != nil && ca.route(method) != nil {
+		return append(buf, path...), true
+	}
+	return buf, false
+}
+
+// validateParamName 校验参数名称
+func validateParamName(name, pattern string) {
+	if name == "" {
+		panic("路由参数名称不能为空: " + pattern)
+	}
+	if strings.ContainsAny(name, ":*{}") {
+		panic("路由参数名称不能包含 ':'、'*'、'{' 或 '}': " + pattern)
+	}
+}
+
+// commonPrefix 公共前缀长度
+func commonPrefix(a, b string) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
 		}
 	}
+	return n
+}
+
+// cleanPath 规范化路径:合并重复的 '/',处理 '.' 和 '..',保留结尾的 '/'
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	cleaned := path.Clean("/" + p)
+	if p[len(p)-1] == '/' && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
 
-	return nil
+// toggleTrailingSlash 添加或去掉结尾的 '/'
+func toggleTrailingSlash(p string) string {
+	if strings.HasSuffix(p, "/") {
+		return p[:len(p)-1]
+	}
+	return p + "/"
 }
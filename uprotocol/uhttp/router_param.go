@@ -0,0 +1,195 @@
+package uhttp
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// paramConstraint 路径参数约束
+type paramConstraint struct {
+	expr  string            // 约束表达式 (类型名称或正则)
+	match func(string) bool // 校验路径段
+}
+
+// paramTypes 内置及注册的参数类型
+var paramTypes = struct {
+	types map[string]func(string) bool
+	mu    sync.RWMutex
+}{
+	types: map[string]func(string) bool{
+		"int":   isIntParam,
+		"uint":  isUintParam,
+		"uuid":  isUUIDParam,
+		"alpha": isAlphaParam,
+		"alnum": isAlnumParam,
+	},
+}
+
+// RegisterParamType 注册路径参数类型,注册后可在路由中使用 {name:type}
+// 需要在注册路由之前调用;类型名称不能与正则表达式混淆 (只使用字母和数字)
+func RegisterParamType(name string, match func(string) bool) {
+	paramTypes.mu.Lock()
+	defer paramTypes.mu.Unlock()
+	paramTypes.types[name] = match
+}
+
+// newParamConstraint 解析约束表达式:已注册的类型名称,否则按正则处理 (完整匹配)
+func newParamConstraint(expr string) (*paramConstraint, error) {
+	paramTypes.mu.RLock()
+	match, ok := paramTypes.types[expr]
+	paramTypes.mu.RUnlock()
+	if ok {
+		return &paramConstraint{expr: expr, match: match}, nil
+	}
+
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, err
+	}
+	return &paramConstraint{expr: expr, match: re.MatchString}, nil
+}
+
+// closingBrace 查找与开头 '{' 匹配的 '}',支持正则中嵌套的 {m,n}
+func closingBrace(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// isIntParam 可选负号加数字
+func isIntParam(s string) bool {
+	if s != "" && s[0] == '-' {
+		s = s[1:]
+	}
+	return isUintParam(s)
+}
+
+// isUintParam 非空数字
+func isUintParam(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isUUIDParam 8-4-4-4-12 格式的 UUID (不区分大小写)
+func isUUIDParam(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			if !isHex(s[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isAlphaParam 非空字母
+func isAlphaParam(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isLetter(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isAlnumParam 非空字母或数字
+func isAlnumParam(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isLetter(s[i]) && (s[i] < '0' || s[i] > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// ParamError 路径参数缺失或格式错误
+// 处理器直接返回该错误时服务器响应 400
+type ParamError struct {
+	Name  string // 参数名称
+	Value string // 原始值
+	Type  string // 期望类型
+	Err   error  // 解析错误
+}
+
+// Error 实现 error 接口
+func (e *ParamError) Error() string {
+	if e.Value == "" {
+		return "缺少路径参数: " + e.Name
+	}
+	return "路径参数 " + e.Name + " 不是有效的 " + e.Type + ": " + e.Value
+}
+
+// Unwrap 返回解析错误
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+// ParamInt 获取 int 类型的路径参数
+func (r *Request) ParamInt(key string) (int, error) {
+	value := r.params.Get(key)
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, &ParamError{Name: key, Value: value, Type: "int", Err: err}
+	}
+	return n, nil
+}
+
+// ParamInt64 获取 int64 类型的路径参数
+func (r *Request) ParamInt64(key string) (int64, error) {
+	value := r.params.Get(key)
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, &ParamError{Name: key, Value: value, Type: "int64", Err: err}
+	}
+	return n, nil
+}
+
+// ParamUUID 获取 UUID 类型的路径参数,返回小写的标准格式
+func (r *Request) ParamUUID(key string) (string, error) {
+	value := r.params.Get(key)
+	if !isUUIDParam(value) {
+		return "", &ParamError{Name: key, Value: value, Type: "uuid"}
+	}
+	return strings.ToLower(value), nil
+}
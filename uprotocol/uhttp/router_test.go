@@ -2,6 +2,8 @@ package uhttp
 
 import (
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/whosafe/uf/ucontext"
@@ -106,8 +108,11 @@ func TestRouterMethodNotAllowed(t *testing.T) {
 	w := httptest.NewRecorder()
 	server.ServeHTTP(w, req)
 
-	if w.Code != 404 {
-		t.Errorf("Expected status 404, got %d", w.Code)
+	if w.Code != 405 {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS" {
+		t.Errorf("Expected Allow 'GET, HEAD, OPTIONS', got '%s'", allow)
 	}
 }
 
@@ -144,3 +149,288 @@ func TestRouterStaticPriority(t *testing.T) {
 		t.Error("Dynamic route should match")
 	}
 }
+
+// serveRoute 发起请求并返回响应
+func serveRoute(server *Server, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+// namedHandler 返回处理器名称和全部参数
+func namedHandler(name string) unet.HandlerFunc {
+	return func(ctx *ucontext.Context, req unet.Request) error {
+		var sb strings.Builder
+		sb.WriteString(name)
+		for _, p := range req.(*Request).params {
+			sb.WriteString(" " + p.Key + "=" + p.Value)
+		}
+		return req.Response().String(200, sb.String())
+	}
+}
+
+// TestRouterBacktracking 测试优先级与回溯
+func TestRouterBacktracking(t *testing.T) {
+	server := New()
+	server.GET("/users/new", namedHandler("new"))
+	server.GET("/users/:id/edit", namedHandler("edit"))
+	server.GET("/users/:id", namedHandler("show"))
+	server.POST("/files/upload", namedHandler("upload"))
+	server.GET("/files/:name", namedHandler("file"))
+	server.GET("/files/*path", namedHandler("catchall"))
+	server.GET("/src/*path", namedHandler("src"))
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"/users/new", "new"},
+		{"/users/new/edit", "edit id=new"},
+		{"/users/42", "show id=42"},
+		{"/users/42/edit", "edit id=42"},
+		{"/files/upload", "file name=upload"}, // 静态节点只有 POST,回溯到参数
+		{"/files/a.txt", "file name=a.txt"},
+		{"/files/a/b/c", "catchall path=a/b/c"},
+		{"/src/", "src path="},
+	}
+
+	for _, tt := range tests {
+		w := serveRoute(server, "GET", tt.target)
+		if w.Code != 200 || w.Body.String() != tt.want {
+			t.Errorf("GET %s = %d %q, want %q", tt.target, w.Code, w.Body.String(), tt.want)
+		}
+	}
+}
+
+// TestRouterConflicts 测试注册时的冲突检测
+func TestRouterConflicts(t *testing.T) {
+	tests := []struct {
+		first, second string
+	}{
+		{"/users/:id", "/users/:id"},
+		{"/users/:id", "/users/:name/posts"},
+		{"/files/*path", "/files/*name"},
+		{"/files/*path/x", ""},
+		{"/users/x:id", ""},
+		{"/users/:", ""},
+	}
+
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected panic registering %q after %q", tt.second, tt.first)
+				}
+			}()
+			router := NewRouter()
+			router.addRoute("GET", tt.first, namedHandler("a"))
+			if tt.second != "" {
+				router.addRoute("GET", tt.second, namedHandler("b"))
+			}
+		}()
+	}
+
+	// 不同方法相同路径不冲突
+	router := NewRouter()
+	router.addRoute("GET", "/users/:id", namedHandler("a"))
+	router.addRoute("PUT", "/users/:id", namedHandler("b"))
+}
+
+// TestRouterAutoHeadOptions 测试自动 HEAD 和 OPTIONS
+func TestRouterAutoHeadOptions(t *testing.T) {
+	server := New()
+	server.GET("/users", namedHandler("list"))
+	server.POST("/users", namedHandler("create"))
+
+	if w := serveRoute(server, "HEAD", "/users"); w.Code != 200 {
+		t.Errorf("Expected HEAD to use GET handler, got %d", w.Code)
+	}
+
+	w := serveRoute(server, "OPTIONS", "/users")
+	if w.Code != 204 {
+		t.Errorf("Expected OPTIONS status 204, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS, POST" {
+		t.Errorf("Expected Allow 'GET, HEAD, OPTIONS, POST', got '%s'", allow)
+	}
+
+	if w := serveRoute(server, "OPTIONS", "/missing"); w.Code != 404 {
+		t.Errorf("Expected 404 for OPTIONS on missing path, got %d", w.Code)
+	}
+}
+
+// TestRouterRedirects 测试结尾斜杠和路径修正重定向
+func TestRouterRedirects(t *testing.T) {
+	server := New()
+	server.GET("/users", namedHandler("list"))
+	server.GET("/docs/", namedHandler("docs"))
+	server.POST("/Orders/:id", namedHandler("order"))
+
+	tests := []struct {
+		method, target string
+		code           int
+		location       string
+	}{
+		{"GET", "/users/", 301, "/users"},
+		{"GET", "/docs", 301, "/docs/"},
+		{"GET", "/USERS?page=2", 301, "/users?page=2"},
+		{"GET", "//users/../users", 301, "/users"},
+		{"POST", "/orders/Abc", 308, "/Orders/Abc"},
+	}
+
+	for _, tt := range tests {
+		w := serveRoute(server, tt.method, tt.target)
+		if w.Code != tt.code || w.Header().Get("Location") != tt.location {
+			t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.target, w.Code, w.Header().Get("Location"), tt.code, tt.location)
+		}
+	}
+
+	cfg := DefaultConfig()
+	cfg.Router = &RouterConfig{}
+	strict := NewWithConfig(cfg)
+	strict.GET("/users", namedHandler("list"))
+	if w := serveRoute(strict, "GET", "/users/"); w.Code != 404 {
+		t.Errorf("Expected 404 with redirects disabled, got %d", w.Code)
+	}
+	if w := serveRoute(strict, "POST", "/users"); w.Code != 404 {
+		t.Errorf("Expected 404 with 405 handling disabled, got %d", w.Code)
+	}
+}
+
+// TestRouterNotFoundHandler 测试自定义 404 处理器经过全局中间件
+func TestRouterNotFoundHandler(t *testing.T) {
+	server := New()
+	server.Use(func(next unet.HandlerFunc) unet.HandlerFunc {
+		return func(ctx *ucontext.Context, req unet.Request) error {
+			req.Response().SetHeader("X-Middleware", "1")
+			return next(ctx, req)
+		}
+	})
+	server.NotFound(func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(404, "custom")
+	})
+
+	w := serveRoute(server, "GET", "/missing")
+	if w.Code != 404 || w.Body.String() != "custom" || w.Header().Get("X-Middleware") != "1" {
+		t.Errorf("Unexpected response %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+}
+
+// TestRouterLookupAllocs 测试路由查找不分配内存
+func TestRouterLookupAllocs(t *testing.T) {
+	router := NewRouter()
+	router.addRoute("GET", "/api/v1/users/:id/posts/:postId", namedHandler("post"))
+	router.addRoute("GET", "/static/*filepath", namedHandler("static"))
+
+	params := make(Params, 0, 8)
+	allocs := testing.AllocsPerRun(100, func() {
+		params = params[:0]
+		if router.find("GET", "/api/v1/users/42/posts/7", &params) == nil {
+			t.Fatal("route not found")
+		}
+		params = params[:0]
+		router.find("GET", "/static/css/app.css", &params)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected 0 allocations, got %v", allocs)
+	}
+}
+
+// BenchmarkRouterLookup 路由查找基准测试
+func BenchmarkRouterLookup(b *testing.B) {
+	router := NewRouter()
+	for _, p := range []string{"/", "/users", "/users/new", "/users/:id", "/users/:id/edit", "/users/:id/posts/:postId", "/static/*filepath"} {
+		router.addRoute("GET", p, namedHandler(p))
+	}
+
+	params := make(Params, 0, 8)
+	b.ReportAllocs()
+	for b.Loop() {
+		params = params[:0]
+		router.find("GET", "/users/42/posts/7", &params)
+	}
+}
+
+// TestRouterTypedParams 测试带约束的参数与回退
+func TestRouterTypedParams(t *testing.T) {
+	server := New()
+	server.GET("/users/{id:int}", namedHandler("byID"))
+	server.GET("/users/{name}", namedHandler("byName"))
+	server.GET("/users/{uid:uuid}/avatar", namedHandler("avatar"))
+	server.GET("/posts/{slug:[a-z0-9-]+}", namedHandler("post"))
+	server.GET("/codes/{code:[0-9]{3}}", namedHandler("code"))
+	server.GET("/files/{path...}", namedHandler("file"))
+
+	tests := []struct {
+		target string
+		code   int
+		want   string
+	}{
+		{"/users/42", 200, "byID id=42"},
+		{"/users/-7", 200, "byID id=-7"},
+		{"/users/alice", 200, "byName name=alice"},
+		{"/users/6F9619FF-8B86-D011-B42D-00C04FC964FF/avatar", 200, "avatar uid=6F9619FF-8B86-D011-B42D-00C04FC964FF"},
+		{"/users/alice/avatar", 404, ""},
+		{"/posts/hello-world-2", 200, "post slug=hello-world-2"},
+		{"/posts/Hello", 404, ""},
+		{"/codes/404", 200, "code code=404"},
+		{"/codes/4040", 404, ""},
+		{"/files/a/b.txt", 200, "file path=a/b.txt"},
+	}
+
+	for _, tt := range tests {
+		w := serveRoute(server, "GET", tt.target)
+		if w.Code != tt.code || (tt.code == 200 && w.Body.String() != tt.want) {
+			t.Errorf("GET %s = %d %q, want %d %q", tt.target, w.Code, w.Body.String(), tt.code, tt.want)
+		}
+	}
+
+	// 约束相同但名称不同时冲突,约束无效时 panic
+	for _, pattern := range []string{"/users/{uid:int}", "/bad/{id:[0-9}", "/bad/{id:int}x", "/bad/{rest...}/x"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected panic registering %s", pattern)
+				}
+			}()
+			server.GET(pattern, namedHandler("bad"))
+		}()
+	}
+}
+
+// TestRequestTypedParams 测试类型化参数读取与 400 响应
+func TestRequestTypedParams(t *testing.T) {
+	server := New()
+	server.GET("/orders/:id", func(ctx *ucontext.Context, req unet.Request) error {
+		id, err := req.(*Request).ParamInt64("id")
+		if err != nil {
+			return err
+		}
+		return req.Response().String(200, strconv.FormatInt(id+1, 10))
+	})
+	server.GET("/items/:id", func(ctx *ucontext.Context, req unet.Request) error {
+		id, err := req.(*Request).ParamUUID("id")
+		if err != nil {
+			return err
+		}
+		return req.Response().String(200, id)
+	})
+
+	if w := serveRoute(server, "GET", "/orders/9000000000"); w.Body.String() != "9000000001" {
+		t.Errorf("Expected 9000000001, got %q", w.Body.String())
+	}
+	if w := serveRoute(server, "GET", "/orders/abc"); w.Code != 400 {
+		t.Errorf("Expected status 400 for invalid int, got %d", w.Code)
+	}
+	if w := serveRoute(server, "GET", "/items/6F9619FF-8B86-D011-B42D-00C04FC964FF"); w.Body.String() != "6f9619ff-8b86-d011-b42d-00c04fc964ff" {
+		t.Errorf("Expected lowercase uuid, got %q", w.Body.String())
+	}
+	if w := serveRoute(server, "GET", "/items/42"); w.Code != 400 {
+		t.Errorf("Expected status 400 for invalid uuid, got %d", w.Code)
+	}
+
+	req := &Request{params: Params{{Key: "id", Value: "x"}}}
+	if _, err := req.ParamInt("missing"); err == nil || !strings.Contains(err.Error(), "缺少路径参数") {
+		t.Errorf("Expected missing param error, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package uhttp
+
+import (
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/umarshal"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// EnvRoutes 设置该环境变量后,Start/Serve 不再监听端口,
+// 而是向标准输出打印一行路由表 JSON 并返回 nil (供 uf routes 命令使用)
+const EnvRoutes = "UF_ROUTES"
+
+// RouteInfo 路由描述
+type RouteInfo struct {
+	Method      string   // 请求方法 (Server.Handle 注册的路由为 "ANY")
+	Pattern     string   // 完整路由模式
+	Name        string   // 路由名称
+	Handler     string   // 处理器函数名称
+	Middlewares []string // 中间件链 (全局中间件在前,分组中间件在后)
+	Group       string   // 分组前缀
+	Host        string   // 虚拟主机模式 (多个模式以 "," 分隔,服务器自身的路由为空)
+}
+
+// Marshal 实现 umarshal.IMarshaler 接口
+func (ri RouteInfo) Marshal(w *umarshal.Writer) error {
+	w.WriteObjectStart()
+	w.WriteObjectField("method")
+	w.WriteString(ri.Method)
+	w.WriteComma()
+	w.WriteObjectField("pattern")
+	w.WriteString(ri.Pattern)
+	w.WriteComma()
+	w.WriteObjectField("name")
+	w.WriteString(ri.Name)
+	w.WriteComma()
+	w.WriteObjectField("handler")
+	w.WriteString(ri.Handler)
+	w.WriteComma()
+	w.WriteObjectField("middlewares")
+	w.WriteArrayStart()
+	for i, name := range ri.Middlewares {
+		if i > 0 {
+			w.WriteComma()
+		}
+		w.WriteString(name)
+	}
+	w.WriteArrayEnd()
+	w.WriteComma()
+	w.WriteObjectField("group")
+	w.WriteString(ri.Group)
+	w.WriteComma()
+	w.WriteObjectField("host")
+	w.WriteString(ri.Host)
+	w.WriteObjectEnd()
+	return nil
+}
+
+// routeList 路由列表
+type routeList []RouteInfo
+
+// Marshal 实现 umarshal.IMarshaler 接口
+func (l routeList) Marshal(w *umarshal.Writer) error {
+	w.WriteArrayStart()
+	for i, ri := range l {
+		if i > 0 {
+			w.WriteComma()
+		}
+		if err := ri.Marshal(w); err != nil {
+			return err
+		}
+	}
+	w.WriteArrayEnd()
+	return nil
+}
+
+// routeTable uf routes 输出的路由表
+type routeTable struct {
+	server  string
+	address string
+	routes  routeList
+}
+
+// Marshal 实现 umarshal.IMarshaler 接口
+func (t *routeTable) Marshal(w *umarshal.Writer) error {
+	w.WriteObjectStart()
+	w.WriteObjectField("server")
+	w.WriteString(t.server)
+	w.WriteComma()
+	w.WriteObjectField("address")
+	w.WriteString(t.address)
+	w.WriteComma()
+	w.WriteObjectField("routes")
+	if err := t.routes.Marshal(w); err != nil {
+		return err
+	}
+	w.WriteObjectEnd()
+	return nil
+}
+
+// Routes 按注册顺序返回已注册的路由,服务器自身的路由在前,虚拟主机的路由按主机创建顺序在后
+func (s *Server) Routes() []RouteInfo {
+	s.mu.RLock()
+	global := append([]unet.MiddlewareFunc(nil), s.middlewares...)
+	s.mu.RUnlock()
+
+	routes := appendRoutes(nil, s.router, "", global)
+	for _, h := range s.hosts {
+		middlewares := append(append([]unet.MiddlewareFunc(nil), global...), h.middlewares...)
+		routes = appendRoutes(routes, h.router, strings.Join(h.patterns, ","), middlewares)
+	}
+	return routes
+}
+
+// appendRoutes 追加路由器中的路由描述
+func appendRoutes(routes []RouteInfo, router *Router, host string, outer []unet.MiddlewareFunc) []RouteInfo {
+	for _, rt := range router.routes {
+		var chain []unet.MiddlewareFunc
+		if !rt.direct {
+			chain = rt.chain(outer)
+		}
+		middlewares := make([]string, 0, len(chain))
+		for _, mw := range chain {
+			middlewares = append(middlewares, funcName(mw))
+		}
+
+		handler := rt.handlerName
+		if handler == "" {
+			handler = funcName(rt.origin)
+		}
+		routes = append(routes, RouteInfo{
+			Method:      rt.method,
+			Pattern:     rt.pattern,
+			Name:        rt.name,
+			Handler:     handler,
+			Middlewares: middlewares,
+			Group:       groupPrefix(rt.group),
+			Host:        host,
+		})
+	}
+	return routes
+}
+
+// groupPrefix 分组前缀,不属于分组时为空
+func groupPrefix(g *Group) string {
+	if g == nil {
+		return ""
+	}
+	return g.prefix
+}
+
+// DebugRoutes 注册返回路由表 JSON 的 GET 路由 (如 "/debug/routes")
+// 路由表包含全部处理器和中间件名称,生产环境应配合鉴权中间件使用或仅在内网开放
+func (s *Server) DebugRoutes(path string) *Route {
+	return s.GET(path, func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().(*Response).JSON(200, routeList(s.Routes()))
+	})
+}
+
+// printRoutes 设置 UF_ROUTES 环境变量时打印路由表,返回是否已打印
+func (s *Server) printRoutes(addr string) bool {
+	if os.Getenv(EnvRoutes) == "" {
+		return false
+	}
+
+	data, err := umarshal.Marshal(&routeTable{
+		server:  s.config.Name,
+		address: addr,
+		routes:  s.Routes(),
+	})
+	if err != nil {
+		s.errorLogger.Error("序列化路由表失败", "error", err)
+		return true
+	}
+	os.Stdout.Write(append(data, '\n'))
+	return true
+}
+
+// funcName 获取函数名称,去掉包路径前缀和闭包后缀
+// 如 "github.com/whosafe/uf/uprotocol/uhttp.MiddlewareTrace.func1" 返回 "uhttp.MiddlewareTrace"
+func funcName(f any) string {
+	v := reflect.ValueOf(f)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+	fn := runtime.FuncForPC(v.Pointer())
+	if fn == nil {
+		return ""
+	}
+
+	name := fn.Name()
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSuffix(name, "-fm")
+
+	// 去掉 .func1 / .func1.2 / .1 等匿名函数后缀
+	for {
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		suffix := name[i+1:]
+		if !isUintParam(strings.TrimPrefix(suffix, "func")) || strings.Count(name, ".") <= 1 {
+			break
+		}
+		name = name[:i]
+	}
+	return name
+}
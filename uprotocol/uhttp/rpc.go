@@ -0,0 +1,60 @@
+package uhttp
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/ujsonrpc"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// rpcRequestKey 在 JSON-RPC 方法上下文中保存 HTTP 请求的键
+type rpcRequestKey struct{}
+
+// RPC 注册 JSON-RPC 2.0 端点
+// 以 POST 路由注册,请求体为单个请求或批量请求;只包含通知时返回 204
+func (s *Server) RPC(path string, registry *ujsonrpc.Registry) *Route {
+	return s.POST(path, newRPCHandlerFunc(registry))
+}
+
+// RPC 注册 JSON-RPC 2.0 端点 (应用组级中间件)
+func (g *Group) RPC(path string, registry *ujsonrpc.Registry) *Route {
+	return g.POST(path, newRPCHandlerFunc(registry))
+}
+
+// RPCRequest 在 JSON-RPC 方法中获取当前 HTTP 请求
+// 非 HTTP 传输 (如 TCP) 调用时返回 nil
+func RPCRequest(ctx *ucontext.Context) *Request {
+	req, _ := ctx.Value(rpcRequestKey{}).(*Request)
+	return req
+}
+
+// newRPCHandlerFunc 将方法注册表包装为 HandlerFunc
+func newRPCHandlerFunc(registry *ujsonrpc.Registry) unet.HandlerFunc {
+	if registry == nil {
+		panic("registry cannot be nil")
+	}
+
+	return func(ctx *ucontext.Context, req unet.Request) error {
+		httpReq := req.(*Request)
+		resp := req.Response().(*Response)
+
+		body, err := httpReq.Body()
+		if err != nil {
+			if errors.Is(err, ErrRequestBodyTooLarge) {
+				return resp.Error(http.StatusRequestEntityTooLarge, CodeInvalidParams, "请求体过大")
+			}
+			return err
+		}
+
+		out := registry.Dispatch(ctx.WithValue(rpcRequestKey{}, httpReq), body)
+		if out == nil {
+			resp.Status(http.StatusNoContent)
+			return nil
+		}
+
+		resp.SetHeader("Content-Type", "application/json")
+		return resp.Bytes(http.StatusOK, out)
+	}
+}
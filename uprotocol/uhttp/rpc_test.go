@@ -0,0 +1,46 @@
+package uhttp
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/ubind"
+	"github.com/whosafe/uf/uprotocol/ujsonrpc"
+)
+
+// TestRPC 测试 JSON-RPC 端点
+func TestRPC(t *testing.T) {
+	registry := ujsonrpc.NewRegistry()
+	registry.Handle("whoami", func(ctx *ucontext.Context, params *ubind.Value) (any, error) {
+		return RPCRequest(ctx).Header("X-User"), nil
+	})
+
+	server := New()
+	server.RPC("/rpc", registry)
+
+	req := httptest.NewRequest("POST", "/rpc", strings.NewReader(`{"jsonrpc":"2.0","method":"whoami","id":1}`))
+	req.Header.Set("X-User", "alice")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status code 200, got %d", w.Code)
+	}
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %s", w.Header().Get("Content-Type"))
+	}
+	if want := `{"jsonrpc":"2.0","result":"alice","id":1}`; w.Body.String() != want {
+		t.Errorf("Expected body %s, got %s", want, w.Body.String())
+	}
+
+	// 只包含通知时返回 204
+	req = httptest.NewRequest("POST", "/rpc", strings.NewReader(`{"jsonrpc":"2.0","method":"whoami"}`))
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	if w.Code != 204 || w.Body.Len() != 0 {
+		t.Errorf("Expected empty 204 for notification, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -2,10 +2,14 @@ package uhttp
 
 import (
 	"context"
+	"errors"
 	"net"
 	"net/http"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/whosafe/uf/ucontext"
 	"github.com/whosafe/uf/ulogger"
@@ -18,9 +22,25 @@ type Server struct {
 	router         *Router
 	middlewares    []unet.MiddlewareFunc
 	httpServer     *http.Server
-	accessLogger   *ulogger.Logger // 访问日志
-	errorLogger    *ulogger.Logger // 错误日志
-	sessionManager *SessionManager // Session 管理器
+	accessLogger   *ulogger.Logger      // 访问日志
+	errorLogger    *ulogger.Logger      // 错误日志
+	sessionManager *SessionManager      // Session 管理器
+	notFound       unet.HandlerFunc     // 路由不存在时的处理器
+	notAllowed     unet.HandlerFunc     // 方法不允许时的处理器
+	hosts          []*Host              // 虚拟主机 (按创建顺序)
+	staticHosts    map[string]*Host     // 静态主机名 -> 虚拟主机
+	hostPatterns   []*hostPattern       // 带参数的主机模式 (参数少的优先)
+	vhosts         map[string]*Host     // 配置文件定义的虚拟主机
+	handlers       *handlerSet          // 编译后的处理器
+	frozen         atomic.Bool          // 已冻结注册 (Start/Serve/Freeze)
+	listener       net.Listener         // 当前监听 (热重启时传递给子进程)
+	handoff        chan struct{}        // 热重启交接完成后关闭
+	proxies        *trustedProxies      // 可信代理
+	forwarded      string               // 采信的转发头 (规范化后)
+	wsConns        map[*WSConn]struct{} // 进行中的 WebSocket 连接 (热重启交接时关闭)
+	wsIdle         chan struct{}        // WebSocket 连接全部结束时关闭
+	wsClosing      bool                 // 正在关闭,之后升级的连接立即关闭
+	wsMu           sync.Mutex
 	mu             sync.RWMutex
 }
 
@@ -39,8 +59,25 @@ func NewWithConfig(cfg *Config) *Server {
 		config:      cfg,
 		router:      NewRouter(),
 		middlewares: make([]unet.MiddlewareFunc, 0),
+		notFound:    defaultNotFound,
+		notAllowed:  defaultMethodNotAllowed,
 	}
 
+	// 解析可信代理
+	if len(cfg.TrustedProxies) > 0 {
+		proxies, err := parseTrustedProxies(cfg.TrustedProxies)
+		if err != nil {
+			ulogger.Warn("可信代理配置无效,不信任任何代理", "error", err)
+		} else {
+			s.proxies = proxies
+		}
+	}
+	forwarded, err := parseForwardedHeader(cfg.ForwardedHeader)
+	if err != nil {
+		ulogger.Warn("转发头配置无效,使用 X-Forwarded-For", "error", err)
+	}
+	s.forwarded = forwarded
+
 	// 创建访问日志 Logger
 	if cfg.AccessLog != nil && cfg.AccessLog.Enabled {
 		accessLogger, err := createLogger(cfg.AccessLog)
@@ -80,10 +117,14 @@ func NewWithConfig(cfg *Config) *Server {
 	// 自动启用静态文件服务
 	if cfg.Static != nil && cfg.Static.Enabled {
 		s.StaticWithConfig(&StaticConfig{
-			Root:   cfg.Static.Root,
-			Prefix: cfg.Static.Prefix,
-			Index:  cfg.Static.Index,
-			Browse: cfg.Static.Browse,
+			Root:          cfg.Static.Root,
+			Prefix:        cfg.Static.Prefix,
+			Index:         cfg.Static.Index,
+			Browse:        cfg.Static.Browse,
+			SPA:           cfg.Static.SPA,
+			Precompressed: cfg.Static.Precompressed,
+			Immutable:     cfg.Static.Immutable,
+			CacheControl:  cfg.Static.CacheControl,
 		})
 	}
 
@@ -166,13 +207,23 @@ func (s *Server) ErrorLogger() *ulogger.Logger {
 }
 
 // Start 启动服务器
+// 优先使用继承的监听;热重启交接完成后返回 nil
+// 设置 UF_ROUTES 环境变量时只打印路由表并返回 nil
 func (s *Server) Start(addr ...string) error {
 	if len(addr) > 0 {
 		s.httpServer.Addr = addr[0]
 	}
+	if s.printRoutes(s.httpServer.Addr) {
+		return nil
+	}
+
+	listener, err := s.Listen()
+	if err != nil {
+		return err
+	}
 
-	s.accessLogger.Info("HTTP 服务器启动", "addr", s.httpServer.Addr)
-	return s.httpServer.ListenAndServe()
+	s.accessLogger.Info("HTTP 服务器启动", "addr", listener.Addr().String())
+	return s.serve(listener, s.httpServer.Serve)
 }
 
 // Stop 停止服务器
@@ -183,7 +234,68 @@ func (s *Server) Stop(ctx context.Context) error {
 
 // Serve 处理连接 (阻塞)
 func (s *Server) Serve(listener net.Listener) error {
-	return s.httpServer.Serve(listener)
+	if s.printRoutes(listener.Addr().String()) {
+		return nil
+	}
+	return s.serve(listener, s.httpServer.Serve)
+}
+
+// serve 在监听上提供服务,启用热重启时登记监听
+// 热重启交接时等待进行中的请求处理完成后返回 nil
+func (s *Server) serve(listener net.Listener, serveFn func(net.Listener) error) error {
+	s.Freeze()
+
+	s.mu.Lock()
+	s.listener = listener
+	s.handoff = nil
+	s.mu.Unlock()
+	s.wsMu.Lock()
+	s.wsClosing = false
+	s.wsMu.Unlock()
+
+	if s.config.Listener != nil && s.config.Listener.GracefulRestart {
+		registerRestart(s)
+		defer unregisterRestart(s)
+	}
+
+	err := serveFn(listener)
+
+	s.mu.RLock()
+	handoff := s.handoff
+	s.mu.RUnlock()
+	if handoff != nil && errors.Is(err, http.ErrServerClosed) {
+		<-handoff
+		return nil
+	}
+	return err
+}
+
+// shutdownTimeout 获取关闭时等待进行中请求的最长时间
+func (s *Server) shutdownTimeout() time.Duration {
+	if s.config.Listener != nil && s.config.Listener.ShutdownTimeout > 0 {
+		return s.config.Listener.ShutdownTimeout
+	}
+	return 30 * time.Second
+}
+
+// handoffShutdown 热重启交接:停止接收新连接并等待进行中的请求完成
+// http.Server.Shutdown 不等待被劫持的连接,WebSocket 连接单独以 1001 关闭,客户端重连到新进程
+func (s *Server) handoffShutdown() {
+	done := make(chan struct{})
+	s.mu.Lock()
+	s.handoff = done
+	s.mu.Unlock()
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout())
+	defer cancel()
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		s.errorLogger.Error("热重启关闭服务器超时", "error", err)
+		s.httpServer.Close()
+	}
+	if err := s.closeWebSockets(ctx); err != nil {
+		s.errorLogger.Error("热重启等待 WebSocket 处理器返回超时", "error", err)
+	}
 }
 
 // MiddlewareConfig 获取中间件配置
@@ -203,12 +315,23 @@ func (s *Server) MiddlewareConfig() *MiddlewareConfig {
 func (s *Server) Use(middleware ...unet.MiddlewareFunc) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if s.router.frozen {
+		panic(frozenMessage)
+	}
 	s.middlewares = append(s.middlewares, middleware...)
+	s.router.gen++
 }
 
 // Handle 注册处理器
 func (s *Server) Handle(pattern string, handler unet.HandlerFunc) {
-	s.router.addRoute("ANY", pattern, handler)
+	s.handle(methodAny, pattern, handler, nil)
+}
+
+// handle 注册服务器自身 (不属于分组) 的路由
+func (s *Server) handle(method, path string, handler unet.HandlerFunc, middlewares []unet.MiddlewareFunc) *Route {
+	rt := s.router.addRoute(method, path, handler)
+	rt.middlewares = middlewares
+	return rt
 }
 
 // ServeHTTP 实现 http.Handler 接口
@@ -222,25 +345,27 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		req.release()
 	}()
 
-	// 查找路由
-	handler, params := s.router.getValue(r.Method, r.URL.Path)
-	if handler == nil {
-		// 404
-		req.response.NotFound("路由不存在")
-		return
+	// 查找已应用中间件的处理器 (优先匹配虚拟主机;未匹配时为重定向、OPTIONS、405 或 404 处理器)
+	handlers := s.currentHandlers()
+	if host := s.matchHost(req); host != nil {
+		handlers = host.handlers
 	}
-
-	// 设置路径参数
-	req.params = params
-
-	// 应用中间件
-	finalHandler := applyMiddlewares(handler, s.middlewares)
+	finalHandler := s.lookup(req, handlers)
 
 	// 创建追踪上下文
 	ctx := ucontext.NewWithContext(r.Context())
 
 	// 执行处理器
 	if err := finalHandler(ctx, req); err != nil {
+		// 路径参数格式错误属于客户端错误
+		var paramErr *ParamError
+		if errors.As(err, &paramErr) {
+			if !req.response.IsWritten() {
+				req.response.BadRequest(paramErr.Error())
+			}
+			return
+		}
+
 		s.errorLogger.ErrorCtx(ctx.Context(), "处理请求失败", "error", err)
 		// 只有在响应未写入时才返回错误响应
 		if !req.response.IsWritten() {
@@ -249,39 +374,139 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// lookup 查找请求的处理器 (已应用中间件),路径参数追加到 req.params
+// 未匹配时依次尝试:结尾斜杠重定向、路径修正重定向、自动 OPTIONS、405,最后为 404
+func (s *Server) lookup(req *Request, hs *handlerSet) unet.HandlerFunc {
+	router := hs.router
+	method, path := req.raw.Method, req.raw.URL.Path
+	if rt := router.find(method, path, &req.params); rt != nil {
+		req.route = rt
+		return rt.handler
+	}
+
+	cfg := s.config.Router
+	if cfg == nil {
+		cfg = DefaultRouterConfig()
+	}
+
+	if method != http.MethodConnect && path != "/" {
+		if cfg.RedirectTrailingSlash {
+			alt := toggleTrailingSlash(path)
+			base := len(req.params)
+			if router.find(method, alt, &req.params) != nil {
+				req.params = req.params[:base]
+				return applyMiddlewares(redirectHandler(method, alt, req.raw.URL.RawQuery), hs.middlewares)
+			}
+		}
+		if cfg.RedirectFixedPath {
+			fixed := cleanPath(path)
+			if target, ok := router.findFold(method, fixed); ok {
+				return applyMiddlewares(redirectHandler(method, target, req.raw.URL.RawQuery), hs.middlewares)
+			}
+			if cfg.RedirectTrailingSlash {
+				if target, ok := router.findFold(method, toggleTrailingSlash(fixed)); ok {
+					return applyMiddlewares(redirectHandler(method, target, req.raw.URL.RawQuery), hs.middlewares)
+				}
+			}
+		}
+	}
+
+	if (method == http.MethodOptions && cfg.HandleOPTIONS) || cfg.HandleMethodNotAllowed {
+		if allow := router.allowed(path); len(allow) > 0 {
+			req.response.SetHeader("Allow", strings.Join(allow, ", "))
+			if method == http.MethodOptions && cfg.HandleOPTIONS {
+				return hs.options
+			}
+			if cfg.HandleMethodNotAllowed {
+				return hs.notAllowed
+			}
+		}
+	}
+
+	return hs.notFound
+}
+
+// redirectHandler 重定向到修正后的路径
+// GET 使用 301,其他方法使用 308 以保留方法和请求体
+func redirectHandler(method, target, rawQuery string) unet.HandlerFunc {
+	code := http.StatusMovedPermanently
+	if method != http.MethodGet {
+		code = http.StatusPermanentRedirect
+	}
+	// 避免 "//host" 被浏览器当作协议相对地址
+	if strings.HasPrefix(target, "//") {
+		target = "/" + strings.TrimLeft(target, "/")
+	}
+	if rawQuery != "" {
+		target += "?" + rawQuery
+	}
+	return func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().Redirect(code, target)
+	}
+}
+
+// defaultNotFound 默认 404 处理器
+func defaultNotFound(ctx *ucontext.Context, req unet.Request) error {
+	return req.Response().(*Response).NotFound("路由不存在")
+}
+
+// defaultMethodNotAllowed 默认 405 处理器
+func defaultMethodNotAllowed(ctx *ucontext.Context, req unet.Request) error {
+	return req.Response().(*Response).Error(http.StatusMethodNotAllowed, CodeNotFound, "请求方法不允许")
+}
+
+// NotFound 设置路由不存在时的处理器 (经过全局中间件)
+func (s *Server) NotFound(handler unet.HandlerFunc) {
+	if s.router.frozen {
+		panic(frozenMessage)
+	}
+	s.notFound = handler
+	s.router.gen++
+}
+
+// MethodNotAllowed 设置方法不允许时的处理器 (经过全局中间件,Allow 头已设置)
+func (s *Server) MethodNotAllowed(handler unet.HandlerFunc) {
+	if s.router.frozen {
+		panic(frozenMessage)
+	}
+	s.notAllowed = handler
+	s.router.gen++
+}
+
 // GET 注册 GET 请求处理器
-func (s *Server) GET(path string, handler unet.HandlerFunc) {
-	s.router.addRoute(http.MethodGet, path, handler)
+// middlewares 为路由级中间件,在全局中间件之后执行
+func (s *Server) GET(path string, handler unet.HandlerFunc, middlewares ...unet.MiddlewareFunc) *Route {
+	return s.handle(http.MethodGet, path, handler, middlewares)
 }
 
 // POST 注册 POST 请求处理器
-func (s *Server) POST(path string, handler unet.HandlerFunc) {
-	s.router.addRoute(http.MethodPost, path, handler)
+func (s *Server) POST(path string, handler unet.HandlerFunc, middlewares ...unet.MiddlewareFunc) *Route {
+	return s.handle(http.MethodPost, path, handler, middlewares)
 }
 
 // PUT 注册 PUT 请求处理器
-func (s *Server) PUT(path string, handler unet.HandlerFunc) {
-	s.router.addRoute(http.MethodPut, path, handler)
+func (s *Server) PUT(path string, handler unet.HandlerFunc, middlewares ...unet.MiddlewareFunc) *Route {
+	return s.handle(http.MethodPut, path, handler, middlewares)
 }
 
 // DELETE 注册 DELETE 请求处理器
-func (s *Server) DELETE(path string, handler unet.HandlerFunc) {
-	s.router.addRoute(http.MethodDelete, path, handler)
+func (s *Server) DELETE(path string, handler unet.HandlerFunc, middlewares ...unet.MiddlewareFunc) *Route {
+	return s.handle(http.MethodDelete, path, handler, middlewares)
 }
 
 // PATCH 注册 PATCH 请求处理器
-func (s *Server) PATCH(path string, handler unet.HandlerFunc) {
-	s.router.addRoute(http.MethodPatch, path, handler)
+func (s *Server) PATCH(path string, handler unet.HandlerFunc, middlewares ...unet.MiddlewareFunc) *Route {
+	return s.handle(http.MethodPatch, path, handler, middlewares)
 }
 
 // HEAD 注册 HEAD 请求处理器
-func (s *Server) HEAD(path string, handler unet.HandlerFunc) {
-	s.router.addRoute(http.MethodHead, path, handler)
+func (s *Server) HEAD(path string, handler unet.HandlerFunc, middlewares ...unet.MiddlewareFunc) *Route {
+	return s.handle(http.MethodHead, path, handler, middlewares)
 }
 
 // OPTIONS 注册 OPTIONS 请求处理器
-func (s *Server) OPTIONS(path string, handler unet.HandlerFunc) {
-	s.router.addRoute(http.MethodOptions, path, handler)
+func (s *Server) OPTIONS(path string, handler unet.HandlerFunc, middlewares ...unet.MiddlewareFunc) *Route {
+	return s.handle(http.MethodOptions, path, handler, middlewares)
 }
 
 // Group 创建路由组
@@ -289,14 +514,6 @@ func (s *Server) Group(prefix string) *Group {
 	return &Group{
 		prefix: prefix,
 		server: s,
+		router: s.router,
 	}
 }
-
-// applyMiddlewares 应用中间件链
-func applyMiddlewares(handler unet.HandlerFunc, middlewares []unet.MiddlewareFunc) unet.HandlerFunc {
-	// 从后往前应用中间件
-	for i := len(middlewares) - 1; i >= 0; i-- {
-		handler = middlewares[i](handler)
-	}
-	return handler
-}
@@ -68,8 +68,8 @@ func (m *SessionManager) setSessionCookie(req *Request, resp unet.Response, sess
 		// 【安全修复】使用安全的默认设置
 		// HttpOnly: 防止 XSS 攻击
 		// SameSite=Lax: 防止 CSRF 攻击
-		// Secure: 在 HTTPS 下传输(通过检测协议)
-		isHTTPS := req.Protocol() == "https" || req.Header("X-Forwarded-Proto") == "https"
+		// Secure: 在 HTTPS 下传输 (X-Forwarded-Proto 只在可信代理转发时采信)
+		isHTTPS := req.Scheme() == "https"
 		resp.SetSessionCookie(m.cookieName, sessionID, "/", "", m.maxAge, isHTTPS, true, http.SameSiteLaxMode)
 	}
 }
@@ -0,0 +1,46 @@
+package uhttp
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+)
+
+// magicSignature 文件头签名
+type magicSignature struct {
+	offset      int
+	magic       []byte
+	contentType string
+}
+
+// magicSignatures http.DetectContentType 未覆盖的常见格式
+var magicSignatures = []magicSignature{
+	{0, []byte("7z\xBC\xAF\x27\x1C"), "application/x-7z-compressed"},
+	{0, []byte("BZh"), "application/x-bzip2"},
+	{0, []byte("\xFD7zXZ\x00"), "application/x-xz"},
+	{0, []byte("\x28\xB5\x2F\xFD"), "application/zstd"},
+	{0, []byte("II*\x00"), "image/tiff"},
+	{0, []byte("MM\x00*"), "image/tiff"},
+	{4, []byte("ftypheic"), "image/heic"},
+	{4, []byte("ftypheix"), "image/heic"},
+	{4, []byte("ftypavif"), "image/avif"},
+	{4, []byte("ftypqt  "), "video/quicktime"},
+	{0, []byte("fLaC"), "audio/flac"},
+	{0, []byte("SQLite format 3\x00"), "application/vnd.sqlite3"},
+	{0, []byte("\x7FELF"), "application/x-elf"},
+}
+
+// SniffContentType 按文件头 (前 512 字节) 探测 MIME 类型,返回不含参数的媒体类型
+// 先匹配内置签名,再使用 http.DetectContentType,无法识别时为 application/octet-stream
+func SniffContentType(head []byte) string {
+	for _, sig := range magicSignatures {
+		if len(head) >= sig.offset+len(sig.magic) && bytes.Equal(head[sig.offset:sig.offset+len(sig.magic)], sig.magic) {
+			return sig.contentType
+		}
+	}
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
@@ -0,0 +1,303 @@
+package uhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/umarshal"
+)
+
+// SSE 错误定义
+var (
+	// ErrSSEClosed 事件流已关闭 (客户端断开、上下文取消或已调用 Close)
+	ErrSSEClosed = errors.New("sse: stream closed")
+
+	// ErrSSEStarted 响应已写入,无法再开启事件流
+	ErrSSEStarted = errors.New("sse: response already written")
+)
+
+// SSEConfig Server-Sent Events 配置
+type SSEConfig struct {
+	HeartbeatInterval time.Duration // 心跳注释间隔,0 表示不发送
+	Retry             time.Duration // 建议客户端重连间隔,0 表示不发送
+}
+
+// DefaultSSEConfig 默认 SSE 配置
+func DefaultSSEConfig() *SSEConfig {
+	return &SSEConfig{
+		HeartbeatInterval: 15 * time.Second,
+	}
+}
+
+// SSEEvent 事件
+type SSEEvent struct {
+	ID    string        // 事件 ID,客户端重连时通过 Last-Event-ID 回传
+	Event string        // 事件类型,为空时客户端触发 message 事件
+	Data  string        // 事件数据,多行数据按行拆分发送
+	Retry time.Duration // 重连间隔,0 表示不发送
+}
+
+// SSEStream 事件流写入器
+// Send / Comment 可在多个 goroutine 中并发调用
+type SSEStream struct {
+	resp        *Response
+	controller  *http.ResponseController
+	lastEventID string
+	events      int64
+
+	done      chan struct{}
+	closeOnce sync.Once
+	stop      context.CancelFunc
+	heartbeat sync.WaitGroup
+	err       error
+	mu        sync.Mutex
+}
+
+// SSE 开启 Server-Sent Events 事件流
+// 写入事件流响应头并立即刷新;客户端断开或 ctx 取消时事件流结束,Done 通道关闭
+// 处理器返回时事件流自动关闭,不需要手动调用 Close
+func (r *Response) SSE(ctx *ucontext.Context, config ...*SSEConfig) (*SSEStream, error) {
+	if stream := r.sse.Load(); stream != nil {
+		return stream, nil
+	}
+	if r.written {
+		return nil, ErrSSEStarted
+	}
+	if ctx != nil && ctx.Err() != nil {
+		return nil, ErrSSEClosed
+	}
+	// 先占有响应再修改响应头,超时中间件已写入 408 时不能再开启事件流
+	if !r.markStreaming() {
+		return nil, ErrSSEClosed
+	}
+
+	cfg := DefaultSSEConfig()
+	if len(config) > 0 && config[0] != nil {
+		cfg = config[0]
+	}
+
+	header := r.writer.Header()
+	header.Set("Content-Type", "text/event-stream; charset=utf-8")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no") // 禁用 Nginx 代理缓冲
+	header.Del("Content-Length")
+	if r.request != nil && r.request.ProtoMajor == 1 {
+		header.Set("Connection", "keep-alive")
+	}
+
+	s := &SSEStream{
+		resp:       r,
+		controller: http.NewResponseController(r.writer),
+		done:       make(chan struct{}),
+	}
+	if r.request != nil {
+		s.lastEventID = r.request.Header.Get("Last-Event-ID")
+		if s.lastEventID == "" {
+			// EventSource polyfill 通过查询参数传递
+			s.lastEventID = r.request.URL.Query().Get("lastEventId")
+		}
+	}
+
+	// 长连接不受服务器写超时限制
+	_ = s.controller.SetWriteDeadline(time.Time{})
+
+	watchCtx := context.Background()
+	if r.request != nil {
+		watchCtx = r.request.Context()
+	}
+	watchCtx, s.stop = context.WithCancel(watchCtx)
+	r.sse.Store(s)
+
+	r.Status(http.StatusOK)
+	if cfg.Retry > 0 {
+		r.writer.Write([]byte("retry: " + strconv.FormatInt(cfg.Retry.Milliseconds(), 10) + "\n\n"))
+	}
+	if err := s.controller.Flush(); err != nil {
+		r.sse.Store(nil)
+		s.finish(err)
+		return nil, err
+	}
+
+	// 监听客户端断开与上下文取消
+	var ctxDone <-chan struct{}
+	if ctx != nil {
+		ctxDone = ctx.Done()
+	}
+	go func() {
+		select {
+		case <-watchCtx.Done():
+		case <-ctxDone:
+		}
+		s.finish(ErrSSEClosed)
+	}()
+
+	if cfg.HeartbeatInterval > 0 {
+		s.heartbeat.Add(1)
+		go s.heartbeatLoop(cfg.HeartbeatInterval)
+	}
+
+	return s, nil
+}
+
+// LastEventID 获取客户端重连时携带的最后事件 ID
+func (s *SSEStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Done 事件流结束时关闭的通道
+func (s *SSEStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err 事件流结束原因,未结束时返回 nil
+func (s *SSEStream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Events 获取已发送的事件数
+func (s *SSEStream) Events() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.events
+}
+
+// Send 发送事件并刷新
+func (s *SSEStream) Send(event *SSEEvent) error {
+	var b strings.Builder
+	if event.ID != "" {
+		b.WriteString("id: ")
+		b.WriteString(sseField(event.ID))
+		b.WriteByte('\n')
+	}
+	if event.Event != "" {
+		b.WriteString("event: ")
+		b.WriteString(sseField(event.Event))
+		b.WriteByte('\n')
+	}
+	if event.Retry > 0 {
+		b.WriteString("retry: ")
+		b.WriteString(strconv.FormatInt(event.Retry.Milliseconds(), 10))
+		b.WriteByte('\n')
+	}
+	for _, line := range sseLines(event.Data) {
+		b.WriteString("data: ")
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+
+	return s.write(b.String(), true)
+}
+
+// SendData 发送仅包含数据的事件
+func (s *SSEStream) SendData(data string) error {
+	return s.Send(&SSEEvent{Data: data})
+}
+
+// SendJSON 以 JSON 编码数据发送事件
+func (s *SSEStream) SendJSON(event string, data any) error {
+	jsonData, err := umarshal.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return s.Send(&SSEEvent{Event: event, Data: string(jsonData)})
+}
+
+// Comment 发送注释 (客户端忽略,常用于保活)
+func (s *SSEStream) Comment(text string) error {
+	var b strings.Builder
+	for _, line := range sseLines(text) {
+		b.WriteString(": ")
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+	return s.write(b.String(), false)
+}
+
+// Close 结束事件流,等待心跳协程退出
+func (s *SSEStream) Close() error {
+	s.finish(ErrSSEClosed)
+	s.heartbeat.Wait()
+	return nil
+}
+
+// write 写入并刷新
+func (s *SSEStream) write(data string, event bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	if _, err := s.resp.writer.Write([]byte(data)); err != nil {
+		s.finishLocked(err)
+		return err
+	}
+	if err := s.controller.Flush(); err != nil {
+		s.finishLocked(err)
+		return err
+	}
+	if event {
+		s.events++
+	}
+	return nil
+}
+
+// heartbeatLoop 定时发送心跳注释
+func (s *SSEStream) heartbeatLoop(interval time.Duration) {
+	defer s.heartbeat.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if s.Comment("ping") != nil {
+				return
+			}
+		}
+	}
+}
+
+// finish 结束事件流
+func (s *SSEStream) finish(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finishLocked(err)
+}
+
+// finishLocked 结束事件流 (需持有锁)
+func (s *SSEStream) finishLocked(err error) {
+	s.closeOnce.Do(func() {
+		s.err = err
+		close(s.done)
+		s.stop()
+	})
+}
+
+// sseField 去除单行字段中的换行符
+func sseField(value string) string {
+	if strings.ContainsAny(value, "\r\n") {
+		value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+	}
+	return value
+}
+
+// sseLines 按 CRLF / LF / CR 拆分多行数据
+func sseLines(data string) []string {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+	return strings.Split(data, "\n")
+}
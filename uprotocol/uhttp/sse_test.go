@@ -0,0 +1,219 @@
+package uhttp
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// readSSEBlock 读取一个以空行结束的事件块
+func readSSEBlock(t *testing.T, r *bufio.Reader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+// TestSSEEvents 测试事件格式与 Last-Event-ID
+func TestSSEEvents(t *testing.T) {
+	server := New()
+	server.GET("/events", func(ctx *ucontext.Context, req unet.Request) error {
+		stream, err := req.Response().(*Response).SSE(ctx, &SSEConfig{Retry: 3 * time.Second})
+		if err != nil {
+			return err
+		}
+		stream.Send(&SSEEvent{ID: "id-" + stream.LastEventID(), Event: "progress", Data: "line1\nline2"})
+		stream.SendData("done")
+		return nil
+	})
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	httpReq, _ := http.NewRequest("GET", ts.URL+"/events", nil)
+	httpReq.Header.Set("Last-Event-ID", "41")
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream; charset=utf-8" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	r := bufio.NewReader(resp.Body)
+	if got := readSSEBlock(t, r); len(got) != 1 || got[0] != "retry: 3000" {
+		t.Fatalf("retry block = %q", got)
+	}
+	want := []string{"id: id-41", "event: progress", "data: line1", "data: line2"}
+	if got := readSSEBlock(t, r); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("event = %q, want %q", got, want)
+	}
+	if got := readSSEBlock(t, r); len(got) != 1 || got[0] != "data: done" {
+		t.Fatalf("event = %q", got)
+	}
+}
+
+// TestSSEHeartbeatAndDisconnect 测试心跳与客户端断开
+func TestSSEHeartbeatAndDisconnect(t *testing.T) {
+	ended := make(chan error, 1)
+
+	server := New()
+	server.GET("/events", func(ctx *ucontext.Context, req unet.Request) error {
+		stream, err := req.Response().(*Response).SSE(ctx, &SSEConfig{HeartbeatInterval: 10 * time.Millisecond})
+		if err != nil {
+			return err
+		}
+		<-stream.Done()
+		ended <- stream.Send(&SSEEvent{Data: "late"})
+		return nil
+	})
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := bufio.NewReader(resp.Body)
+	if got := readSSEBlock(t, r); len(got) != 1 || got[0] != ": ping" {
+		t.Fatalf("heartbeat = %q", got)
+	}
+	resp.Body.Close()
+
+	select {
+	case err := <-ended:
+		if err == nil {
+			t.Fatal("send after disconnect should fail")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream did not end after client disconnect")
+	}
+}
+
+// TestSSEContextCancel 测试上下文取消结束事件流
+func TestSSEContextCancel(t *testing.T) {
+	server := New()
+	server.GET("/events", func(ctx *ucontext.Context, req unet.Request) error {
+		cctx, cancel := ucontext.WithCancel(ctx)
+		stream, err := req.Response().(*Response).SSE(cctx)
+		if err != nil {
+			return err
+		}
+		stream.SendData("first")
+		cancel()
+		<-stream.Done()
+		if stream.Err() != ErrSSEClosed {
+			t.Errorf("Err() = %v", stream.Err())
+		}
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/events", nil))
+
+	if w.Body.String() != "data: first\n\n" {
+		t.Fatalf("body = %q", w.Body.String())
+	}
+}
+
+// TestSSETimeoutMiddleware 测试事件流不受超时中间件中断
+func TestSSETimeoutMiddleware(t *testing.T) {
+	server := New()
+	server.Use(MiddlewareTimeout(20 * time.Millisecond))
+	server.GET("/events", func(ctx *ucontext.Context, req unet.Request) error {
+		stream, err := req.Response().(*Response).SSE(ctx)
+		if err != nil {
+			return err
+		}
+		time.Sleep(60 * time.Millisecond)
+		return stream.SendData("after-timeout")
+	})
+	server.GET("/slow", func(ctx *ucontext.Context, req unet.Request) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/events", nil))
+	if w.Code != 200 || w.Body.String() != "data: after-timeout\n\n" {
+		t.Fatalf("stream = %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	if w.Code != 408 {
+		t.Fatalf("slow status = %d", w.Code)
+	}
+}
+
+// TestSSETakeover 测试事件流与超时响应只有一方占有响应
+func TestSSETakeover(t *testing.T) {
+	server := New()
+	server.GET("/timed-out", func(ctx *ucontext.Context, req unet.Request) error {
+		resp := req.Response().(*Response)
+		// 模拟超时中间件先占有响应
+		if !resp.markTimedOut() {
+			t.Error("Expected timeout to claim response")
+		}
+		if _, err := resp.SSE(ctx); err != ErrSSEClosed {
+			t.Errorf("Expected ErrSSEClosed after timeout, got %v", err)
+		}
+		return nil
+	})
+	server.GET("/streaming", func(ctx *ucontext.Context, req unet.Request) error {
+		resp := req.Response().(*Response)
+		if _, err := resp.SSE(ctx); err != nil {
+			return err
+		}
+		if resp.markTimedOut() {
+			t.Error("Expected timeout to lose after stream started")
+		}
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/timed-out", nil))
+	if w.Header().Get("Content-Type") != "" {
+		t.Errorf("Expected headers untouched, got %q", w.Header().Get("Content-Type"))
+	}
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/streaming", nil))
+	if w.Code != 200 || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+		t.Errorf("stream = %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
+
+// TestSSEAfterWrite 测试响应写入后无法开启事件流
+func TestSSEAfterWrite(t *testing.T) {
+	server := New()
+	server.GET("/events", func(ctx *ucontext.Context, req unet.Request) error {
+		resp := req.Response().(*Response)
+		resp.Status(200)
+		if _, err := resp.SSE(ctx); err != ErrSSEStarted {
+			t.Errorf("err = %v", err)
+		}
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/events", nil))
+}
@@ -1,23 +1,72 @@
 package uhttp
 
 import (
+	"bytes"
+	"errors"
+	"html/template"
+	"io"
+	"io/fs"
+	"mime"
 	"net/http"
+	"net/url"
 	"os"
-	"path/filepath"
+	"path"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/whosafe/uf/ucontext"
 	"github.com/whosafe/uf/uprotocol/unet"
 )
 
+// immutableCacheControl 带哈希文件名的资源使用的 Cache-Control
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
 // StaticConfig 静态文件配置
 type StaticConfig struct {
-	Root   string   // 静态文件根目录
-	Prefix string   // URL 前缀
-	Index  []string // 索引文件列表
-	Browse bool     // 是否允许目录浏览
+	Root           string             // 静态文件根目录 (FS 为空时使用)
+	FS             fs.FS              // 文件系统根 (如 embed.FS、fs.Sub 的结果),优先于 Root
+	Prefix         string             // URL 前缀
+	Index          []string           // 索引文件列表,默认 index.html、index.htm
+	Browse         bool               // 是否允许目录浏览
+	BrowseTemplate *template.Template // 目录列表模板,数据为 *DirListing,为空时使用内置模板
+	SPA            bool               // 单页应用模式: 不存在且没有扩展名的路径返回根目录的索引文件
+	Precompressed  bool               // 客户端接受时返回预压缩的 .br / .gz 同名文件
+	Immutable      bool               // 带哈希的文件名 (如 app.3f9a1c2b.js) 设置一年的 immutable 缓存
+	CacheControl   string             // 其他文件的 Cache-Control,为空时不设置
+}
+
+// DirListing 目录列表模板数据
+type DirListing struct {
+	Path    string            // 目录的 URL 路径,以 / 结尾
+	Entries []DirListingEntry // 目录在前,按名称排序
+}
+
+// DirListingEntry 目录列表项
+type DirListingEntry struct {
+	Name    string
+	URL     string // 相对链接,目录以 / 结尾
+	IsDir   bool
+	Size    int64
+	ModTime time.Time
 }
 
+// defaultBrowseTemplate 内置目录列表模板
+var defaultBrowseTemplate = template.Must(template.New("browse").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Index of {{.Path}}</title></head>
+<body>
+<h1>Index of {{.Path}}</h1>
+<table>
+<tr><th>Name</th><th>Size</th><th>Modified</th></tr>
+{{if ne .Path "/"}}<tr><td><a href="../">../</a></td><td></td><td></td></tr>
+{{end}}{{range .Entries}}<tr><td><a href="{{.URL}}">{{.Name}}{{if .IsDir}}/{{end}}</a></td><td>{{if not .IsDir}}{{.Size}}{{end}}</td><td>{{if not .ModTime.IsZero}}{{.ModTime.Format "2006-01-02 15:04"}}{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
 // Static 注册静态文件服务
 func (s *Server) Static(prefix, root string) {
 	s.StaticWithConfig(&StaticConfig{
@@ -28,87 +77,294 @@ func (s *Server) Static(prefix, root string) {
 	})
 }
 
+// StaticFS 注册文件系统 (如 embed.FS) 的静态文件服务
+//
+//	//go:embed dist
+//	var dist embed.FS
+//
+//	sub, _ := fs.Sub(dist, "dist")
+//	server.StaticFS("/", sub)
+func (s *Server) StaticFS(prefix string, fsys fs.FS) {
+	s.StaticWithConfig(&StaticConfig{
+		FS:     fsys,
+		Prefix: prefix,
+		Index:  []string{"index.html", "index.htm"},
+	})
+}
+
 // StaticWithConfig 使用配置注册静态文件服务
 func (s *Server) StaticWithConfig(cfg *StaticConfig) {
 	// 确保前缀以 / 开头
 	if !strings.HasPrefix(cfg.Prefix, "/") {
 		cfg.Prefix = "/" + cfg.Prefix
 	}
+	if len(cfg.Index) == 0 {
+		cfg.Index = []string{"index.html", "index.htm"}
+	}
 
-	// 创建文件服务器
-	fileServer := http.FileServer(http.Dir(cfg.Root))
+	h := &staticHandler{cfg: cfg, fsys: cfg.FS, tmpl: cfg.BrowseTemplate}
+	if h.fsys == nil {
+		h.fsys = os.DirFS(cfg.Root)
+	}
+	if h.tmpl == nil {
+		h.tmpl = defaultBrowseTemplate
+	}
 
 	// 注册路由
-	pattern := cfg.Prefix + "/*filepath"
-	s.GET(pattern, func(ctx *ucontext.Context, req unet.Request) error {
-		httpReq := req.(*Request)
-		httpResp := req.Response().(*Response)
+	s.GET(strings.TrimSuffix(cfg.Prefix, "/")+"/*filepath", h.serve)
+}
+
+// staticHandler 静态文件处理器
+type staticHandler struct {
+	cfg   *StaticConfig
+	fsys  fs.FS
+	tmpl  *template.Template
+	etags sync.Map // 文件名 -> ETag,只缓存没有修改时间的文件 (embed.FS)
+}
+
+// serve 处理静态文件请求
+func (h *staticHandler) serve(ctx *ucontext.Context, req unet.Request) error {
+	httpReq := req.(*Request)
+	httpResp := httpReq.response
 
-		// 获取文件路径
-		path := httpReq.Param("filepath")
-		if path == "" {
-			path = "/"
+	// 以 / 为根清理路径,.. 不会越过根目录
+	urlPath := path.Clean("/" + httpReq.Param("filepath"))
+	name := strings.TrimPrefix(urlPath, "/")
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) {
+		return httpResp.Forbidden("非法路径")
+	}
+
+	info, err := fs.Stat(h.fsys, name)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		// 单页应用: 前端路由交给索引文件处理,带扩展名的路径 (缺失的资源) 仍返回 404
+		if h.cfg.SPA && path.Ext(urlPath) == "" {
+			if index := h.findIndex("."); index != "" {
+				return h.serveFile(httpReq, index, true)
+			}
 		}
+		return httpResp.NotFound("文件不存在")
+	}
 
-		// 【安全修复】清理路径,防止路径遍历攻击
-		path = filepath.Clean(path)
+	if !info.IsDir() {
+		return h.serveFile(httpReq, name, slices.Contains(h.cfg.Index, path.Base(name)))
+	}
+
+	// 目录以 / 结尾,保证索引文件和目录列表中的相对链接正确
+	if !strings.HasSuffix(httpReq.raw.URL.Path, "/") {
+		location := httpReq.raw.URL.Path + "/"
+		if httpReq.raw.URL.RawQuery != "" {
+			location += "?" + httpReq.raw.URL.RawQuery
+		}
+		return httpResp.Redirect(http.StatusMovedPermanently, location)
+	}
+	if index := h.findIndex(name); index != "" {
+		return h.serveFile(httpReq, index, true)
+	}
+	if !h.cfg.Browse {
+		return httpResp.Forbidden("不允许目录浏览")
+	}
+	return h.serveListing(httpReq, name, urlPath)
+}
 
-		// 【安全修复】拒绝包含 .. 的路径
-		if strings.Contains(path, "..") {
-			return httpResp.Forbidden("非法路径")
+// findIndex 查找目录中的索引文件,没有时返回空字符串
+func (h *staticHandler) findIndex(dir string) string {
+	for _, index := range h.cfg.Index {
+		name := path.Join(dir, index)
+		if info, err := fs.Stat(h.fsys, name); err == nil && !info.IsDir() {
+			return name
 		}
+	}
+	return ""
+}
 
-		// 构建完整路径
-		fullPath := filepath.Join(cfg.Root, path)
+// serveFile 输出文件,支持 Range、条件请求和预压缩文件
+// document 为 true 表示索引文件 (含单页应用回退),设置 no-cache 而不是 immutable 缓存
+func (h *staticHandler) serveFile(httpReq *Request, name string, document bool) error {
+	header := httpReq.response.writer.Header()
+	ctype := mime.TypeByExtension(path.Ext(name))
+	if ctype != "" {
+		header.Set("Content-Type", ctype)
+	}
 
-		// 【安全修复】验证最终路径是否在根目录内
-		absRoot, err := filepath.Abs(cfg.Root)
-		if err != nil {
-			return httpResp.InternalError("服务器配置错误")
+	switch {
+	case document:
+		// 索引文件引用了带哈希的资源,必须每次校验才能拿到新版本
+		if h.cfg.Immutable || h.cfg.CacheControl != "" {
+			header.Set("Cache-Control", "no-cache")
 		}
-		absPath, err := filepath.Abs(fullPath)
-		if err != nil {
-			return httpResp.InternalError("路径解析失败")
+	case h.cfg.Immutable && hashedName(path.Base(name)):
+		header.Set("Cache-Control", immutableCacheControl)
+	case h.cfg.CacheControl != "":
+		header.Set("Cache-Control", h.cfg.CacheControl)
+	}
+
+	serveName := name
+	if h.cfg.Precompressed {
+		header.Add("Vary", "Accept-Encoding")
+		if variant, encoding := h.precompressed(name, httpReq.raw.Header.Get("Accept-Encoding")); variant != "" {
+			serveName = variant
+			header.Set("Content-Encoding", encoding)
+			if ctype == "" {
+				// 不能按压缩后的内容探测类型
+				header.Set("Content-Type", "application/octet-stream")
+			}
 		}
-		// 确保访问路径在根目录内
-		if !strings.HasPrefix(absPath, absRoot) {
-			return httpResp.Forbidden("非法路径")
+	}
+
+	f, err := h.fsys.Open(serveName)
+	if err != nil {
+		header.Del("Content-Encoding")
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		// 不支持 Seek 的文件读入内存
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
 		}
+		content = bytes.NewReader(data)
+	}
 
-		// 检查文件是否存在
-		info, err := os.Stat(fullPath)
+	// 没有修改时间 (embed.FS) 时用内容生成 ETag,条件请求仍然有效
+	if info.ModTime().IsZero() && header.Get("ETag") == "" {
+		etag, err := h.etag(serveName, content)
 		if err != nil {
-			if os.IsNotExist(err) {
-				return httpResp.NotFound("文件不存在")
-			}
 			return err
 		}
+		header.Set("ETag", etag)
+	}
 
-		// 如果是目录
-		if info.IsDir() {
-			// 尝试查找索引文件
-			for _, index := range cfg.Index {
-				indexPath := filepath.Join(fullPath, index)
-				if _, err := os.Stat(indexPath); err == nil {
-					path = filepath.Join(path, index)
-					break
-				}
-			}
+	httpReq.response.written = true
+	http.ServeContent(httpReq.response.writer, httpReq.raw, name, info.ModTime(), content)
+	return nil
+}
 
-			// 如果不允许目录浏览且没有找到索引文件
-			if !cfg.Browse {
-				return httpResp.Forbidden("不允许目录浏览")
-			}
+// precompressed 查找客户端接受的预压缩文件,优先 br
+func (h *staticHandler) precompressed(name, acceptEncoding string) (string, string) {
+	if acceptEncoding == "" {
+		return "", ""
+	}
+	for _, variant := range [...]struct{ ext, encoding string }{{".br", "br"}, {".gz", "gzip"}} {
+		if encodingQuality(acceptEncoding, variant.encoding) <= 0 {
+			continue
+		}
+		if info, err := fs.Stat(h.fsys, name+variant.ext); err == nil && !info.IsDir() {
+			return name + variant.ext, variant.encoding
+		}
+	}
+	return "", ""
+}
+
+// etag 计算并缓存文件内容的 ETag
+func (h *staticHandler) etag(name string, content io.ReadSeeker) (string, error) {
+	if etag, ok := h.etags.Load(name); ok {
+		return etag.(string), nil
+	}
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := computeETag(data, false)
+	h.etags.Store(name, etag)
+	return etag, nil
+}
+
+// serveListing 输出目录列表
+func (h *staticHandler) serveListing(httpReq *Request, name, urlPath string) error {
+	entries, err := fs.ReadDir(h.fsys, name)
+	if err != nil {
+		return err
+	}
+
+	listing := &DirListing{Path: strings.TrimSuffix(urlPath, "/") + "/"}
+	dirs := make([]DirListingEntry, 0, len(entries))
+	files := make([]DirListingEntry, 0, len(entries))
+	for _, entry := range entries {
+		item := DirListingEntry{Name: entry.Name(), IsDir: entry.IsDir()}
+		item.URL = (&url.URL{Path: item.Name}).String()
+		if info, err := entry.Info(); err == nil {
+			item.Size, item.ModTime = info.Size(), info.ModTime()
 		}
+		if item.IsDir {
+			item.URL += "/"
+			dirs = append(dirs, item)
+		} else {
+			files = append(files, item)
+		}
+	}
+	listing.Entries = append(dirs, files...)
 
-		// 设置正确的 URL 路径
-		httpReq.raw.URL.Path = path
+	httpReq.response.SetHeader("Content-Type", "text/html; charset=utf-8")
+	httpReq.response.Status(http.StatusOK)
+	return h.tmpl.Execute(httpReq.response.writer, listing)
+}
 
-		// 使用文件服务器处理
-		fileServer.ServeHTTP(httpReq.writer, httpReq.raw)
+// hashedName 检查文件名是否带内容哈希 (如 app.3f9a1c2b.js、index-BxK3a9_f.js)
+// 哈希段不能是第一段,至少 8 个字符
+func hashedName(base string) bool {
+	stem := strings.TrimSuffix(base, path.Ext(base))
+	for i, segment := range strings.FieldsFunc(stem, func(r rune) bool { return r == '.' || r == '-' }) {
+		if i > 0 && len(segment) >= 8 && hashLike(segment) {
+			return true
+		}
+	}
+	return false
+}
 
-		return nil
-	})
+// hashLike 检查片段是否像内容哈希,必须同时含字母和数字:
+// 十六进制 (3f9a1c2b)、大写 base32 (ABCD1234) 或大小写混合的 base64url (BxK3a9_f);
+// 单词加数字 (library2、component20240101) 和驼峰名 (MyComponent2) 不算
+func hashLike(s string) bool {
+	var digit, upper, lower bool
+	hex := true
+	run, maxRun := 0, 0 // 最长连续小写字母,用于排除驼峰名
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' {
+			run++
+			maxRun = max(maxRun, run)
+		} else {
+			run = 0
+		}
+		switch {
+		case c >= '0' && c <= '9':
+			digit = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+			hex = hex && c <= 'f'
+		case c >= 'A' && c <= 'Z':
+			upper = true
+			hex = hex && c <= 'F'
+		case c == '_':
+			hex = false
+		default:
+			return false
+		}
+	}
+	switch {
+	case !digit || !(upper || lower):
+		return false
+	case hex || !lower:
+		return true
+	default:
+		return upper && maxRun <= 4
+	}
 }
 
 // File 发送文件
@@ -0,0 +1,195 @@
+package uhttp
+
+import (
+	"html/template"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// staticFS 测试用前端构建产物
+func staticFS() fstest.MapFS {
+	return fstest.MapFS{
+		"index.html":                 {Data: []byte("<html>app</html>")},
+		"assets/app.3f9a1c2b.js":     {Data: []byte("console.log('app')")},
+		"assets/app.3f9a1c2b.js.br":  {Data: []byte("br-data")},
+		"assets/app.3f9a1c2b.js.gz":  {Data: []byte("gz-data")},
+		"assets/logo.svg":            {Data: []byte("<svg></svg>")},
+		"docs/index.html":            {Data: []byte("<html>docs</html>")},
+		"files/a.txt":                {Data: []byte("a")},
+		"files/sub/b.txt":            {Data: []byte("b")},
+		"files/<script>.txt":         {Data: []byte("x")},
+		"assets/index-BxK3a9_f.css":  {Data: []byte("body{}")},
+		"assets/component-button.js": {Data: []byte("button")},
+	}
+}
+
+// TestStaticFS 测试 fs.FS 根、单页应用回退和缓存头
+func TestStaticFS(t *testing.T) {
+	server := New()
+	server.StaticWithConfig(&StaticConfig{
+		FS:            staticFS(),
+		Prefix:        "/",
+		SPA:           true,
+		Precompressed: true,
+		Immutable:     true,
+		CacheControl:  "public, max-age=300",
+	})
+
+	tests := []struct {
+		path, acceptEncoding  string
+		code                  int
+		body, cache, encoding string
+	}{
+		{"/", "", 200, "<html>app</html>", "no-cache", ""},
+		{"/users/42", "", 200, "<html>app</html>", "no-cache", ""},
+		{"/missing.js", "", 404, "", "", ""},
+		{"/docs/", "", 200, "<html>docs</html>", "no-cache", ""},
+		{"/docs", "", 301, "", "", ""},
+		{"/assets/app.3f9a1c2b.js", "", 200, "console.log('app')", immutableCacheControl, ""},
+		{"/assets/app.3f9a1c2b.js", "gzip, br", 200, "br-data", immutableCacheControl, "br"},
+		{"/assets/app.3f9a1c2b.js", "gzip, br;q=0", 200, "gz-data", immutableCacheControl, "gzip"},
+		{"/assets/index-BxK3a9_f.css", "", 200, "body{}", immutableCacheControl, ""},
+		{"/assets/component-button.js", "", 200, "button", "public, max-age=300", ""},
+		{"/assets/logo.svg", "gzip", 200, "<svg></svg>", "public, max-age=300", ""},
+		{"/files/", "", 403, "", "", ""},
+		{"/../index.html", "", 200, "<html>app</html>", "no-cache", ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.path, nil)
+		if tt.acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+
+		if w.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d", tt.path, tt.code, w.Code)
+			continue
+		}
+		if tt.code != 200 {
+			continue
+		}
+		if w.Body.String() != tt.body {
+			t.Errorf("%s (%s): expected body %q, got %q", tt.path, tt.acceptEncoding, tt.body, w.Body.String())
+		}
+		if got := w.Header().Get("Cache-Control"); got != tt.cache {
+			t.Errorf("%s: expected Cache-Control %q, got %q", tt.path, tt.cache, got)
+		}
+		if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+			t.Errorf("%s: expected Content-Encoding %q, got %q", tt.path, tt.encoding, got)
+		}
+	}
+
+	// 预压缩文件保留原始类型
+	r := httptest.NewRequest("GET", "/assets/app.3f9a1c2b.js", nil)
+	r.Header.Set("Accept-Encoding", "br")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	if ct := w.Header().Get("Content-Type"); !strings.Contains(ct, "javascript") {
+		t.Errorf("Expected javascript Content-Type, got %q", ct)
+	}
+
+	// 没有修改时间的文件使用内容 ETag
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected ETag for file without modification time")
+	}
+	r = httptest.NewRequest("GET", "/assets/app.3f9a1c2b.js", nil)
+	r.Header.Set("Accept-Encoding", "br")
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	if w.Code != 304 {
+		t.Errorf("Expected 304, got %d", w.Code)
+	}
+}
+
+// TestStaticBrowse 测试目录列表
+func TestStaticBrowse(t *testing.T) {
+	server := New()
+	server.StaticWithConfig(&StaticConfig{FS: staticFS(), Prefix: "/static", Browse: true})
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/static/files/", nil))
+	body := w.Body.String()
+	if w.Code != 200 || !strings.Contains(body, `href="sub/"`) || !strings.Contains(body, `href="a.txt"`) {
+		t.Fatalf("Unexpected listing: %d %s", w.Code, body)
+	}
+	if strings.Contains(body, "<script>") || strings.Index(body, "sub/") > strings.Index(body, "a.txt") {
+		t.Errorf("Listing not escaped or directories not first: %s", body)
+	}
+
+	// 有索引文件的目录返回索引文件,而不是目录列表
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/static/docs/", nil))
+	if w.Body.String() != "<html>docs</html>" {
+		t.Errorf("Expected index file, got %q", w.Body.String())
+	}
+
+	// 自定义模板
+	tmpl := template.Must(template.New("list").Parse(`{{.Path}}:{{range .Entries}}{{.Name}},{{end}}`))
+	server = New()
+	server.StaticWithConfig(&StaticConfig{FS: staticFS(), Prefix: "/static", Browse: true, BrowseTemplate: tmpl})
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/static/files/sub/", nil))
+	if w.Body.String() != "/files/sub/:b.txt," {
+		t.Errorf("Unexpected custom listing: %q", w.Body.String())
+	}
+}
+
+// TestStaticDirIndex 测试磁盘目录找到索引文件后不再拒绝访问
+func TestStaticDirIndex(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "blog"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "blog", "index.html"), []byte("blog"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := New()
+	server.Static("/static", root)
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/static/blog/", nil))
+	if w.Code != 200 || w.Body.String() != "blog" {
+		t.Errorf("Expected index file, got %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Last-Modified") == "" {
+		t.Error("Expected Last-Modified for disk file")
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/static/", nil))
+	if w.Code != 403 {
+		t.Errorf("Expected 403 for directory without index, got %d", w.Code)
+	}
+}
+
+// TestHashedName 测试哈希文件名识别
+func TestHashedName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"app.3f9a1c2b.js":         true,
+		"index-BxK3a9_f.css":      true,
+		"chunk.abcdef12.js":       true,
+		"chunk-ABCD1234.js":       true,
+		"index-CjK4yd1R.js":       true,
+		"jquery.min.js":           false,
+		"my-library2.js":          false,
+		"user.profile2.js":        false,
+		"ui-component20240101.js": false,
+		"build-20240101.js":       false,
+		"app-MyComponent2.js":     false,
+		"component-template.js":   false,
+		"3f9a1c2b.js":             false,
+		"logo.svg":                false,
+	} {
+		if got := hashedName(name); got != want {
+			t.Errorf("hashedName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
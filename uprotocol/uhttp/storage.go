@@ -0,0 +1,179 @@
+package uhttp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uerror"
+)
+
+// ErrObjectNotFound 存储中不存在该对象
+var ErrObjectNotFound = errors.New("storage object not found")
+
+// Storage 文件存储后端
+// 键使用 / 分隔,不能以 / 开头,不能包含 . 或 .. 路径段
+type Storage interface {
+	// Save 保存 r 的全部内容,size 为 -1 表示长度未知 (流式上传)
+	// r 返回错误时放弃保存并返回该错误
+	Save(ctx *ucontext.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open 打开对象,不存在时返回 ErrObjectNotFound
+	Open(ctx *ucontext.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象,不存在时不返回错误
+	Delete(ctx *ucontext.Context, key string) error
+}
+
+// validStorageKey 检查存储键
+func validStorageKey(key string) error {
+	if key == "." || !fs.ValidPath(key) {
+		return uerror.New("存储键无效: " + key)
+	}
+	return nil
+}
+
+// LocalStorage 本地文件系统存储
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage 创建本地文件系统存储,键对应 root 下的相对路径
+func NewLocalStorage(root string) *LocalStorage {
+	return &LocalStorage{root: root}
+}
+
+// path 键对应的文件路径
+func (s *LocalStorage) path(key string) (string, error) {
+	if err := validStorageKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+// Save 实现 Storage 接口,先写入同目录的临时文件,完成后重命名,读取失败时不留下残缺文件
+func (s *LocalStorage) Save(ctx *ucontext.Context, key string, r io.Reader, size int64, contentType string) error {
+	dst, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return uerror.Wrap(err, "创建目录失败")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return uerror.Wrap(err, "创建文件失败")
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return uerror.Wrap(err, "写入文件失败")
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		os.Remove(tmp.Name())
+		return uerror.Wrap(err, "保存文件失败")
+	}
+	return nil
+}
+
+// Open 实现 Storage 接口
+func (s *LocalStorage) Open(ctx *ucontext.Context, key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	return f, err
+}
+
+// Delete 实现 Storage 接口
+func (s *LocalStorage) Delete(ctx *ucontext.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// MemoryStorage 内存存储,用于测试和小文件
+type MemoryStorage struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+// memoryObject 内存中的对象
+type memoryObject struct {
+	data        []byte
+	contentType string
+}
+
+// NewMemoryStorage 创建内存存储
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{objects: make(map[string]memoryObject)}
+}
+
+// Save 实现 Storage 接口
+func (s *MemoryStorage) Save(ctx *ucontext.Context, key string, r io.Reader, size int64, contentType string) error {
+	if err := validStorageKey(key); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if size > 0 {
+		buf.Grow(int(size))
+	}
+	if _, err := io.Copy(&buf, r); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.objects[key] = memoryObject{data: buf.Bytes(), contentType: contentType}
+	s.mu.Unlock()
+	return nil
+}
+
+// Open 实现 Storage 接口
+func (s *MemoryStorage) Open(ctx *ucontext.Context, key string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	obj, ok := s.objects[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrObjectNotFound
+	}
+	return io.NopCloser(bytes.NewReader(obj.data)), nil
+}
+
+// Delete 实现 Storage 接口
+func (s *MemoryStorage) Delete(ctx *ucontext.Context, key string) error {
+	s.mu.Lock()
+	delete(s.objects, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// Keys 已保存的键 (排序)
+func (s *MemoryStorage) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return sortedKeys(s.objects)
+}
+
+// ContentType 对象保存时的 Content-Type
+func (s *MemoryStorage) ContentType(key string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.objects[key].contentType
+}
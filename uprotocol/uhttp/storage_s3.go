@@ -0,0 +1,367 @@
+package uhttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uerror"
+)
+
+// s3UnsignedPayload 不对请求体签名 (流式上传无法预先计算哈希)
+const s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Config S3 兼容存储配置 (AWS S3、MinIO、阿里云 OSS、腾讯云 COS 等)
+type S3Config struct {
+	Endpoint  string       // 服务地址,如 https://s3.us-east-1.amazonaws.com、http://127.0.0.1:9000
+	Region    string       // 区域,默认 us-east-1
+	Bucket    string       // 存储桶
+	AccessKey string       // 访问密钥 ID
+	SecretKey string       // 访问密钥
+	PathStyle bool         // 使用路径风格 (endpoint/bucket/key),否则使用虚拟主机风格 (bucket.endpoint/key)
+	PartSize  int64        // 长度未知时的分片大小,默认 8MB,最小 5MB
+	Client    *http.Client // HTTP 客户端,默认 http.DefaultClient
+}
+
+// S3Storage S3 兼容存储,使用 AWS Signature V4 签名
+// 长度未知的内容按 PartSize 分片上传,内存中最多缓冲一个分片
+type S3Storage struct {
+	cfg      S3Config
+	endpoint *url.URL
+}
+
+// NewS3Storage 创建 S3 兼容存储
+func NewS3Storage(cfg S3Config) (*S3Storage, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, uerror.New("S3 endpoint 无效: " + cfg.Endpoint)
+	}
+	if cfg.Bucket == "" {
+		return nil, uerror.New("S3 bucket 不能为空")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.PartSize <= 0 {
+		cfg.PartSize = 8 << 20
+	}
+	cfg.PartSize = max(cfg.PartSize, 5<<20)
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	return &S3Storage{cfg: cfg, endpoint: endpoint}, nil
+}
+
+// Save 实现 Storage 接口
+func (s *S3Storage) Save(ctx *ucontext.Context, key string, r io.Reader, size int64, contentType string) error {
+	if err := validStorageKey(key); err != nil {
+		return err
+	}
+	if size >= 0 {
+		return s.putObject(ctx, key, r, size, contentType)
+	}
+
+	// 长度未知: 第一个分片读满前结束时直接上传,否则使用分片上传
+	part := make([]byte, s.cfg.PartSize)
+	n, err := io.ReadFull(r, part)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return s.putObject(ctx, key, bytes.NewReader(part[:n]), int64(n), contentType)
+	}
+	if err != nil {
+		return err
+	}
+	return s.multipartUpload(ctx, key, r, part, contentType)
+}
+
+// Open 实现 Storage 接口
+func (s *S3Storage) Open(ctx *ucontext.Context, key string) (io.ReadCloser, error) {
+	if err := validStorageKey(key); err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete 实现 Storage 接口
+func (s *S3Storage) Delete(ctx *ucontext.Context, key string) error {
+	if err := validStorageKey(key); err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, 0, nil)
+	if err == ErrObjectNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// putObject 单次上传对象
+func (s *S3Storage) putObject(ctx *ucontext.Context, key string, r io.Reader, size int64, contentType string) error {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, nil, r, size, header)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// s3CompletedPart 已上传的分片
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// multipartUpload 分片上传,first 为已读满的第一个分片
+func (s *S3Storage) multipartUpload(ctx *ucontext.Context, key string, r io.Reader, first []byte, contentType string) error {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, 0, header)
+	if err != nil {
+		return err
+	}
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = xml.NewDecoder(resp.Body).Decode(&initiated)
+	resp.Body.Close()
+	if err != nil || initiated.UploadID == "" {
+		return uerror.New("S3 分片上传初始化失败")
+	}
+	uploadID := initiated.UploadID
+
+	parts, err := s.uploadParts(ctx, key, uploadID, r, first)
+	if err != nil {
+		// 放弃分片上传,释放已上传的分片
+		if resp, abortErr := s.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, 0, nil); abortErr == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	body, _ := xml.Marshal(struct {
+		XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+		Parts   []s3CompletedPart `xml:"Part"`
+	}{Parts: parts})
+	resp, err = s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, bytes.NewReader(body), int64(len(body)), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// CompleteMultipartUpload 出错时也可能返回 200,错误在响应体中
+	var result struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err == nil && result.XMLName.Local == "Error" {
+		return uerror.New("S3 分片上传完成失败: " + result.Code + " " + result.Message)
+	}
+	return nil
+}
+
+// uploadParts 依次上传分片
+func (s *S3Storage) uploadParts(ctx *ucontext.Context, key, uploadID string, r io.Reader, part []byte) ([]s3CompletedPart, error) {
+	var parts []s3CompletedPart
+	n := len(part)
+	for number := 1; ; number++ {
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+		resp, err := s.do(ctx, http.MethodPut, key, query, bytes.NewReader(part[:n]), int64(n), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+		parts = append(parts, s3CompletedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+
+		if n < len(part) {
+			return parts, nil
+		}
+		var readErr error
+		n, readErr = io.ReadFull(r, part)
+		if readErr == io.EOF {
+			return parts, nil
+		}
+		if readErr != nil && readErr != io.ErrUnexpectedEOF {
+			return nil, readErr
+		}
+	}
+}
+
+// objectURL 对象的 URL
+func (s *S3Storage) objectURL(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	base := strings.TrimSuffix(u.Path, "/")
+	if s.cfg.PathStyle {
+		u.Path = base + "/" + s.cfg.Bucket + "/" + key
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = base + "/" + key
+	}
+	u.RawPath = s3EscapePath(u.Path)
+	u.RawQuery = s3CanonicalQuery(query)
+	return &u
+}
+
+// do 发送签名请求,非 2xx 响应转换为错误 (404 为 ErrObjectNotFound)
+func (s *S3Storage) do(ctx *ucontext.Context, method, key string, query url.Values, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	var reqCtx context.Context = context.Background()
+	if ctx != nil {
+		reqCtx = ctx
+	}
+	req, err := http.NewRequestWithContext(reqCtx, method, s.objectURL(key, query).String(), body)
+	if err != nil {
+		return nil, uerror.Wrap(err, "创建 S3 请求失败")
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.ContentLength = size
+		if size == 0 {
+			req.Body = http.NoBody
+		}
+	}
+	s3Sign(req, s.cfg.Region, s.cfg.AccessKey, s.cfg.SecretKey, s3UnsignedPayload, time.Now())
+
+	resp, err := s.cfg.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && query.Get("uploadId") == "" {
+		return nil, ErrObjectNotFound
+	}
+	var result struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&result)
+	return nil, uerror.New(fmt.Sprintf("S3 %s %s 失败: %d %s %s", method, key, resp.StatusCode, result.Code, result.Message))
+}
+
+// s3Sign 使用 AWS Signature V4 签名请求
+// 签名 host、content-type、content-md5、range 和所有 x-amz-* 请求头
+func s3Sign(req *http.Request, region, accessKey, secretKey, payloadHash string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for k, v := range req.Header {
+		name := strings.ToLower(k)
+		if name == "content-type" || name == "content-md5" || name == "range" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := sortedKeys(headers)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3EscapePath(req.URL.Path),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	sum := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := s3HMAC([]byte("AWS4"+secretKey), date)
+	key = s3HMAC(key, region)
+	key = s3HMAC(key, "s3")
+	key = s3HMAC(key, "aws4_request")
+	signature := hex.EncodeToString(s3HMAC(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// s3HMAC HMAC-SHA256
+func s3HMAC(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3CanonicalQuery 按键排序并编码的查询字符串
+func s3CanonicalQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, k := range sortedKeys(query) {
+		for _, v := range query[k] {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(s3Escape(k, true))
+			b.WriteByte('=')
+			b.WriteString(s3Escape(v, true))
+		}
+	}
+	return b.String()
+}
+
+// s3EscapePath 编码路径,保留 /
+func s3EscapePath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return s3Escape(p, false)
+}
+
+// s3Escape 按 SigV4 规则编码 (RFC 3986 非保留字符不编码),encodeSlash 为 false 时保留 /
+func s3Escape(s string, encodeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hexDigits[c>>4])
+		b.WriteByte(hexDigits[c&15])
+	}
+	return b.String()
+}
@@ -2,6 +2,7 @@ package uhttp
 
 import (
 	"crypto/tls"
+	"net"
 
 	"github.com/whosafe/uf/uerror"
 )
@@ -37,7 +38,7 @@ func (s *Server) StartTLS(addr, certFile, keyFile string) error {
 	s.httpServer.TLSConfig = tlsConfig
 
 	// 启动 HTTPS 服务器
-	if err := s.httpServer.ListenAndServeTLS(certFile, keyFile); err != nil {
+	if err := s.serveTLS(certFile, keyFile); err != nil {
 		s.errorLogger.Error("HTTPS 服务器启动失败", "error", err)
 		return uerror.Wrap(err, "HTTPS 服务器启动失败")
 	}
@@ -64,10 +65,27 @@ func (s *Server) StartTLSWithConfig(addr string, tlsCfg *TLSConfig) error {
 	s.httpServer.TLSConfig = tlsConfig
 
 	// 启动 HTTPS 服务器
-	if err := s.httpServer.ListenAndServeTLS(tlsCfg.CertFile, tlsCfg.KeyFile); err != nil {
+	if err := s.serveTLS(tlsCfg.CertFile, tlsCfg.KeyFile); err != nil {
 		s.errorLogger.Error("HTTPS 服务器启动失败", "error", err)
 		return uerror.Wrap(err, "HTTPS 服务器启动失败")
 	}
 
 	return nil
 }
+
+// serveTLS 创建监听并提供 HTTPS 服务 (支持继承监听与热重启)
+func (s *Server) serveTLS(certFile, keyFile string) error {
+	if s.printRoutes(s.httpServer.Addr) {
+		return nil
+	}
+
+	listener, err := s.Listen()
+	if err != nil {
+		return err
+	}
+
+	s.accessLogger.Info("HTTPS 服务器启动", "addr", listener.Addr().String())
+	return s.serve(listener, func(l net.Listener) error {
+		return s.httpServer.ServeTLS(l, certFile, keyFile)
+	})
+}
@@ -0,0 +1,422 @@
+package uhttp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uerror"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// tus 协议常量
+const (
+	TusResumable  = "1.0.0"
+	tusExtensions = "creation,creation-with-upload,creation-defer-length,termination,checksum"
+	tusChecksums  = "sha1,md5,sha256,sha512"
+	tusOctets     = "application/offset+octet-stream"
+
+	// StatusChecksumMismatch 校验和不匹配 (tus checksum 扩展)
+	StatusChecksumMismatch = 460
+)
+
+// TusConfig 可续传上传配置
+type TusConfig struct {
+	Dir     string   // 分片数据目录 (必填),每个上传对应一个文件
+	Store   TusStore // 上传状态存储,默认内存
+	MaxSize int64    // 单个上传的最大字节数 (Tus-Max-Size),0 为不限制
+}
+
+// TusHook 上传完成回调
+// ctx 为请求的子 Span,元数据 tus.upload_id 为上传 ID
+type TusHook func(ctx *ucontext.Context, upload *TusUpload) error
+
+// TusHandler tus 1.0 可续传上传处理器
+// 支持 core 协议及 creation、creation-with-upload、creation-defer-length、termination、checksum 扩展
+type TusHandler struct {
+	cfg   TusConfig
+	hooks []TusHook
+	mu    sync.Mutex
+	busy  map[string]bool // 正在写入的上传 ID,同一上传同时只处理一个写请求
+}
+
+// NewTusHandler 创建可续传上传处理器
+func NewTusHandler(cfg TusConfig) (*TusHandler, error) {
+	if cfg.Dir == "" {
+		return nil, uerror.New("tus 数据目录不能为空")
+	}
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, uerror.Wrap(err, "创建 tus 数据目录失败")
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryTusStore()
+	}
+	return &TusHandler{cfg: cfg, busy: make(map[string]bool)}, nil
+}
+
+// OnComplete 注册上传完成回调,按注册顺序执行
+// 回调在完成上传的 PATCH 请求中同步执行,返回错误时该请求失败,但数据和状态保留
+func (h *TusHandler) OnComplete(hook TusHook) {
+	h.hooks = append(h.hooks, hook)
+}
+
+// Path 上传数据文件的路径
+func (h *TusHandler) Path(id string) string {
+	return filepath.Join(h.cfg.Dir, id)
+}
+
+// Tus 在 prefix 下挂载可续传上传处理器
+//
+//	POST    prefix      创建上传
+//	HEAD    prefix/:id  查询偏移量
+//	PATCH   prefix/:id  追加数据
+//	DELETE  prefix/:id  终止上传
+func (s *Server) Tus(prefix string, h *TusHandler) {
+	s.Group("").Tus(prefix, h)
+}
+
+// Tus 在分组的 prefix 下挂载可续传上传处理器,参见 Server.Tus
+func (g *Group) Tus(prefix string, h *TusHandler) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	base := g.prefix + prefix
+
+	g.OPTIONS(prefix, h.options)
+	g.POST(prefix, func(ctx *ucontext.Context, req unet.Request) error {
+		return h.create(ctx, req.(*Request), base)
+	})
+	g.HEAD(prefix+"/:id", h.head)
+	g.PATCH(prefix+"/:id", h.patch)
+	g.DELETE(prefix+"/:id", h.terminate)
+}
+
+// options 返回服务端支持的版本和扩展,不要求 Tus-Resumable
+func (h *TusHandler) options(ctx *ucontext.Context, req unet.Request) error {
+	resp := req.(*Request).response
+	resp.SetHeader("Tus-Resumable", TusResumable)
+	resp.SetHeader("Tus-Version", TusResumable)
+	resp.SetHeader("Tus-Extension", tusExtensions)
+	resp.SetHeader("Tus-Checksum-Algorithm", tusChecksums)
+	if h.cfg.MaxSize > 0 {
+		resp.SetHeader("Tus-Max-Size", strconv.FormatInt(h.cfg.MaxSize, 10))
+	}
+	resp.Status(http.StatusNoContent)
+	return nil
+}
+
+// checkVersion 检查 Tus-Resumable,不支持时返回 412
+func (h *TusHandler) checkVersion(httpReq *Request) bool {
+	resp := httpReq.response
+	resp.SetHeader("Tus-Resumable", TusResumable)
+	if httpReq.raw.Header.Get("Tus-Resumable") == TusResumable {
+		return true
+	}
+	resp.SetHeader("Tus-Version", TusResumable)
+	resp.Status(http.StatusPreconditionFailed)
+	return false
+}
+
+// create 创建上传 (creation 扩展),请求体不为空时同时写入第一块数据 (creation-with-upload)
+func (h *TusHandler) create(ctx *ucontext.Context, httpReq *Request, base string) error {
+	if !h.checkVersion(httpReq) {
+		return nil
+	}
+	resp := httpReq.response
+	header := httpReq.raw.Header
+
+	upload := &TusUpload{ID: randomFileName(""), Length: -1, CreatedAt: time.Now()}
+	if header.Get("Upload-Defer-Length") == "" {
+		length, err := strconv.ParseInt(header.Get("Upload-Length"), 10, 64)
+		if err != nil || length < 0 {
+			return resp.String(http.StatusBadRequest, "Upload-Length 无效")
+		}
+		upload.Length = length
+	} else if header.Get("Upload-Defer-Length") != "1" || header.Get("Upload-Length") != "" {
+		return resp.String(http.StatusBadRequest, "Upload-Defer-Length 无效")
+	}
+	if h.cfg.MaxSize > 0 && upload.Length > h.cfg.MaxSize {
+		return resp.String(http.StatusRequestEntityTooLarge, "超过最大上传大小")
+	}
+	metadata, err := parseTusMetadata(header.Get("Upload-Metadata"))
+	if err != nil {
+		return resp.String(http.StatusBadRequest, err.Error())
+	}
+	upload.Metadata = metadata
+
+	f, err := os.OpenFile(h.Path(upload.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return uerror.Wrap(err, "创建上传文件失败")
+	}
+	f.Close()
+	if err := h.cfg.Store.Create(ctx, upload); err != nil {
+		os.Remove(h.Path(upload.ID))
+		return err
+	}
+
+	resp.SetHeader("Location", base+"/"+upload.ID)
+	if header.Get("Content-Type") == tusOctets && httpReq.raw.ContentLength != 0 {
+		// 新上传的 ID 尚未返回给客户端,不需要加锁
+		if code, err := h.write(ctx, httpReq, upload); code != 0 || err != nil {
+			// 上传已创建,客户端可以通过 HEAD 续传
+			if err != nil {
+				return err
+			}
+			return resp.String(code, http.StatusText(code))
+		}
+		resp.SetHeader("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	} else if upload.Done() {
+		// 长度为 0 的上传创建即完成
+		if err := h.complete(ctx, upload); err != nil {
+			return err
+		}
+	}
+	resp.Status(http.StatusCreated)
+	return nil
+}
+
+// head 返回上传偏移量
+func (h *TusHandler) head(ctx *ucontext.Context, req unet.Request) error {
+	httpReq := req.(*Request)
+	if !h.checkVersion(httpReq) {
+		return nil
+	}
+	resp := httpReq.response
+	resp.SetHeader("Cache-Control", "no-store")
+
+	upload, err := h.cfg.Store.Get(ctx, httpReq.Param("id"))
+	if errors.Is(err, ErrUploadNotFound) {
+		resp.Status(http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	resp.SetHeader("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if upload.Length >= 0 {
+		resp.SetHeader("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	} else {
+		resp.SetHeader("Upload-Defer-Length", "1")
+	}
+	if len(upload.Metadata) > 0 {
+		resp.SetHeader("Upload-Metadata", encodeTusMetadata(upload.Metadata))
+	}
+	resp.Status(http.StatusOK)
+	return nil
+}
+
+// patch 在 Upload-Offset 处追加数据
+func (h *TusHandler) patch(ctx *ucontext.Context, req unet.Request) error {
+	httpReq := req.(*Request)
+	if !h.checkVersion(httpReq) {
+		return nil
+	}
+	resp := httpReq.response
+	if httpReq.raw.Header.Get("Content-Type") != tusOctets {
+		return resp.String(http.StatusUnsupportedMediaType, "Content-Type 必须为 "+tusOctets)
+	}
+
+	id := httpReq.Param("id")
+	if !h.tryLock(id) {
+		return resp.String(http.StatusLocked, "上传正在被其他请求写入")
+	}
+	defer h.unlock(id)
+
+	upload, err := h.cfg.Store.Get(ctx, id)
+	if errors.Is(err, ErrUploadNotFound) {
+		return resp.String(http.StatusNotFound, "上传不存在")
+	}
+	if err != nil {
+		return err
+	}
+	offset, err := strconv.ParseInt(httpReq.raw.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return resp.String(http.StatusBadRequest, "Upload-Offset 无效")
+	}
+	if offset != upload.Offset {
+		return resp.String(http.StatusConflict, "Upload-Offset 与服务端偏移量不一致")
+	}
+
+	// creation-defer-length: 延迟设置的长度在 PATCH 中给出
+	if value := httpReq.raw.Header.Get("Upload-Length"); value != "" {
+		length, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || length < upload.Offset || (upload.Length >= 0 && length != upload.Length) {
+			return resp.String(http.StatusBadRequest, "Upload-Length 无效")
+		}
+		if h.cfg.MaxSize > 0 && length > h.cfg.MaxSize {
+			return resp.String(http.StatusRequestEntityTooLarge, "超过最大上传大小")
+		}
+		upload.Length = length
+	}
+
+	code, err := h.write(ctx, httpReq, upload)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return resp.String(code, http.StatusText(code))
+	}
+	resp.SetHeader("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	resp.Status(http.StatusNoContent)
+	return nil
+}
+
+// write 把请求体写入数据文件并更新偏移量,完成时执行回调
+// 返回非 0 状态码表示请求被拒绝 (数据已丢弃)
+func (h *TusHandler) write(ctx *ucontext.Context, httpReq *Request, upload *TusUpload) (int, error) {
+	// checksum 扩展: Upload-Checksum: 算法 base64摘要
+	var hasher hash.Hash
+	var expected []byte
+	if value := httpReq.raw.Header.Get("Upload-Checksum"); value != "" {
+		algorithm, digest, _ := strings.Cut(value, " ")
+		hasher = newHash(algorithm)
+		var err error
+		expected, err = base64.StdEncoding.DecodeString(digest)
+		if hasher == nil || err != nil {
+			return http.StatusBadRequest, nil
+		}
+	}
+
+	// 可写入的总长度上限,-1 为不限制
+	limit := int64(-1)
+	if h.cfg.MaxSize > 0 {
+		limit = h.cfg.MaxSize
+	}
+	if upload.Length >= 0 {
+		limit = upload.Length
+	}
+
+	f, err := os.OpenFile(h.Path(upload.ID), os.O_WRONLY, 0)
+	if err != nil {
+		return 0, uerror.Wrap(err, "打开上传文件失败")
+	}
+	defer f.Close()
+	if _, err := f.Seek(upload.Offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	var body io.Reader = httpReq.raw.Body
+	if limit >= 0 {
+		body = io.LimitReader(body, limit-upload.Offset+1)
+	}
+	var w io.Writer = f
+	if hasher != nil {
+		w = io.MultiWriter(f, hasher)
+	}
+	n, copyErr := io.Copy(w, body)
+
+	// 丢弃本次数据: 超出长度或校验和不匹配
+	reject, discard := 0, false
+	switch {
+	case limit >= 0 && upload.Offset+n > limit:
+		reject = http.StatusRequestEntityTooLarge
+	case hasher != nil && copyErr == nil && !bytes.Equal(hasher.Sum(nil), expected):
+		reject = StatusChecksumMismatch
+	case hasher != nil && copyErr != nil:
+		// 带校验和的数据不完整时无法校验,整块丢弃
+		discard = true
+	}
+	if reject != 0 || discard {
+		n = 0
+		if err := f.Truncate(upload.Offset); err != nil {
+			return 0, err
+		}
+		if reject != 0 {
+			return reject, nil
+		}
+	}
+
+	// 连接中断时保留已收到的数据,客户端从新的偏移量续传
+	// 偏移量按比较并设置更新:其他实例已从同一偏移量写入时本次失败 (同一偏移量的数据相同,文件不会损坏)
+	offset := upload.Offset
+	upload.Offset += n
+	if err := h.cfg.Store.Update(ctx, upload, offset); err != nil {
+		switch {
+		case errors.Is(err, ErrUploadNotFound):
+			return http.StatusNotFound, nil
+		case errors.Is(err, ErrUploadConflict):
+			return http.StatusConflict, nil
+		}
+		return 0, err
+	}
+	if copyErr != nil {
+		return 0, uerror.Wrap(copyErr, "读取上传数据失败")
+	}
+	if upload.Done() {
+		return 0, h.complete(ctx, upload)
+	}
+	return 0, nil
+}
+
+// complete 在子 Span 中依次执行完成回调
+func (h *TusHandler) complete(ctx *ucontext.Context, upload *TusUpload) error {
+	if len(h.hooks) == 0 {
+		return nil
+	}
+	span := ucontext.NewSpanContext(ctx.Trace())
+	span.SetMetadata("tus.upload_id", upload.ID)
+	hookCtx := ucontext.NewWithContext(ucontext.WithContext(ctx.Context(), span))
+
+	for _, hook := range h.hooks {
+		if err := hook(hookCtx, upload.clone()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// terminate 终止上传 (termination 扩展),删除数据和状态
+func (h *TusHandler) terminate(ctx *ucontext.Context, req unet.Request) error {
+	httpReq := req.(*Request)
+	if !h.checkVersion(httpReq) {
+		return nil
+	}
+	resp := httpReq.response
+	id := httpReq.Param("id")
+
+	if !h.tryLock(id) {
+		return resp.String(http.StatusLocked, "上传正在被其他请求写入")
+	}
+	defer h.unlock(id)
+
+	if _, err := h.cfg.Store.Get(ctx, id); errors.Is(err, ErrUploadNotFound) {
+		return resp.String(http.StatusNotFound, "上传不存在")
+	} else if err != nil {
+		return err
+	}
+	if err := h.cfg.Store.Delete(ctx, id); err != nil {
+		return err
+	}
+	if err := os.Remove(h.Path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	resp.Status(http.StatusNoContent)
+	return nil
+}
+
+// tryLock 标记上传正在写入,已在写入时返回 false
+func (h *TusHandler) tryLock(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.busy[id] {
+		return false
+	}
+	h.busy[id] = true
+	return true
+}
+
+// unlock 清除写入标记
+func (h *TusHandler) unlock(id string) {
+	h.mu.Lock()
+	delete(h.busy, id)
+	h.mu.Unlock()
+}
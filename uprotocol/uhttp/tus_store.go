@@ -0,0 +1,248 @@
+package uhttp
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/udb/redis"
+	"github.com/whosafe/uf/uerror"
+)
+
+var (
+	// ErrUploadNotFound 上传不存在 (未创建、已终止或已过期)
+	ErrUploadNotFound = uerror.New("上传不存在")
+	// ErrUploadConflict 偏移量已被其他请求更新 (多实例同时写入同一上传)
+	ErrUploadConflict = uerror.New("上传偏移量已变化")
+)
+
+// TusUpload 可续传上传的状态
+type TusUpload struct {
+	ID        string            // 上传 ID
+	Length    int64             // 总长度,-1 表示延迟设置 (Upload-Defer-Length)
+	Offset    int64             // 已接收的字节数
+	Metadata  map[string]string // Upload-Metadata
+	CreatedAt time.Time         // 创建时间
+}
+
+// Done 是否已接收全部数据
+func (u *TusUpload) Done() bool {
+	return u.Length >= 0 && u.Offset == u.Length
+}
+
+// clone 复制上传状态
+func (u *TusUpload) clone() *TusUpload {
+	c := *u
+	if u.Metadata != nil {
+		c.Metadata = make(map[string]string, len(u.Metadata))
+		for k, v := range u.Metadata {
+			c.Metadata[k] = v
+		}
+	}
+	return &c
+}
+
+// TusStore 可续传上传的状态存储
+type TusStore interface {
+	// Create 保存新上传
+	Create(ctx *ucontext.Context, upload *TusUpload) error
+	// Get 获取上传,不存在时返回 ErrUploadNotFound
+	Get(ctx *ucontext.Context, id string) (*TusUpload, error)
+	// Update 更新长度和偏移量,offset 为写入前的偏移量
+	// 必须原子地比较并设置:上传不存在时返回 ErrUploadNotFound,当前偏移量不是 offset 时返回 ErrUploadConflict
+	Update(ctx *ucontext.Context, upload *TusUpload, offset int64) error
+	// Delete 删除上传
+	Delete(ctx *ucontext.Context, id string) error
+}
+
+// MemoryTusStore 内存状态存储 (单实例)
+type MemoryTusStore struct {
+	mu      sync.RWMutex
+	uploads map[string]*TusUpload
+}
+
+// NewMemoryTusStore 创建内存状态存储
+func NewMemoryTusStore() *MemoryTusStore {
+	return &MemoryTusStore{uploads: make(map[string]*TusUpload)}
+}
+
+// Create 实现 TusStore 接口
+func (s *MemoryTusStore) Create(ctx *ucontext.Context, upload *TusUpload) error {
+	s.mu.Lock()
+	s.uploads[upload.ID] = upload.clone()
+	s.mu.Unlock()
+	return nil
+}
+
+// Get 实现 TusStore 接口
+func (s *MemoryTusStore) Get(ctx *ucontext.Context, id string) (*TusUpload, error) {
+	s.mu.RLock()
+	upload, ok := s.uploads[id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrUploadNotFound
+	}
+	return upload.clone(), nil
+}
+
+// Update 实现 TusStore 接口
+func (s *MemoryTusStore) Update(ctx *ucontext.Context, upload *TusUpload, offset int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.uploads[upload.ID]
+	if !ok {
+		return ErrUploadNotFound
+	}
+	if current.Offset != offset {
+		return ErrUploadConflict
+	}
+	current.Length, current.Offset = upload.Length, upload.Offset
+	return nil
+}
+
+// Delete 实现 TusStore 接口
+func (s *MemoryTusStore) Delete(ctx *ucontext.Context, id string) error {
+	s.mu.Lock()
+	delete(s.uploads, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// RedisTusStore Redis 状态存储 (多实例共享,分片数据目录需要同时共享)
+// 每个上传保存为一个哈希,超过 ttl 未更新时过期
+type RedisTusStore struct {
+	conn   *redis.Connection
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisTusStore 创建 Redis 状态存储
+// prefix 默认 "tus:",ttl 默认 24 小时
+func NewRedisTusStore(conn *redis.Connection, prefix string, ttl time.Duration) *RedisTusStore {
+	if prefix == "" {
+		prefix = "tus:"
+	}
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return &RedisTusStore{conn: conn, prefix: prefix, ttl: ttl}
+}
+
+// Create 实现 TusStore 接口
+func (s *RedisTusStore) Create(ctx *ucontext.Context, upload *TusUpload) error {
+	key := s.prefix + upload.ID
+	if _, err := s.conn.HSet(ctx, key,
+		"length", upload.Length,
+		"offset", upload.Offset,
+		"metadata", encodeTusMetadata(upload.Metadata),
+		"created", upload.CreatedAt.Unix(),
+	); err != nil {
+		return err
+	}
+	_, err := s.conn.Expire(ctx, key, s.ttl)
+	return err
+}
+
+// Get 实现 TusStore 接口
+func (s *RedisTusStore) Get(ctx *ucontext.Context, id string) (*TusUpload, error) {
+	fields, err := s.conn.HGetAll(ctx, s.prefix+id)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, ErrUploadNotFound
+	}
+
+	upload := &TusUpload{ID: id}
+	if upload.Length, err = strconv.ParseInt(fields["length"], 10, 64); err != nil {
+		return nil, uerror.Wrap(err, "上传状态损坏: "+id)
+	}
+	if upload.Offset, err = strconv.ParseInt(fields["offset"], 10, 64); err != nil {
+		return nil, uerror.Wrap(err, "上传状态损坏: "+id)
+	}
+	if upload.Metadata, err = parseTusMetadata(fields["metadata"]); err != nil {
+		return nil, err
+	}
+	created, _ := strconv.ParseInt(fields["created"], 10, 64)
+	upload.CreatedAt = time.Unix(created, 0)
+	return upload, nil
+}
+
+// redisTusUpdate 比较并设置偏移量
+// 参数: KEYS[1] 状态键, ARGV[1] 写入前的偏移量, ARGV[2] 长度, ARGV[3] 新偏移量, ARGV[4] 过期秒数
+// 返回: -1 不存在 (已过期或已终止,不会重建缺少元数据的哈希), 0 偏移量已变化, 1 成功
+var redisTusUpdate = redis.NewScript(`
+local offset = redis.call('HGET', KEYS[1], 'offset')
+if not offset then
+	return -1
+end
+if offset ~= ARGV[1] then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'length', ARGV[2], 'offset', ARGV[3])
+redis.call('EXPIRE', KEYS[1], ARGV[4])
+return 1
+`)
+
+// Update 实现 TusStore 接口
+func (s *RedisTusStore) Update(ctx *ucontext.Context, upload *TusUpload, offset int64) error {
+	reply, err := s.conn.Run(ctx, redisTusUpdate, []string{s.prefix + upload.ID},
+		offset, upload.Length, upload.Offset, int64(s.ttl/time.Second))
+	if err != nil {
+		return err
+	}
+	switch reply {
+	case int64(1):
+		return nil
+	case int64(0):
+		return ErrUploadConflict
+	case int64(-1):
+		return ErrUploadNotFound
+	default:
+		return uerror.New("上传状态脚本返回格式错误")
+	}
+}
+
+// Delete 实现 TusStore 接口
+func (s *RedisTusStore) Delete(ctx *ucontext.Context, id string) error {
+	_, err := s.conn.Del(ctx, s.prefix+id)
+	return err
+}
+
+// parseTusMetadata 解析 Upload-Metadata: 键 base64值,键,...
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for header != "" {
+		var pair string
+		pair, header, _ = strings.Cut(header, ",")
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, uerror.New("Upload-Metadata 格式错误")
+		}
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, uerror.Wrap(err, "Upload-Metadata 值不是 base64: "+key)
+		}
+		metadata[key] = string(decoded)
+	}
+	return metadata, nil
+}
+
+// encodeTusMetadata 编码 Upload-Metadata (按键排序)
+func encodeTusMetadata(metadata map[string]string) string {
+	var b strings.Builder
+	for _, key := range sortedKeys(metadata) {
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(key)
+		if value := metadata[key]; value != "" {
+			b.WriteByte(' ')
+			b.WriteString(base64.StdEncoding.EncodeToString([]byte(value)))
+		}
+	}
+	return b.String()
+}
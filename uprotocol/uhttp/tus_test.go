@@ -0,0 +1,229 @@
+package uhttp
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/whosafe/uf/ucontext"
+)
+
+// tusRequest 发送 tus 请求,headers 为键值对
+func tusRequest(server *Server, method, target, body string, headers ...string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	r := httptest.NewRequest(method, target, reader)
+	r.Header.Set("Tus-Resumable", TusResumable)
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	return w
+}
+
+// TestTus 测试 tus 核心协议与扩展
+func TestTus(t *testing.T) {
+	h, err := NewTusHandler(TusConfig{Dir: t.TempDir(), MaxSize: 1 << 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var completed *TusUpload
+	var hookTrace *ucontext.TraceContext
+	h.OnComplete(func(ctx *ucontext.Context, upload *TusUpload) error {
+		completed, hookTrace = upload, ctx.Trace()
+		return nil
+	})
+
+	server := New()
+	api := server.Group("/api")
+	api.Tus("/files", h)
+
+	// OPTIONS 不要求 Tus-Resumable
+	r := httptest.NewRequest("OPTIONS", "/api/files", nil)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	if w.Code != 204 || !strings.Contains(w.Header().Get("Tus-Extension"), "checksum") || w.Header().Get("Tus-Max-Size") != "1048576" {
+		t.Fatalf("Unexpected OPTIONS response: %d %v", w.Code, w.Header())
+	}
+
+	// 缺少 Tus-Resumable
+	r = httptest.NewRequest("POST", "/api/files", nil)
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	if w.Code != 412 || w.Header().Get("Tus-Version") != TusResumable {
+		t.Errorf("Expected 412, got %d", w.Code)
+	}
+
+	metadata := "filename " + base64.StdEncoding.EncodeToString([]byte("视频.mp4")) + ",private"
+	w = tusRequest(server, "POST", "/api/files", "", "Upload-Length", "11", "Upload-Metadata", metadata)
+	location := w.Header().Get("Location")
+	if w.Code != 201 || !strings.HasPrefix(location, "/api/files/") {
+		t.Fatalf("Create failed: %d %q", w.Code, location)
+	}
+	id := strings.TrimPrefix(location, "/api/files/")
+
+	w = tusRequest(server, "HEAD", location, "")
+	if w.Code != 200 || w.Header().Get("Upload-Offset") != "0" || w.Header().Get("Upload-Length") != "11" ||
+		w.Header().Get("Cache-Control") != "no-store" || w.Header().Get("Upload-Metadata") != metadata {
+		t.Fatalf("Unexpected HEAD: %d %v", w.Code, w.Header())
+	}
+
+	patch := func(offset, body string, headers ...string) *httptest.ResponseRecorder {
+		headers = append([]string{"Content-Type", "application/offset+octet-stream", "Upload-Offset", offset}, headers...)
+		return tusRequest(server, "PATCH", location, body, headers...)
+	}
+	if w = patch("0", "hello"); w.Code != 204 || w.Header().Get("Upload-Offset") != "5" {
+		t.Fatalf("First PATCH failed: %d %s", w.Code, w.Body.String())
+	}
+	if w = patch("0", "hello"); w.Code != 409 {
+		t.Errorf("Expected 409 for stale offset, got %d", w.Code)
+	}
+	if w = tusRequest(server, "PATCH", location, "x", "Upload-Offset", "5"); w.Code != 415 {
+		t.Errorf("Expected 415 without offset content type, got %d", w.Code)
+	}
+
+	// 校验和不匹配时丢弃本次数据
+	if w = patch("5", " world", "Upload-Checksum", "sha1 "+base64.StdEncoding.EncodeToString(make([]byte, 20))); w.Code != StatusChecksumMismatch {
+		t.Errorf("Expected 460, got %d", w.Code)
+	}
+	if w = patch("5", " world", "Upload-Checksum", "crc99 AAAA"); w.Code != 400 {
+		t.Errorf("Expected 400 for unsupported checksum, got %d", w.Code)
+	}
+	if w = patch("5", " world!"); w.Code != 413 {
+		t.Errorf("Expected 413 beyond Upload-Length, got %d", w.Code)
+	}
+	if completed != nil {
+		t.Fatal("Hook must not run before completion")
+	}
+
+	sum := sha1.Sum([]byte(" world"))
+	if w = patch("5", " world", "Upload-Checksum", "sha1 "+base64.StdEncoding.EncodeToString(sum[:])); w.Code != 204 || w.Header().Get("Upload-Offset") != "11" {
+		t.Fatalf("Final PATCH failed: %d %s", w.Code, w.Body.String())
+	}
+	if data, _ := os.ReadFile(h.Path(id)); string(data) != "hello world" {
+		t.Errorf("Unexpected data: %q", data)
+	}
+	if completed == nil || completed.ID != id || completed.Metadata["filename"] != "视频.mp4" || !completed.Done() {
+		t.Fatalf("Unexpected completed upload: %+v", completed)
+	}
+	if hookTrace == nil || hookTrace.ParentSpanID == "" || hookTrace.GetMetadata("tus.upload_id") != id {
+		t.Errorf("Hook should run in a child span: %+v", hookTrace)
+	}
+
+	// termination
+	if w = tusRequest(server, "DELETE", location, ""); w.Code != 204 {
+		t.Errorf("Expected 204 for DELETE, got %d", w.Code)
+	}
+	if _, err := os.Stat(h.Path(id)); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Expected data file removed")
+	}
+	if w = tusRequest(server, "HEAD", location, ""); w.Code != 404 {
+		t.Errorf("Expected 404 after DELETE, got %d", w.Code)
+	}
+	if w = tusRequest(server, "POST", "/api/files", "", "Upload-Length", "2000000"); w.Code != 413 {
+		t.Errorf("Expected 413 beyond Tus-Max-Size, got %d", w.Code)
+	}
+}
+
+// TestTusDeferLength 测试延迟长度与创建时上传
+func TestTusDeferLength(t *testing.T) {
+	h, _ := NewTusHandler(TusConfig{Dir: t.TempDir()})
+	done := 0
+	h.OnComplete(func(ctx *ucontext.Context, upload *TusUpload) error {
+		done++
+		return nil
+	})
+	server := New()
+	server.Tus("/files/", h)
+
+	w := tusRequest(server, "POST", "/files", "abc", "Upload-Defer-Length", "1", "Content-Type", "application/offset+octet-stream")
+	location := w.Header().Get("Location")
+	if w.Code != 201 || w.Header().Get("Upload-Offset") != "3" {
+		t.Fatalf("Create with upload failed: %d %v", w.Code, w.Header())
+	}
+	if w = tusRequest(server, "HEAD", location, ""); w.Header().Get("Upload-Defer-Length") != "1" {
+		t.Errorf("Expected deferred length, got %v", w.Header())
+	}
+
+	w = tusRequest(server, "PATCH", location, "def", "Content-Type", "application/offset+octet-stream",
+		"Upload-Offset", "3", "Upload-Length", "6")
+	if w.Code != 204 || done != 1 {
+		t.Errorf("Expected completion, got %d (done=%d)", w.Code, done)
+	}
+
+	// 长度为 0 的上传创建即完成
+	if w = tusRequest(server, "POST", "/files", "", "Upload-Length", "0"); w.Code != 201 || done != 2 {
+		t.Errorf("Expected empty upload to complete, got %d (done=%d)", w.Code, done)
+	}
+}
+
+// racingTusStore 在更新前模拟其他实例的操作
+type racingTusStore struct {
+	*MemoryTusStore
+	before func(id string)
+}
+
+// Update 实现 TusStore 接口
+func (s *racingTusStore) Update(ctx *ucontext.Context, upload *TusUpload, offset int64) error {
+	if s.before != nil {
+		s.before(upload.ID)
+	}
+	return s.MemoryTusStore.Update(ctx, upload, offset)
+}
+
+// TestTusConcurrentUpdate 测试偏移量按比较并设置更新
+func TestTusConcurrentUpdate(t *testing.T) {
+	store := &racingTusStore{MemoryTusStore: NewMemoryTusStore()}
+	h, err := NewTusHandler(TusConfig{Dir: t.TempDir(), Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := New()
+	server.Tus("/files", h)
+	ctx := ucontext.New()
+
+	location := tusRequest(server, "POST", "/files", "", "Upload-Length", "10").Header().Get("Location")
+	patch := func(offset, body string) int {
+		return tusRequest(server, "PATCH", location, body, "Content-Type", tusOctets, "Upload-Offset", offset).Code
+	}
+
+	// 其他实例先从同一偏移量写入
+	store.before = func(id string) {
+		store.MemoryTusStore.Update(ctx, &TusUpload{ID: id, Length: 10, Offset: 5}, 0)
+	}
+	if code := patch("0", "hello"); code != 409 {
+		t.Errorf("Expected 409 when another instance advanced the offset, got %d", code)
+	}
+
+	// 写入期间上传被终止,不会重建状态
+	store.before = func(id string) { store.Delete(ctx, id) }
+	if code := patch("5", "world"); code != 404 {
+		t.Errorf("Expected 404 when upload was terminated, got %d", code)
+	}
+	id := strings.TrimPrefix(location, "/files/")
+	if _, err := store.Get(ctx, id); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("Expected terminated upload to stay deleted, got %v", err)
+	}
+}
+
+// TestTusMetadata 测试 Upload-Metadata 编解码
+func TestTusMetadata(t *testing.T) {
+	metadata, err := parseTusMetadata("name YS50eHQ=, flag ,type dGV4dC9wbGFpbg==")
+	if err != nil || metadata["name"] != "a.txt" || metadata["type"] != "text/plain" || metadata["flag"] != "" {
+		t.Fatalf("Unexpected metadata: %v %v", metadata, err)
+	}
+	if got := encodeTusMetadata(metadata); got != "flag,name YS50eHQ=,type dGV4dC9wbGFpbg==" {
+		t.Errorf("Unexpected encoding: %s", got)
+	}
+	if _, err := parseTusMetadata("name !!!"); err == nil {
+		t.Error("Expected error for invalid base64")
+	}
+}
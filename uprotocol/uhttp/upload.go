@@ -6,7 +6,6 @@ import (
 	"mime/multipart"
 	"os"
 	"path/filepath"
-	"time"
 
 	"github.com/whosafe/uf/uerror"
 )
@@ -115,8 +114,8 @@ func (r *Request) SaveUploadedFileWithConfig(file *multipart.FileHeader, cfg *Fi
 	// 【安全修复】强制使用 filepath.Base 清理文件名,防止路径遍历攻击
 	filename := filepath.Base(file.Filename)
 	if cfg.GenerateName {
-		ext := filepath.Ext(file.Filename)
-		filename = fmt.Sprintf("%d%s", time.Now().Unix(), ext) // 简单示例,实际应使用更好的方法
+		// 随机名称,并发上传不会重名
+		filename = randomFileName(filepath.Ext(file.Filename))
 	}
 
 	// 保存文件
@@ -0,0 +1,389 @@
+package uhttp
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uerror"
+	"github.com/whosafe/uf/uvalidator"
+	"github.com/whosafe/uf/uvalidator/rule"
+)
+
+// 上传错误
+var (
+	// ErrFileTooLarge 单个文件超过大小限制
+	ErrFileTooLarge = errors.New("upload file too large")
+	// ErrUploadTooLarge 上传内容超过总大小限制
+	ErrUploadTooLarge = errors.New("upload too large")
+	// ErrTooManyFiles 上传文件数量超过限制
+	ErrTooManyFiles = errors.New("too many upload files")
+)
+
+// UploadConfig 流式上传配置
+type UploadConfig struct {
+	Storage      Storage                         // 存储后端 (必填)
+	MaxFileSize  int64                           // 单个文件最大字节数,默认 32MB
+	MaxTotalSize int64                           // 请求体最大字节数 (所有文件和字段),默认 128MB
+	MaxFieldSize int64                           // 普通字段最大字节数,默认 1MB
+	MaxFiles     int                             // 最多文件数,0 为不限制
+	Hashes       []string                        // 上传时计算的哈希: md5, sha1, sha256, sha512,默认 sha256
+	Rules        map[string][]uvalidator.Rule    // 文件字段的验证规则
+	KeyFunc      func(file *UploadedFile) string // 生成存储键,默认 年/月/日/随机串.扩展名
+}
+
+// UploadedFile 已保存的上传文件
+type UploadedFile struct {
+	Field       string            // 表单字段名
+	Filename    string            // 客户端文件名 (已去掉路径)
+	Key         string            // 存储键
+	Size        int64             // 文件字节数
+	ContentType string            // 按文件内容探测的 MIME 类型 (不含参数)
+	Hashes      map[string]string // 算法 -> 十六进制摘要
+}
+
+// Ext 客户端文件名的扩展名 (小写,含 .)
+func (f *UploadedFile) Ext() string {
+	return strings.ToLower(path.Ext(f.Filename))
+}
+
+// UploadForm 流式上传的解析结果
+type UploadForm struct {
+	Values map[string][]string        // 普通字段
+	Files  map[string][]*UploadedFile // 文件字段
+}
+
+// Value 获取普通字段的第一个值
+func (f *UploadForm) Value(name string) string {
+	if values := f.Values[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// File 获取文件字段的第一个文件
+func (f *UploadForm) File(name string) *UploadedFile {
+	if files := f.Files[name]; len(files) > 0 {
+		return files[0]
+	}
+	return nil
+}
+
+// Upload 流式解析 multipart 请求,文件边读取边写入存储,不在内存或临时文件中缓冲
+// 按内容前 512 字节探测 MIME 类型,读取时计算哈希,按 Rules 验证。任一文件失败时
+// 删除本次已保存的文件并返回错误: 超限为 ErrFileTooLarge / ErrUploadTooLarge / ErrTooManyFiles,
+// 验证失败为 uvalidator.ValidationErrors
+func (r *Request) Upload(ctx *ucontext.Context, cfg *UploadConfig) (*UploadForm, error) {
+	if cfg.Storage == nil {
+		return nil, uerror.New("上传配置缺少 Storage")
+	}
+	u := &uploader{cfg: *cfg, ctx: ctx}
+	if u.cfg.MaxFileSize <= 0 {
+		u.cfg.MaxFileSize = 32 << 20
+	}
+	if u.cfg.MaxTotalSize <= 0 {
+		u.cfg.MaxTotalSize = 128 << 20
+	}
+	if u.cfg.MaxFieldSize <= 0 {
+		u.cfg.MaxFieldSize = 1 << 20
+	}
+	if len(u.cfg.Hashes) == 0 {
+		u.cfg.Hashes = []string{"sha256"}
+	}
+	for _, name := range u.cfg.Hashes {
+		if newHash(name) == nil {
+			return nil, uerror.New("不支持的哈希算法: " + name)
+		}
+	}
+
+	if err := r.decodeBody(); err != nil {
+		return nil, err
+	}
+	r.raw.Body = http.MaxBytesReader(r.writer, r.raw.Body, u.cfg.MaxTotalSize)
+	reader, err := r.raw.MultipartReader()
+	if err != nil {
+		return nil, uerror.Wrap(err, "解析 multipart 请求失败")
+	}
+
+	form := &UploadForm{Values: make(map[string][]string), Files: make(map[string][]*UploadedFile)}
+	if err := u.read(reader, form); err != nil {
+		u.cleanup(form)
+		return nil, err
+	}
+	if err := u.validateRequired(form); err != nil {
+		u.cleanup(form)
+		return nil, err
+	}
+	return form, nil
+}
+
+// uploader 一次流式上传的状态
+type uploader struct {
+	cfg   UploadConfig
+	ctx   *ucontext.Context
+	files int
+}
+
+// read 逐个读取 part,普通字段读入内存,文件写入存储
+func (u *uploader) read(reader *multipart.Reader, form *UploadForm) error {
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return u.readError(err)
+		}
+
+		field := part.FormName()
+		if field == "" {
+			part.Close()
+			continue
+		}
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, u.cfg.MaxFieldSize+1))
+			part.Close()
+			if err != nil {
+				return u.readError(err)
+			}
+			if int64(len(value)) > u.cfg.MaxFieldSize {
+				return uerror.Wrap(ErrUploadTooLarge, "字段 "+field+" 超过大小限制")
+			}
+			form.Values[field] = append(form.Values[field], string(value))
+			continue
+		}
+
+		u.files++
+		if u.cfg.MaxFiles > 0 && u.files > u.cfg.MaxFiles {
+			part.Close()
+			return ErrTooManyFiles
+		}
+		file, err := u.save(field, part)
+		part.Close()
+		if file != nil {
+			form.Files[field] = append(form.Files[field], file)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// save 探测类型、验证并把文件流式写入存储
+// 返回的文件已写入存储 (需要清理) 时 file 不为 nil
+func (u *uploader) save(field string, part *multipart.Part) (*UploadedFile, error) {
+	file := &UploadedFile{Field: field, Filename: path.Base(strings.ReplaceAll(part.FileName(), "\\", "/"))}
+	rules := u.cfg.Rules[field]
+	limit, sizeRule := u.fileLimit(rules)
+
+	// 读取文件头探测类型,之后与剩余内容拼接
+	head := make([]byte, 512)
+	n, err := io.ReadFull(part, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, u.readError(err)
+	}
+	head = head[:n]
+	file.ContentType = SniffContentType(head)
+
+	// 扩展名和类型在写入前验证,大小在写入后验证
+	if err := validateFile(file, rules, false); err != nil {
+		return nil, err
+	}
+
+	hashes := make([]hash.Hash, len(u.cfg.Hashes))
+	writers := make([]io.Writer, len(hashes))
+	for i, name := range u.cfg.Hashes {
+		hashes[i] = newHash(name)
+		writers[i] = hashes[i]
+	}
+	counter := &limitedCounter{r: io.MultiReader(bytes.NewReader(head), part), limit: limit}
+	content := io.TeeReader(counter, io.MultiWriter(writers...))
+
+	if u.cfg.KeyFunc != nil {
+		file.Key = u.cfg.KeyFunc(file)
+	} else {
+		file.Key = defaultUploadKey(file)
+	}
+	if err := u.cfg.Storage.Save(u.ctx, file.Key, content, -1, file.ContentType); err != nil {
+		// 存储可能已写入部分数据
+		u.cfg.Storage.Delete(u.ctx, file.Key)
+		if counter.exceeded {
+			if sizeRule != nil {
+				return nil, uvalidator.ValidationErrors{
+					uvalidator.NewFieldError(field, sizeRule.Name(), counter.n, sizeRule.GetMessage(field)),
+				}
+			}
+			return nil, uerror.Wrap(ErrFileTooLarge, "文件 "+file.Filename+" 超过大小限制")
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, ErrUploadTooLarge
+		}
+		return nil, uerror.Wrap(err, "保存上传文件失败")
+	}
+
+	file.Size = counter.n
+	file.Hashes = make(map[string]string, len(hashes))
+	for i, name := range u.cfg.Hashes {
+		file.Hashes[name] = hex.EncodeToString(hashes[i].Sum(nil))
+	}
+	if err := validateFile(file, rules, true); err != nil {
+		return file, err
+	}
+	return file, nil
+}
+
+// fileLimit 文件字段的大小上限,取 MaxFileSize 和 FileSize 规则中较小的值
+// 上限来自 FileSize 规则时同时返回该规则,超限按验证失败处理
+func (u *uploader) fileLimit(rules []uvalidator.Rule) (int64, *rule.FileSize) {
+	limit := u.cfg.MaxFileSize
+	var sizeRule *rule.FileSize
+	for _, r := range rules {
+		if size, ok := r.(*rule.FileSize); ok && size.MaxSize > 0 && size.MaxSize < limit {
+			limit, sizeRule = size.MaxSize, size
+		}
+	}
+	return limit, sizeRule
+}
+
+// readError 转换读取请求体时的错误
+func (u *uploader) readError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return ErrUploadTooLarge
+	}
+	return uerror.Wrap(err, "读取上传内容失败")
+}
+
+// validateRequired 检查 Required 规则的文件字段是否上传
+func (u *uploader) validateRequired(form *UploadForm) error {
+	var errs uvalidator.ValidationErrors
+	for field, rules := range u.cfg.Rules {
+		if len(form.Files[field]) > 0 {
+			continue
+		}
+		for _, r := range rules {
+			if _, ok := r.(*rule.Required); ok {
+				errs = append(errs, uvalidator.NewFieldError(field, r.Name(), nil, r.GetMessage(field)))
+			}
+		}
+	}
+	if errs.HasErrors() {
+		return errs
+	}
+	return nil
+}
+
+// cleanup 删除本次已保存的文件
+func (u *uploader) cleanup(form *UploadForm) {
+	for _, files := range form.Files {
+		for _, file := range files {
+			u.cfg.Storage.Delete(u.ctx, file.Key)
+		}
+	}
+}
+
+// validateFile 按规则验证文件
+// FileExtension 验证文件名,MimeType 验证探测到的类型,FileSize 验证大小 (sized 为 true 时),
+// 其他规则 (Required 除外) 的值为 *UploadedFile
+func validateFile(file *UploadedFile, rules []uvalidator.Rule, sized bool) error {
+	var errs uvalidator.ValidationErrors
+	for _, r := range rules {
+		var value any
+		switch r.(type) {
+		case *rule.Required:
+			continue
+		case *rule.FileExtension:
+			value = file.Filename
+		case *rule.MimeType:
+			value = file.ContentType
+		case *rule.FileSize:
+			if !sized {
+				continue
+			}
+			value = file.Size
+		default:
+			if !sized {
+				continue
+			}
+			value = file
+		}
+		if !r.Validate(value) {
+			errs = append(errs, uvalidator.NewFieldError(file.Field, r.Name(), value, r.GetMessage(file.Field)))
+		}
+	}
+	if errs.HasErrors() {
+		return errs
+	}
+	return nil
+}
+
+// limitedCounter 统计读取的字节数,超过 limit 时返回 ErrFileTooLarge
+type limitedCounter struct {
+	r        io.Reader
+	n        int64
+	limit    int64
+	exceeded bool
+}
+
+// Read 实现 io.Reader 接口
+func (c *limitedCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if c.n > c.limit {
+		c.exceeded = true
+		return 0, ErrFileTooLarge
+	}
+	return n, err
+}
+
+// newHash 创建哈希,不支持的算法返回 nil
+func newHash(name string) hash.Hash {
+	switch name {
+	case "md5":
+		return md5.New()
+	case "sha1":
+		return sha1.New()
+	case "sha256":
+		return sha256.New()
+	case "sha512":
+		return sha512.New()
+	}
+	return nil
+}
+
+// defaultUploadKey 默认存储键: 年/月/日/32 位随机十六进制 + 扩展名
+func defaultUploadKey(file *UploadedFile) string {
+	return time.Now().Format("2006/01/02/") + randomFileName(file.Ext())
+}
+
+// randomFileName 随机文件名,扩展名只保留字母和数字
+func randomFileName(ext string) string {
+	var b [16]byte
+	rand.Read(b[:])
+	name := hex.EncodeToString(b[:])
+
+	ext = strings.ToLower(ext)
+	if len(ext) < 2 || len(ext) > 16 {
+		return name
+	}
+	for i := 1; i < len(ext); i++ {
+		if c := ext[i]; (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return name
+		}
+	}
+	return name + ext
+}
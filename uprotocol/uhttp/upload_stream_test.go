@@ -0,0 +1,247 @@
+package uhttp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/unet"
+	"github.com/whosafe/uf/uvalidator"
+	"github.com/whosafe/uf/uvalidator/rule"
+)
+
+// pngHeader PNG 文件头
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// multipartBody 构造 multipart 请求体,parts 为 字段名, 文件名 (空为普通字段), 内容
+func multipartBody(t *testing.T, parts ...[3]string) (*bytes.Buffer, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, p := range parts {
+		var err error
+		var part io.Writer
+		if p[1] == "" {
+			part, err = w.CreateFormField(p[0])
+		} else {
+			part, err = w.CreateFormFile(p[0], p[1])
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(p[2]))
+	}
+	w.Close()
+	return &buf, w.FormDataContentType()
+}
+
+// uploadServer 使用配置处理上传的服务器,结果写入 form / err
+func uploadServer(cfg *UploadConfig, form **UploadForm, uploadErr *error) *Server {
+	server := New()
+	server.POST("/upload", func(ctx *ucontext.Context, req unet.Request) error {
+		*form, *uploadErr = req.(*Request).Upload(ctx, cfg)
+		return req.Response().String(200, "ok")
+	})
+	return server
+}
+
+// TestUpload 测试流式上传、类型探测和哈希
+func TestUpload(t *testing.T) {
+	storage := NewMemoryStorage()
+	var form *UploadForm
+	var err error
+	server := uploadServer(&UploadConfig{Storage: storage, Hashes: []string{"sha256", "md5"}}, &form, &err)
+
+	image := string(pngHeader) + strings.Repeat("x", 2000)
+	body, contentType := multipartBody(t,
+		[3]string{"title", "", "头像"},
+		[3]string{"avatar", `C:\Users\me\photo.PNG`, image},
+		[3]string{"docs", "a.txt", "hello"},
+		[3]string{"docs", "b.txt", "world"},
+	)
+	r := httptest.NewRequest("POST", "/upload", body)
+	r.Header.Set("Content-Type", contentType)
+	server.ServeHTTP(httptest.NewRecorder(), r)
+
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if form.Value("title") != "头像" || len(form.Files["docs"]) != 2 {
+		t.Fatalf("Unexpected form: %+v", form)
+	}
+	avatar := form.File("avatar")
+	sum := sha256.Sum256([]byte(image))
+	if avatar.Filename != "photo.PNG" || avatar.ContentType != "image/png" || avatar.Size != int64(len(image)) ||
+		avatar.Hashes["sha256"] != hex.EncodeToString(sum[:]) || avatar.Hashes["md5"] == "" {
+		t.Errorf("Unexpected file: %+v", avatar)
+	}
+	if !strings.HasSuffix(avatar.Key, ".png") || form.Files["docs"][0].Key == form.Files["docs"][1].Key {
+		t.Errorf("Unexpected keys: %s, %s", avatar.Key, form.Files["docs"][0].Key)
+	}
+	if form.File("docs").ContentType != "text/plain" || storage.ContentType(avatar.Key) != "image/png" {
+		t.Errorf("Unexpected content types: %s", form.File("docs").ContentType)
+	}
+
+	rc, openErr := storage.Open(nil, avatar.Key)
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	data, _ := io.ReadAll(rc)
+	if string(data) != image {
+		t.Error("Stored content mismatch")
+	}
+}
+
+// TestUploadLimits 测试大小和数量限制,失败时清理已保存的文件
+func TestUploadLimits(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  UploadConfig
+		want error
+	}{
+		{"file", UploadConfig{MaxFileSize: 100}, ErrFileTooLarge},
+		{"total", UploadConfig{MaxTotalSize: 300}, ErrUploadTooLarge},
+		{"count", UploadConfig{MaxFiles: 1}, ErrTooManyFiles},
+		{"field", UploadConfig{MaxFieldSize: 4}, ErrUploadTooLarge},
+	}
+	for _, tt := range tests {
+		storage := NewMemoryStorage()
+		tt.cfg.Storage = storage
+		var form *UploadForm
+		var err error
+		server := uploadServer(&tt.cfg, &form, &err)
+
+		body, contentType := multipartBody(t,
+			[3]string{"small", "a.txt", "small"},
+			[3]string{"note", "", "note"},
+			[3]string{"big", "b.txt", strings.Repeat("b", 200)},
+			[3]string{"note", "", "long note"},
+		)
+		r := httptest.NewRequest("POST", "/upload", body)
+		r.Header.Set("Content-Type", contentType)
+		server.ServeHTTP(httptest.NewRecorder(), r)
+
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+		if keys := storage.Keys(); len(keys) != 0 {
+			t.Errorf("%s: expected cleanup, got %v", tt.name, keys)
+		}
+	}
+}
+
+// TestUploadRules 测试 uvalidator 文件规则
+func TestUploadRules(t *testing.T) {
+	rules := map[string][]uvalidator.Rule{
+		"avatar": {rule.NewRequired(), rule.NewFileExtension("png", "jpg"), rule.NewMimeType("image/png", "image/jpeg"), rule.NewFileSize(0, 1000)},
+	}
+	tests := []struct {
+		name, filename, content, rule string
+	}{
+		{"ok", "a.png", string(pngHeader), ""},
+		{"extension", "a.gif", string(pngHeader), "file_extension"},
+		{"disguised", "a.png", "<html><script>alert(1)</script></html>", "mime_type"},
+		{"size", "a.png", string(pngHeader) + strings.Repeat("x", 2000), "file_size"},
+		{"missing", "", "", "required"},
+	}
+	for _, tt := range tests {
+		storage := NewMemoryStorage()
+		var form *UploadForm
+		var err error
+		server := uploadServer(&UploadConfig{Storage: storage, Rules: rules}, &form, &err)
+
+		parts := [][3]string{{"name", "", "x"}}
+		if tt.filename != "" {
+			parts = append(parts, [3]string{"avatar", tt.filename, tt.content})
+		}
+		body, contentType := multipartBody(t, parts...)
+		r := httptest.NewRequest("POST", "/upload", body)
+		r.Header.Set("Content-Type", contentType)
+		server.ServeHTTP(httptest.NewRecorder(), r)
+
+		if tt.rule == "" {
+			if err != nil || len(storage.Keys()) != 1 {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		var verrs uvalidator.ValidationErrors
+		if !errors.As(err, &verrs) || verrs.First().Rule != tt.rule || verrs.First().Field != "avatar" {
+			t.Errorf("%s: expected %s validation error, got %v", tt.name, tt.rule, err)
+		}
+		if len(storage.Keys()) != 0 {
+			t.Errorf("%s: expected cleanup", tt.name)
+		}
+	}
+}
+
+// TestLocalStorage 测试本地文件系统存储
+func TestLocalStorage(t *testing.T) {
+	root := t.TempDir()
+	storage := NewLocalStorage(root)
+
+	if err := storage.Save(nil, "2025/01/a.txt", strings.NewReader("hello"), 5, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "2025", "01", "a.txt")); string(data) != "hello" {
+		t.Errorf("Unexpected content: %q", data)
+	}
+
+	// 读取失败时不留下文件
+	failing := io.MultiReader(strings.NewReader("partial"), iotestErrReader{})
+	if err := storage.Save(nil, "b.txt", failing, -1, ""); err == nil {
+		t.Error("Expected read error")
+	}
+	if entries, _ := os.ReadDir(root); len(entries) != 1 {
+		t.Errorf("Expected no leftover files, got %d entries", len(entries))
+	}
+
+	for _, key := range []string{"../x", "/abs", "a/../b", "."} {
+		if err := storage.Save(nil, key, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("Expected invalid key error for %q", key)
+		}
+	}
+
+	if _, err := storage.Open(nil, "missing"); err != ErrObjectNotFound {
+		t.Errorf("Expected ErrObjectNotFound, got %v", err)
+	}
+	if err := storage.Delete(nil, "2025/01/a.txt"); err != nil {
+		t.Error(err)
+	}
+	if err := storage.Delete(nil, "2025/01/a.txt"); err != nil {
+		t.Errorf("Delete of missing key should succeed: %v", err)
+	}
+}
+
+// iotestErrReader 总是返回错误的 Reader
+type iotestErrReader struct{}
+
+func (iotestErrReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+// TestSniffContentType 测试文件头探测
+func TestSniffContentType(t *testing.T) {
+	for head, want := range map[string]string{
+		string(pngHeader):                  "image/png",
+		"\xff\xd8\xff\xe0":                 "image/jpeg",
+		"%PDF-1.7":                         "application/pdf",
+		"7z\xBC\xAF\x27\x1C\x00\x04":       "application/x-7z-compressed",
+		"\x00\x00\x00\x18ftypheic\x00\x00": "image/heic",
+		"plain text":                       "text/plain",
+		"\x00\x01\x02\x03":                 "application/octet-stream",
+	} {
+		if got := SniffContentType([]byte(head)); got != want {
+			t.Errorf("SniffContentType(%q) = %s, want %s", head, got, want)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package uhttp
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+const (
+	// HeaderAcceptVersion 客户端指定 API 版本的请求头
+	HeaderAcceptVersion = "Accept-Version"
+	// HeaderAPIVersion 响应实际使用的 API 版本
+	HeaderAPIVersion = "API-Version"
+)
+
+// Versions 版本化 API
+// 每个版本的路由同时注册在 "前缀/版本/路径" 和 "前缀/路径" 下:
+// 前者由 URL 选择版本,后者按 Accept-Version 头或 Accept 媒体类型
+// (如 application/vnd.app.v2+json) 选择,未指定或该版本没有此路由时使用最新版本
+type Versions struct {
+	group    *Group
+	versions []*Version                  // 按声明顺序,最后声明的为最新版本
+	dispatch map[string]*versionDispatch // method + " " + 相对路径
+}
+
+// Version API 版本分组,路由注册方法与 Group 相同
+type Version struct {
+	*routeGroup
+	name     string
+	key      string // 去掉 v 前缀的名称,匹配时不区分大小写
+	index    int
+	versions *Versions
+
+	// 弃用相关响应头 (Deprecate 设置)
+	deprecation string
+	sunset      string
+	links       []string
+}
+
+// Deprecation 版本弃用信息
+type Deprecation struct {
+	At     time.Time // 弃用时间 (Deprecation 头),为零时输出 "true"
+	Sunset time.Time // 停止服务时间 (Sunset 头),可选
+	Link   string    // 迁移说明文档 (Link 头 rel="deprecation"),可选
+}
+
+// versionDispatch 未带版本前缀的路由,按请求选择版本
+type versionDispatch struct {
+	versions *Versions
+	routes   []*Route // 下标与 Versions.versions 一致,该版本没有此路由时为 nil
+}
+
+// Versions 在 prefix 下创建版本化 API,参见 Group.Versions
+func (s *Server) Versions(prefix string) *Versions {
+	return s.Group("").Versions(prefix)
+}
+
+// Versions 在分组的 prefix 下创建版本化 API
+//
+//	api := server.Versions("/api")
+//	v1 := api.Version("v1")
+//	v2 := api.Version("v2")
+//	v1.GET("/users", listUsersV1) // /api/v1/users,以及 Accept-Version: v1 时的 /api/users
+//	v2.GET("/users", listUsersV2) // /api/v2/users,以及未指定版本时的 /api/users
+func (g *Group) Versions(prefix string) *Versions {
+	group := g.Group(prefix)
+	group.version = nil
+	return &Versions{
+		group:    group,
+		dispatch: make(map[string]*versionDispatch),
+	}
+}
+
+// Version 声明版本,名称作为 URL 中的版本段 (如 "v1");后声明的版本视为更新的版本
+// 按请求头匹配时忽略 v 前缀和大小写,"2"、"v2"、"V2" 均匹配 "v2";名称重复时 panic
+func (vs *Versions) Version(name string) *Version {
+	if vs.group.router.frozen {
+		panic(frozenMessage)
+	}
+	if name == "" || strings.ContainsAny(name, "/{}:*") {
+		panic("API 版本名称无效: " + name)
+	}
+	if vs.Get(name) != nil {
+		panic("API 版本重复: " + name)
+	}
+
+	v := &Version{
+		name:     name,
+		key:      versionKey(name),
+		index:    len(vs.versions),
+		versions: vs,
+	}
+	v.routeGroup = vs.group.Group("/" + name)
+	v.routeGroup.version = v
+	v.routeGroup.middlewares = []unet.MiddlewareFunc{v.headers}
+	vs.versions = append(vs.versions, v)
+	return v
+}
+
+// Get 按名称获取版本 (规则同请求头匹配),不存在时返回 nil
+func (vs *Versions) Get(name string) *Version {
+	key := versionKey(name)
+	for _, v := range vs.versions {
+		if strings.EqualFold(v.key, key) {
+			return v
+		}
+	}
+	return nil
+}
+
+// Latest 最新版本 (最后声明的版本),未声明时返回 nil
+func (vs *Versions) Latest() *Version {
+	if len(vs.versions) == 0 {
+		return nil
+	}
+	return vs.versions[len(vs.versions)-1]
+}
+
+// Name 版本名称
+func (v *Version) Name() string {
+	return v.name
+}
+
+// Deprecate 标记版本已弃用,该版本的响应带 Deprecation (RFC 9745)、Sunset (RFC 8594) 和 Link 头
+func (v *Version) Deprecate(d Deprecation) *Version {
+	if v.router.frozen {
+		panic(frozenMessage)
+	}
+
+	v.deprecation = "true"
+	if !d.At.IsZero() {
+		v.deprecation = "@" + strconv.FormatInt(d.At.Unix(), 10)
+	}
+	v.sunset = ""
+	if !d.Sunset.IsZero() {
+		v.sunset = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+	v.links = v.links[:0]
+	if d.Link != "" {
+		v.links = append(v.links, "<"+d.Link+`>; rel="deprecation"`)
+	}
+	return v
+}
+
+// Deprecated 版本是否已弃用
+func (v *Version) Deprecated() bool {
+	return v.deprecation != ""
+}
+
+// headers 版本分组的第一个中间件,写入版本及弃用相关响应头
+func (v *Version) headers(next unet.HandlerFunc) unet.HandlerFunc {
+	return func(ctx *ucontext.Context, req unet.Request) error {
+		resp := req.Response()
+		resp.SetHeader(HeaderAPIVersion, v.name)
+		if v.deprecation != "" {
+			resp.SetHeader("Deprecation", v.deprecation)
+			if v.sunset != "" {
+				resp.SetHeader("Sunset", v.sunset)
+			}
+			for _, link := range v.links {
+				resp.AddHeader("Link", link)
+			}
+		}
+		return next(ctx, req)
+	}
+}
+
+// register 版本分组注册路由后,在未带版本前缀的路径下注册分发路由
+func (v *Version) register(rt *Route) {
+	vs := v.versions
+	path := rt.pattern[len(v.routeGroup.prefix):]
+	key := rt.method + " " + path
+
+	d, ok := vs.dispatch[key]
+	if !ok {
+		d = &versionDispatch{versions: vs}
+		vs.dispatch[key] = d
+		dispatcher := vs.group.handle(rt.method, path, d.serve, nil)
+		dispatcher.direct = true
+		dispatcher.dispatch = d
+		dispatcher.handlerName = "versions" + vs.group.prefix
+	}
+	for len(d.routes) <= v.index {
+		d.routes = append(d.routes, nil)
+	}
+	d.routes[v.index] = rt
+}
+
+// serve 按请求选择版本并调用该版本路由编译后的处理器
+func (d *versionDispatch) serve(ctx *ucontext.Context, req unet.Request) error {
+	httpReq := req.(*Request)
+	rt := d.route(requestedVersion(httpReq.raw.Header))
+	httpReq.route = rt
+	httpReq.response.AddHeader("Vary", "Accept-Version, Accept")
+	return rt.handler(ctx, req)
+}
+
+// route 查找请求版本的路由,未指定或该版本没有此路由时使用有此路由的最新版本
+func (d *versionDispatch) route(requested string) *Route {
+	if requested != "" {
+		key := versionKey(requested)
+		for i, rt := range d.routes {
+			if rt != nil && strings.EqualFold(d.versions.versions[i].key, key) {
+				return rt
+			}
+		}
+	}
+	for i := len(d.routes) - 1; i >= 0; i-- {
+		if d.routes[i] != nil {
+			return d.routes[i]
+		}
+	}
+	return nil
+}
+
+// requestedVersion 从 Accept-Version 头或 Accept 媒体类型中获取请求的版本
+func requestedVersion(header http.Header) string {
+	if v := strings.TrimSpace(header.Get(HeaderAcceptVersion)); v != "" {
+		return v
+	}
+	return mediaTypeVersion(header.Get("Accept"))
+}
+
+// mediaTypeVersion 解析 application/vnd.<厂商>.<版本>[+json] 形式的媒体类型中的版本
+func mediaTypeVersion(accept string) string {
+	for accept != "" {
+		var item string
+		item, accept, _ = strings.Cut(accept, ",")
+		item, _, _ = strings.Cut(item, ";")
+		_, subtype, ok := strings.Cut(strings.TrimSpace(item), "/")
+		if !ok || !strings.HasPrefix(subtype, "vnd.") {
+			continue
+		}
+		subtype, _, _ = strings.Cut(subtype[len("vnd."):], "+")
+		if i := strings.LastIndexByte(subtype, '.'); i > 0 && i < len(subtype)-1 {
+			return subtype[i+1:]
+		}
+	}
+	return ""
+}
+
+// versionKey 去掉版本名称的 v 前缀
+func versionKey(name string) string {
+	if len(name) > 1 && (name[0] == 'v' || name[0] == 'V') {
+		return name[1:]
+	}
+	return name
+}
@@ -0,0 +1,96 @@
+package uhttp
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestVersions 测试 API 版本选择
+func TestVersions(t *testing.T) {
+	server := New()
+	api := server.Versions("/api")
+	v1 := api.Version("v1")
+	v2 := api.Version("v2")
+	v1.GET("/users/:id", namedHandler("v1"))
+	v1.GET("/legacy", namedHandler("legacy"))
+	v2.GET("/users/:id", namedHandler("v2"))
+	v2.Group("/admin").POST("/jobs", namedHandler("v2 jobs"))
+
+	tests := []struct {
+		method, target, acceptVersion, accept, want, version string
+	}{
+		{"GET", "/api/v1/users/1", "", "", "v1 id=1", "v1"},
+		{"GET", "/api/v2/users/1", "v1", "", "v2 id=1", "v2"},
+		{"GET", "/api/users/1", "", "", "v2 id=1", "v2"},
+		{"GET", "/api/users/1", "v1", "", "v1 id=1", "v1"},
+		{"GET", "/api/users/1", "1", "", "v1 id=1", "v1"},
+		{"GET", "/api/users/1", "", "application/vnd.app.v1+json", "v1 id=1", "v1"},
+		{"GET", "/api/users/1", "", "text/html, application/vnd.app.V1+json; q=0.9", "v1 id=1", "v1"},
+		{"GET", "/api/users/1", "v9", "", "v2 id=1", "v2"},
+		{"GET", "/api/legacy", "v2", "", "legacy", "v1"},
+		{"POST", "/api/admin/jobs", "", "", "v2 jobs", "v2"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.target, nil)
+		if tt.acceptVersion != "" {
+			r.Header.Set(HeaderAcceptVersion, tt.acceptVersion)
+		}
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+		if w.Body.String() != tt.want || w.Header().Get(HeaderAPIVersion) != tt.version {
+			t.Errorf("%s %s (%q %q) = %q %q, want %q %q", tt.method, tt.target, tt.acceptVersion, tt.accept,
+				w.Body.String(), w.Header().Get(HeaderAPIVersion), tt.want, tt.version)
+		}
+	}
+
+	if w := serveRoute(server, "GET", "/api/users/1"); w.Header().Get("Vary") != "Accept-Version, Accept" {
+		t.Errorf("Expected Vary header, got %q", w.Header().Get("Vary"))
+	}
+	if w := serveRoute(server, "DELETE", "/api/users/1"); w.Code != 405 {
+		t.Errorf("Expected 405, got %d", w.Code)
+	}
+	if api.Latest() != v2 || api.Get("V1") != v1 || api.Get("v3") != nil {
+		t.Error("Unexpected version lookup")
+	}
+}
+
+// TestVersionDeprecation 测试弃用版本的响应头
+func TestVersionDeprecation(t *testing.T) {
+	server := New()
+	api := server.Versions("/api")
+	v1 := api.Version("v1").Deprecate(Deprecation{
+		At:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Sunset: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Link:   "https://example.com/migrate",
+	})
+	v1.GET("/ping", namedHandler("v1"))
+	api.Version("v2").GET("/ping", namedHandler("v2"))
+
+	r := httptest.NewRequest("GET", "/api/ping", nil)
+	r.Header.Set(HeaderAcceptVersion, "v1")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	if w.Header().Get("Deprecation") != "@1735689600" ||
+		w.Header().Get("Sunset") != "Thu, 01 Jan 2026 00:00:00 GMT" ||
+		w.Header().Get("Link") != `<https://example.com/migrate>; rel="deprecation"` {
+		t.Errorf("Unexpected deprecation headers: %v", w.Header())
+	}
+
+	if w := serveRoute(server, "GET", "/api/ping"); w.Header().Get("Deprecation") != "" {
+		t.Errorf("Expected no deprecation header for v2, got %q", w.Header().Get("Deprecation"))
+	}
+	if !v1.Deprecated() {
+		t.Error("Expected v1 deprecated")
+	}
+
+	// 分发路由在路由表中不显示中间件
+	for _, rt := range server.Routes() {
+		if rt.Pattern == "/api/ping" && (rt.Handler != "versions/api" || len(rt.Middlewares) != 0) {
+			t.Errorf("Unexpected dispatch route: %+v", rt)
+		}
+	}
+}
@@ -0,0 +1,519 @@
+package uhttp
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uerror"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// wsAcceptGUID 握手使用的固定 GUID (RFC 6455 1.3)
+const wsAcceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WSHandler WebSocket 处理器
+// 处理器返回后连接自动关闭,返回正常关闭错误时不记录错误日志
+type WSHandler func(ctx *ucontext.Context, conn *WSConn) error
+
+// WSConfig WebSocket 配置
+type WSConfig struct {
+	// 缓冲区与限制
+	ReadBufferSize    int   // 读缓冲区大小
+	MaxMessageSize    int64 // 最大消息字节数 (解压后),0 表示不限制
+	WriteFragmentSize int   // 写入分片大小,0 表示不分片
+
+	// 超时与心跳
+	HandshakeTimeout time.Duration // 握手超时 (客户端)
+	WriteTimeout     time.Duration // 写超时
+	PingInterval     time.Duration // 服务端 Ping 间隔,0 表示不发送
+	PongWait         time.Duration // 读超时,每收到一帧重置,0 表示不限制
+
+	// 压缩
+	EnableCompression bool // 是否启用 permessage-deflate
+	CompressionLevel  int  // 压缩级别 (flate 级别)
+
+	// 子协议
+	Subprotocols []string // 支持的子协议,按优先级排列
+
+	// 跨站 WebSocket 劫持保护
+	// AllowedOrigins 为空时只允许与 Host 同源的 Origin;"*" 允许所有来源
+	AllowedOrigins []string
+	// CheckOrigin 自定义来源检查,设置后忽略 AllowedOrigins
+	CheckOrigin func(req *Request) bool
+}
+
+// DefaultWSConfig 默认 WebSocket 配置
+func DefaultWSConfig() *WSConfig {
+	return &WSConfig{
+		ReadBufferSize:    4096,
+		MaxMessageSize:    1 << 20, // 1MB
+		WriteFragmentSize: 0,
+		HandshakeTimeout:  10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		PingInterval:      30 * time.Second,
+		PongWait:          60 * time.Second,
+		EnableCompression: false,
+		CompressionLevel:  1, // flate.BestSpeed
+	}
+}
+
+// WS 注册 WebSocket 处理器
+// 以 GET 路由注册,全局中间件 (Trace、Session、Recovery 等) 在握手阶段生效
+func (s *Server) WS(path string, handler WSHandler, config ...*WSConfig) *Route {
+	return s.GET(path, newWSHandlerFunc(handler, config))
+}
+
+// WS 注册 WebSocket 处理器 (应用组级中间件)
+func (g *Group) WS(path string, handler WSHandler, config ...*WSConfig) *Route {
+	return g.GET(path, newWSHandlerFunc(handler, config))
+}
+
+// newWSHandlerFunc 将 WSHandler 包装为 HandlerFunc
+func newWSHandlerFunc(handler WSHandler, config []*WSConfig) unet.HandlerFunc {
+	if handler == nil {
+		panic("handler cannot be nil")
+	}
+	cfg := DefaultWSConfig()
+	if len(config) > 0 && config[0] != nil {
+		cfg = config[0]
+	}
+
+	return func(ctx *ucontext.Context, req unet.Request) error {
+		httpReq := req.(*Request)
+
+		conn, err := upgradeWS(httpReq, cfg)
+		if err != nil {
+			// 握手失败已写入错误响应
+			return nil
+		}
+		defer conn.closeConn()
+		if server := httpReq.Server(); server != nil {
+			server.trackWS(conn)
+			defer server.untrackWS(conn)
+		}
+
+		// 心跳
+		if cfg.PingInterval > 0 {
+			go conn.keepAlive(cfg.PingInterval)
+		}
+
+		// 处理器 panic 时发送 1011 关闭帧,再交给 Recovery 中间件处理
+		defer func() {
+			if r := recover(); r != nil {
+				conn.CloseWithCode(WSCloseInternalServerErr, "internal server error")
+				panic(r)
+			}
+		}()
+
+		err = handler(ctx, conn)
+		if err == nil || IsWSCloseError(err, WSCloseNormalClosure, WSCloseGoingAway, WSCloseNoStatusReceived) {
+			conn.Close()
+			return nil
+		}
+		if IsWSCloseError(err) {
+			// 对端异常关闭或协议错误,连接已关闭
+			return nil
+		}
+
+		conn.CloseWithCode(WSCloseInternalServerErr, "")
+		return err
+	}
+}
+
+// trackWS 登记进行中的连接
+func (s *Server) trackWS(conn *WSConn) {
+	s.wsMu.Lock()
+	defer s.wsMu.Unlock()
+	if s.wsConns == nil {
+		s.wsConns = make(map[*WSConn]struct{})
+	}
+	s.wsConns[conn] = struct{}{}
+	if s.wsClosing {
+		// 停止接收请求前已开始的升级
+		conn.CloseWithCode(WSCloseGoingAway, "server restarting")
+	}
+}
+
+// untrackWS 处理器返回后注销连接
+func (s *Server) untrackWS(conn *WSConn) {
+	s.wsMu.Lock()
+	defer s.wsMu.Unlock()
+	delete(s.wsConns, conn)
+	if len(s.wsConns) == 0 && s.wsIdle != nil {
+		close(s.wsIdle)
+		s.wsIdle = nil
+	}
+}
+
+// closeWebSockets 以 1001 关闭所有连接并等待处理器返回
+// 调用前应已停止接收新请求
+func (s *Server) closeWebSockets(ctx context.Context) error {
+	s.wsMu.Lock()
+	s.wsClosing = true
+	if len(s.wsConns) == 0 {
+		s.wsMu.Unlock()
+		return nil
+	}
+	if s.wsIdle == nil {
+		s.wsIdle = make(chan struct{})
+	}
+	idle := s.wsIdle
+	conns := make([]*WSConn, 0, len(s.wsConns))
+	for conn := range s.wsConns {
+		conns = append(conns, conn)
+	}
+	s.wsMu.Unlock()
+
+	for _, conn := range conns {
+		conn.CloseWithCode(WSCloseGoingAway, "server restarting")
+	}
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// keepAlive 定期发送 Ping
+func (c *WSConn) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Ping(nil); err != nil {
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// IsWebSocketUpgrade 检查请求是否为 WebSocket 升级请求
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") &&
+		headerContainsToken(r.Header, "Upgrade", "websocket")
+}
+
+// upgradeWS 完成服务端握手 (RFC 6455 4.2)
+func upgradeWS(req *Request, cfg *WSConfig) (*WSConn, error) {
+	r := req.raw
+	resp := req.response
+
+	if r.Method != http.MethodGet || !IsWebSocketUpgrade(r) {
+		resp.SetHeader("Upgrade", "websocket")
+		resp.Error(http.StatusUpgradeRequired, CodeInvalidParams, "需要 WebSocket 升级请求")
+		return nil, errors.New("websocket: not an upgrade request")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		resp.SetHeader("Sec-WebSocket-Version", "13")
+		resp.Error(http.StatusUpgradeRequired, CodeInvalidParams, "不支持的 WebSocket 版本")
+		return nil, errors.New("websocket: unsupported version")
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		resp.BadRequest("无效的 Sec-WebSocket-Key")
+		return nil, errors.New("websocket: invalid key")
+	}
+
+	// 跨站 WebSocket 劫持保护
+	if !checkWSOrigin(req, cfg) {
+		resp.Forbidden("WebSocket 来源不被允许")
+		return nil, errors.New("websocket: origin not allowed")
+	}
+
+	subprotocol := selectWSSubprotocol(r, cfg.Subprotocols)
+	compress := cfg.EnableCompression && negotiateWSDeflate(strings.Join(r.Header.Values("Sec-WebSocket-Extensions"), ","))
+
+	// 标记为长连接,超时中间件不再写入 408 而是等待处理器返回
+	if !resp.markHijacked() {
+		return nil, errors.New("websocket: request timed out")
+	}
+	netConn, brw, err := http.NewResponseController(req.writer).Hijack()
+	if err != nil {
+		resp.InternalError("WebSocket 升级失败")
+		return nil, uerror.Wrap(err, "websocket: hijack failed")
+	}
+
+	// 清除 http.Server 设置的读写超时
+	netConn.SetDeadline(time.Time{})
+
+	// 构建 101 响应,保留中间件设置的响应头 (如 X-Trace-ID、Set-Cookie)
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	b.WriteString("Sec-WebSocket-Accept: " + computeWSAccept(key) + "\r\n")
+	if subprotocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	if compress {
+		b.WriteString("Sec-WebSocket-Extensions: " + wsDeflateResponse + "\r\n")
+	}
+	for name, values := range req.writer.Header() {
+		switch http.CanonicalHeaderKey(name) {
+		case "Content-Type", "Content-Length", "Upgrade", "Connection":
+			continue
+		}
+		for _, v := range values {
+			b.WriteString(name + ": " + v + "\r\n")
+		}
+	}
+	b.WriteString("\r\n")
+
+	if cfg.WriteTimeout > 0 {
+		netConn.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout))
+	}
+	if _, err := netConn.Write([]byte(b.String())); err != nil {
+		netConn.Close()
+		return nil, uerror.Wrap(err, "websocket: write handshake failed")
+	}
+	netConn.SetWriteDeadline(time.Time{})
+
+	// 标记响应已写入,供日志中间件记录状态码
+	resp.statusCode = http.StatusSwitchingProtocols
+	resp.written = true
+
+	var br *bufio.Reader
+	if brw != nil && brw.Reader.Buffered() > 0 {
+		br = brw.Reader
+	}
+	conn := newWSConn(netConn, br, true, cfg)
+	conn.subprotocol = subprotocol
+	conn.compress = compress
+	conn.request = req
+	return conn, nil
+}
+
+// checkWSOrigin 检查请求来源
+func checkWSOrigin(req *Request, cfg *WSConfig) bool {
+	if cfg.CheckOrigin != nil {
+		return cfg.CheckOrigin(req)
+	}
+
+	origin := req.raw.Header.Get("Origin")
+	// 非浏览器客户端不发送 Origin
+	if origin == "" {
+		return true
+	}
+
+	for _, allowed := range cfg.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	if len(cfg.AllowedOrigins) > 0 {
+		return false
+	}
+
+	// 默认只允许同源
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, req.Host())
+}
+
+// selectWSSubprotocol 选择子协议
+func selectWSSubprotocol(r *http.Request, supported []string) string {
+	if len(supported) == 0 {
+		return ""
+	}
+	for _, offered := range headerTokens(r.Header, "Sec-WebSocket-Protocol") {
+		for _, s := range supported {
+			if offered == s {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// computeWSAccept 计算 Sec-WebSocket-Accept
+func computeWSAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + wsAcceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerTokens 解析逗号分隔的请求头
+func headerTokens(header http.Header, name string) []string {
+	var tokens []string
+	for _, v := range header.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tokens = append(tokens, t)
+			}
+		}
+	}
+	return tokens
+}
+
+// headerContainsToken 检查请求头是否包含指定 token (不区分大小写)
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, t := range headerTokens(header, name) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// ============================================================================
+// 客户端
+// ============================================================================
+
+// WSDialer WebSocket 客户端
+type WSDialer struct {
+	Config    *WSConfig   // 连接配置,为 nil 时使用默认配置
+	TLSConfig *tls.Config // wss 使用的 TLS 配置
+}
+
+// DialWS 使用默认配置连接 WebSocket 服务器
+func DialWS(ctx context.Context, rawURL string, header http.Header) (*WSConn, *http.Response, error) {
+	return (&WSDialer{}).Dial(ctx, rawURL, header)
+}
+
+// Dial 连接 WebSocket 服务器 (ws:// 或 wss://)
+func (d *WSDialer) Dial(ctx context.Context, rawURL string, header http.Header) (*WSConn, *http.Response, error) {
+	cfg := d.Config
+	if cfg == nil {
+		cfg = DefaultWSConfig()
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, uerror.Wrap(err, "websocket: invalid url")
+	}
+
+	var useTLS bool
+	switch u.Scheme {
+	case "ws":
+	case "wss":
+		useTLS = true
+	default:
+		return nil, nil, uerror.New("websocket: unsupported scheme " + u.Scheme)
+	}
+
+	hostPort := u.Host
+	if u.Port() == "" {
+		if useTLS {
+			hostPort = net.JoinHostPort(u.Hostname(), "443")
+		} else {
+			hostPort = net.JoinHostPort(u.Hostname(), "80")
+		}
+	}
+
+	if cfg.HandshakeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.HandshakeTimeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", hostPort)
+	if err != nil {
+		return nil, nil, uerror.Wrap(err, "websocket: dial failed")
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		netConn.SetDeadline(deadline)
+	}
+
+	if useTLS {
+		tlsConfig := d.TLSConfig.Clone()
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = u.Hostname()
+		}
+		tlsConn := tls.Client(netConn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			netConn.Close()
+			return nil, nil, uerror.Wrap(err, "websocket: tls handshake failed")
+		}
+		netConn = tlsConn
+	}
+
+	conn, resp, err := clientWSHandshake(netConn, u, header, cfg)
+	if err != nil {
+		netConn.Close()
+		return nil, resp, err
+	}
+	netConn.SetDeadline(time.Time{})
+	return conn, resp, nil
+}
+
+// clientWSHandshake 完成客户端握手
+func clientWSHandshake(netConn net.Conn, u *url.URL, header http.Header, cfg *WSConfig) (*WSConn, *http.Response, error) {
+	var keyBytes [16]byte
+	if _, err := rand.Read(keyBytes[:]); err != nil {
+		return nil, nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(keyBytes[:])
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	if req.URL.Path == "" {
+		req.URL.Path = "/"
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if len(cfg.Subprotocols) > 0 {
+		req.Header.Set("Sec-WebSocket-Protocol", strings.Join(cfg.Subprotocols, ", "))
+	}
+	if cfg.EnableCompression {
+		req.Header.Set("Sec-WebSocket-Extensions", wsDeflateOffer)
+	}
+
+	if err := req.Write(netConn); err != nil {
+		return nil, nil, uerror.Wrap(err, "websocket: write handshake failed")
+	}
+
+	br := bufio.NewReaderSize(netConn, cfg.ReadBufferSize)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, nil, uerror.Wrap(err, "websocket: read handshake failed")
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		!headerContainsToken(resp.Header, "Upgrade", "websocket") ||
+		!headerContainsToken(resp.Header, "Connection", "upgrade") {
+		return nil, resp, uerror.New(fmt.Sprintf("websocket: bad handshake status %d", resp.StatusCode))
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != computeWSAccept(key) {
+		return nil, resp, uerror.New("websocket: invalid Sec-WebSocket-Accept")
+	}
+
+	conn := newWSConn(netConn, br, false, cfg)
+	conn.subprotocol = resp.Header.Get("Sec-WebSocket-Protocol")
+	conn.compress = cfg.EnableCompression && negotiateWSDeflate(resp.Header.Get("Sec-WebSocket-Extensions"))
+	return conn, resp, nil
+}
@@ -0,0 +1,631 @@
+package uhttp
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/ubind"
+	"github.com/whosafe/uf/uprotocol/umarshal"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// WebSocket 消息类型 (RFC 6455 5.2)
+const (
+	WSTextMessage   = 1  // 文本消息
+	WSBinaryMessage = 2  // 二进制消息
+	WSCloseMessage  = 8  // 关闭帧
+	WSPingMessage   = 9  // Ping 帧
+	WSPongMessage   = 10 // Pong 帧
+
+	wsContinuation = 0 // 延续帧
+)
+
+// WebSocket 关闭码 (RFC 6455 7.4.1)
+const (
+	WSCloseNormalClosure           = 1000 // 正常关闭
+	WSCloseGoingAway               = 1001 // 端点离开
+	WSCloseProtocolError           = 1002 // 协议错误
+	WSCloseUnsupportedData         = 1003 // 不支持的数据类型
+	WSCloseNoStatusReceived        = 1005 // 未收到状态码 (不可在帧中发送)
+	WSCloseAbnormalClosure         = 1006 // 异常断开 (不可在帧中发送)
+	WSCloseInvalidFramePayloadData = 1007 // 数据格式错误 (如非 UTF-8 文本)
+	WSClosePolicyViolation         = 1008 // 违反策略
+	WSCloseMessageTooBig           = 1009 // 消息过大
+	WSCloseMandatoryExtension      = 1010 // 缺少扩展
+	WSCloseInternalServerErr       = 1011 // 服务器内部错误
+	WSCloseTLSHandshake            = 1015 // TLS 握手失败 (不可在帧中发送)
+)
+
+// 错误定义
+var (
+	// ErrWSClosed 连接已关闭
+	ErrWSClosed = errors.New("websocket: connection closed")
+
+	// ErrWSInvalidControl 控制帧负载超过 125 字节
+	ErrWSInvalidControl = errors.New("websocket: invalid control frame")
+)
+
+// WSCloseError 收到关闭帧或协议错误时返回
+type WSCloseError struct {
+	Code int    // 关闭码
+	Text string // 关闭原因
+}
+
+// Error 实现 error 接口
+func (e *WSCloseError) Error() string {
+	return "websocket: close " + strconv.Itoa(e.Code) + " " + e.Text
+}
+
+// IsWSCloseError 检查是否为指定关闭码的关闭错误,未指定关闭码时只检查类型
+func IsWSCloseError(err error, codes ...int) bool {
+	var ce *WSCloseError
+	if !errors.As(err, &ce) {
+		return false
+	}
+	if len(codes) == 0 {
+		return true
+	}
+	for _, code := range codes {
+		if ce.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// WSConn WebSocket 连接
+// 读取方法只能由一个 goroutine 调用,写入方法可以并发调用
+type WSConn struct {
+	id          string
+	conn        net.Conn
+	br          *bufio.Reader
+	isServer    bool
+	subprotocol string
+	request     *Request
+
+	// 压缩 (permessage-deflate)
+	compress         bool
+	compressionLevel int
+
+	// 限制与超时
+	maxMessageSize int64
+	writeTimeout   time.Duration
+	pongWait       time.Duration
+	fragmentSize   int
+
+	// 控制帧回调
+	pingHandler func(data []byte) error
+	pongHandler func(data []byte) error
+
+	writeMu   sync.Mutex
+	closeSent atomic.Bool
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	store   map[string]any
+	storeMu sync.RWMutex
+}
+
+// newWSConn 创建 WebSocket 连接
+func newWSConn(conn net.Conn, br *bufio.Reader, isServer bool, cfg *WSConfig) *WSConn {
+	if br == nil {
+		br = bufio.NewReaderSize(conn, cfg.ReadBufferSize)
+	}
+	return &WSConn{
+		id:               ucontext.GenerateID(),
+		conn:             conn,
+		br:               br,
+		isServer:         isServer,
+		compressionLevel: cfg.CompressionLevel,
+		maxMessageSize:   cfg.MaxMessageSize,
+		writeTimeout:     cfg.WriteTimeout,
+		pongWait:         cfg.PongWait,
+		fragmentSize:     cfg.WriteFragmentSize,
+		closed:           make(chan struct{}),
+		store:            make(map[string]any),
+	}
+}
+
+// ID 获取连接 ID
+func (c *WSConn) ID() string {
+	return c.id
+}
+
+// Subprotocol 获取协商的子协议
+func (c *WSConn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// Compressed 是否协商了 permessage-deflate 压缩
+func (c *WSConn) Compressed() bool {
+	return c.compress
+}
+
+// Request 获取握手请求 (仅服务端,处理器返回后失效)
+func (c *WSConn) Request() *Request {
+	return c.request
+}
+
+// Session 获取握手请求关联的 Session
+func (c *WSConn) Session() (unet.Session, error) {
+	if c.request == nil {
+		return nil, errors.New("session manager not found")
+	}
+	return c.request.Session()
+}
+
+// RemoteAddr 获取远程地址
+func (c *WSConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// LocalAddr 获取本地地址
+func (c *WSConn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// Get 获取连接存储的值
+func (c *WSConn) Get(key string) (any, bool) {
+	c.storeMu.RLock()
+	defer c.storeMu.RUnlock()
+	val, ok := c.store[key]
+	return val, ok
+}
+
+// Set 设置连接存储的值
+func (c *WSConn) Set(key string, value any) {
+	c.storeMu.Lock()
+	defer c.storeMu.Unlock()
+	c.store[key] = value
+}
+
+// Done 连接关闭时关闭的 channel
+func (c *WSConn) Done() <-chan struct{} {
+	return c.closed
+}
+
+// SetPingHandler 设置收到 Ping 时的回调 (默认自动回复 Pong)
+func (c *WSConn) SetPingHandler(h func(data []byte) error) {
+	c.pingHandler = h
+}
+
+// SetPongHandler 设置收到 Pong 时的回调
+func (c *WSConn) SetPongHandler(h func(data []byte) error) {
+	c.pongHandler = h
+}
+
+// ReadMessage 读取一条完整消息
+// 自动处理控制帧与分片;收到关闭帧时回复关闭帧并返回 *WSCloseError
+func (c *WSConn) ReadMessage() (messageType int, data []byte, err error) {
+	compressed := false
+
+	for {
+		if c.pongWait > 0 {
+			c.conn.SetReadDeadline(time.Now().Add(c.pongWait))
+		}
+
+		frame, err := c.readFrame()
+		if err != nil {
+			return 0, nil, c.handleReadError(err)
+		}
+
+		switch frame.opcode {
+		case WSPingMessage:
+			if c.pingHandler != nil {
+				if err := c.pingHandler(frame.payload); err != nil {
+					return 0, nil, err
+				}
+			} else if err := c.WriteControl(WSPongMessage, frame.payload); err != nil && !errors.Is(err, ErrWSClosed) {
+				return 0, nil, err
+			}
+			continue
+		case WSPongMessage:
+			if c.pongHandler != nil {
+				if err := c.pongHandler(frame.payload); err != nil {
+					return 0, nil, err
+				}
+			}
+			continue
+		case WSCloseMessage:
+			return 0, nil, c.handleCloseFrame(frame.payload)
+		case WSTextMessage, WSBinaryMessage:
+			if messageType != 0 {
+				return 0, nil, c.fail(WSCloseProtocolError, "unexpected data frame in fragmented message")
+			}
+			messageType = int(frame.opcode)
+			compressed = frame.rsv1
+		case wsContinuation:
+			if messageType == 0 {
+				return 0, nil, c.fail(WSCloseProtocolError, "unexpected continuation frame")
+			}
+			if frame.rsv1 {
+				return 0, nil, c.fail(WSCloseProtocolError, "rsv1 set on continuation frame")
+			}
+		default:
+			return 0, nil, c.fail(WSCloseProtocolError, "unknown opcode")
+		}
+
+		if c.maxMessageSize > 0 && int64(len(data)+len(frame.payload)) > c.maxMessageSize {
+			return 0, nil, c.fail(WSCloseMessageTooBig, "message too big")
+		}
+		data = append(data, frame.payload...)
+
+		if frame.fin {
+			break
+		}
+	}
+
+	if compressed {
+		data, err = wsDecompress(data, c.maxMessageSize)
+		if err != nil {
+			if errors.Is(err, errWSDecompressTooBig) {
+				return 0, nil, c.fail(WSCloseMessageTooBig, "message too big")
+			}
+			return 0, nil, c.fail(WSCloseInvalidFramePayloadData, "invalid compressed data")
+		}
+	}
+
+	if messageType == WSTextMessage && !utf8.Valid(data) {
+		return 0, nil, c.fail(WSCloseInvalidFramePayloadData, "invalid utf-8 text")
+	}
+
+	return messageType, data, nil
+}
+
+// ReadJSON 读取一条消息并绑定 JSON 数据
+func (c *WSConn) ReadJSON(obj ubind.Binder) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	val := ubind.ParseJSON(data)
+	return ubind.Bind(val, obj)
+}
+
+// WriteMessage 发送一条消息
+// 启用压缩时压缩负载,设置 WriteFragmentSize 时按大小分片发送
+func (c *WSConn) WriteMessage(messageType int, data []byte) error {
+	if messageType != WSTextMessage && messageType != WSBinaryMessage {
+		if messageType >= WSCloseMessage {
+			return c.WriteControl(messageType, data)
+		}
+		return errors.New("websocket: invalid message type")
+	}
+
+	compressed := false
+	if c.compress && len(data) > 0 {
+		var err error
+		data, err = wsCompress(data, c.compressionLevel)
+		if err != nil {
+			return err
+		}
+		compressed = true
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent.Load() {
+		return ErrWSClosed
+	}
+	c.setWriteDeadline()
+
+	// 分片发送
+	opcode := byte(messageType)
+	if c.fragmentSize > 0 && len(data) > c.fragmentSize {
+		first := true
+		for len(data) > 0 {
+			n := min(c.fragmentSize, len(data))
+			if err := c.writeFrame(n == len(data), first && compressed, opcode, data[:n]); err != nil {
+				return err
+			}
+			data = data[n:]
+			opcode = wsContinuation
+			first = false
+		}
+		return nil
+	}
+
+	return c.writeFrame(true, compressed, opcode, data)
+}
+
+// WriteText 发送文本消息
+func (c *WSConn) WriteText(text string) error {
+	return c.WriteMessage(WSTextMessage, []byte(text))
+}
+
+// WriteJSON 发送 JSON 文本消息 (使用 umarshal 序列化)
+func (c *WSConn) WriteJSON(data any) error {
+	jsonData, err := umarshal.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(WSTextMessage, jsonData)
+}
+
+// WriteControl 发送控制帧 (Ping/Pong/Close)
+func (c *WSConn) WriteControl(messageType int, data []byte) error {
+	if len(data) > 125 {
+		return ErrWSInvalidControl
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent.Load() {
+		return ErrWSClosed
+	}
+	if messageType == WSCloseMessage {
+		c.closeSent.Store(true)
+	}
+	c.setWriteDeadline()
+	return c.writeFrame(true, false, byte(messageType), data)
+}
+
+// Ping 发送 Ping 帧
+func (c *WSConn) Ping(data []byte) error {
+	return c.WriteControl(WSPingMessage, data)
+}
+
+// Close 正常关闭连接
+func (c *WSConn) Close() error {
+	return c.CloseWithCode(WSCloseNormalClosure, "")
+}
+
+// CloseWithCode 发送关闭帧后关闭底层连接
+func (c *WSConn) CloseWithCode(code int, reason string) error {
+	err := c.WriteControl(WSCloseMessage, formatWSClosePayload(code, reason))
+	if errors.Is(err, ErrWSClosed) {
+		err = nil
+	}
+	c.closeConn()
+	return err
+}
+
+// closeConn 关闭底层连接
+func (c *WSConn) closeConn() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.conn.Close()
+	})
+}
+
+// setWriteDeadline 设置写超时 (需持有 writeMu)
+func (c *WSConn) setWriteDeadline() {
+	if c.writeTimeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	}
+}
+
+// fail 以指定关闭码终止连接
+func (c *WSConn) fail(code int, reason string) error {
+	c.CloseWithCode(code, reason)
+	return &WSCloseError{Code: code, Text: reason}
+}
+
+// handleReadError 处理读取错误
+func (c *WSConn) handleReadError(err error) error {
+	var pe *wsProtocolError
+	if errors.As(err, &pe) {
+		return c.fail(pe.code, pe.reason)
+	}
+	c.closeConn()
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) {
+		return &WSCloseError{Code: WSCloseAbnormalClosure, Text: "unexpected EOF"}
+	}
+	return err
+}
+
+// handleCloseFrame 处理关闭帧:校验关闭码,回复关闭帧并关闭连接
+func (c *WSConn) handleCloseFrame(payload []byte) error {
+	code := WSCloseNoStatusReceived
+	text := ""
+
+	switch {
+	case len(payload) == 1:
+		return c.fail(WSCloseProtocolError, "invalid close payload")
+	case len(payload) >= 2:
+		code = int(binary.BigEndian.Uint16(payload))
+		if !isValidWSCloseCode(code) {
+			return c.fail(WSCloseProtocolError, "invalid close code")
+		}
+		if !utf8.Valid(payload[2:]) {
+			return c.fail(WSCloseInvalidFramePayloadData, "invalid utf-8 close reason")
+		}
+		text = string(payload[2:])
+	}
+
+	// 回复关闭帧
+	echo := code
+	if echo == WSCloseNoStatusReceived {
+		echo = WSCloseNormalClosure
+	}
+	c.CloseWithCode(echo, "")
+
+	return &WSCloseError{Code: code, Text: text}
+}
+
+// ============================================================================
+// 帧读写 (RFC 6455 5.2)
+// ============================================================================
+
+// wsFrame WebSocket 帧
+type wsFrame struct {
+	fin     bool
+	rsv1    bool
+	opcode  byte
+	payload []byte
+}
+
+// wsProtocolError 协议错误
+type wsProtocolError struct {
+	code   int
+	reason string
+}
+
+// Error 实现 error 接口
+func (e *wsProtocolError) Error() string {
+	return "websocket: " + e.reason
+}
+
+// readFrame 读取一帧
+func (c *WSConn) readFrame() (wsFrame, error) {
+	var frame wsFrame
+	var header [8]byte
+
+	if _, err := io.ReadFull(c.br, header[:2]); err != nil {
+		return frame, err
+	}
+
+	frame.fin = header[0]&0x80 != 0
+	frame.rsv1 = header[0]&0x40 != 0
+	frame.opcode = header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7f)
+
+	if header[0]&0x30 != 0 {
+		return frame, &wsProtocolError{WSCloseProtocolError, "reserved bits set"}
+	}
+	if frame.rsv1 && (!c.compress || frame.opcode >= WSCloseMessage) {
+		return frame, &wsProtocolError{WSCloseProtocolError, "rsv1 set without compression"}
+	}
+
+	switch length {
+	case 126:
+		if _, err := io.ReadFull(c.br, header[:2]); err != nil {
+			return frame, err
+		}
+		length = uint64(binary.BigEndian.Uint16(header[:2]))
+	case 127:
+		if _, err := io.ReadFull(c.br, header[:8]); err != nil {
+			return frame, err
+		}
+		length = binary.BigEndian.Uint64(header[:8])
+		if length>>63 != 0 {
+			return frame, &wsProtocolError{WSCloseProtocolError, "invalid payload length"}
+		}
+	}
+
+	// 控制帧不可分片且负载不超过 125 字节
+	if frame.opcode >= WSCloseMessage && (!frame.fin || length > 125) {
+		return frame, &wsProtocolError{WSCloseProtocolError, "invalid control frame"}
+	}
+
+	// 客户端发送的帧必须掩码,服务端发送的帧不可掩码
+	if masked != c.isServer {
+		return frame, &wsProtocolError{WSCloseProtocolError, "invalid frame masking"}
+	}
+
+	if c.maxMessageSize > 0 && length > uint64(c.maxMessageSize) {
+		return frame, &wsProtocolError{WSCloseMessageTooBig, "message too big"}
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, key[:]); err != nil {
+			return frame, err
+		}
+	}
+
+	frame.payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, frame.payload); err != nil {
+		return frame, err
+	}
+	if masked {
+		maskWSBytes(key, frame.payload)
+	}
+
+	return frame, nil
+}
+
+// writeFrame 写入一帧 (需持有 writeMu)
+func (c *WSConn) writeFrame(fin, rsv1 bool, opcode byte, payload []byte) error {
+	var header [14]byte
+	n := 2
+
+	header[0] = opcode
+	if fin {
+		header[0] |= 0x80
+	}
+	if rsv1 {
+		header[0] |= 0x40
+	}
+
+	length := len(payload)
+	switch {
+	case length <= 125:
+		header[1] = byte(length)
+	case length <= 0xffff:
+		header[1] = 126
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+		n += 2
+	default:
+		header[1] = 127
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+		n += 8
+	}
+
+	// 客户端必须使用随机掩码
+	if !c.isServer {
+		header[1] |= 0x80
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		copy(header[n:], key[:])
+		n += 4
+
+		masked := make([]byte, length)
+		copy(masked, payload)
+		maskWSBytes(key, masked)
+		payload = masked
+	}
+
+	buffers := net.Buffers{header[:n], payload}
+	_, err := buffers.WriteTo(c.conn)
+	return err
+}
+
+// maskWSBytes 对负载应用掩码 (掩码与去掩码相同)
+func maskWSBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i&3]
+	}
+}
+
+// formatWSClosePayload 构建关闭帧负载
+func formatWSClosePayload(code int, reason string) []byte {
+	if code == WSCloseNoStatusReceived {
+		return nil
+	}
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	payload := make([]byte, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	copy(payload[2:], reason)
+	return payload
+}
+
+// isValidWSCloseCode 检查关闭码是否可以出现在关闭帧中
+func isValidWSCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003:
+		return true
+	case code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package uhttp
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+)
+
+// permessage-deflate 扩展 (RFC 7692)
+// 双向使用 no_context_takeover,每条消息独立压缩,连接间可复用压缩器
+
+// wsDeflateTail 解压时追加的尾部: 同步刷新标记 + 空的最终块
+var wsDeflateTail = []byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}
+
+// errWSDecompressTooBig 解压后超过最大消息大小
+var errWSDecompressTooBig = errors.New("websocket: decompressed message too big")
+
+// wsFlateWriterPools 按压缩级别划分的压缩器池 (级别 -2 ~ 9)
+var wsFlateWriterPools [12]sync.Pool
+
+// wsFlateReaderPool 解压器池
+var wsFlateReaderPool sync.Pool
+
+// wsCompress 压缩消息负载,去除尾部同步标记
+func wsCompress(data []byte, level int) ([]byte, error) {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		level = flate.DefaultCompression
+	}
+
+	var buf bytes.Buffer
+	pool := &wsFlateWriterPools[level-flate.HuffmanOnly]
+	fw, _ := pool.Get().(*flate.Writer)
+	if fw == nil {
+		var err error
+		if fw, err = flate.NewWriter(&buf, level); err != nil {
+			return nil, err
+		}
+	} else {
+		fw.Reset(&buf)
+	}
+	defer pool.Put(fw)
+
+	if _, err := fw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := fw.Flush(); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), wsDeflateTail[:4]), nil
+}
+
+// wsDecompress 解压消息负载,limit > 0 时限制解压后大小
+func wsDecompress(data []byte, limit int64) ([]byte, error) {
+	src := io.MultiReader(bytes.NewReader(data), bytes.NewReader(wsDeflateTail))
+
+	fr, _ := wsFlateReaderPool.Get().(io.ReadCloser)
+	if fr == nil {
+		fr = flate.NewReader(src)
+	} else {
+		fr.(flate.Resetter).Reset(src, nil)
+	}
+	defer wsFlateReaderPool.Put(fr)
+
+	var r io.Reader = fr
+	if limit > 0 {
+		r = io.LimitReader(fr, limit+1)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && int64(len(out)) > limit {
+		return nil, errWSDecompressTooBig
+	}
+	return out, nil
+}
+
+// wsDeflateResponse 服务端接受 permessage-deflate 时的响应扩展
+const wsDeflateResponse = "permessage-deflate; server_no_context_takeover; client_no_context_takeover"
+
+// wsDeflateOffer 客户端请求 permessage-deflate 时的扩展
+const wsDeflateOffer = "permessage-deflate; server_no_context_takeover; client_no_context_takeover"
+
+// negotiateWSDeflate 解析客户端扩展请求,判断是否可以启用 permessage-deflate
+func negotiateWSDeflate(header string) bool {
+	for _, offer := range strings.Split(header, ",") {
+		params := strings.Split(offer, ";")
+		if strings.TrimSpace(params[0]) != "permessage-deflate" {
+			continue
+		}
+
+		ok := true
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			switch strings.TrimSpace(name) {
+			case "server_no_context_takeover", "client_no_context_takeover", "client_max_window_bits":
+			case "server_max_window_bits":
+				// 压缩器固定使用 32KB 窗口,无法满足更小的窗口要求
+				if strings.Trim(strings.TrimSpace(value), `"`) != "15" {
+					ok = false
+				}
+			default:
+				ok = false
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,349 @@
+package uhttp
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/ulogger"
+	"github.com/whosafe/uf/uprotocol/umarshal"
+)
+
+// HubMessage 广播消息 (用于多实例分发)
+type HubMessage struct {
+	Node string // 发送节点 ID
+	Room string // 房间,为空表示广播到所有连接
+	Type int    // 消息类型 (WSTextMessage / WSBinaryMessage)
+	Data []byte // 消息内容
+}
+
+// HubBroker 多实例广播分发接口
+type HubBroker interface {
+	// Publish 发布消息到其他实例
+	Publish(msg *HubMessage) error
+
+	// Subscribe 订阅其他实例的消息 (阻塞,直到 Close)
+	Subscribe(handler func(msg *HubMessage)) error
+
+	// Close 关闭订阅
+	Close() error
+}
+
+// Hub WebSocket 连接管理器
+// 管理连接与房间,支持全局广播和房间广播;设置 HubBroker 后广播会分发到其他实例
+type Hub struct {
+	nodeID string
+	conns  map[*WSConn]map[string]struct{} // 连接 -> 所在房间
+	rooms  map[string]map[*WSConn]struct{} // 房间 -> 连接
+	broker HubBroker
+	logger *ulogger.Logger
+	mu     sync.RWMutex
+}
+
+// NewHub 创建连接管理器
+func NewHub() *Hub {
+	return &Hub{
+		nodeID: ucontext.GenerateID(),
+		conns:  make(map[*WSConn]map[string]struct{}),
+		rooms:  make(map[string]map[*WSConn]struct{}),
+		logger: ulogger.Default(),
+	}
+}
+
+// NewHubWithBroker 创建支持多实例分发的连接管理器
+// 后台订阅 broker 消息,调用 Close 停止订阅
+func NewHubWithBroker(broker HubBroker) *Hub {
+	h := NewHub()
+	h.broker = broker
+
+	go func() {
+		err := broker.Subscribe(func(msg *HubMessage) {
+			// 忽略本节点发出的消息
+			if msg.Node == h.nodeID {
+				return
+			}
+			h.deliver(msg.Room, msg.Type, msg.Data)
+		})
+		if err != nil {
+			h.logger.Error("Hub 订阅失败", "error", err)
+		}
+	}()
+
+	return h
+}
+
+// SetLogger 设置日志 Logger
+func (h *Hub) SetLogger(logger *ulogger.Logger) {
+	h.logger = logger
+}
+
+// Register 注册连接
+func (h *Hub) Register(conn *WSConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.conns[conn]; !ok {
+		h.conns[conn] = make(map[string]struct{})
+	}
+}
+
+// Unregister 注销连接并退出所有房间
+func (h *Hub) Unregister(conn *WSConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for room := range h.conns[conn] {
+		h.leaveLocked(conn, room)
+	}
+	delete(h.conns, conn)
+}
+
+// Join 加入房间 (未注册的连接会自动注册)
+func (h *Hub) Join(conn *WSConn, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	rooms, ok := h.conns[conn]
+	if !ok {
+		rooms = make(map[string]struct{})
+		h.conns[conn] = rooms
+	}
+	rooms[room] = struct{}{}
+
+	members, ok := h.rooms[room]
+	if !ok {
+		members = make(map[*WSConn]struct{})
+		h.rooms[room] = members
+	}
+	members[conn] = struct{}{}
+}
+
+// Leave 退出房间
+func (h *Hub) Leave(conn *WSConn, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leaveLocked(conn, room)
+}
+
+// leaveLocked 退出房间 (需持有锁)
+func (h *Hub) leaveLocked(conn *WSConn, room string) {
+	delete(h.conns[conn], room)
+	if members, ok := h.rooms[room]; ok {
+		delete(members, conn)
+		if len(members) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+}
+
+// Count 获取连接数
+func (h *Hub) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns)
+}
+
+// RoomCount 获取房间内连接数
+func (h *Hub) RoomCount(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Rooms 获取所有房间
+func (h *Hub) Rooms() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	rooms := make([]string, 0, len(h.rooms))
+	for room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	return rooms
+}
+
+// Broadcast 广播到所有连接
+func (h *Hub) Broadcast(messageType int, data []byte) error {
+	return h.BroadcastRoom("", messageType, data)
+}
+
+// BroadcastRoom 广播到房间内所有连接,room 为空时广播到所有连接
+func (h *Hub) BroadcastRoom(room string, messageType int, data []byte) error {
+	h.deliver(room, messageType, data)
+
+	if h.broker != nil {
+		return h.broker.Publish(&HubMessage{
+			Node: h.nodeID,
+			Room: room,
+			Type: messageType,
+			Data: data,
+		})
+	}
+	return nil
+}
+
+// BroadcastJSON 以 JSON 文本消息广播到房间,room 为空时广播到所有连接
+func (h *Hub) BroadcastJSON(room string, data any) error {
+	jsonData, err := umarshal.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return h.BroadcastRoom(room, WSTextMessage, jsonData)
+}
+
+// Close 关闭所有连接并停止订阅
+func (h *Hub) Close() error {
+	h.mu.Lock()
+	conns := make([]*WSConn, 0, len(h.conns))
+	for conn := range h.conns {
+		conns = append(conns, conn)
+	}
+	h.conns = make(map[*WSConn]map[string]struct{})
+	h.rooms = make(map[string]map[*WSConn]struct{})
+	h.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.CloseWithCode(WSCloseGoingAway, "server shutdown")
+	}
+
+	if h.broker != nil {
+		return h.broker.Close()
+	}
+	return nil
+}
+
+// deliver 发送到本实例的连接
+// 写入失败的连接会被注销并关闭
+func (h *Hub) deliver(room string, messageType int, data []byte) {
+	h.mu.RLock()
+	var targets []*WSConn
+	if room == "" {
+		targets = make([]*WSConn, 0, len(h.conns))
+		for conn := range h.conns {
+			targets = append(targets, conn)
+		}
+	} else {
+		targets = make([]*WSConn, 0, len(h.rooms[room]))
+		for conn := range h.rooms[room] {
+			targets = append(targets, conn)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, conn := range targets {
+		if err := conn.WriteMessage(messageType, data); err != nil {
+			h.Unregister(conn)
+			if !errors.Is(err, ErrWSClosed) {
+				conn.CloseWithCode(WSCloseGoingAway, "")
+			}
+		}
+	}
+}
+
+// ============================================================================
+// 内存分发 (单进程多 Hub,用于测试或同进程多服务器)
+// ============================================================================
+
+// MemoryHubBroker 内存分发器
+// 同一个 MemoryHubBroker 可被多个 Hub 共享
+type MemoryHubBroker struct {
+	subscribers map[int]chan *HubMessage
+	nextID      int
+	mu          sync.RWMutex
+}
+
+// NewMemoryHubBroker 创建内存分发器
+func NewMemoryHubBroker() *MemoryHubBroker {
+	return &MemoryHubBroker{
+		subscribers: make(map[int]chan *HubMessage),
+	}
+}
+
+// Publish 发布消息到所有订阅者
+func (b *MemoryHubBroker) Publish(msg *HubMessage) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, ch := range b.subscribers {
+		ch <- msg
+	}
+	return nil
+}
+
+// Subscribe 订阅消息 (阻塞,直到 Close)
+func (b *MemoryHubBroker) Subscribe(handler func(msg *HubMessage)) error {
+	ch := make(chan *HubMessage, 256)
+
+	b.mu.Lock()
+	if b.subscribers == nil {
+		b.mu.Unlock()
+		return ErrWSClosed
+	}
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = ch
+	b.mu.Unlock()
+
+	for msg := range ch {
+		handler(msg)
+	}
+	return nil
+}
+
+// Close 关闭所有订阅
+func (b *MemoryHubBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ch := range b.subscribers {
+		close(ch)
+	}
+	b.subscribers = nil
+	return nil
+}
+
+// ============================================================================
+// 消息编码
+// ============================================================================
+
+// encodeHubMessage 编码广播消息
+// 格式: [类型 1B][节点长度 2B][节点][房间长度 2B][房间][数据]
+func encodeHubMessage(msg *HubMessage) []byte {
+	buf := make([]byte, 0, 5+len(msg.Node)+len(msg.Room)+len(msg.Data))
+	buf = append(buf, byte(msg.Type))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(msg.Node)))
+	buf = append(buf, msg.Node...)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(msg.Room)))
+	buf = append(buf, msg.Room...)
+	buf = append(buf, msg.Data...)
+	return buf
+}
+
+// errInvalidHubMessage 广播消息格式错误
+var errInvalidHubMessage = errors.New("websocket: invalid hub message")
+
+// decodeHubMessage 解码广播消息
+func decodeHubMessage(data []byte) (*HubMessage, error) {
+	msg := &HubMessage{}
+	if len(data) < 3 {
+		return nil, errInvalidHubMessage
+	}
+	msg.Type = int(data[0])
+	data = data[1:]
+
+	n := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	if len(data) < n+2 {
+		return nil, errInvalidHubMessage
+	}
+	msg.Node = string(data[:n])
+	data = data[n:]
+
+	n = int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	if len(data) < n {
+		return nil, errInvalidHubMessage
+	}
+	msg.Room = string(data[:n])
+	msg.Data = data[n:]
+
+	return msg, nil
+}
@@ -0,0 +1,77 @@
+package uhttp
+
+import (
+	"sync"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/udb/redis"
+)
+
+// RedisHubBroker 基于 Redis Pub/Sub 的多实例分发器
+// 所有实例订阅同一频道,Hub 通过节点 ID 忽略自身发出的消息
+type RedisHubBroker struct {
+	conn    *redis.Connection
+	channel string // 频道名称,默认 "ws:hub"
+	pubsub  *goredis.PubSub
+	closed  bool
+	mu      sync.Mutex
+}
+
+// NewRedisHubBroker 创建 Redis 分发器
+// conn: Redis 连接
+// channel: 频道名称,如果为空则使用默认值 "ws:hub"
+func NewRedisHubBroker(conn *redis.Connection, channel string) *RedisHubBroker {
+	if channel == "" {
+		channel = "ws:hub"
+	}
+	return &RedisHubBroker{
+		conn:    conn,
+		channel: channel,
+	}
+}
+
+// Publish 发布消息到频道
+func (b *RedisHubBroker) Publish(msg *HubMessage) error {
+	_, err := b.conn.Publish(ucontext.New(), b.channel, encodeHubMessage(msg))
+	return err
+}
+
+// Subscribe 订阅频道 (阻塞,直到 Close)
+func (b *RedisHubBroker) Subscribe(handler func(msg *HubMessage)) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrWSClosed
+	}
+	ctx := ucontext.New()
+	b.pubsub = b.conn.Subscribe(ctx, b.channel)
+	pubsub := b.pubsub
+	b.mu.Unlock()
+
+	// 等待订阅确认
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+
+	for m := range pubsub.Channel() {
+		msg, err := decodeHubMessage([]byte(m.Payload))
+		if err != nil {
+			continue
+		}
+		handler(msg)
+	}
+	return nil
+}
+
+// Close 关闭订阅
+func (b *RedisHubBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	if b.pubsub != nil {
+		return b.pubsub.Close()
+	}
+	return nil
+}
@@ -0,0 +1,452 @@
+package uhttp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+)
+
+// newWSTestServer 创建 WebSocket 测试服务器
+func newWSTestServer(t *testing.T, handler WSHandler, cfg *WSConfig) (*Server, string) {
+	t.Helper()
+
+	server := New()
+	server.Use(MiddlewareTrace(), MiddlewareRecovery())
+	server.WS("/ws", handler, cfg)
+
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	return server, "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws"
+}
+
+// echoWSHandler 回显处理器
+func echoWSHandler(ctx *ucontext.Context, conn *WSConn) error {
+	for {
+		msgType, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if err := conn.WriteMessage(msgType, data); err != nil {
+			return err
+		}
+	}
+}
+
+// TestWebSocketEcho 测试握手与文本/二进制消息
+func TestWebSocketEcho(t *testing.T) {
+	_, url := newWSTestServer(t, echoWSHandler, nil)
+
+	conn, resp, err := DialWS(context.Background(), url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	// 中间件设置的响应头应保留在握手响应中
+	if resp.Header.Get("X-Trace-ID") == "" {
+		t.Error("Expected X-Trace-ID header in handshake response")
+	}
+
+	if err := conn.WriteText("hello"); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	msgType, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if msgType != WSTextMessage || string(data) != "hello" {
+		t.Errorf("Expected text 'hello', got %d '%s'", msgType, data)
+	}
+
+	// 大于 65535 字节的二进制消息 (64 位长度)
+	big := bytes.Repeat([]byte{0xab}, 70000)
+	conn.WriteMessage(WSBinaryMessage, big)
+	msgType, data, err = conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if msgType != WSBinaryMessage || !bytes.Equal(data, big) {
+		t.Errorf("Binary message mismatch, got type %d len %d", msgType, len(data))
+	}
+}
+
+// TestWebSocketFragmentation 测试分片消息
+func TestWebSocketFragmentation(t *testing.T) {
+	_, url := newWSTestServer(t, echoWSHandler, nil)
+
+	cfg := DefaultWSConfig()
+	cfg.WriteFragmentSize = 7
+	conn, _, err := (&WSDialer{Config: cfg}).Dial(context.Background(), url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	text := "分片消息 fragmented message"
+	conn.WriteText(text)
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if string(data) != text {
+		t.Errorf("Expected '%s', got '%s'", text, data)
+	}
+}
+
+// TestWebSocketCompression 测试 permessage-deflate
+func TestWebSocketCompression(t *testing.T) {
+	serverCfg := DefaultWSConfig()
+	serverCfg.EnableCompression = true
+
+	compressed := make(chan bool, 1)
+	_, url := newWSTestServer(t, func(ctx *ucontext.Context, conn *WSConn) error {
+		compressed <- conn.Compressed()
+		return echoWSHandler(ctx, conn)
+	}, serverCfg)
+
+	clientCfg := DefaultWSConfig()
+	clientCfg.EnableCompression = true
+	conn, resp, err := (&WSDialer{Config: clientCfg}).Dial(context.Background(), url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	if !strings.Contains(resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate") {
+		t.Error("Expected permessage-deflate to be negotiated")
+	}
+	if !<-compressed {
+		t.Error("Server connection should be compressed")
+	}
+
+	text := strings.Repeat("compress me ", 1000)
+	for i := 0; i < 3; i++ {
+		conn.WriteText(text)
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if string(data) != text {
+			t.Fatalf("Compressed echo mismatch, got len %d", len(data))
+		}
+	}
+}
+
+// TestWebSocketPingClose 测试 Ping/Pong 与关闭码
+func TestWebSocketPingClose(t *testing.T) {
+	closeErr := make(chan error, 1)
+	_, url := newWSTestServer(t, func(ctx *ucontext.Context, conn *WSConn) error {
+		_, _, err := conn.ReadMessage()
+		closeErr <- err
+		return err
+	}, nil)
+
+	conn, _, err := DialWS(context.Background(), url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+
+	pong := make(chan string, 1)
+	conn.SetPongHandler(func(data []byte) error {
+		pong <- string(data)
+		return nil
+	})
+	conn.Ping([]byte("ping"))
+
+	// 读取 Pong,随后发送关闭帧并读取服务器回复
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		conn.WriteControl(WSCloseMessage, formatWSClosePayload(4001, "bye"))
+	}()
+	// 服务器回显关闭码
+	_, _, err = conn.ReadMessage()
+	if !IsWSCloseError(err, 4001) {
+		t.Errorf("Expected echoed close 4001, got %v", err)
+	}
+
+	select {
+	case got := <-pong:
+		if got != "ping" {
+			t.Errorf("Expected pong 'ping', got '%s'", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Pong was not received")
+	}
+
+	select {
+	case err := <-closeErr:
+		if !IsWSCloseError(err, 4001) {
+			t.Errorf("Expected server to receive close 4001, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Server did not receive close frame")
+	}
+}
+
+// TestWebSocketUnmaskedFrame 测试客户端未掩码帧触发协议错误
+func TestWebSocketUnmaskedFrame(t *testing.T) {
+	closeErr := make(chan error, 1)
+	_, url := newWSTestServer(t, func(ctx *ucontext.Context, conn *WSConn) error {
+		_, _, err := conn.ReadMessage()
+		closeErr <- err
+		return err
+	}, nil)
+
+	conn, _, err := DialWS(context.Background(), url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	// 伪装为服务端写入未掩码帧
+	conn.isServer = true
+	conn.WriteText("unmasked")
+
+	select {
+	case err := <-closeErr:
+		if !IsWSCloseError(err, WSCloseProtocolError) {
+			t.Errorf("Expected protocol error, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Server did not reject unmasked frame")
+	}
+}
+
+// TestWebSocketHandshakeErrors 测试握手失败
+func TestWebSocketHandshakeErrors(t *testing.T) {
+	server := New()
+	server.WS("/ws", echoWSHandler)
+
+	// 非升级请求
+	req := httptest.NewRequest("GET", "/ws", nil)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusUpgradeRequired {
+		t.Errorf("Expected status 426, got %d", w.Code)
+	}
+
+	// 跨站来源
+	req = httptest.NewRequest("GET", "/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Origin", "http://evil.example.com")
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+}
+
+// TestWebSocketTimeout 测试超时中间件不中断已升级的连接
+func TestWebSocketTimeout(t *testing.T) {
+	server := New()
+	server.Use(MiddlewareTimeout(100 * time.Millisecond))
+	server.WS("/ws", func(ctx *ucontext.Context, conn *WSConn) error {
+		time.Sleep(300 * time.Millisecond)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return echoWSHandler(ctx, conn)
+	}, nil)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	conn, _, err := DialWS(context.Background(), "ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.WriteText("still here"); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != "still here" {
+		t.Errorf("Expected echo after timeout elapsed, got %q %v", data, err)
+	}
+
+	// 超时中间件已接管时不能再劫持连接
+	resp := &Response{}
+	if !resp.markTimedOut() || resp.markHijacked() || resp.IsHijacked() {
+		t.Error("Expected hijack to fail after timeout")
+	}
+}
+
+// TestWebSocketHandoffClose 测试热重启交接时以 1001 关闭连接并等待处理器返回
+func TestWebSocketHandoffClose(t *testing.T) {
+	returned := make(chan struct{})
+	server, url := newWSTestServer(t, func(ctx *ucontext.Context, conn *WSConn) error {
+		defer close(returned)
+		return echoWSHandler(ctx, conn)
+	}, nil)
+
+	conn, _, err := DialWS(context.Background(), url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	conn.WriteText("hi")
+	conn.ReadMessage()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := server.closeWebSockets(ctx); err != nil {
+		t.Fatalf("closeWebSockets: %v", err)
+	}
+	select {
+	case <-returned:
+	default:
+		t.Error("Expected handler to return before closeWebSockets")
+	}
+	if _, _, err := conn.ReadMessage(); !IsWSCloseError(err, WSCloseGoingAway) {
+		t.Errorf("Expected close 1001, got %v", err)
+	}
+}
+
+// TestWebSocketAccept 测试 RFC 6455 示例中的 Accept 计算
+func TestWebSocketAccept(t *testing.T) {
+	if got := computeWSAccept("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Unexpected accept key: %s", got)
+	}
+}
+
+// TestWebSocketRawHandshake 测试原始握手
+func TestWebSocketRawHandshake(t *testing.T) {
+	_, url := newWSTestServer(t, echoWSHandler, nil)
+	addr := strings.TrimPrefix(strings.TrimSuffix(url, "/ws"), "ws://")
+
+	raw, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer raw.Close()
+
+	raw.Write([]byte("GET /ws HTTP/1.1\r\nHost: " + addr + "\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+
+	resp, err := http.ReadResponse(bufio.NewReader(raw), nil)
+	if err != nil {
+		t.Fatalf("Read response failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("Expected status 101, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Unexpected accept header: %s", resp.Header.Get("Sec-WebSocket-Accept"))
+	}
+}
+
+// TestWebSocketHub 测试房间与广播
+func TestWebSocketHub(t *testing.T) {
+	hub := NewHub()
+	joined := make(chan struct{}, 2)
+
+	_, url := newWSTestServer(t, func(ctx *ucontext.Context, conn *WSConn) error {
+		hub.Register(conn)
+		defer hub.Unregister(conn)
+
+		room := conn.Request().Query("room")
+		if room != "" {
+			hub.Join(conn, room)
+		}
+		joined <- struct{}{}
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return err
+			}
+		}
+	}, nil)
+
+	a, _, err := DialWS(context.Background(), url+"?room=a", nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer a.Close()
+	b, _, err := DialWS(context.Background(), url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer b.Close()
+	<-joined
+	<-joined
+
+	if hub.Count() != 2 || hub.RoomCount("a") != 1 {
+		t.Fatalf("Unexpected hub state: count=%d room a=%d", hub.Count(), hub.RoomCount("a"))
+	}
+
+	hub.BroadcastRoom("a", WSTextMessage, []byte("room"))
+	hub.Broadcast(WSTextMessage, []byte("all"))
+
+	for _, want := range []string{"room", "all"} {
+		_, data, err := a.ReadMessage()
+		if err != nil || string(data) != want {
+			t.Errorf("Client a expected '%s', got '%s' (%v)", want, data, err)
+		}
+	}
+	_, data, err := b.ReadMessage()
+	if err != nil || string(data) != "all" {
+		t.Errorf("Client b expected 'all', got '%s' (%v)", data, err)
+	}
+}
+
+// TestWebSocketHubBroker 测试多实例分发
+func TestWebSocketHubBroker(t *testing.T) {
+	broker := NewMemoryHubBroker()
+	hub1 := NewHubWithBroker(broker)
+	hub2 := NewHubWithBroker(broker)
+	defer broker.Close()
+
+	ready := make(chan struct{})
+	_, url := newWSTestServer(t, func(ctx *ucontext.Context, conn *WSConn) error {
+		hub2.Join(conn, "news")
+		defer hub2.Unregister(conn)
+		close(ready)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return err
+			}
+		}
+	}, nil)
+
+	conn, _, err := DialWS(context.Background(), url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	<-ready
+
+	// 等待订阅建立
+	time.Sleep(20 * time.Millisecond)
+
+	// hub1 广播,hub2 的连接收到
+	hub1.BroadcastRoom("news", WSTextMessage, []byte("from node 1"))
+
+	conn.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil || string(data) != "from node 1" {
+		t.Errorf("Expected 'from node 1', got '%s' (%v)", data, err)
+	}
+}
+
+// TestHubMessageCodec 测试广播消息编解码
+func TestHubMessageCodec(t *testing.T) {
+	msg := &HubMessage{Node: "n1", Room: "room", Type: WSBinaryMessage, Data: []byte{0, 1, 2}}
+	got, err := decodeHubMessage(encodeHubMessage(msg))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if got.Node != msg.Node || got.Room != msg.Room || got.Type != msg.Type || !bytes.Equal(got.Data, msg.Data) {
+		t.Errorf("Codec mismatch: %+v", got)
+	}
+}
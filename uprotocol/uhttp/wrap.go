@@ -0,0 +1,201 @@
+package uhttp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uerror"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// mountParam Mount 注册的通配符参数名称
+const mountParam = "mountPath"
+
+// WrapHandler 将 http.Handler 转换为处理器
+// 请求的 context 携带追踪信息 (ucontext.FromContext 可取出),路径参数可通过 r.PathValue 获取;
+// 写入响应时同步 Response 的状态码,访问日志等中间件可以正常记录
+func WrapHandler(h http.Handler) unet.HandlerFunc {
+	return func(ctx *ucontext.Context, req unet.Request) error {
+		httpReq := req.(*Request)
+		raw := httpReq.raw.WithContext(stdContext(ctx))
+		for _, p := range httpReq.params {
+			raw.SetPathValue(p.Key, p.Value)
+		}
+		h.ServeHTTP(&responseWriter{ResponseWriter: httpReq.response.writer, resp: httpReq.response}, raw)
+		return nil
+	}
+}
+
+// wrapCallKey 标准中间件调用状态在 context 中的 key
+type wrapCallKey struct{}
+
+// wrapCall 一次标准中间件调用的状态
+type wrapCall struct {
+	next unet.HandlerFunc
+	req  *Request
+	err  error
+}
+
+// WrapMiddleware 将标准库中间件 func(http.Handler) http.Handler 转换为中间件
+// 标准中间件替换的 *http.Request (如注入 context 值) 和 ResponseWriter (如压缩) 对后续处理器生效;
+// 标准中间件未调用下一个处理器时 (如鉴权失败直接写响应),后续处理器不会执行
+func WrapMiddleware(mw func(http.Handler) http.Handler) unet.MiddlewareFunc {
+	// 标准中间件只构造一次,调用状态通过请求 context 传递
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call, ok := r.Context().Value(wrapCallKey{}).(*wrapCall)
+		if !ok {
+			http.Error(w, "中间件丢失了请求上下文", http.StatusInternalServerError)
+			return
+		}
+
+		req := call.req
+		raw, writer := req.raw, req.response.writer
+		req.raw, req.response.request = r, r
+		if rw, ok := w.(*responseWriter); !ok || rw.resp != req.response {
+			req.writer, req.response.writer = w, w
+		}
+		defer func() {
+			req.raw, req.response.request = raw, raw
+			req.writer, req.response.writer = writer, writer
+		}()
+
+		call.err = call.next(ucontext.NewWithContext(r.Context()), req)
+	}))
+
+	return func(next unet.HandlerFunc) unet.HandlerFunc {
+		return func(ctx *ucontext.Context, req unet.Request) error {
+			httpReq := req.(*Request)
+			call := &wrapCall{next: next, req: httpReq}
+			raw := httpReq.raw.WithContext(context.WithValue(stdContext(ctx), wrapCallKey{}, call))
+			h.ServeHTTP(&responseWriter{ResponseWriter: httpReq.response.writer, resp: httpReq.response}, raw)
+			return call.err
+		}
+	}
+}
+
+// Mount 挂载 http.Handler 到前缀下,转发前去掉前缀
+// 前缀本身及其下所有路径、所有方法均转发,处理器看到的路径以 "/" 开头;
+// 需要保留完整路径的处理器 (如 net/http/pprof) 使用 Handle("/debug/pprof/{path...}", WrapHandler(h))
+func (s *Server) Mount(prefix string, h http.Handler) {
+	s.Group("").Mount(prefix, h)
+}
+
+// Mount 挂载 http.Handler 到分组前缀下,参见 Server.Mount
+func (g *Group) Mount(prefix string, h http.Handler) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix != "" && prefix[0] != '/' {
+		panic("挂载前缀必须以 / 开头: " + prefix)
+	}
+
+	handler := stripPrefix(WrapHandler(h))
+	name := handlerName(h)
+	if g.prefix+prefix != "" {
+		g.handle(methodAny, prefix, handler, nil).handlerName = name
+	}
+	g.handle(methodAny, prefix+"/{"+mountParam+"...}", handler, nil).handlerName = name
+}
+
+// stripPrefix 将请求路径替换为挂载前缀之后的部分
+func stripPrefix(next unet.HandlerFunc) unet.HandlerFunc {
+	return func(ctx *ucontext.Context, req unet.Request) error {
+		httpReq := req.(*Request)
+		raw := httpReq.raw
+		rest := "/" + httpReq.params.Get(mountParam)
+
+		u := *raw.URL
+		matched := strings.TrimSuffix(u.Path, rest)
+		if u.RawPath != "" {
+			rawRest, ok := strings.CutPrefix(u.RawPath, matched)
+			if !ok {
+				rawRest = ""
+			}
+			u.RawPath = rawRest
+		}
+		u.Path = rest
+
+		r := new(http.Request)
+		*r = *raw
+		r.URL = &u
+		httpReq.raw = r
+		defer func() { httpReq.raw = raw }()
+
+		// 挂载参数不暴露给处理器
+		params := httpReq.params
+		if n := len(params); n > 0 && params[n-1].Key == mountParam {
+			httpReq.params = params[:n-1]
+			defer func() { httpReq.params = params }()
+		}
+		return next(ctx, req)
+	}
+}
+
+// handlerName 获取 http.Handler 的名称 (用于路由表)
+func handlerName(h http.Handler) string {
+	if f, ok := h.(http.HandlerFunc); ok {
+		return funcName(f)
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", h), "*")
+}
+
+// stdContext 获取携带追踪信息的标准 context
+func stdContext(ctx *ucontext.Context) context.Context {
+	std := ctx.Context()
+	if ucontext.FromContext(std) == nil && ctx.Trace() != nil {
+		std = ucontext.WithContext(std, ctx.Trace())
+	}
+	return std
+}
+
+// responseWriter 交给 net/http 处理器的 ResponseWriter
+// 转发写入并同步 Response 的状态码和写入状态
+type responseWriter struct {
+	http.ResponseWriter
+	resp *Response
+}
+
+// WriteHeader 写入状态码
+func (w *responseWriter) WriteHeader(code int) {
+	if !w.resp.written {
+		w.resp.statusCode = code
+		w.resp.written = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write 写入响应体
+func (w *responseWriter) Write(data []byte) (int, error) {
+	if !w.resp.written {
+		w.resp.statusCode = http.StatusOK
+		w.resp.written = true
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush 实现 http.Flusher 接口
+func (w *responseWriter) Flush() {
+	if !w.resp.written {
+		w.resp.statusCode = http.StatusOK
+		w.resp.written = true
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack 实现 http.Hijacker 接口
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, uerror.New("ResponseWriter 不支持 Hijack")
+	}
+	w.resp.written = true
+	return hijacker.Hijack()
+}
+
+// Unwrap 返回原始 ResponseWriter (供 http.ResponseController 使用)
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,184 @@
+package uhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// echoPath 输出 net/http 处理器看到的路径
+var echoPath = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte(r.URL.Path + " " + r.URL.EscapedPath() + " " + r.PathValue("tenant")))
+})
+
+// TestMount 测试挂载 http.Handler
+func TestMount(t *testing.T) {
+	server := New()
+	var status int
+	server.Use(func(next unet.HandlerFunc) unet.HandlerFunc {
+		return func(ctx *ucontext.Context, req unet.Request) error {
+			err := next(ctx, req)
+			status = req.Response().(*Response).StatusCode()
+			return err
+		}
+	})
+	server.Mount("/metrics", echoPath)
+	server.Group("/t/{tenant}").Mount("/admin/", echoPath)
+	server.GET("/metrics/own", namedHandler("own"))
+
+	tests := []struct {
+		method, target, want string
+	}{
+		{"GET", "/metrics", "/ / "},
+		{"GET", "/metrics/", "/ / "},
+		{"POST", "/metrics/a/b", "/a/b /a/b "},
+		{"GET", "/metrics/a%2Fb", "/a/b /a%2Fb "},
+		{"GET", "/t/acme/admin/users", "/users /users acme"},
+		{"GET", "/metrics/own", "own"},
+	}
+	for _, tt := range tests {
+		status = 0
+		w := serveRoute(server, tt.method, tt.target)
+		if w.Body.String() != tt.want {
+			t.Errorf("%s %s = %q, want %q", tt.method, tt.target, w.Body.String(), tt.want)
+		}
+		if tt.want != "own" && (w.Code != http.StatusAccepted || status != http.StatusAccepted) {
+			t.Errorf("%s %s: expected status 202 recorded by middleware, got %d / %d", tt.method, tt.target, w.Code, status)
+		}
+	}
+
+	routes := server.Routes()
+	if len(routes) != 5 || routes[0].Pattern != "/metrics" || routes[1].Pattern != "/metrics/{mountPath...}" ||
+		routes[0].Method != "ANY" || routes[0].Handler == "" || routes[0].Handler != routes[1].Handler {
+		t.Errorf("Unexpected mount routes: %+v", routes)
+	}
+	if routes[2].Group != "/t/{tenant}" || routes[2].Pattern != "/t/{tenant}/admin" {
+		t.Errorf("Unexpected group mount route: %+v", routes[2])
+	}
+
+	if h := handlerName(http.NotFoundHandler()); h != "http.NotFound" {
+		t.Errorf("Unexpected handler name: %s", h)
+	}
+	if h := handlerName(http.NewServeMux()); h != "http.ServeMux" {
+		t.Errorf("Expected http.ServeMux, got %s", h)
+	}
+}
+
+// TestWrapMiddleware 测试标准库中间件适配
+func TestWrapMiddleware(t *testing.T) {
+	type ctxKey struct{}
+	constructed := 0
+
+	// 注入 context 值并替换 ResponseWriter
+	inject := func(next http.Handler) http.Handler {
+		constructed++
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Std", "1")
+			next.ServeHTTP(&upperWriter{w}, r.WithContext(context.WithValue(r.Context(), ctxKey{}, "from-std")))
+		})
+	}
+	// 鉴权失败时直接返回
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	server := New()
+	server.Use(WrapMiddleware(inject))
+	g := server.Group("/api")
+	g.Use(WrapMiddleware(auth))
+	g.GET("/value", func(ctx *ucontext.Context, req unet.Request) error {
+		v, _ := ctx.Value(ctxKey{}).(string)
+		raw, _ := req.(*Request).Raw().Context().Value(ctxKey{}).(string)
+		return req.Response().String(200, v+" "+raw)
+	})
+	g.GET("/fail", func(ctx *ucontext.Context, req unet.Request) error {
+		return errors.New("boom")
+	})
+
+	w := serveRoute(server, "GET", "/api/value")
+	if w.Code != http.StatusUnauthorized || w.Header().Get("X-Std") != "1" {
+		t.Errorf("Expected 401 from std middleware, got %d", w.Code)
+	}
+
+	r := httptest.NewRequest("GET", "/api/value", nil)
+	r.Header.Set("Authorization", "token")
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	if w.Code != 200 || w.Body.String() != "FROM-STD FROM-STD" {
+		t.Errorf("Expected context value and wrapped writer, got %d %q", w.Code, w.Body.String())
+	}
+
+	// 处理器错误穿过标准中间件返回
+	r = httptest.NewRequest("GET", "/api/fail", nil)
+	r.Header.Set("Authorization", "token")
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 for handler error, got %d", w.Code)
+	}
+
+	if constructed != 1 {
+		t.Errorf("Expected std middleware constructed once, got %d", constructed)
+	}
+}
+
+// upperWriter 将响应体转为大写
+type upperWriter struct {
+	http.ResponseWriter
+}
+
+func (w *upperWriter) Write(data []byte) (int, error) {
+	return w.ResponseWriter.Write([]byte(strings.ToUpper(string(data))))
+}
+
+// TestSubHandlerTrace 测试作为子处理器时的追踪传递
+func TestSubHandlerTrace(t *testing.T) {
+	inner := New()
+	inner.Use(MiddlewareTrace())
+	var traceID string
+	inner.GET("/ping", func(ctx *ucontext.Context, req unet.Request) error {
+		traceID = ctx.Trace().TraceID
+		return req.Response().String(200, "pong")
+	})
+
+	// 挂载到另一个 uhttp.Server
+	outer := New()
+	outer.Use(MiddlewareTrace())
+	outer.Mount("/inner", inner)
+
+	r := httptest.NewRequest("GET", "/inner/ping", nil)
+	r.Header.Set(ucontext.HeaderTraceID, "trace-outer")
+	w := httptest.NewRecorder()
+	outer.ServeHTTP(w, r)
+	if w.Body.String() != "pong" || traceID != "trace-outer" || w.Header().Get("X-Trace-ID") != "trace-outer" {
+		t.Errorf("Expected trace-outer propagated, got %q / %q", traceID, w.Header().Get("X-Trace-ID"))
+	}
+
+	// 挂载到 http.ServeMux,外层由 ucontext.HTTPMiddleware 注入追踪
+	mux := http.NewServeMux()
+	mux.Handle("/svc/", http.StripPrefix("/svc", inner))
+	var outerTrace string
+	handler := ucontext.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outerTrace = ucontext.FromContext(r.Context()).TraceID
+		mux.ServeHTTP(w, r)
+	}))
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/svc/ping", nil))
+	if w.Body.String() != "pong" || outerTrace == "" || traceID != outerTrace {
+		t.Errorf("Expected trace %q propagated into sub-handler, got %q", outerTrace, traceID)
+	}
+}
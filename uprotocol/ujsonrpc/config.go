@@ -0,0 +1,26 @@
+package ujsonrpc
+
+import "time"
+
+// Config JSON-RPC TCP 服务器配置
+type Config struct {
+	// 基础配置
+	Address string // 监听地址
+
+	// 限制配置
+	MaxLineSize int // 单行 (单个负载) 最大字节数
+
+	// 超时配置
+	IdleTimeout  time.Duration // 连接空闲超时,0 表示不限制
+	WriteTimeout time.Duration // 写超时,0 表示不限制
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		Address:      ":9100",
+		MaxLineSize:  1 << 20, // 1MB
+		IdleTimeout:  5 * time.Minute,
+		WriteTimeout: 10 * time.Second,
+	}
+}
@@ -0,0 +1,41 @@
+package ujsonrpc
+
+import (
+	"time"
+
+	"github.com/whosafe/uf/uconfig"
+	"github.com/whosafe/uf/uconv"
+)
+
+// globalConfig 全局配置
+var globalConfig *Config
+
+// init 自动注册配置回调
+func init() {
+	globalConfig = DefaultConfig()
+	uconfig.Register("jsonrpc", globalConfig.UnmarshalYAML)
+}
+
+// GetConfig 获取全局配置
+func GetConfig() *Config {
+	return globalConfig
+}
+
+// UnmarshalYAML 实现 uconfig.Unmarshaler 接口
+func (c *Config) UnmarshalYAML(key string, node *uconfig.Node) error {
+	switch key {
+	case "address":
+		c.Address = node.String()
+	case "max_line_size":
+		c.MaxLineSize = uconv.ToIntDef(node, 1<<20)
+	case "idle_timeout":
+		if d, err := time.ParseDuration(node.String()); err == nil {
+			c.IdleTimeout = d
+		}
+	case "write_timeout":
+		if d, err := time.ParseDuration(node.String()); err == nil {
+			c.WriteTimeout = d
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package ujsonrpc
+
+import (
+	"strconv"
+
+	"github.com/whosafe/uf/uprotocol/ubind"
+	"github.com/whosafe/uf/uprotocol/umarshal"
+)
+
+// 标准错误码 (JSON-RPC 2.0 规范 5.1)
+const (
+	CodeParseError     = -32700 // 无效的 JSON
+	CodeInvalidRequest = -32600 // 不是合法的请求对象
+	CodeMethodNotFound = -32601 // 方法不存在
+	CodeInvalidParams  = -32602 // 参数无效
+	CodeInternalError  = -32603 // 内部错误
+)
+
+// Error JSON-RPC 错误对象
+// 方法返回 *Error 时原样作为响应中的 error 成员
+type Error struct {
+	Code    int    // 错误码
+	Message string // 错误描述
+	Data    any    // 附加数据,为 nil 时不输出
+}
+
+// NewError 创建错误对象
+func NewError(code int, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Error 实现 error 接口
+func (e *Error) Error() string {
+	return "jsonrpc: [" + strconv.Itoa(e.Code) + "] " + e.Message
+}
+
+// Marshal 实现 umarshal.IMarshaler 接口
+func (e *Error) Marshal(w *umarshal.Writer) error {
+	w.WriteObjectStart()
+	w.WriteObjectField("code")
+	w.WriteInt(e.Code)
+	w.WriteComma()
+	w.WriteObjectField("message")
+	w.WriteString(e.Message)
+	if e.Data != nil {
+		w.WriteComma()
+		w.WriteObjectField("data")
+		if err := umarshal.MarshalToWriter(w, e.Data); err != nil {
+			return err
+		}
+	}
+	w.WriteObjectEnd()
+	return nil
+}
+
+// request 已解析的请求对象
+type request struct {
+	id     *ubind.Value // 请求 ID,通知时为 nil
+	method string
+	params *ubind.Value
+}
+
+// isNotification 是否为通知 (不返回响应)
+func (r *request) isNotification() bool {
+	return r.id == nil
+}
+
+// parseRequest 校验并解析单个请求对象
+// 返回错误时 id 尽量取自原始请求,无法识别时为 null
+func parseRequest(val *ubind.Value) (*request, *ubind.Value, *Error) {
+	if val == nil || val.Type != ubind.TypeObject {
+		return nil, nullID, NewError(CodeInvalidRequest, "Invalid Request")
+	}
+
+	id, hasID := val.Object["id"]
+	if hasID {
+		switch id.Type {
+		case ubind.TypeString, ubind.TypeNumber, ubind.TypeNull:
+		default:
+			return nil, nullID, NewError(CodeInvalidRequest, "Invalid Request")
+		}
+	}
+	errID := id
+	if !hasID {
+		errID = nullID
+	}
+
+	version := val.Object["jsonrpc"]
+	if version == nil || version.Type != ubind.TypeString || version.String != "2.0" {
+		return nil, errID, NewError(CodeInvalidRequest, "Invalid Request")
+	}
+
+	method := val.Object["method"]
+	if method == nil || method.Type != ubind.TypeString || method.String == "" {
+		return nil, errID, NewError(CodeInvalidRequest, "Invalid Request")
+	}
+
+	params := val.Object["params"]
+	if params != nil && params.Type != ubind.TypeObject && params.Type != ubind.TypeArray {
+		return nil, errID, NewError(CodeInvalidRequest, "Invalid Request")
+	}
+
+	req := &request{method: method.String, params: params}
+	if hasID {
+		req.id = id
+	}
+	return req, nil, nil
+}
+
+// nullID 无法识别请求 ID 时使用的 null
+var nullID = &ubind.Value{Type: ubind.TypeNull}
+
+// response 响应对象
+type response struct {
+	id     *ubind.Value
+	result any
+	err    *Error
+}
+
+// Marshal 实现 umarshal.IMarshaler 接口
+func (r *response) Marshal(w *umarshal.Writer) error {
+	w.WriteObjectStart()
+	w.WriteObjectField("jsonrpc")
+	w.WriteString("2.0")
+	w.WriteComma()
+
+	if r.err != nil {
+		w.WriteObjectField("error")
+		if err := r.err.Marshal(w); err != nil {
+			return err
+		}
+	} else {
+		w.WriteObjectField("result")
+		if err := umarshal.MarshalToWriter(w, r.result); err != nil {
+			return err
+		}
+	}
+
+	w.WriteComma()
+	w.WriteObjectField("id")
+	writeID(w, r.id)
+	w.WriteObjectEnd()
+	return nil
+}
+
+// writeID 原样写回请求 ID
+func writeID(w *umarshal.Writer, id *ubind.Value) {
+	switch {
+	case id == nil:
+		w.WriteNull()
+	case id.Type == ubind.TypeString:
+		w.WriteString(id.String)
+	case id.Type == ubind.TypeNumber:
+		w.WriteRawString(strconv.FormatFloat(id.Number, 'f', -1, 64))
+	default:
+		w.WriteNull()
+	}
+}
+
+// batchResponse 批量响应
+type batchResponse []*response
+
+// Marshal 实现 umarshal.IMarshaler 接口
+func (b batchResponse) Marshal(w *umarshal.Writer) error {
+	w.WriteArrayStart()
+	for i, resp := range b {
+		if i > 0 {
+			w.WriteComma()
+		}
+		if err := resp.Marshal(w); err != nil {
+			return err
+		}
+	}
+	w.WriteArrayEnd()
+	return nil
+}
@@ -0,0 +1,255 @@
+package ujsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uerror"
+	"github.com/whosafe/uf/ulogger"
+	"github.com/whosafe/uf/uprotocol/ubind"
+	"github.com/whosafe/uf/uprotocol/umarshal"
+)
+
+// MethodFunc 方法处理函数
+// params 为原始参数 (对象或数组),未传参数时为 nil
+type MethodFunc func(ctx *ucontext.Context, params *ubind.Value) (any, error)
+
+// Registry 方法注册表
+// 与传输层无关,可同时用于 HTTP 端点和 TCP 服务器
+type Registry struct {
+	methods  map[string]MethodFunc
+	codes    map[int]int // uerror 错误码 -> JSON-RPC 错误码
+	maxBatch int
+	logger   *ulogger.Logger
+	mu       sync.RWMutex
+}
+
+// NewRegistry 创建方法注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		methods:  make(map[string]MethodFunc),
+		codes:    make(map[int]int),
+		maxBatch: 100,
+		logger:   ulogger.Default(),
+	}
+}
+
+// Handle 注册方法 (使用原始参数)
+func (r *Registry) Handle(method string, fn MethodFunc) {
+	if fn == nil {
+		panic("method func cannot be nil")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.methods[method] = fn
+}
+
+// Register 注册带类型参数的方法
+// 参数通过 ubind.Binder 绑定:对象按字段名绑定,数组按位置以 "0"、"1"... 为键绑定;
+// 绑定失败时返回 CodeInvalidParams,结果通过 umarshal 编码
+func Register[P any, PT interface {
+	*P
+	ubind.Binder
+}, R any](r *Registry, method string, fn func(ctx *ucontext.Context, params PT) (R, error)) {
+	if fn == nil {
+		panic("method func cannot be nil")
+	}
+
+	r.Handle(method, func(ctx *ucontext.Context, params *ubind.Value) (any, error) {
+		p := PT(new(P))
+		if err := bindParams(params, p); err != nil {
+			return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+		}
+		return fn(ctx, p)
+	})
+}
+
+// bindParams 绑定参数
+func bindParams(params *ubind.Value, obj ubind.Binder) error {
+	if params == nil {
+		return nil
+	}
+	if params.Type == ubind.TypeArray {
+		for i, item := range params.Array {
+			if err := obj.Bind(strconv.Itoa(i), item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return ubind.Bind(params, obj)
+}
+
+// MapCode 设置 uerror 错误码到 JSON-RPC 错误码的映射
+// 未映射的非零错误码原样作为 JSON-RPC 错误码返回
+func (r *Registry) MapCode(code, rpcCode int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codes[code] = rpcCode
+}
+
+// SetMaxBatch 设置单个批量请求的最大请求数,<= 0 表示不限制
+func (r *Registry) SetMaxBatch(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxBatch = n
+}
+
+// SetLogger 设置日志 Logger
+func (r *Registry) SetLogger(logger *ulogger.Logger) {
+	r.logger = logger
+}
+
+// Methods 获取已注册的方法名 (按名称排序)
+func (r *Registry) Methods() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	methods := make([]string, 0, len(r.methods))
+	for method := range r.methods {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// Dispatch 处理一个 JSON-RPC 负载 (单个请求或批量请求)
+// 返回编码后的响应;负载只包含通知时返回 nil,不应向客户端写入任何内容
+func (r *Registry) Dispatch(ctx *ucontext.Context, data []byte) []byte {
+	// ubind 解析到第一个值即停止,先校验整个负载,值之后的非空白内容视为解析错误
+	var val *ubind.Value
+	if json.Valid(data) {
+		val = ubind.ParseJSON(data)
+	}
+	if val == nil {
+		return encode(&response{id: nullID, err: NewError(CodeParseError, "Parse error")})
+	}
+
+	// 单个请求
+	if val.Type != ubind.TypeArray {
+		resp := r.call(ctx, val)
+		if resp == nil {
+			return nil
+		}
+		return encode(resp)
+	}
+
+	// 批量请求
+	if len(val.Array) == 0 {
+		return encode(&response{id: nullID, err: NewError(CodeInvalidRequest, "Invalid Request")})
+	}
+	r.mu.RLock()
+	maxBatch := r.maxBatch
+	r.mu.RUnlock()
+	if maxBatch > 0 && len(val.Array) > maxBatch {
+		return encode(&response{id: nullID, err: NewError(CodeInvalidRequest, "batch too large")})
+	}
+
+	results := make([]*response, len(val.Array))
+	var wg sync.WaitGroup
+	for i, item := range val.Array {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.call(ctx, item)
+		}()
+	}
+	wg.Wait()
+
+	batch := make(batchResponse, 0, len(results))
+	for _, resp := range results {
+		if resp != nil {
+			batch = append(batch, resp)
+		}
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	return encode(batch)
+}
+
+// call 执行单个请求,通知返回 nil
+func (r *Registry) call(ctx *ucontext.Context, val *ubind.Value) *response {
+	req, errID, rpcErr := parseRequest(val)
+	if rpcErr != nil {
+		return &response{id: errID, err: rpcErr}
+	}
+
+	r.mu.RLock()
+	fn, ok := r.methods[req.method]
+	r.mu.RUnlock()
+
+	var result any
+	var err error
+	if !ok {
+		err = NewError(CodeMethodNotFound, "Method not found")
+	} else {
+		result, err = r.invoke(ctx, fn, req)
+	}
+
+	// 通知不返回响应,方法执行失败时只记录日志
+	if req.isNotification() {
+		if err != nil && ok {
+			r.logger.WarnCtx(ctx.Context(), "JSON-RPC 通知处理失败", "method", req.method, "error", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return &response{id: req.id, err: r.toError(err)}
+	}
+	return &response{id: req.id, result: result}
+}
+
+// invoke 调用方法并恢复 panic
+func (r *Registry) invoke(ctx *ucontext.Context, fn MethodFunc, req *request) (result any, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			r.logger.ErrorCtx(ctx.Context(), "Panic recovered",
+				"method", req.method,
+				"error", rec,
+				"stack", string(debug.Stack()),
+			)
+			err = uerror.New(fmt.Sprintf("panic recovered: %v", rec))
+		}
+	}()
+	return fn(ctx, req.params)
+}
+
+// toError 将方法返回的错误转换为 JSON-RPC 错误对象
+// *Error 原样返回;*uerror.Error 按 MapCode 映射错误码,错误码为 0 时视为内部错误;
+// 其他错误统一返回 CodeInternalError,不向客户端暴露内部信息
+func (r *Registry) toError(err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+
+	var uerr *uerror.Error
+	if errors.As(err, &uerr) && uerr.Code != 0 {
+		r.mu.RLock()
+		code, ok := r.codes[uerr.Code]
+		r.mu.RUnlock()
+		if !ok {
+			code = uerr.Code
+		}
+		return &Error{Code: code, Message: uerr.Message}
+	}
+
+	return NewError(CodeInternalError, "Internal error")
+}
+
+// encode 编码响应
+func encode(v umarshal.IMarshaler) []byte {
+	data, err := umarshal.Marshal(v)
+	if err != nil {
+		data, _ = umarshal.Marshal(&response{id: nullID, err: NewError(CodeInternalError, "Internal error")})
+	}
+	return data
+}
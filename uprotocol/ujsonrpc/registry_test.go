@@ -0,0 +1,138 @@
+package ujsonrpc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uerror"
+	"github.com/whosafe/uf/uprotocol/ubind"
+)
+
+// addParams 测试参数
+type addParams struct {
+	A int
+	B int
+}
+
+// Bind 实现 ubind.Binder 接口 (支持按名称和按位置)
+func (p *addParams) Bind(key string, value *ubind.Value) error {
+	if !value.IsNumber() {
+		return errors.New(key + " must be a number")
+	}
+	switch key {
+	case "a", "0":
+		p.A = value.Int()
+	case "b", "1":
+		p.B = value.Int()
+	}
+	return nil
+}
+
+// newTestRegistry 创建测试注册表
+func newTestRegistry() *Registry {
+	r := NewRegistry()
+	Register(r, "add", func(ctx *ucontext.Context, p *addParams) (int, error) {
+		return p.A + p.B, nil
+	})
+	r.Handle("fail", func(ctx *ucontext.Context, params *ubind.Value) (any, error) {
+		return nil, uerror.NewWithCode(10003, "未授权")
+	})
+	r.Handle("internal", func(ctx *ucontext.Context, params *ubind.Value) (any, error) {
+		return nil, errors.New("db password leaked")
+	})
+	r.Handle("panic", func(ctx *ucontext.Context, params *ubind.Value) (any, error) {
+		panic("boom")
+	})
+	return r
+}
+
+// TestDispatch 测试单个请求与标准错误
+func TestDispatch(t *testing.T) {
+	r := newTestRegistry()
+	r.MapCode(10003, -32001)
+	ctx := ucontext.New()
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"named", `{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":2},"id":1}`,
+			`{"jsonrpc":"2.0","result":3,"id":1}`},
+		{"positional", `{"jsonrpc":"2.0","method":"add","params":[4,5],"id":"x"}`,
+			`{"jsonrpc":"2.0","result":9,"id":"x"}`},
+		{"parse error", `{"jsonrpc":"2.0","method"`,
+			`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`},
+		{"trailing data", `{"jsonrpc":"2.0","method":"add","params":[1,2],"id":1} {"id":2}`,
+			`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`},
+		{"trailing whitespace", "{\"jsonrpc\":\"2.0\",\"method\":\"add\",\"params\":[1,2],\"id\":1} \r\n",
+			`{"jsonrpc":"2.0","result":3,"id":1}`},
+		{"invalid request", `{"jsonrpc":"1.0","method":"add","id":7}`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":7}`},
+		{"method not found", `{"jsonrpc":"2.0","method":"nope","id":2}`,
+			`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":2}`},
+		{"invalid params", `{"jsonrpc":"2.0","method":"add","params":{"a":"x"},"id":3}`,
+			`{"jsonrpc":"2.0","error":{"code":-32602,"message":"a must be a number"},"id":3}`},
+		{"uerror mapped", `{"jsonrpc":"2.0","method":"fail","id":4}`,
+			`{"jsonrpc":"2.0","error":{"code":-32001,"message":"未授权"},"id":4}`},
+		{"internal hidden", `{"jsonrpc":"2.0","method":"internal","id":5}`,
+			`{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"},"id":5}`},
+		{"panic", `{"jsonrpc":"2.0","method":"panic","id":6}`,
+			`{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"},"id":6}`},
+		{"empty batch", `[]`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(r.Dispatch(ctx, []byte(tt.in)))
+			if got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDispatchNotification 测试通知不返回响应
+func TestDispatchNotification(t *testing.T) {
+	r := newTestRegistry()
+	ctx := ucontext.New()
+
+	for _, in := range []string{
+		`{"jsonrpc":"2.0","method":"add","params":[1,2]}`,
+		`{"jsonrpc":"2.0","method":"nope"}`,
+		`[{"jsonrpc":"2.0","method":"add"},{"jsonrpc":"2.0","method":"fail"}]`,
+	} {
+		if out := r.Dispatch(ctx, []byte(in)); out != nil {
+			t.Errorf("%s: expected no response, got %s", in, out)
+		}
+	}
+}
+
+// TestDispatchBatch 测试批量请求
+func TestDispatchBatch(t *testing.T) {
+	r := newTestRegistry()
+	ctx := ucontext.New()
+
+	in := `[
+		{"jsonrpc":"2.0","method":"add","params":[1,1],"id":1},
+		{"jsonrpc":"2.0","method":"add","params":[2,2]},
+		1,
+		{"jsonrpc":"2.0","method":"nope","id":"b"}
+	]`
+	want := `[{"jsonrpc":"2.0","result":2,"id":1},` +
+		`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null},` +
+		`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":"b"}]`
+
+	if got := string(r.Dispatch(ctx, []byte(in))); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	r.SetMaxBatch(2)
+	got := string(r.Dispatch(ctx, []byte(in)))
+	if !strings.Contains(got, `"code":-32600`) {
+		t.Errorf("expected batch too large error, got %s", got)
+	}
+}
@@ -0,0 +1,245 @@
+package ujsonrpc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uerror"
+	"github.com/whosafe/uf/ulogger"
+)
+
+// ErrServerRunning 服务器已在运行
+var ErrServerRunning = uerror.New("JSON-RPC 服务器已在运行")
+
+// Server JSON-RPC TCP 服务器
+// 每行一个负载 (单个请求或批量请求),响应同样以换行结尾;
+// 同一连接上的请求按顺序处理
+type Server struct {
+	config   *Config
+	registry *Registry
+	logger   *ulogger.Logger
+
+	// 运行状态
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	closed   atomic.Bool
+	mu       sync.Mutex
+}
+
+// New 创建 TCP 服务器
+func New(registry *Registry) *Server {
+	return NewWithConfig(registry, GetConfig())
+}
+
+// NewWithConfig 使用配置创建 TCP 服务器
+func NewWithConfig(registry *Registry, cfg *Config) *Server {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	if cfg.MaxLineSize <= 0 {
+		cfg.MaxLineSize = 1 << 20
+	}
+
+	return &Server{
+		config:   cfg,
+		registry: registry,
+		logger:   ulogger.Default(),
+		conns:    make(map[net.Conn]struct{}),
+	}
+}
+
+// SetLogger 设置日志 Logger
+func (s *Server) SetLogger(logger *ulogger.Logger) {
+	s.logger = logger
+}
+
+// Registry 获取方法注册表
+func (s *Server) Registry() *Registry {
+	return s.registry
+}
+
+// Start 启动服务器
+func (s *Server) Start(addr string) error {
+	if addr == "" {
+		addr = s.config.Address
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return uerror.Wrap(err, "JSON-RPC 监听失败")
+	}
+
+	s.logger.Info("JSON-RPC 服务器启动", "addr", listener.Addr().String())
+	return s.Serve(listener)
+}
+
+// Stop 停止服务器
+// 停止接收新连接,等待进行中的请求完成,ctx 超时后强制关闭连接;停止后可再次调用 Serve
+func (s *Server) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	listener, cancel := s.listener, s.cancel
+	s.mu.Unlock()
+
+	if listener == nil {
+		return nil
+	}
+
+	s.logger.Info("正在关闭 JSON-RPC 服务器...")
+	s.closed.Store(true)
+	listener.Close()
+
+	// 唤醒阻塞在读取上的连接,处理中的请求写完响应后退出
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.SetReadDeadline(time.Now())
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.reset(listener)
+		return nil
+	case <-ctx.Done():
+		cancel()
+		s.mu.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mu.Unlock()
+		s.reset(listener)
+		return ctx.Err()
+	}
+}
+
+// reset 清除运行状态,允许再次调用 Serve
+func (s *Server) reset(listener net.Listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == listener {
+		s.listener = nil
+	}
+}
+
+// Serve 处理连接 (阻塞)
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	if s.listener != nil {
+		s.mu.Unlock()
+		return ErrServerRunning
+	}
+	s.listener = listener
+	baseCtx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.closed.Store(false)
+	// 接收循环计入 wg,Stop 返回前确认它已退出
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	defer func() {
+		cancel()
+		s.wg.Done()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.closed.Load() {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		if s.closed.Load() {
+			s.mu.Unlock()
+			conn.Close()
+			continue
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.serveConn(baseCtx, conn)
+	}
+}
+
+// Addr 获取监听地址,未启动时返回 nil
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// serveConn 处理单个连接
+func (s *Server) serveConn(baseCtx context.Context, conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+		s.wg.Done()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, min(4096, s.config.MaxLineSize)), s.config.MaxLineSize)
+	writer := bufio.NewWriter(conn)
+
+	for {
+		if s.config.IdleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.config.IdleTimeout))
+		}
+		// 在设置读超时之后检查,避免覆盖 Stop 设置的立即超时
+		if s.closed.Load() {
+			return
+		}
+
+		if !scanner.Scan() {
+			if err := scanner.Err(); errors.Is(err, bufio.ErrTooLong) {
+				s.logger.Warn("JSON-RPC 请求超过最大长度", "remote", conn.RemoteAddr().String())
+			}
+			return
+		}
+
+		line := scanner.Bytes()
+		if len(line) == 0 || (len(line) == 1 && line[0] == '\r') {
+			continue
+		}
+
+		ctx := ucontext.NewWithContext(baseCtx)
+		resp := s.registry.Dispatch(ctx, line)
+		if resp == nil {
+			continue
+		}
+
+		if s.config.WriteTimeout > 0 {
+			conn.SetWriteDeadline(time.Now().Add(s.config.WriteTimeout))
+		}
+		writer.Write(resp)
+		writer.WriteByte('\n')
+		if err := writer.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package ujsonrpc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestServerTCP 测试换行分帧的 TCP 传输
+func TestServerTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.MaxLineSize = 256
+	server := NewWithConfig(newTestRegistry(), cfg)
+
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// 通知不产生响应,后续请求的响应紧随其后
+	payload := `{"jsonrpc":"2.0","method":"add","params":[1,2]}` + "\n" +
+		"\n" +
+		`{"jsonrpc":"2.0","method":"add","params":{"a":20,"b":22},"id":1}` + "\r\n" +
+		`[{"jsonrpc":"2.0","method":"add","params":[1,1],"id":2}]` + "\n"
+	if _, err := conn.Write([]byte(payload)); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := bufio.NewReader(conn)
+	for _, want := range []string{
+		`{"jsonrpc":"2.0","result":42,"id":1}` + "\n",
+		`[{"jsonrpc":"2.0","result":2,"id":2}]` + "\n",
+	} {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line != want {
+			t.Errorf("got %q, want %q", line, want)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := server.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	// 服务器关闭后连接被关闭
+	if _, err := reader.ReadString('\n'); err == nil {
+		t.Error("expected connection to be closed after Stop")
+	}
+}
+
+// TestServerLineTooLong 测试超长负载关闭连接
+func TestServerLineTooLong(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.MaxLineSize = 64
+	server := NewWithConfig(newTestRegistry(), cfg)
+	go server.Serve(listener)
+	defer server.Stop(context.Background())
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	long := make([]byte, 128)
+	for i := range long {
+		long[i] = ' '
+	}
+	conn.Write(append(long, '\n'))
+
+	if _, err := bufio.NewReader(conn).ReadString('\n'); err == nil {
+		t.Error("expected connection to be closed")
+	}
+}
+
+// TestServerRestart 测试停止后再次启动
+func TestServerRestart(t *testing.T) {
+	server := New(newTestRegistry())
+	for i := 0; i < 2; i++ {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		served := make(chan error, 1)
+		go func() { served <- server.Serve(listener) }()
+
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		conn.Write([]byte(`{"jsonrpc":"2.0","method":"add","params":[1,2],"id":1}` + "\n"))
+		if _, err := bufio.NewReader(conn).ReadString('\n'); err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+		conn.Close()
+
+		if err := server.Serve(listener); !errors.Is(err, ErrServerRunning) {
+			t.Errorf("run %d: expected ErrServerRunning, got %v", i, err)
+		}
+		if err := server.Stop(context.Background()); err != nil {
+			t.Fatalf("run %d: Stop: %v", i, err)
+		}
+		if err := <-served; err != nil {
+			t.Fatalf("run %d: Serve: %v", i, err)
+		}
+		if server.Addr() != nil {
+			t.Errorf("run %d: expected nil Addr after Stop", i)
+		}
+	}
+}
@@ -12,6 +12,8 @@ const (
 	ProtocolTCP Protocol = "tcp"
 	// ProtocolQUIC QUIC 协议
 	ProtocolQUIC Protocol = "quic"
+	// ProtocolUDP UDP 协议
+	ProtocolUDP Protocol = "udp"
 )
 
 // String 返回协议字符串
@@ -0,0 +1,43 @@
+package uquic
+
+import (
+	"crypto/tls"
+	"time"
+)
+
+// Config QUIC 服务器配置
+type Config struct {
+	// 基础配置
+	Address string // 监听地址 (UDP)
+
+	// TLS 配置 (QUIC 强制使用 TLS 1.3)
+	CertFile  string      // 证书文件路径
+	KeyFile   string      // 密钥文件路径
+	TLSConfig *tls.Config // 自定义 TLS 配置,设置后忽略 CertFile/KeyFile
+
+	// 0-RTT 配置
+	// 0-RTT 数据不具备重放保护,仅建议用于幂等请求
+	Allow0RTT bool // 是否允许 0-RTT 连接
+
+	// 超时配置
+	HandshakeIdleTimeout time.Duration // 握手超时
+	MaxIdleTimeout       time.Duration // 连接空闲超时
+	KeepAlivePeriod      time.Duration // 心跳间隔,0 表示不发送
+
+	// 流量控制
+	MaxIncomingStreams int64 // 单连接最大并发请求流数
+	MaxHeaderBytes     int   // 最大请求头字节数
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		Address:              ":8443",
+		Allow0RTT:            false,
+		HandshakeIdleTimeout: 5 * time.Second,
+		MaxIdleTimeout:       30 * time.Second,
+		KeepAlivePeriod:      15 * time.Second,
+		MaxIncomingStreams:   100,
+		MaxHeaderBytes:       1 << 20, // 1MB
+	}
+}
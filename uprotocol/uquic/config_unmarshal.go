@@ -0,0 +1,53 @@
+package uquic
+
+import (
+	"time"
+
+	"github.com/whosafe/uf/uconfig"
+	"github.com/whosafe/uf/uconv"
+)
+
+// globalConfig 全局配置
+var globalConfig *Config
+
+// init 自动注册配置回调
+func init() {
+	globalConfig = DefaultConfig()
+	uconfig.Register("quic", globalConfig.UnmarshalYAML)
+}
+
+// GetConfig 获取全局配置
+func GetConfig() *Config {
+	return globalConfig
+}
+
+// UnmarshalYAML 实现 uconfig.Unmarshaler 接口
+func (c *Config) UnmarshalYAML(key string, node *uconfig.Node) error {
+	switch key {
+	case "address":
+		c.Address = node.String()
+	case "cert_file":
+		c.CertFile = node.String()
+	case "key_file":
+		c.KeyFile = node.String()
+	case "allow_0rtt":
+		c.Allow0RTT = uconv.ToBoolDef(node, false)
+	case "handshake_idle_timeout":
+		if d, err := time.ParseDuration(node.String()); err == nil {
+			c.HandshakeIdleTimeout = d
+		}
+	case "max_idle_timeout":
+		if d, err := time.ParseDuration(node.String()); err == nil {
+			c.MaxIdleTimeout = d
+		}
+	case "keep_alive_period":
+		if d, err := time.ParseDuration(node.String()); err == nil {
+			c.KeepAlivePeriod = d
+		}
+	case "max_incoming_streams":
+		c.MaxIncomingStreams = int64(uconv.ToIntDef(node, 100))
+	case "max_header_bytes":
+		c.MaxHeaderBytes = uconv.ToIntDef(node, 1<<20)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package uquic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/whosafe/uf/uerror"
+	"github.com/whosafe/uf/uprotocol/uhttp"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// 错误定义
+var (
+	// ErrStreamListener QUIC 基于 UDP,不支持面向流的 net.Listener
+	ErrStreamListener = uerror.New("QUIC 服务器不支持面向流的监听器,请使用 ServePacketConn")
+)
+
+// 编译期检查是否实现 unet.Server 接口
+var _ unet.Server = (*Server)(nil)
+
+// Server QUIC 服务器 (实现 unet.Server 接口)
+// 基于 HTTP/3,每个 QUIC 双向流对应一个请求,
+// 路由和中间件复用 uhttp.Server,同一套处理器可同时服务 HTTP 与 QUIC
+type Server struct {
+	config    *Config
+	handler   *uhttp.Server
+	h3Server  *http3.Server
+	tlsConfig *tls.Config
+	mu        sync.Mutex
+}
+
+// New 创建新的 QUIC 服务器
+// handler 提供路由与中间件,为 nil 时创建新的 uhttp.Server
+func New(handler *uhttp.Server) *Server {
+	return NewWithConfig(GetConfig(), handler)
+}
+
+// NewWithConfig 使用配置创建 QUIC 服务器
+func NewWithConfig(cfg *Config, handler *uhttp.Server) *Server {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	if handler == nil {
+		handler = uhttp.New()
+	}
+
+	s := &Server{
+		config:  cfg,
+		handler: handler,
+	}
+
+	// 创建 HTTP/3 Server
+	s.h3Server = &http3.Server{
+		Addr:           cfg.Address,
+		Handler:        handler,
+		MaxHeaderBytes: cfg.MaxHeaderBytes,
+		QUICConfig: &quic.Config{
+			Allow0RTT:            cfg.Allow0RTT,
+			HandshakeIdleTimeout: cfg.HandshakeIdleTimeout,
+			MaxIdleTimeout:       cfg.MaxIdleTimeout,
+			KeepAlivePeriod:      cfg.KeepAlivePeriod,
+			MaxIncomingStreams:   cfg.MaxIncomingStreams,
+		},
+	}
+
+	return s
+}
+
+// HTTP 获取底层 uhttp.Server (用于注册路由)
+func (s *Server) HTTP() *uhttp.Server {
+	return s.handler
+}
+
+// Start 启动服务器
+func (s *Server) Start(addr string) error {
+	if addr == "" {
+		addr = s.config.Address
+	}
+
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return uerror.Wrap(err, "QUIC 监听失败")
+	}
+	defer conn.Close()
+
+	s.handler.AccessLogger().Info("QUIC 服务器启动", "addr", conn.LocalAddr().String())
+	return s.ServePacketConn(conn)
+}
+
+// Stop 停止服务器
+// 先停止接收新连接并等待进行中的请求完成,ctx 超时后强制关闭
+func (s *Server) Stop(ctx context.Context) error {
+	s.handler.AccessLogger().Info("正在关闭 QUIC 服务器...")
+
+	return s.h3Server.Shutdown(ctx)
+}
+
+// Serve 处理连接 (阻塞)
+// QUIC 运行在 UDP 之上,无法使用 net.Listener,请使用 ServePacketConn
+func (s *Server) Serve(listener net.Listener) error {
+	return ErrStreamListener
+}
+
+// ServePacketConn 在已有的 UDP 连接上提供服务 (阻塞)
+func (s *Server) ServePacketConn(conn net.PacketConn) error {
+	tlsConfig, err := s.buildTLSConfig()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.h3Server.TLSConfig = tlsConfig
+	s.mu.Unlock()
+
+	err = s.h3Server.Serve(conn)
+	if errors.Is(err, http.ErrServerClosed) || errors.Is(err, quic.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Use 注册全局中间件
+func (s *Server) Use(middleware ...unet.MiddlewareFunc) {
+	s.handler.Use(middleware...)
+}
+
+// Handle 注册处理器
+func (s *Server) Handle(pattern string, handler unet.HandlerFunc) {
+	s.handler.Handle(pattern, handler)
+}
+
+// SetQUICHeaders 设置 Alt-Svc 响应头,引导 HTTP/1.1、HTTP/2 客户端升级到 HTTP/3
+func (s *Server) SetQUICHeaders(hdr http.Header) error {
+	return s.h3Server.SetQUICHeaders(hdr)
+}
+
+// buildTLSConfig 构建 TLS 配置
+func (s *Server) buildTLSConfig() (*tls.Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tlsConfig != nil {
+		return s.tlsConfig, nil
+	}
+
+	var tlsConfig *tls.Config
+	if s.config.TLSConfig != nil {
+		tlsConfig = s.config.TLSConfig.Clone()
+	} else {
+		if s.config.CertFile == "" || s.config.KeyFile == "" {
+			return nil, uerror.New("证书文件和密钥文件不能为空")
+		}
+		cert, err := tls.LoadX509KeyPair(s.config.CertFile, s.config.KeyFile)
+		if err != nil {
+			return nil, uerror.Wrap(err, "加载证书失败")
+		}
+		tlsConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+		}
+	}
+
+	// QUIC 强制使用 TLS 1.3,并设置 h3 ALPN
+	tlsConfig.MinVersion = tls.VersionTLS13
+	s.tlsConfig = http3.ConfigureTLSConfig(tlsConfig)
+	return s.tlsConfig, nil
+}
@@ -0,0 +1,166 @@
+package uquic
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/uhttp"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// newSelfSignedTLS 生成自签名证书
+func newSelfSignedTLS(t *testing.T) (*tls.Config, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("生成证书失败: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("解析证书失败: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}, pool
+}
+
+// startTestServer 在回环 UDP 上启动测试服务器
+func startTestServer(t *testing.T, server *Server) (string, chan error) {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听 UDP 失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.ServePacketConn(conn)
+	}()
+
+	return conn.LocalAddr().String(), done
+}
+
+// TestServerRequest 测试通过 QUIC 复用 uhttp 的路由和中间件
+func TestServerRequest(t *testing.T) {
+	tlsConfig, pool := newSelfSignedTLS(t)
+
+	handler := uhttp.New()
+	handler.GET("/users/:id", func(ctx *ucontext.Context, req unet.Request) error {
+		return req.Response().String(200, string(req.Protocol())+":"+req.Param("id"))
+	})
+
+	cfg := DefaultConfig()
+	cfg.TLSConfig = tlsConfig
+	server := NewWithConfig(cfg, handler)
+
+	var middlewareCalled atomic.Bool
+	server.Use(func(next unet.HandlerFunc) unet.HandlerFunc {
+		return func(ctx *ucontext.Context, req unet.Request) error {
+			middlewareCalled.Store(true)
+			return next(ctx, req)
+		}
+	})
+
+	addr, done := startTestServer(t, server)
+
+	transport := &http3.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+	defer transport.Close()
+	client := &http.Client{Transport: transport, Timeout: 5 * time.Second}
+
+	resp, err := client.Get("https://" + addr + "/users/42")
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if string(body) != "quic:42" {
+		t.Errorf("Expected body 'quic:42', got '%s'", body)
+	}
+	if !middlewareCalled.Load() {
+		t.Error("Middleware should be called")
+	}
+
+	// 优雅关闭
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Stop(ctx); err != nil {
+		t.Errorf("Stop failed: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ServePacketConn should return nil after Stop, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("ServePacketConn did not return after Stop")
+	}
+}
+
+// TestServerServeListener 测试 Serve 拒绝流式监听器
+func TestServerServeListener(t *testing.T) {
+	server := NewWithConfig(DefaultConfig(), nil)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听 TCP 失败: %v", err)
+	}
+	defer ln.Close()
+
+	if err := server.Serve(ln); err != ErrStreamListener {
+		t.Errorf("Expected ErrStreamListener, got %v", err)
+	}
+}
+
+// TestServerMissingCert 测试缺少证书时返回错误
+func TestServerMissingCert(t *testing.T) {
+	server := NewWithConfig(DefaultConfig(), nil)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听 UDP 失败: %v", err)
+	}
+	defer conn.Close()
+
+	if err := server.ServePacketConn(conn); err == nil {
+		t.Error("Expected error when certificate is missing")
+	}
+}
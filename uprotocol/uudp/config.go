@@ -0,0 +1,36 @@
+package uudp
+
+import "runtime"
+
+// Config UDP 服务器配置
+type Config struct {
+	// 基础配置
+	Address string // 监听地址
+
+	// 并发配置
+	Readers   int // 读取 goroutine 数量
+	Workers   int // 处理 goroutine 数量
+	QueueSize int // 待处理数据报队列长度,队列满时丢弃新数据报
+
+	// 缓冲区配置
+	MaxPacketSize  int // 单个数据报最大字节数
+	ReadBufferSize int // Socket 接收缓冲区字节数,0 表示使用系统默认值
+
+	// 功能开关
+	EnableTrace bool // 是否为每个数据报生成追踪信息 (有额外开销)
+	EnableReply bool // 是否允许处理器回复数据报
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		Address:        ":9000",
+		Readers:        1,
+		Workers:        runtime.NumCPU(),
+		QueueSize:      4096,
+		MaxPacketSize:  65535,
+		ReadBufferSize: 4 << 20, // 4MB
+		EnableTrace:    false,
+		EnableReply:    false,
+	}
+}
@@ -0,0 +1,45 @@
+package uudp
+
+import (
+	"runtime"
+
+	"github.com/whosafe/uf/uconfig"
+	"github.com/whosafe/uf/uconv"
+)
+
+// globalConfig 全局配置
+var globalConfig *Config
+
+// init 自动注册配置回调
+func init() {
+	globalConfig = DefaultConfig()
+	uconfig.Register("udp", globalConfig.UnmarshalYAML)
+}
+
+// GetConfig 获取全局配置
+func GetConfig() *Config {
+	return globalConfig
+}
+
+// UnmarshalYAML 实现 uconfig.Unmarshaler 接口
+func (c *Config) UnmarshalYAML(key string, node *uconfig.Node) error {
+	switch key {
+	case "address":
+		c.Address = node.String()
+	case "readers":
+		c.Readers = uconv.ToIntDef(node, 1)
+	case "workers":
+		c.Workers = uconv.ToIntDef(node, runtime.NumCPU())
+	case "queue_size":
+		c.QueueSize = uconv.ToIntDef(node, 4096)
+	case "max_packet_size":
+		c.MaxPacketSize = uconv.ToIntDef(node, 65535)
+	case "read_buffer_size":
+		c.ReadBufferSize = uconv.ToIntDef(node, 4<<20)
+	case "enable_trace":
+		c.EnableTrace = uconv.ToBoolDef(node, false)
+	case "enable_reply":
+		c.EnableReply = uconv.ToBoolDef(node, false)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package uudp
+
+import (
+	"bytes"
+	"net"
+
+	"github.com/whosafe/uf/uerror"
+)
+
+// 错误定义
+var (
+	// ErrInvalidPacket 数据报格式错误
+	ErrInvalidPacket = uerror.New("数据报格式错误")
+)
+
+// Packet 解码后的数据报
+type Packet struct {
+	Route   string            // 路由键,匹配 Handle 注册的 pattern
+	Params  map[string]string // 解码出的参数,通过 req.Param 获取
+	Payload []byte            // 负载,供 Body/Bind 使用
+	ReplyTo net.Addr          // 回复地址,为 nil 时回复到来源地址
+}
+
+// reset 重置数据报 (复用 Params)
+func (p *Packet) reset() {
+	p.Route = ""
+	p.Payload = nil
+	p.ReplyTo = nil
+	for k := range p.Params {
+		delete(p.Params, k)
+	}
+}
+
+// SetParam 设置参数
+func (p *Packet) SetParam(key, value string) {
+	if p.Params == nil {
+		p.Params = make(map[string]string)
+	}
+	p.Params[key] = value
+}
+
+// Decoder 数据报解码器
+// data 仅在 Decode 调用及后续处理器执行期间有效,需要保留时请复制
+type Decoder interface {
+	Decode(data []byte, pkt *Packet) error
+}
+
+// DecoderFunc 函数形式的解码器
+type DecoderFunc func(data []byte, pkt *Packet) error
+
+// Decode 实现 Decoder 接口
+func (f DecoderFunc) Decode(data []byte, pkt *Packet) error {
+	return f(data, pkt)
+}
+
+// RawDecoder 原始解码器
+// 不解析路由,整个数据报作为负载,由 "*" 处理器处理
+func RawDecoder() Decoder {
+	return DecoderFunc(func(data []byte, pkt *Packet) error {
+		pkt.Payload = data
+		return nil
+	})
+}
+
+// PrefixDecoder 前缀解码器
+// 以 sep 之前的内容作为路由,之后的内容作为负载
+// 例如 "cpu.load 0.75" 使用空格分隔时路由为 "cpu.load",负载为 "0.75"
+func PrefixDecoder(sep byte) Decoder {
+	return DecoderFunc(func(data []byte, pkt *Packet) error {
+		i := bytes.IndexByte(data, sep)
+		if i < 0 {
+			pkt.Route = string(data)
+			return nil
+		}
+		pkt.Route = string(data[:i])
+		pkt.Payload = data[i+1:]
+		return nil
+	})
+}
+
+// StatsDDecoder StatsD 协议解码器
+// 格式: <name>:<value>|<type>[|@<rate>][|#<tags>]
+// 路由为指标类型 (c, g, ms, h, s),参数包含 name, value, type, rate, tags
+func StatsDDecoder() Decoder {
+	return DecoderFunc(func(data []byte, pkt *Packet) error {
+		data = bytes.TrimRight(data, "\r\n")
+
+		colon := bytes.IndexByte(data, ':')
+		if colon <= 0 {
+			return ErrInvalidPacket
+		}
+		pkt.SetParam("name", string(data[:colon]))
+
+		fields := bytes.Split(data[colon+1:], []byte{'|'})
+		if len(fields) < 2 || len(fields[0]) == 0 || len(fields[1]) == 0 {
+			return ErrInvalidPacket
+		}
+		pkt.SetParam("value", string(fields[0]))
+		pkt.SetParam("type", string(fields[1]))
+
+		for _, f := range fields[2:] {
+			if len(f) < 2 {
+				continue
+			}
+			switch f[0] {
+			case '@':
+				pkt.SetParam("rate", string(f[1:]))
+			case '#':
+				pkt.SetParam("tags", string(f[1:]))
+			}
+		}
+
+		pkt.Route = string(fields[1])
+		pkt.Payload = data
+		return nil
+	})
+}
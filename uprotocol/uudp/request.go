@@ -0,0 +1,192 @@
+package uudp
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/whosafe/uf/uerror"
+	"github.com/whosafe/uf/uprotocol/ubind"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// 错误定义
+var (
+	// ErrNotSupported UDP 不支持该操作
+	ErrNotSupported = uerror.New("UDP 不支持该操作")
+)
+
+// requestPool Request 对象池
+var requestPool = sync.Pool{
+	New: func() any {
+		return &Request{
+			store:    make(map[string]any),
+			response: &Response{},
+		}
+	},
+}
+
+// Request UDP 数据报请求 (实现 unet.Request 接口)
+// 每个数据报对应一个 Request,处理器返回后回收,不可在处理器外持有
+type Request struct {
+	packet   Packet
+	remote   net.Addr
+	local    net.Addr
+	store    map[string]any
+	response *Response
+	server   *Server
+}
+
+// newRequest 创建新的 Request (从对象池获取)
+func newRequest(server *Server, remote net.Addr) *Request {
+	req := requestPool.Get().(*Request)
+	req.packet.reset()
+	req.remote = remote
+	req.local = server.localAddr()
+	// 清空 store
+	for k := range req.store {
+		delete(req.store, k)
+	}
+	req.response.reset(server, req)
+	req.server = server
+	return req
+}
+
+// release 释放 Request 回对象池
+func (r *Request) release() {
+	r.packet.Payload = nil
+	r.remote = nil
+	r.local = nil
+	r.server = nil
+	r.response.server = nil
+	r.response.request = nil
+	requestPool.Put(r)
+}
+
+// Protocol 获取协议类型
+func (r *Request) Protocol() unet.Protocol {
+	return unet.ProtocolUDP
+}
+
+// RemoteAddr 获取远程地址
+func (r *Request) RemoteAddr() net.Addr {
+	return r.remote
+}
+
+// LocalAddr 获取本地地址
+func (r *Request) LocalAddr() net.Addr {
+	return r.local
+}
+
+// Get 获取存储的值
+func (r *Request) Get(key string) (any, bool) {
+	val, ok := r.store[key]
+	return val, ok
+}
+
+// Set 设置存储的值
+func (r *Request) Set(key string, value any) {
+	r.store[key] = value
+}
+
+// Bind 绑定负载数据 (自动识别格式)
+func (r *Request) Bind(obj ubind.Binder) error {
+	val := ubind.Parse(r.packet.Payload)
+	return ubind.Bind(val, obj)
+}
+
+// Response 获取响应接口
+func (r *Request) Response() unet.Response {
+	return r.response
+}
+
+// Param 获取解码器生成的参数
+func (r *Request) Param(key string) string {
+	return r.packet.Params[key]
+}
+
+// Query UDP 无查询参数,始终返回空字符串
+func (r *Request) Query(key string) string {
+	return ""
+}
+
+// QueryDefault UDP 无查询参数,始终返回默认值
+func (r *Request) QueryDefault(key, def string) string {
+	return def
+}
+
+// Header UDP 无请求头,始终返回空字符串
+func (r *Request) Header(key string) string {
+	return ""
+}
+
+// Method 获取请求方法 (UDP 固定为 DATAGRAM)
+func (r *Request) Method() string {
+	return "DATAGRAM"
+}
+
+// Path 获取路由键
+func (r *Request) Path() string {
+	return r.packet.Route
+}
+
+// Body 获取负载
+// 返回的切片仅在处理器执行期间有效
+func (r *Request) Body() ([]byte, error) {
+	return r.packet.Payload, nil
+}
+
+// Cookie UDP 无 Cookie
+func (r *Request) Cookie(name string) (*http.Cookie, error) {
+	return nil, http.ErrNoCookie
+}
+
+// BindJSON 绑定 JSON 负载
+func (r *Request) BindJSON(obj ubind.Binder) error {
+	val := ubind.ParseJSON(r.packet.Payload)
+	return ubind.Bind(val, obj)
+}
+
+// BindForm 绑定 Form 负载
+func (r *Request) BindForm(obj ubind.Binder) error {
+	val := ubind.ParseForm(r.packet.Payload)
+	return ubind.Bind(val, obj)
+}
+
+// BindQuery UDP 无查询参数
+func (r *Request) BindQuery(obj ubind.Binder) error {
+	return ErrNotSupported
+}
+
+// URL 获取请求 URL (udp://本地地址/路由)
+func (r *Request) URL() *url.URL {
+	u := &url.URL{Scheme: "udp", Path: "/" + r.packet.Route}
+	if r.local != nil {
+		u.Host = r.local.String()
+	}
+	return u
+}
+
+// Session UDP 不支持 Session
+func (r *Request) Session() (unet.Session, error) {
+	return nil, ErrNotSupported
+}
+
+// Packet 获取解码后的数据报
+func (r *Request) Packet() *Packet {
+	return &r.packet
+}
+
+// Server 获取 Server
+func (r *Request) Server() *Server {
+	return r.server
+}
+
+// replyAddr 获取回复地址
+func (r *Request) replyAddr() net.Addr {
+	if r.packet.ReplyTo != nil {
+		return r.packet.ReplyTo
+	}
+	return r.remote
+}
@@ -0,0 +1,110 @@
+package uudp
+
+import (
+	"net/http"
+
+	"github.com/whosafe/uf/uerror"
+	"github.com/whosafe/uf/uprotocol/umarshal"
+)
+
+// 错误定义
+var (
+	// ErrReplyDisabled 未启用回复
+	ErrReplyDisabled = uerror.New("未启用 UDP 回复")
+)
+
+// Response UDP 响应 (实现 unet.Response 接口)
+// 每次写入发送一个数据报到回复地址,需要启用 Config.EnableReply
+type Response struct {
+	server     *Server
+	request    *Request
+	statusCode int
+	written    bool
+}
+
+// reset 重置响应
+func (r *Response) reset(server *Server, req *Request) {
+	r.server = server
+	r.request = req
+	r.statusCode = 0
+	r.written = false
+}
+
+// JSON 回复 JSON 数据报
+func (r *Response) JSON(code int, data any) error {
+	jsonData, err := umarshal.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return r.Bytes(code, jsonData)
+}
+
+// String 回复字符串数据报
+func (r *Response) String(code int, text string) error {
+	return r.Bytes(code, []byte(text))
+}
+
+// Bytes 回复字节数据报
+func (r *Response) Bytes(code int, data []byte) error {
+	r.statusCode = code
+	_, err := r.Write(data)
+	return err
+}
+
+// HTML 回复 HTML 数据报
+func (r *Response) HTML(code int, html string) error {
+	return r.Bytes(code, []byte(html))
+}
+
+// Redirect UDP 不支持重定向
+func (r *Response) Redirect(code int, url string) error {
+	return ErrNotSupported
+}
+
+// SetHeader UDP 无响应头,忽略
+func (r *Response) SetHeader(key, value string) {}
+
+// AddHeader UDP 无响应头,忽略
+func (r *Response) AddHeader(key, value string) {}
+
+// SetCookie UDP 无 Cookie,忽略
+func (r *Response) SetCookie(cookie *http.Cookie) {}
+
+// Status 设置状态码 (仅记录,不发送)
+func (r *Response) Status(code int) {
+	if !r.written {
+		r.statusCode = code
+	}
+}
+
+// Write 发送一个数据报 (实现 io.Writer)
+func (r *Response) Write(data []byte) (int, error) {
+	if !r.server.config.EnableReply {
+		return 0, ErrReplyDisabled
+	}
+	addr := r.request.replyAddr()
+	if addr == nil {
+		return 0, ErrReplyDisabled
+	}
+
+	n, err := r.server.conn.WriteTo(data, addr)
+	if err != nil {
+		return n, err
+	}
+	r.written = true
+	return n, nil
+}
+
+// StatusCode 获取状态码
+func (r *Response) StatusCode() int {
+	return r.statusCode
+}
+
+// IsWritten 检查是否已回复
+func (r *Response) IsWritten() bool {
+	return r.written
+}
+
+// SetSessionCookie UDP 无 Cookie,忽略
+func (r *Response) SetSessionCookie(name string, id string, path string, domain string, age int, secure bool, only bool, site http.SameSite) {
+}
@@ -0,0 +1,400 @@
+package uudp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uerror"
+	"github.com/whosafe/uf/ulogger"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// 错误定义
+var (
+	// ErrStreamListener UDP 不支持面向流的 net.Listener
+	ErrStreamListener = uerror.New("UDP 服务器不支持面向流的监听器,请使用 ServePacketConn")
+
+	// ErrServerRunning 服务器已在运行
+	ErrServerRunning = uerror.New("UDP 服务器已在运行")
+)
+
+// 编译期检查是否实现 unet.Server 接口
+var _ unet.Server = (*Server)(nil)
+
+// RouteAny 兜底路由,处理未匹配的数据报
+const RouteAny = "*"
+
+// Server UDP 数据报服务器 (实现 unet.Server 接口)
+// 读取 goroutine 将数据报放入队列,处理 goroutine 解码后按路由分发,
+// 每个数据报对应一个 Request
+type Server struct {
+	config      *Config
+	decoder     atomic.Pointer[Decoder] // 数据报解码器,处理 goroutine 无锁读取
+	logger      *ulogger.Logger
+	handlers    map[string]unet.HandlerFunc
+	middlewares []unet.MiddlewareFunc
+	routes      atomic.Pointer[routeTable] // 已应用中间件的路由表
+	mu          sync.Mutex
+
+	// 运行状态
+	conn    net.PacketConn
+	local   net.Addr
+	queue   chan *packetBuffer
+	bufPool sync.Pool
+	baseCtx context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+	closed  atomic.Bool
+
+	stats stats
+}
+
+// routeTable 路由表
+type routeTable struct {
+	handlers map[string]unet.HandlerFunc
+	fallback unet.HandlerFunc
+}
+
+// packetBuffer 数据报缓冲区
+type packetBuffer struct {
+	buf  []byte
+	n    int
+	addr net.Addr
+}
+
+// stats 统计计数
+type stats struct {
+	received      atomic.Uint64
+	dropped       atomic.Uint64
+	decodeErrors  atomic.Uint64
+	unrouted      atomic.Uint64
+	handlerErrors atomic.Uint64
+}
+
+// Stats 统计快照
+type Stats struct {
+	Received      uint64 // 已接收数据报数
+	Dropped       uint64 // 队列满被丢弃的数据报数
+	DecodeErrors  uint64 // 解码失败数
+	Unrouted      uint64 // 无匹配路由数
+	HandlerErrors uint64 // 处理器返回错误或 panic 数
+}
+
+// New 创建新的 UDP 服务器
+func New() *Server {
+	return NewWithConfig(GetConfig())
+}
+
+// NewWithConfig 使用配置创建 UDP 服务器
+// 使用 cfg 的副本,填充默认值不会修改调用方的配置
+func NewWithConfig(cfg *Config) *Server {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	c := *cfg
+	cfg = &c
+	if cfg.Readers <= 0 {
+		cfg.Readers = 1
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1
+	}
+	if cfg.MaxPacketSize <= 0 {
+		cfg.MaxPacketSize = 65535
+	}
+
+	s := &Server{
+		config:   cfg,
+		logger:   ulogger.Default(),
+		handlers: make(map[string]unet.HandlerFunc),
+	}
+	s.SetDecoder(RawDecoder())
+	s.bufPool.New = func() any {
+		return &packetBuffer{buf: make([]byte, cfg.MaxPacketSize)}
+	}
+	s.rebuild()
+
+	return s
+}
+
+// SetDecoder 设置数据报解码器
+func (s *Server) SetDecoder(decoder Decoder) {
+	s.decoder.Store(&decoder)
+}
+
+// SetLogger 设置日志 Logger
+func (s *Server) SetLogger(logger *ulogger.Logger) {
+	s.logger = logger
+}
+
+// Logger 获取日志 Logger
+func (s *Server) Logger() *ulogger.Logger {
+	return s.logger
+}
+
+// Start 启动服务器
+func (s *Server) Start(addr string) error {
+	if addr == "" {
+		addr = s.config.Address
+	}
+
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return uerror.Wrap(err, "UDP 监听失败")
+	}
+	defer conn.Close()
+
+	s.logger.Info("UDP 服务器启动", "addr", conn.LocalAddr().String())
+	return s.ServePacketConn(conn)
+}
+
+// Stop 停止服务器
+// 停止读取新数据报并等待队列中的数据报处理完成,ctx 超时后取消处理器上下文
+func (s *Server) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	conn, done, cancel := s.conn, s.done, s.cancel
+	s.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+
+	s.logger.Info("正在关闭 UDP 服务器...")
+	s.closed.Store(true)
+	// 唤醒阻塞在 ReadFrom 上的读取 goroutine
+	conn.SetReadDeadline(time.Now())
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		cancel()
+		return ctx.Err()
+	}
+}
+
+// Serve 处理连接 (阻塞)
+// UDP 无法使用 net.Listener,请使用 ServePacketConn
+func (s *Server) Serve(listener net.Listener) error {
+	return ErrStreamListener
+}
+
+// ServePacketConn 在已有的 UDP 连接上提供服务 (阻塞)
+func (s *Server) ServePacketConn(conn net.PacketConn) error {
+	s.mu.Lock()
+	if s.conn != nil {
+		s.mu.Unlock()
+		return ErrServerRunning
+	}
+	s.conn = conn
+	s.local = conn.LocalAddr()
+	s.queue = make(chan *packetBuffer, s.config.QueueSize)
+	s.baseCtx, s.cancel = context.WithCancel(context.Background())
+	s.done = make(chan struct{})
+	s.closed.Store(false)
+	s.mu.Unlock()
+
+	if udpConn, ok := conn.(*net.UDPConn); ok && s.config.ReadBufferSize > 0 {
+		if err := udpConn.SetReadBuffer(s.config.ReadBufferSize); err != nil {
+			s.logger.Warn("设置 UDP 接收缓冲区失败", "error", err)
+		}
+	}
+
+	// 启动处理 goroutine
+	var workers sync.WaitGroup
+	for i := 0; i < s.config.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			s.work()
+		}()
+	}
+
+	// 启动读取 goroutine
+	var readers sync.WaitGroup
+	errCh := make(chan error, s.config.Readers)
+	for i := 0; i < s.config.Readers; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			if err := s.read(conn); err != nil {
+				errCh <- err
+				// 读取失败时唤醒其他读取 goroutine
+				s.closed.Store(true)
+				conn.SetReadDeadline(time.Now())
+			}
+		}()
+	}
+
+	// 读取结束后关闭队列,等待剩余数据报处理完成
+	readers.Wait()
+	close(s.queue)
+	workers.Wait()
+
+	s.mu.Lock()
+	s.cancel()
+	close(s.done)
+	s.conn = nil
+	s.mu.Unlock()
+
+	select {
+	case err := <-errCh:
+		return uerror.Wrap(err, "读取 UDP 数据报失败")
+	default:
+		return nil
+	}
+}
+
+// Use 注册全局中间件
+func (s *Server) Use(middleware ...unet.MiddlewareFunc) {
+	s.mu.Lock()
+	s.middlewares = append(s.middlewares, middleware...)
+	s.mu.Unlock()
+	s.rebuild()
+}
+
+// Handle 注册处理器
+// pattern 匹配解码器生成的 Packet.Route,RouteAny ("*") 处理所有未匹配的数据报
+func (s *Server) Handle(pattern string, handler unet.HandlerFunc) {
+	if handler == nil {
+		panic("handler cannot be nil")
+	}
+	s.mu.Lock()
+	s.handlers[pattern] = handler
+	s.mu.Unlock()
+	s.rebuild()
+}
+
+// Stats 获取统计快照
+func (s *Server) Stats() Stats {
+	return Stats{
+		Received:      s.stats.received.Load(),
+		Dropped:       s.stats.dropped.Load(),
+		DecodeErrors:  s.stats.decodeErrors.Load(),
+		Unrouted:      s.stats.unrouted.Load(),
+		HandlerErrors: s.stats.handlerErrors.Load(),
+	}
+}
+
+// rebuild 重建路由表
+// 中间件在注册时一次性应用,避免每个数据报重复构建处理链
+func (s *Server) rebuild() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	table := &routeTable{handlers: make(map[string]unet.HandlerFunc, len(s.handlers))}
+	for pattern, handler := range s.handlers {
+		final := applyMiddlewares(handler, s.middlewares)
+		if pattern == RouteAny {
+			table.fallback = final
+			continue
+		}
+		table.handlers[pattern] = final
+	}
+	s.routes.Store(table)
+}
+
+// localAddr 获取本地地址
+func (s *Server) localAddr() net.Addr {
+	return s.local
+}
+
+// read 读取循环
+func (s *Server) read(conn net.PacketConn) error {
+	for {
+		pb := s.bufPool.Get().(*packetBuffer)
+		n, addr, err := conn.ReadFrom(pb.buf)
+		if err != nil {
+			s.bufPool.Put(pb)
+			if s.closed.Load() {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return err
+		}
+
+		s.stats.received.Add(1)
+		pb.n = n
+		pb.addr = addr
+
+		// 队列满时丢弃,避免阻塞读取导致内核缓冲区溢出
+		select {
+		case s.queue <- pb:
+		default:
+			s.stats.dropped.Add(1)
+			s.bufPool.Put(pb)
+		}
+	}
+}
+
+// work 处理循环
+func (s *Server) work() {
+	for pb := range s.queue {
+		s.handlePacket(pb)
+		pb.addr = nil
+		s.bufPool.Put(pb)
+	}
+}
+
+// handlePacket 处理单个数据报
+func (s *Server) handlePacket(pb *packetBuffer) {
+	req := newRequest(s, pb.addr)
+	defer req.release()
+
+	if err := (*s.decoder.Load()).Decode(pb.buf[:pb.n], &req.packet); err != nil {
+		s.stats.decodeErrors.Add(1)
+		s.logger.Debug("解码 UDP 数据报失败", "remote", pb.addr.String(), "error", err)
+		return
+	}
+
+	routes := s.routes.Load()
+	handler, ok := routes.handlers[req.packet.Route]
+	if !ok {
+		handler = routes.fallback
+	}
+	if handler == nil {
+		s.stats.unrouted.Add(1)
+		return
+	}
+
+	// 追踪信息按需生成,关闭时复用无追踪的上下文
+	var ctx *ucontext.Context
+	if s.config.EnableTrace {
+		ctx = ucontext.NewWithContext(ucontext.NewContext(s.baseCtx))
+	} else {
+		ctx = ucontext.NewWithoutTrace(s.baseCtx)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			s.stats.handlerErrors.Add(1)
+			s.logger.ErrorCtx(ctx.Context(), "Panic recovered", "error", fmt.Sprint(r), "route", req.packet.Route)
+		}
+	}()
+
+	if err := handler(ctx, req); err != nil {
+		s.stats.handlerErrors.Add(1)
+		s.logger.ErrorCtx(ctx.Context(), "处理数据报失败", "error", err, "route", req.packet.Route)
+	}
+}
+
+// applyMiddlewares 应用中间件链
+func applyMiddlewares(handler unet.HandlerFunc, middlewares []unet.MiddlewareFunc) unet.HandlerFunc {
+	// 从后往前应用中间件
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
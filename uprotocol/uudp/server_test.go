@@ -0,0 +1,279 @@
+package uudp
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/whosafe/uf/ucontext"
+	"github.com/whosafe/uf/uprotocol/unet"
+)
+
+// startTestServer 在回环地址上启动测试服务器
+func startTestServer(tb testing.TB, server *Server) (net.Addr, chan error) {
+	tb.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatalf("监听 UDP 失败: %v", err)
+	}
+	tb.Cleanup(func() { conn.Close() })
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.ServePacketConn(conn)
+	}()
+
+	return conn.LocalAddr(), done
+}
+
+// stopTestServer 停止测试服务器
+func stopTestServer(tb testing.TB, server *Server, done chan error) {
+	tb.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Stop(ctx); err != nil {
+		tb.Errorf("Stop failed: %v", err)
+	}
+	if err := <-done; err != nil {
+		tb.Errorf("ServePacketConn returned error: %v", err)
+	}
+}
+
+// dial 创建客户端连接
+func dial(tb testing.TB, addr net.Addr) *net.UDPConn {
+	tb.Helper()
+
+	conn, err := net.DialUDP("udp", nil, addr.(*net.UDPAddr))
+	if err != nil {
+		tb.Fatalf("连接 UDP 失败: %v", err)
+	}
+	tb.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// TestServerRouting 测试解码器路由与参数
+func TestServerRouting(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Workers = 2
+	server := NewWithConfig(cfg)
+	server.SetDecoder(StatsDDecoder())
+
+	received := make(chan string, 1)
+	server.Handle("c", func(ctx *ucontext.Context, req unet.Request) error {
+		if req.Protocol() != unet.ProtocolUDP {
+			t.Errorf("Expected protocol udp, got %s", req.Protocol())
+		}
+		if ctx.Trace() != nil {
+			t.Error("Trace should be nil when EnableTrace is false")
+		}
+		received <- req.Param("name") + "=" + req.Param("value")
+		return nil
+	})
+
+	addr, done := startTestServer(t, server)
+	client := dial(t, addr)
+
+	if _, err := client.Write([]byte("api.requests:1|c|@0.5")); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+
+	select {
+	case got := <-received:
+		if got != "api.requests=1" {
+			t.Errorf("Expected 'api.requests=1', got '%s'", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Handler was not called")
+	}
+
+	stopTestServer(t, server, done)
+}
+
+// TestServerReply 测试回复与追踪
+func TestServerReply(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.EnableReply = true
+	cfg.EnableTrace = true
+	server := NewWithConfig(cfg)
+	server.SetDecoder(PrefixDecoder(' '))
+
+	var middlewareCalls atomic.Int32
+	server.Use(func(next unet.HandlerFunc) unet.HandlerFunc {
+		return func(ctx *ucontext.Context, req unet.Request) error {
+			middlewareCalls.Add(1)
+			return next(ctx, req)
+		}
+	})
+	server.Handle("ping", func(ctx *ucontext.Context, req unet.Request) error {
+		if ctx.Trace() == nil {
+			t.Error("Trace should not be nil when EnableTrace is true")
+		}
+		body, _ := req.Body()
+		return req.Response().String(200, "pong "+string(body))
+	})
+
+	addr, done := startTestServer(t, server)
+	client := dial(t, addr)
+
+	if _, err := client.Write([]byte("ping hello")); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 64)
+	n, err := client.Read(buf)
+	if err != nil {
+		t.Fatalf("读取回复失败: %v", err)
+	}
+	if string(buf[:n]) != "pong hello" {
+		t.Errorf("Expected 'pong hello', got '%s'", buf[:n])
+	}
+	if middlewareCalls.Load() != 1 {
+		t.Errorf("Expected middleware to be called once, got %d", middlewareCalls.Load())
+	}
+
+	stopTestServer(t, server, done)
+}
+
+// TestServerFallbackAndErrors 测试兜底路由与统计
+func TestServerFallbackAndErrors(t *testing.T) {
+	server := NewWithConfig(DefaultConfig())
+	server.SetDecoder(StatsDDecoder())
+
+	handled := make(chan struct{}, 2)
+	server.Handle(RouteAny, func(ctx *ucontext.Context, req unet.Request) error {
+		handled <- struct{}{}
+		// 未启用回复时返回 ErrReplyDisabled
+		if err := req.Response().String(200, "ok"); err != ErrReplyDisabled {
+			t.Errorf("Expected ErrReplyDisabled, got %v", err)
+		}
+		panic("boom")
+	})
+
+	addr, done := startTestServer(t, server)
+	client := dial(t, addr)
+
+	client.Write([]byte("invalid"))
+	client.Write([]byte("mem.used:42|g"))
+
+	select {
+	case <-handled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Fallback handler was not called")
+	}
+
+	stopTestServer(t, server, done)
+
+	stats := server.Stats()
+	if stats.Received != 2 {
+		t.Errorf("Expected 2 received, got %d", stats.Received)
+	}
+	if stats.DecodeErrors != 1 {
+		t.Errorf("Expected 1 decode error, got %d", stats.DecodeErrors)
+	}
+	if stats.HandlerErrors != 1 {
+		t.Errorf("Expected 1 handler error, got %d", stats.HandlerErrors)
+	}
+}
+
+// TestServerServeListener 测试 Serve 拒绝流式监听器
+func TestServerServeListener(t *testing.T) {
+	server := NewWithConfig(DefaultConfig())
+	if err := server.Serve(nil); err != ErrStreamListener {
+		t.Errorf("Expected ErrStreamListener, got %v", err)
+	}
+}
+
+// benchmarkLoopback 回环性能测试
+func benchmarkLoopback(b *testing.B, enableTrace bool) {
+	cfg := DefaultConfig()
+	cfg.EnableTrace = enableTrace
+	cfg.QueueSize = 65536
+	server := NewWithConfig(cfg)
+	server.SetDecoder(StatsDDecoder())
+
+	var handled atomic.Int64
+	server.Handle(RouteAny, func(ctx *ucontext.Context, req unet.Request) error {
+		handled.Add(1)
+		return nil
+	})
+
+	addr, done := startTestServer(b, server)
+	client := dial(b, addr)
+	packet := []byte("api.latency:12|ms|@0.1|#env:prod")
+
+	b.SetBytes(int64(len(packet)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		client.Write(packet)
+	}
+
+	// 等待处理完成 (丢弃的数据报不计入)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		stats := server.Stats()
+		if uint64(handled.Load())+stats.Dropped >= stats.Received && stats.Received > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	b.StopTimer()
+
+	stopTestServer(b, server, done)
+	stats := server.Stats()
+	b.ReportMetric(float64(stats.Dropped), "dropped")
+}
+
+// BenchmarkServerLoopback 回环性能测试 (关闭追踪)
+func BenchmarkServerLoopback(b *testing.B) {
+	benchmarkLoopback(b, false)
+}
+
+// BenchmarkServerLoopbackTrace 回环性能测试 (开启追踪)
+func BenchmarkServerLoopbackTrace(b *testing.B) {
+	benchmarkLoopback(b, true)
+}
+
+// TestServerSetDecoder 测试运行中替换解码器,且不修改调用方的配置
+func TestServerSetDecoder(t *testing.T) {
+	cfg := &Config{}
+	server := NewWithConfig(cfg)
+	if cfg.Workers != 0 || cfg.MaxPacketSize != 0 {
+		t.Errorf("Expected caller config to be unchanged, got %+v", cfg)
+	}
+
+	received := make(chan string, 1)
+	server.Handle(RouteAny, func(ctx *ucontext.Context, req unet.Request) error {
+		received <- "any"
+		return nil
+	})
+	server.Handle("c", func(ctx *ucontext.Context, req unet.Request) error {
+		received <- "c"
+		return nil
+	})
+
+	addr, done := startTestServer(t, server)
+	client := dial(t, addr)
+
+	for _, want := range []string{"any", "c"} {
+		if _, err := client.Write([]byte("api.requests:1|c")); err != nil {
+			t.Fatalf("发送失败: %v", err)
+		}
+		select {
+		case got := <-received:
+			if got != want {
+				t.Errorf("Expected route %q, got %q", want, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Handler was not called")
+		}
+		server.SetDecoder(StatsDDecoder())
+	}
+
+	stopTestServer(t, server, done)
+}